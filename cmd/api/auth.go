@@ -1,23 +1,84 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/hex"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/ratelimit"
+)
+
+// apiKey is one accepted credential. limiter is nil for unlimited keys. id is
+// a digest of secret, safe to store alongside the data the key creates.
+type apiKey struct {
+	secret  []byte
+	id      string
+	limiter *ratelimit.TokenBucket
+}
+
+type apiKeyCtxKey struct{}
+
+// apiKeyID returns the id of the API key that authenticated r, or "" outside
+// requireAPIKey.
+func apiKeyID(r *http.Request) string {
+	id, _ := r.Context().Value(apiKeyCtxKey{}).(string)
+	return id
+}
+
+var (
+	apiKeysOnce sync.Once
+	apiKeys     []apiKey
 )
 
+// loadAPIKeys reads the accepted credentials once. API_KEYS lists them as
+// "key" or "key=N" with N requests per minute; keys without their own limit
+// get API_RATE_LIMIT (0, the default, means unlimited). The legacy single
+// API_SECRET_KEY is still accepted alongside them.
+func loadAPIKeys() []apiKey {
+	apiKeysOnce.Do(func() {
+		limits := ratelimit.ParseKeyLimits(config.List("API_KEYS"))
+		if legacy := strings.TrimSpace(os.Getenv("API_SECRET_KEY")); legacy != "" {
+			if _, ok := limits[legacy]; !ok {
+				limits[legacy] = 0
+			}
+		}
+		defaultLimit := config.Int("API_RATE_LIMIT", 0)
+		for secret, perMinute := range limits {
+			if perMinute == 0 {
+				perMinute = defaultLimit
+			}
+			sum := sha256.Sum256([]byte(secret))
+			apiKeys = append(apiKeys, apiKey{
+				secret:  []byte(secret),
+				id:      hex.EncodeToString(sum[:8]),
+				limiter: ratelimit.NewTokenBucket(perMinute),
+			})
+		}
+	})
+	return apiKeys
+}
+
 // requireAPIKey is middleware that validates the Bearer token in the
-// Authorization header before allowing a request through to the handler.
+// Authorization header and applies the matched key's rate limit before
+// allowing a request through to the handler.
 func requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		expectedKey := os.Getenv("API_SECRET_KEY")
+		keys := loadAPIKeys()
 
-		// Failsafe: lock down the server if the operator forgot to set the key.
+		// Failsafe: lock down the server if the operator forgot to set a key.
 		// Returning 500 rather than 401 makes it immediately obvious during
 		// deployment that this is a server misconfiguration, not a bad token.
-		if expectedKey == "" {
-			http.Error(w, "Server configuration error: API_SECRET_KEY not set", http.StatusInternalServerError)
+		if len(keys) == 0 {
+			http.Error(w, "Server configuration error: API_KEYS / API_SECRET_KEY not set", http.StatusInternalServerError)
 			return
 		}
 
@@ -28,12 +89,25 @@ func requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
 
 		// ConstantTimeCompare always examines every byte of both inputs before
 		// returning, so response latency carries no information about how many
-		// leading characters of the guess were correct.
-		if subtle.ConstantTimeCompare([]byte(token), []byte(expectedKey)) != 1 {
+		// leading characters of the guess were correct. Every key is compared,
+		// even after a match, so latency does not reveal which one matched.
+		var matched *apiKey
+		for i := range keys {
+			if subtle.ConstantTimeCompare([]byte(token), keys[i].secret) == 1 {
+				matched = &keys[i]
+			}
+		}
+		if matched == nil {
 			http.Error(w, `{"error": "Unauthorized: Invalid or missing API Key"}`, http.StatusUnauthorized)
 			return
 		}
 
-		next(w, r)
+		if ok, wait := matched.limiter.Allow(time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, `{"error": "Rate limit exceeded for this API Key"}`, http.StatusTooManyRequests)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), apiKeyCtxKey{}, matched.id)))
 	}
 }
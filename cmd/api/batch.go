@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"mailvetter/internal/store"
+	"mailvetter/internal/validator"
+)
+
+// maxBatchEmails bounds a synchronous batch; larger lists belong on /upload.
+const maxBatchEmails = 100
+
+type batchRequest struct {
+	Emails []string `json:"emails"`
+}
+
+// batchHandler verifies a small list of addresses synchronously. Results are
+// returned in the order submitted, one per input, so clients can zip them
+// back to their rows by position.
+//
+// Request body: {"emails": ["a@example.com", ...]}
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req batchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Malformed JSON body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Emails) == 0 {
+		http.Error(w, "Missing 'emails'", http.StatusBadRequest)
+		return
+	}
+	if len(req.Emails) > maxBatchEmails {
+		http.Error(w, fmt.Sprintf("At most %d emails per batch; use /upload for larger lists", maxBatchEmails), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// A full batch can outlast the server-wide WriteTimeout; give this
+	// response as long as the batch can take, plus time to write it.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(validator.BatchDuration(len(req.Emails)) + 10*time.Second)); err != nil {
+		log.Printf("⚠️  /verify/batch could not extend the write deadline: %v", err)
+	}
+
+	results := validator.VerifyBatch(r.Context(), req.Emails)
+
+	for _, res := range results {
+		if res.Error != "" || res.Reason == validator.ReasonMalformed || res.Cached {
+			continue
+		}
+		if err := store.RecordHistory(r.Context(), res.Email, string(res.Status), res.Score); err != nil {
+			log.Printf("⚠️  Failed to record history for %s: %v", res.Email, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Printf("❌ Error encoding /verify/batch response: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"mailvetter/internal/cache"
+)
+
+// cacheStatsHandler reports this API process's domain cache size and hit
+// rate, for tuning INFRA_CACHE_TTL, CATCHALL_CACHE_TTL and
+// NEGATIVE_CACHE_TTL. Workers keep caches of their own, which this does not
+// cover.
+func cacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cache.DomainCache.Stats()); err != nil {
+		log.Printf("❌ Error encoding /cache/stats response: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"mailvetter/internal/validator"
+)
+
+// catchAllHandler runs the ghost-probe logic for a single domain and reports
+// how the catch-all verdict was reached.
+//
+// Query parameters:
+//
+//	domain — domain to check (required)
+//	fresh  — "true" to bypass the smtp_host cache and always probe
+func catchAllHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	domain := r.URL.Query().Get("domain")
+	if domain == "" {
+		http.Error(w, "Missing 'domain' parameter", http.StatusBadRequest)
+		return
+	}
+	fresh := r.URL.Query().Get("fresh") == "true"
+
+	report, err := validator.CheckCatchAll(r.Context(), domain, fresh)
+	if err != nil && report.MX == "" {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil && r.Context().Err() != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusGatewayTimeout)
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("❌ Error encoding /catchall response for %s: %v", domain, err)
+	}
+}
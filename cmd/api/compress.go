@@ -0,0 +1,146 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipMinSize is the smallest response worth compressing; below it the gzip
+// header and trailer eat most of the saving.
+const gzipMinSize = 1400
+
+// gzipResponse compresses next's response when the client accepts gzip and
+// the body reaches gzipMinSize. It goes innermost, inside requireAPIKey, so
+// auth and rate-limit errors are written untouched:
+//
+//	enableCORS(requireAPIKey(gzipResponse(handler)))
+func gzipResponse(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next(w, r)
+			return
+		}
+
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.Close()
+		next(gw, r)
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip: named
+// explicitly, or covered by "*", with a non-zero q.
+func acceptsGzip(header string) bool {
+	star := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		ok := true
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if q, err := strconv.ParseFloat(v, 64); strings.EqualFold(k, "q") && err == nil && q == 0 {
+				ok = false
+			}
+		}
+		if name == "gzip" {
+			return ok
+		}
+		star = ok
+	}
+	return star
+}
+
+// gzipWriter holds back the status and the first gzipMinSize bytes of a
+// response, then commits to compressing it or — if the handler finishes
+// first — writes it as is.
+type gzipWriter struct {
+	http.ResponseWriter
+	status    int
+	buf       []byte
+	gz        *gzip.Writer
+	committed bool
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	if g.status == 0 {
+		g.status = code
+	}
+}
+
+func (g *gzipWriter) Write(p []byte) (int, error) {
+	if !g.committed {
+		g.buf = append(g.buf, p...)
+		if len(g.buf) < gzipMinSize {
+			return len(p), nil
+		}
+		if err := g.commit(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+	return g.ResponseWriter.Write(p)
+}
+
+// commit sends the held-back status and bytes, compressed if compress is set
+// and the handler has not chosen an encoding of its own.
+func (g *gzipWriter) commit(compress bool) error {
+	g.committed = true
+	h := g.Header()
+	if compress && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := g.Write(buf)
+	return err
+}
+
+// Flush commits early, so streamed responses are not held back, then pushes
+// out whatever the compressor has buffered.
+func (g *gzipWriter) Flush() {
+	if !g.committed {
+		g.commit(len(g.buf) > 0)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the response; a body still under gzipMinSize goes out
+// uncompressed.
+func (g *gzipWriter) Close() error {
+	if !g.committed {
+		return g.commit(false)
+	}
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
+
+// Unwrap lets http.NewResponseController reach the underlying writer, e.g.
+// for /export lifting its write deadline.
+func (g *gzipWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"mailvetter/internal/export"
+	"mailvetter/internal/store"
+)
+
+// exportHandler streams every result of a job as a CSV download with the
+// columns email, score, status, reachability.
+//
+// Query parameters:
+//
+//	id — job UUID (required)
+//
+// Rows are written straight from the database cursor as they are read, so
+// memory use is constant regardless of job size.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	var found int
+	err := store.DB.QueryRow(ctx, `SELECT 1 FROM jobs WHERE id = $1`, jobID).Scan(&found)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to look up job %s for /export: %v", jobID, err)
+		http.Error(w, "Failed to look up job", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := store.DB.Query(ctx, `
+		SELECT email, score, data
+		FROM   results
+		WHERE  job_id = $1
+		ORDER  BY id ASC
+	`, jobID)
+	if err != nil {
+		http.Error(w, "Failed to fetch results", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	src := func(yield func(export.Row) error) error {
+		for rows.Next() {
+			var row export.Row
+			if err := rows.Scan(&row.Email, &row.Score, &row.Data); err != nil {
+				return err
+			}
+			if err := yield(row); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}
+
+	// A large job takes longer to stream than the server-wide WriteTimeout
+	// allows; the client disconnecting still cancels ctx and the query.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("⚠️  /export could not lift the write deadline for job %s: %v", jobID, err)
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="mailvetter-%s.csv"`, jobID))
+
+	// Headers are already sent once the first row is written, so a failure
+	// mid-stream can only be logged; the client sees a truncated file.
+	if err := export.Write(w, export.FormatCSV, src); err != nil {
+		log.Printf("❌ Error streaming /export for job %s: %v", jobID, err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"mailvetter/internal/store"
+)
+
+// HistoryResponse is an address's verdict history plus its summary.
+type HistoryResponse struct {
+	Email string `json:"email"`
+	store.HistorySummary
+	Entries []store.HistoryEntry `json:"entries"`
+}
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// historyHandler returns the recorded verdicts for a single address.
+//
+// Query parameters:
+//
+//	email — address to look up (required)
+//	limit — most recent entries to return (default: 100, max: 1000)
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !store.HistoryEnabled {
+		http.Error(w, "History is disabled (set HISTORY_ENABLED=true)", http.StatusNotFound)
+		return
+	}
+
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		http.Error(w, "Missing 'email' parameter", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	entries, err := store.FetchHistory(r.Context(), email, limit)
+	if err != nil {
+		http.Error(w, "Failed to fetch history", http.StatusInternalServerError)
+		return
+	}
+
+	// entries is capped at limit; the summary covers the whole history.
+	summary, err := store.SummarizeHistory(r.Context(), email)
+	if err != nil {
+		http.Error(w, "Failed to fetch history", http.StatusInternalServerError)
+		return
+	}
+
+	resp := HistoryResponse{
+		Email:          store.NormalizeEmail(email),
+		HistorySummary: summary,
+		Entries:        entries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
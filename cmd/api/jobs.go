@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"mailvetter/internal/store"
+)
+
+// CancelJobResponse reports a cancelled job and how many of its emails will
+// not be verified. Remaining includes tasks a worker had already picked up;
+// those finish normally.
+type CancelJobResponse struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	Remaining int    `json:"remaining"`
+}
+
+// cancelJobHandler stops a queued job. Workers skip every task of a cancelled
+// job that they have not already started.
+//
+// Query parameters:
+//
+//	id — job UUID (required)
+func cancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+		return
+	}
+
+	remaining, err := store.CancelJob(r.Context(), jobID)
+	switch {
+	case errors.Is(err, store.ErrJobNotFound):
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, store.ErrJobFinished):
+		http.Error(w, "Job already completed", http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("❌ Failed to cancel job %s: %v", jobID, err)
+		http.Error(w, "Failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🛑 Job %s cancelled (%d emails not yet processed)", jobID, remaining)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CancelJobResponse{ID: jobID, Status: store.JobStatusCancelled, Remaining: remaining})
+}
@@ -14,8 +14,12 @@ import (
 	"time"
 
 	"mailvetter/internal/cache"
+	"mailvetter/internal/config"
+	"mailvetter/internal/lookup"
+	"mailvetter/internal/metrics"
 	"mailvetter/internal/proxy"
 	"mailvetter/internal/queue"
+	"mailvetter/internal/ratelimit"
 	"mailvetter/internal/store"
 	"mailvetter/internal/validator"
 )
@@ -32,6 +36,13 @@ func main() {
 	}
 	fmt.Println("✅ Connected to Redis Queue")
 
+	// Fleet-wide per-provider SMTP caps share the Redis instance above so
+	// that every process (API and workers) draws from the same slot pool.
+	if caps := ratelimit.ParseCaps(config.List("SMTP_PROVIDER_CAPS")); len(caps) > 0 {
+		ratelimit.InitFleet(queue.Client, caps)
+		fmt.Printf("🚦 Fleet-wide SMTP caps enabled: %v\n", caps)
+	}
+
 	// 2. Initialize Database
 	dbURL := os.Getenv("DB_URL")
 	if dbURL == "" {
@@ -44,10 +55,9 @@ func main() {
 	fmt.Println("✅ Connected to PostgreSQL & Migrations Applied")
 
 	// 3. Initialize Proxy Manager
-	proxyListRaw := os.Getenv("PROXY_LIST")
-	if proxyListRaw != "" {
-		proxies := strings.Split(proxyListRaw, ",")
-
+	// PROXY_LIST, or the standard HTTPS_PROXY/HTTP_PROXY/ALL_PROXY variables
+	// when it is unset. Hosts in NO_PROXY are always reached directly.
+	if proxies := proxy.ListFromEnv(); len(proxies) > 0 {
 		proxyLimitStr := os.Getenv("PROXY_CONCURRENCY")
 		proxyLimit, err := strconv.Atoi(proxyLimitStr)
 		if err != nil || proxyLimit <= 0 {
@@ -62,6 +72,11 @@ func main() {
 			log.Fatalf("❌ Failed to initialize proxy manager: %v", err)
 		}
 
+		if trusted := config.List("PROXY_TRUSTED"); len(trusted) > 0 {
+			n := proxy.Global.SetTrusted(trusted)
+			fmt.Printf("🛡️  %d of %d PROXY_TRUSTED entries matched (lenient health gating)\n", n, len(trusted))
+		}
+
 		fmt.Printf("🛡️  Proxy rotation enabled (%d proxies loaded, max %d concurrent HTTP)\n", len(proxies), cap(proxy.Semaphore))
 		if smtpProxyEnabled {
 			fmt.Println("⚠️  SMTP Proxying is ENABLED (Port 25 traffic will route through proxies)")
@@ -84,13 +99,54 @@ func main() {
 	cache.StartCleanup(ctx, 5*time.Minute)
 	fmt.Println("✅ Cache eviction goroutine started (interval: 5m)")
 
+	// Probe every proxy in the background; failing ones leave rotation
+	// until a later check passes.
+	if proxy.Enabled() {
+		proxy.StartHealthChecks(ctx, proxy.HealthCheckInterval)
+		fmt.Printf("✅ Proxy health checks started (interval: %s)\n", proxy.HealthCheckInterval)
+	}
+
+	// Extend the built-in disposable-domain list from a local file and/or a
+	// URL refreshed in the background.
+	if path := config.String("DISPOSABLE_LIST_PATH", ""); path != "" {
+		if err := lookup.LoadDisposableList(path); err != nil {
+			fmt.Printf("⚠️  Failed to load disposable list %s: %v\n", path, err)
+		}
+	}
+	if url := config.String("DISPOSABLE_LIST_URL", ""); url != "" {
+		interval := config.Duration("DISPOSABLE_LIST_REFRESH", 24*time.Hour)
+		lookup.StartDisposableRefresh(ctx, url, interval)
+		fmt.Printf("✅ Disposable list refresh enabled (interval: %s)\n", interval)
+	}
+
+	// Look up our outbound IPs on the DNSBLs in the background; a listing
+	// is the usual cause of SMTP servers rejecting probes wholesale.
+	if config.Bool("DNSBL_SELFCHECK", true) {
+		go lookup.LogDNSBLSelfCheck(ctx)
+	}
+
 	// 6. Define Handlers
 	mux := http.NewServeMux()
 	mux.HandleFunc("/verify", enableCORS(requireAPIKey(verifyHandler)))
+	mux.HandleFunc("/verify/batch", enableCORS(requireAPIKey(gzipResponse(batchHandler))))
+	mux.HandleFunc("/score", enableCORS(requireAPIKey(scoreHandler)))
 	mux.HandleFunc("/upload", enableCORS(requireAPIKey(uploadHandler)))
 	mux.HandleFunc("/status", enableCORS(requireAPIKey(statusHandler)))
-	mux.HandleFunc("/results", enableCORS(requireAPIKey(resultsHandler)))
+	mux.HandleFunc("/status/stream", enableCORS(requireAPIKey(statusStreamHandler)))
+	mux.HandleFunc("/jobs/cancel", enableCORS(requireAPIKey(cancelJobHandler)))
+	mux.HandleFunc("/results", enableCORS(requireAPIKey(gzipResponse(resultsHandler))))
+	mux.HandleFunc("/result", enableCORS(requireAPIKey(resultHandler)))
+	mux.HandleFunc("/results/lookup", enableCORS(requireAPIKey(gzipResponse(resultsLookupHandler))))
+	mux.HandleFunc("/export", enableCORS(requireAPIKey(gzipResponse(exportHandler))))
+	mux.HandleFunc("/catchall", enableCORS(requireAPIKey(catchAllHandler)))
+	mux.HandleFunc("/history", enableCORS(requireAPIKey(historyHandler)))
+	mux.HandleFunc("/workers", enableCORS(requireAPIKey(workersHandler)))
+	mux.HandleFunc("/proxies", enableCORS(requireAPIKey(proxiesHandler)))
+	mux.HandleFunc("/selfcheck", enableCORS(requireAPIKey(selfCheckHandler)))
+	mux.HandleFunc("/cache/stats", enableCORS(requireAPIKey(cacheStatsHandler)))
+	mux.HandleFunc("/admin/trace", enableCORS(requireAPIKey(traceHandler)))
 	mux.HandleFunc("/info", enableCORS(infoHandler))
+	mux.HandleFunc("/metrics", metrics.Handler)
 	mux.Handle("/", http.FileServer(http.Dir("./static")))
 
 	// 7. Server Configuration
@@ -166,6 +222,21 @@ func verifyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	domain := parts[1]
 
+	// raw=true returns the collected signals without a verdict, for callers
+	// that apply their own scoring model. No history is recorded.
+	if r.URL.Query().Get("raw") == "true" {
+		raw, err := validator.VerifyRaw(r.Context(), email, domain)
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil && r.Context().Err() != nil {
+			raw.Error = err.Error()
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+		if err := json.NewEncoder(w).Encode(raw); err != nil {
+			log.Printf("❌ Error encoding raw /verify response for %s: %v", email, err)
+		}
+		return
+	}
+
 	start := time.Now()
 	result, err := validator.VerifyEmail(r.Context(), email, domain)
 	result.Duration = time.Since(start).String()
@@ -180,6 +251,12 @@ func verifyHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err == nil && !result.Cached {
+		if err := store.RecordHistory(r.Context(), email, string(result.Status), result.Score); err != nil {
+			log.Printf("⚠️  Failed to record history for %s: %v", email, err)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(result); err != nil {
 		log.Printf("❌ Error encoding /verify response for %s: %v", email, err)
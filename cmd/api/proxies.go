@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"mailvetter/internal/proxy"
+)
+
+// ProxiesResponse reports this process's proxy pool. Each API and worker
+// process health-checks its own copy of the pool, so this reflects the API's
+// view; workers reach the same proxies from their own hosts.
+type ProxiesResponse struct {
+	Total   int            `json:"total"`
+	Healthy int            `json:"healthy"`
+	Proxies []proxy.Status `json:"proxies"`
+}
+
+// proxiesHandler reports every proxy's health-check state and whether it is
+// currently in rotation.
+func proxiesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses := proxy.Global.Statuses()
+	if statuses == nil {
+		statuses = []proxy.Status{}
+	}
+	resp := ProxiesResponse{Total: len(statuses), Proxies: statuses}
+	for _, s := range statuses {
+		if s.Healthy {
+			resp.Healthy++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -2,28 +2,28 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 
+	"mailvetter/internal/models"
 	"mailvetter/internal/store"
+	"mailvetter/internal/validator"
 )
 
-// ResultRow represents a single verified email row returned by the API.
-type ResultRow struct {
-	Email string          `json:"email"`
-	Score int             `json:"score"`
-	Data  json.RawMessage `json:"data"`
-}
-
 // ResultsPage wraps a page of results with metadata the client needs to
-// paginate without making a separate count query.
+// paginate without making a separate count query. NextAfterID, when set, is
+// the cursor for the following page.
 type ResultsPage struct {
-	JobID      string      `json:"job_id"`
-	Page       int         `json:"page"`
-	PageSize   int         `json:"page_size"`
-	TotalCount int         `json:"total_count"`
-	HasMore    bool        `json:"has_more"`
-	Results    []ResultRow `json:"results"`
+	JobID       string            `json:"job_id"`
+	Page        int               `json:"page,omitempty"`
+	PageSize    int               `json:"page_size"`
+	TotalCount  int               `json:"total_count"`
+	HasMore     bool              `json:"has_more"`
+	NextAfterID int64             `json:"next_after_id,omitempty"`
+	Results     []store.ResultRow `json:"results"`
 }
 
 const (
@@ -36,12 +36,17 @@ const (
 // Query parameters:
 //
 //	id        — job UUID (required)
-//	page      — 1-based page number (default: 1)
+//	after_id  — cursor: return rows after this one (the previous page's next_after_id)
+//	after     — alias for after_id
+//	page      — 1-based page number, ignored with after_id (default: 1)
 //	page_size — rows per page (default: 500, max: 2000)
+//	status    — only rows with this verdict, e.g. "catch_all" (optional)
+//	min_score — only rows scoring at least this much (optional)
 //
-// The composite index idx_results_job_id_id added in the issue #5 fix means
-// the LIMIT/OFFSET query is resolved entirely via index scan — no sort step,
-// no sequential scan, constant memory on the server side regardless of job size.
+// Prefer after_id for walking large jobs: it seeks directly into the
+// (job_id, id) index, so every page costs the same, whereas page N is a
+// LIMIT/OFFSET query that scans and discards all earlier rows. Both modes
+// return next_after_id, so a client can switch to cursors after any page.
 func resultsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -54,9 +59,26 @@ func resultsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Parse after_id (or its alias after); when present it takes precedence
+	// over page.
+	cursorParam := "after_id"
+	if !r.URL.Query().Has(cursorParam) && r.URL.Query().Has("after") {
+		cursorParam = "after"
+	}
+	var afterID int64
+	if a := r.URL.Query().Get(cursorParam); a != "" {
+		parsed, err := strconv.ParseInt(a, 10, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, fmt.Sprintf("Invalid '%s' parameter", cursorParam), http.StatusBadRequest)
+			return
+		}
+		afterID = parsed
+	}
+	keyset := r.URL.Query().Has(cursorParam)
+
 	// Parse page (1-based).
 	page := 1
-	if p := r.URL.Query().Get("page"); p != "" {
+	if p := r.URL.Query().Get("page"); p != "" && !keyset {
 		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
 			page = parsed
 		}
@@ -73,7 +95,29 @@ func resultsHandler(w http.ResponseWriter, r *http.Request) {
 		pageSize = maxPageSize
 	}
 
-	offset := (page - 1) * pageSize
+	q := store.ResultsQuery{JobID: jobID, AfterID: afterID, Limit: pageSize}
+	if !keyset {
+		q.Offset = (page - 1) * pageSize
+	}
+
+	// Optional verdict filters.
+	if status := r.URL.Query().Get("status"); status != "" {
+		if !validStatus(status) {
+			http.Error(w, "Invalid 'status' parameter", http.StatusBadRequest)
+			return
+		}
+		q.Status = status
+	}
+	if ms := r.URL.Query().Get("min_score"); ms != "" {
+		minScore, err := strconv.Atoi(ms)
+		if err != nil {
+			http.Error(w, "Invalid 'min_score' parameter", http.StatusBadRequest)
+			return
+		}
+		q.MinScore = &minScore
+	}
+	filtered := q.Status != "" || q.MinScore != nil
+
 	ctx := r.Context()
 
 	// Fetch total_count from the jobs table so we can populate has_more and
@@ -89,44 +133,171 @@ func resultsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch exactly one page of results using the composite index
-	// (job_id, id) added in the issue #5 fix. The index satisfies both the
-	// WHERE clause and the ORDER BY in a single scan with no sort step.
-	rows, err := store.DB.Query(ctx, `
-		SELECT email, score, data
-		FROM   results
-		WHERE  job_id = $1
-		ORDER  BY id ASC
-		LIMIT  $2
-		OFFSET $3
-	`, jobID, pageSize, offset)
+	// (job_id, id) added in the issue #5 fix, or (job_id, status, id) when
+	// filtering by status. Either satisfies both the WHERE clause and the
+	// ORDER BY in a single scan with no sort step.
+	results, err := store.FetchResults(ctx, q)
 	if err != nil {
+		log.Printf("❌ Failed to fetch results for job %s: %v", jobID, err)
 		http.Error(w, "Failed to fetch results", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	results := make([]ResultRow, 0, pageSize)
-	for rows.Next() {
-		var row ResultRow
-		if err := rows.Scan(&row.Email, &row.Score, &row.Data); err != nil {
-			continue
-		}
-		results = append(results, row)
-	}
-	if err := rows.Err(); err != nil {
-		http.Error(w, "Error reading results", http.StatusInternalServerError)
-		return
+	// total_count counts the whole job, so a filtered or cursor page can only
+	// tell there may be more by coming back full.
+	hasMore := q.Offset+len(results) < totalCount
+	if filtered || keyset {
+		hasMore = len(results) == pageSize
 	}
 
 	resp := ResultsPage{
 		JobID:      jobID,
-		Page:       page,
 		PageSize:   pageSize,
 		TotalCount: totalCount,
-		HasMore:    offset+len(results) < totalCount,
+		HasMore:    hasMore,
 		Results:    results,
 	}
+	if !keyset {
+		resp.Page = page
+	}
+	if hasMore && len(results) > 0 {
+		resp.NextAfterID = results[len(results)-1].ID
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+// maxLookupEmails bounds one /results/lookup request; larger extractions
+// belong on /export.
+const maxLookupEmails = 1000
+
+type resultsLookupRequest struct {
+	Emails []string `json:"emails"`
+}
+
+// ResultsLookup answers a /results/lookup request: one row per requested
+// address, in the order submitted, with found=false for misses.
+type ResultsLookup struct {
+	JobID   string            `json:"job_id"`
+	Results []store.LookupRow `json:"results"`
+}
+
+// resultsLookupHandler returns a job's results for a caller-provided subset
+// of addresses, without paging through the whole job.
+//
+// Query parameters:
+//
+//	id — job UUID (required)
+//
+// Request body: {"emails": ["a@example.com", ...]}
+func resultsLookupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+		return
+	}
+
+	var req resultsLookupRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Malformed JSON body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Emails) == 0 {
+		http.Error(w, "Missing 'emails'", http.StatusBadRequest)
+		return
+	}
+	if len(req.Emails) > maxLookupEmails {
+		http.Error(w, fmt.Sprintf("At most %d emails per lookup; use /export for whole jobs", maxLookupEmails), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ctx := r.Context()
+	var exists bool
+	if err := store.DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM jobs WHERE id = $1)`, jobID).Scan(&exists); err != nil || !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := store.LookupResults(ctx, jobID, req.Emails)
+	if err != nil {
+		log.Printf("❌ Failed to look up results for job %s: %v", jobID, err)
+		http.Error(w, "Failed to fetch results", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ResultsLookup{JobID: jobID, Results: rows})
+}
+
+// resultHandler returns the most recent stored result for one address, from
+// whichever job verified it last.
+//
+// Query parameters:
+//
+//	email   — address to look up (required, case-insensitive)
+//	rescore — "true" scores the stored analysis again under the current
+//	          scoring config; a row stored verdict-only gets 409
+//
+// Rescoring reads the stored signals only: nothing is probed and the stored
+// row is left as it was.
+func resultHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		http.Error(w, "Missing 'email' parameter", http.StatusBadRequest)
+		return
+	}
+
+	result, err := store.LatestResult(r.Context(), email)
+	if errors.Is(err, store.ErrResultNotFound) {
+		http.Error(w, "No stored result for this email", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to look up the stored result for %s: %v", email, err)
+		http.Error(w, "Failed to fetch result", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("rescore") == "true" {
+		rescored, err := validator.Rescore(result.Data)
+		if errors.Is(err, validator.ErrVerdictOnly) {
+			http.Error(w, "Stored result has no analysis to rescore (stored with STORE_FULL_ANALYSIS=false)", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("❌ Failed to rescore the stored result for %s: %v", email, err)
+			http.Error(w, "Failed to rescore result", http.StatusInternalServerError)
+			return
+		}
+		data, err := json.Marshal(rescored)
+		if err != nil {
+			log.Printf("❌ Failed to encode the rescored result for %s: %v", email, err)
+			http.Error(w, "Failed to rescore result", http.StatusInternalServerError)
+			return
+		}
+		result.Score, result.Status, result.Data = rescored.Score, string(rescored.Status), data
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// validStatus reports whether s is one of the verdicts the worker stores.
+func validStatus(s string) bool {
+	switch models.VerificationStatus(s) {
+	case models.StatusValid, models.StatusInvalid, models.StatusRisky, models.StatusCatchAll, models.StatusUnknown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"mailvetter/internal/validator"
+)
+
+// scoreHandler scores a caller-supplied RiskAnalysis without running any
+// probes — the counterpart to /verify?raw=true for callers that collect or
+// store signals themselves.
+//
+// Request body: a RiskAnalysis object, e.g. {"smtp_status": 250, "has_spf": true}
+func scoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	analysis, err := validator.DecodeAnalysis(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(validator.ScoreAnalysis(analysis)); err != nil {
+		log.Printf("❌ Error encoding /score response: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"mailvetter/internal/lookup"
+)
+
+// SelfCheckResponse reports whether this instance's outbound IPs are on the
+// blocklists SMTP servers consult.
+type SelfCheckResponse struct {
+	Zones    []string             `json:"zones"`
+	Outbound []lookup.DNSBLReport `json:"outbound"`
+	Error    string               `json:"error,omitempty"`
+}
+
+// selfCheckHandler runs the DNSBL self-check on demand. It is diagnostic
+// only: a listed IP explains SMTP rejections but does not stop probing.
+func selfCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	resp := SelfCheckResponse{Zones: lookup.DNSBLZones, Outbound: []lookup.DNSBLReport{}}
+	if reports, err := lookup.SelfCheckDNSBL(ctx); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Outbound = reports
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("❌ Error encoding /selfcheck response: %v", err)
+	}
+}
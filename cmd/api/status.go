@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
+	"mailvetter/internal/queue"
 	"mailvetter/internal/store"
 )
 
@@ -15,6 +19,10 @@ type JobStatusResponse struct {
 	ProcessedCount int        `json:"processed_count"`
 	CreatedAt      time.Time  `json:"created_at"`
 	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	ExportStatus   *string    `json:"export_status,omitempty"`
+	// DeadLetterDepth is the fleet-wide number of tasks that exhausted their
+	// retries (see worker.MaxTaskRetries); omitted if Redis cannot be read.
+	DeadLetterDepth *int64 `json:"dead_letter_depth,omitempty"`
 }
 
 func statusHandler(w http.ResponseWriter, r *http.Request) {
@@ -30,10 +38,26 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	job, err := fetchJobStatus(ctx, jobID)
+	if err != nil {
+		// If no rows found, it means the ID is wrong
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	if depth, err := queue.DeadDepth(ctx); err == nil {
+		job.DeadLetterDepth = &depth
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func fetchJobStatus(ctx context.Context, jobID string) (JobStatusResponse, error) {
 	var job JobStatusResponse
 
 	query := `
-		SELECT id, status, total_count, processed_count, created_at, completed_at 
+		SELECT id, status, total_count, processed_count, created_at, completed_at, export_status
 		FROM jobs 
 		WHERE id = $1
 	`
@@ -45,14 +69,83 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 		&job.ProcessedCount,
 		&job.CreatedAt,
 		&job.CompletedAt,
+		&job.ExportStatus,
 	)
+	return job, err
+}
+
+// statusStreamInterval is how often /status/stream reports progress.
+const statusStreamInterval = 1 * time.Second
+
+// statusStreamHandler streams a job's progress as Server-Sent Events: a
+// "progress" event with the /status body about once per second, then a final
+// "completed" (or "cancelled") event, after which the stream closes. The
+// loop ends as soon as the client disconnects.
+//
+// EventSource cannot send an Authorization header, so browsers read the
+// stream with fetch() instead.
+func statusStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "Missing 'id' parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	job, err := fetchJobStatus(ctx, jobID)
 	if err != nil {
-		// If no rows found, it means the ID is wrong
 		http.Error(w, "Job not found", http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(job)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	// The server's WriteTimeout would cut the stream off mid-job, so each
+	// event gets a deadline of its own instead.
+	rc := http.NewResponseController(w)
+	send := func(event string, job JobStatusResponse) error {
+		rc.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		data, _ := json.Marshal(job)
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	ticker := time.NewTicker(statusStreamInterval)
+	defer ticker.Stop()
+
+	for {
+		switch job.Status {
+		case "completed", store.JobStatusCancelled:
+			send(job.Status, job)
+			return
+		}
+		if err := send("progress", job); err != nil {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if job, err = fetchJobStatus(ctx, jobID); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("⚠️  /status/stream for job %s: %v", jobID, err)
+				fmt.Fprintf(w, "event: error\ndata: {\"error\":\"failed to read job status\"}\n\n")
+				rc.Flush()
+			}
+			return
+		}
+	}
 }
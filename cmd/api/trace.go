@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"mailvetter/internal/validator"
+)
+
+type traceRequest struct {
+	Email string `json:"email"`
+}
+
+// traceHandler replays one address through VerifyEmail with every diagnostic
+// on: SMTP transcript, MX detail, per-probe timings, cache provenance and the
+// probes that were skipped and why. It is the one-stop answer to "what
+// exactly happened for this address?".
+//
+// Request body: {"email": "jane@example.com"}
+func traceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req traceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Body must be JSON with an 'email' field", http.StatusBadRequest)
+		return
+	}
+
+	trace, err := validator.TraceEmail(r.Context(), req.Email)
+	if trace == nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil && r.Context().Err() != nil {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}
+	if err := json.NewEncoder(w).Encode(trace); err != nil {
+		log.Printf("❌ Error encoding /admin/trace response for %s: %v", req.Email, err)
+	}
+}
@@ -1,25 +1,40 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"time"
+	"strings"
 
+	"mailvetter/internal/export"
+	"mailvetter/internal/outbound"
 	"mailvetter/internal/queue"
 	"mailvetter/internal/store"
+	"mailvetter/internal/upload"
+	"mailvetter/internal/validator"
+	"mailvetter/internal/webhook"
 
 	"github.com/google/uuid"
 )
 
+// UploadResponse reports the created job. TotalRows counts the addresses in
+// the file; UniqueRows the ones left after dropping repeats, which is what
+// the job verifies and its total_count.
 type UploadResponse struct {
-	JobID     string `json:"job_id"`
-	TotalRows int    `json:"total_rows"`
-	Message   string `json:"message"`
+	JobID      string `json:"job_id"`
+	TotalRows  int    `json:"total_rows"`
+	UniqueRows int    `json:"unique_rows"`
+	Message    string `json:"message"`
 }
 
+// maxIdempotencyKey bounds the Idempotency-Key header; clients normally send
+// a UUID.
+const maxIdempotencyKey = 255
+
+// uploadHandler creates a job from an uploaded list of addresses. A client
+// that may retry the upload sends an Idempotency-Key header; a repeat within
+// store.IdempotencyTTL answers with the job already created.
 func uploadHandler(w http.ResponseWriter, r *http.Request) {
 	// 1. Only allow POST
 	if r.Method != http.MethodPost {
@@ -27,64 +42,110 @@ func uploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	idempotencyKey := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	if len(idempotencyKey) > maxIdempotencyKey {
+		http.Error(w, fmt.Sprintf("'Idempotency-Key' longer than %d characters", maxIdempotencyKey), http.StatusBadRequest)
+		return
+	}
+
 	// 2. Parse Multipart Form (Max 10MB)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
 		http.Error(w, "File too large or malformed", http.StatusBadRequest)
 		return
 	}
 
-	file, _, err := r.FormFile("file")
+	file, header, err := r.FormFile("file")
 	if err != nil {
 		http.Error(w, "Missing 'file' parameter", http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
 
-	// 3. Read CSV
-	reader := csv.NewReader(file)
-	var emails []string
-	isFirstRow := true
+	// 3. Read the list: CSV by default, or a JSON array or one address per
+	// line, going by the file's extension and content type.
+	format := upload.DetectFormat(header.Header.Get("Content-Type"), header.Filename)
+	emails, err := upload.ParseEmails(file, format)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid %s format", strings.ToUpper(string(format))), http.StatusBadRequest)
+		return
+	}
+
+	// Real-world dumps repeat addresses heavily; verify each one once.
+	totalRows := len(emails)
+	emails = validator.DedupeEmails(emails)
 
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			http.Error(w, "Invalid CSV format", http.StatusBadRequest)
+	// 4. Create Job in Postgres
+	jobID := uuid.New().String()
+	ctx := r.Context()
+
+	// Optional export destination: a presigned PUT URL the finished results
+	// are uploaded to when the job completes. It must be https to a public
+	// host, and one of EXPORT_URL_HOSTS when that is set.
+	var exportURL, exportFormat *string
+	if raw := r.FormValue("export_url"); raw != "" {
+		if err := outbound.ValidateURL(raw, export.AllowedHosts); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid 'export_url' parameter: %v", err), http.StatusBadRequest)
 			return
 		}
+		format := string(export.ParseFormat(r.FormValue("export_format")))
+		exportURL, exportFormat = &raw, &format
+	}
 
-		if len(record) > 0 {
-			val := record[0]
-			// Skip the row if it's the first row and looks like a header
-			if isFirstRow && (val == "email" || val == "Email" || val == "Email Address") {
-				isFirstRow = false
-				continue
-			}
-			isFirstRow = false
-
-			if val != "" {
-				emails = append(emails, val)
-			}
+	// Optional completion webhook, POSTed once the job finishes so the
+	// uploader need not poll /status.
+	var callbackURL *string
+	if raw := r.FormValue("callback_url"); raw != "" {
+		if err := outbound.ValidateURL(raw, webhook.AllowedHosts); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid 'callback_url' parameter: %v", err), http.StatusBadRequest)
+			return
 		}
+		callbackURL = &raw
 	}
 
-	// 4. Create Job in Postgres
-	jobID := uuid.New().String()
-	ctx := r.Context()
+	// Optional job class, which picks the worker fleet that processes the
+	// job (see queue.Routes).
+	fleet, ok := queue.Route(r.FormValue("class"))
+	if !ok {
+		http.Error(w, "Unknown 'class' parameter", http.StatusBadRequest)
+		return
+	}
 
-	query := `INSERT INTO jobs (id, status, total_count, created_at) VALUES ($1, 'pending', $2, $3)`
-	_, err = store.DB.Exec(ctx, query, jobID, len(emails), time.Now())
+	// An Idempotency-Key makes a retried upload return the job the first
+	// attempt created rather than start a duplicate.
+	job, existing, err := store.CreateJob(ctx, store.NewJob{
+		ID:             jobID,
+		TotalRows:      totalRows,
+		TotalCount:     len(emails),
+		ExportURL:      exportURL,
+		ExportFormat:   exportFormat,
+		CallbackURL:    callbackURL,
+		APIKey:         apiKeyID(r),
+		IdempotencyKey: idempotencyKey,
+	})
 	if err != nil {
 		fmt.Printf("DB Error: %v\n", err)
 		http.Error(w, "Failed to create job", http.StatusInternalServerError)
 		return
 	}
+	if existing {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(UploadResponse{
+			JobID:      job.ID,
+			TotalRows:  job.TotalRows,
+			UniqueRows: job.TotalCount,
+			Message:    "Job already created for this Idempotency-Key.",
+		})
+		return
+	}
 
 	// 5. Push to Redis Queue
-	if err := queue.EnqueueBatch(ctx, jobID, emails); err != nil {
+	if err := queue.EnqueueBatch(ctx, jobID, emails, fleet); err != nil {
 		fmt.Printf("Redis Error: %v\n", err)
+		// Free the Idempotency-Key so the client's retry is not answered
+		// with this half-queued job.
+		if err := store.AbandonJob(context.WithoutCancel(ctx), jobID); err != nil {
+			fmt.Printf("DB Error: abandoning job %s: %v\n", jobID, err)
+		}
 		http.Error(w, "Failed to queue tasks", http.StatusInternalServerError)
 		return
 	}
@@ -92,9 +153,10 @@ func uploadHandler(w http.ResponseWriter, r *http.Request) {
 	// 6. Return Success
 	w.Header().Set("Content-Type", "application/json")
 	resp := UploadResponse{
-		JobID:     jobID,
-		TotalRows: len(emails),
-		Message:   "Job created and queued. Processing started.",
+		JobID:      jobID,
+		TotalRows:  totalRows,
+		UniqueRows: len(emails),
+		Message:    "Job created and queued. Processing started.",
 	}
 	json.NewEncoder(w).Encode(resp)
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"mailvetter/internal/worker"
+)
+
+// WorkersResponse summarises the fleet-wide worker heartbeats so operators can
+// spot silent throughput degradation caused by stuck worker slots.
+type WorkersResponse struct {
+	Busy         int                `json:"busy"`
+	Stuck        int                `json:"stuck"`
+	StuckWorkers []worker.Heartbeat `json:"stuck_workers"`
+}
+
+// workersHandler reports how many worker goroutines are mid-task and how many
+// of those have exceeded the per-job timeout by more than worker.StuckMargin.
+func workersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	beats, err := worker.ListHeartbeats(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to read worker heartbeats", http.StatusInternalServerError)
+		return
+	}
+
+	stuck := worker.FindStuck(beats, time.Now())
+	if stuck == nil {
+		stuck = []worker.Heartbeat{}
+	}
+
+	resp := WorkersResponse{
+		Busy:         len(beats),
+		Stuck:        len(stuck),
+		StuckWorkers: stuck,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
@@ -11,9 +12,16 @@ import (
 	"time"
 
 	"mailvetter/internal/cache"
+	"mailvetter/internal/config"
+	"mailvetter/internal/lookup"
+	"mailvetter/internal/metrics"
 	"mailvetter/internal/proxy"
 	"mailvetter/internal/queue"
+	"mailvetter/internal/ratelimit"
+	"mailvetter/internal/sink"
 	"mailvetter/internal/store"
+	"mailvetter/internal/validator"
+	"mailvetter/internal/webhook"
 	"mailvetter/internal/worker"
 )
 
@@ -30,6 +38,13 @@ func main() {
 	}
 	log.Println("✅ Connected to Redis")
 
+	// Fleet-wide per-provider SMTP caps share the Redis instance above so
+	// that every worker process draws from the same slot pool.
+	if caps := ratelimit.ParseCaps(config.List("SMTP_PROVIDER_CAPS")); len(caps) > 0 {
+		ratelimit.InitFleet(queue.Client, caps)
+		log.Printf("🚦 Fleet-wide SMTP caps enabled: %v", caps)
+	}
+
 	// 2. Initialize Database
 	dbURL := os.Getenv("DB_URL")
 	if dbURL == "" {
@@ -41,12 +56,12 @@ func main() {
 	log.Println("✅ Connected to PostgreSQL")
 
 	// 3. Initialize Proxy Manager
-	proxyListRaw := os.Getenv("PROXY_LIST")
+	// PROXY_LIST, or the standard HTTPS_PROXY/HTTP_PROXY/ALL_PROXY variables
+	// when it is unset. Hosts in NO_PROXY are always reached directly.
+	proxies := proxy.ListFromEnv()
 	smtpProxyEnabled := false
 
-	if proxyListRaw != "" {
-		proxies := strings.Split(proxyListRaw, ",")
-
+	if len(proxies) > 0 {
 		proxyLimitStr := os.Getenv("PROXY_CONCURRENCY")
 		proxyLimit, err := strconv.Atoi(proxyLimitStr)
 		if err != nil || proxyLimit <= 0 {
@@ -61,6 +76,11 @@ func main() {
 			log.Fatalf("❌ Failed to initialize proxy manager: %v", err)
 		}
 
+		if trusted := config.List("PROXY_TRUSTED"); len(trusted) > 0 {
+			n := proxy.Global.SetTrusted(trusted)
+			log.Printf("🛡️  %d of %d PROXY_TRUSTED entries matched (lenient health gating)\n", n, len(trusted))
+		}
+
 		log.Printf("🛡️  Proxy rotation enabled (%d proxies loaded, max %d concurrent HTTP)\n", len(proxies), cap(proxy.Semaphore))
 		if smtpProxyEnabled {
 			log.Println("⚠️  SMTP Proxying is ENABLED (Port 25 traffic will route through proxies)")
@@ -79,7 +99,7 @@ func main() {
 		concurrency = c
 		log.Printf("🔧 WORKER_CONCURRENCY explicitly set to %d", concurrency)
 	} else {
-		if proxyListRaw != "" && smtpProxyEnabled {
+		if len(proxies) > 0 && smtpProxyEnabled {
 			actualProxyLimit := cap(proxy.Semaphore)
 			concurrency = actualProxyLimit * 2
 			if concurrency < 10 {
@@ -104,6 +124,92 @@ func main() {
 	cache.StartCleanup(ctx, 5*time.Minute)
 	log.Println("✅ Cache eviction goroutine started (interval: 5m)")
 
+	// Probe every proxy in the background; failing ones leave rotation
+	// until a later check passes.
+	if proxy.Enabled() {
+		proxy.StartHealthChecks(ctx, proxy.HealthCheckInterval)
+		log.Printf("✅ Proxy health checks started (interval: %s)", proxy.HealthCheckInterval)
+	}
+
+	// Look up our outbound IPs on the DNSBLs in the background; a listing
+	// is the usual cause of SMTP servers rejecting probes wholesale.
+	if config.Bool("DNSBL_SELFCHECK", true) {
+		go lookup.LogDNSBLSelfCheck(ctx)
+	}
+
+	// Extend the built-in disposable-domain list from a local file and/or a
+	// URL refreshed in the background.
+	if path := config.String("DISPOSABLE_LIST_PATH", ""); path != "" {
+		if err := lookup.LoadDisposableList(path); err != nil {
+			log.Printf("⚠️  Failed to load disposable list %s: %v", path, err)
+		}
+	}
+	if url := config.String("DISPOSABLE_LIST_URL", ""); url != "" {
+		interval := config.Duration("DISPOSABLE_LIST_REFRESH", 24*time.Hour)
+		lookup.StartDisposableRefresh(ctx, url, interval)
+		log.Printf("✅ Disposable list refresh enabled (interval: %s)", interval)
+	}
+
+	// Warm the cache from the previous process's snapshot so a rolling deploy
+	// does not re-probe every hot domain cold. Entries keep their original
+	// expiry, so anything stale is dropped on load.
+	snapshotPath := config.String("CACHE_SNAPSHOT_PATH", "")
+	if snapshotPath != "" {
+		if n, err := cache.DomainCache.LoadFile(snapshotPath); err != nil {
+			log.Printf("⚠️  Failed to load cache snapshot %s: %v", snapshotPath, err)
+		} else {
+			log.Printf("✅ Restored %d cache entries from %s", n, snapshotPath)
+		}
+		interval := config.Duration("CACHE_SNAPSHOT_INTERVAL", 5*time.Minute)
+		cache.StartSnapshots(ctx, snapshotPath, interval)
+		log.Printf("✅ Cache snapshots enabled (interval: %s)", interval)
+	}
+
+	// Keep the most-requested domains' infra entries from expiring cold while
+	// they are still being requested. Opt-in: it spends lookups on traffic
+	// that has not arrived yet.
+	if config.Bool("CACHE_WARM_ENABLED", false) {
+		validator.StartCacheWarmer(ctx)
+		log.Printf("✅ Cache warmer started (top %d domains, interval: %s)", validator.CacheWarmTopK, validator.CacheWarmInterval)
+	}
+
+	// Stream completed results to Kafka alongside the Postgres write.
+	if k := sink.KafkaFromEnv(); k != nil {
+		sink.Register(k)
+		log.Printf("✅ Publishing results to %s", k.Name())
+	}
+
+	// Deliver job-completion webhooks off the task path.
+	webhook.Start()
+	log.Println("✅ Webhook dispatcher started")
+
+	// Watch the fleet-wide heartbeat hash for workers whose task has outlived
+	// the per-job deadline — a sign of a probe blocked in a call that ignores
+	// context cancellation.
+	worker.StartMonitor(ctx, 1*time.Minute)
+	log.Println("✅ Heartbeat monitor started (interval: 1m)")
+
+	// The worker serves no API, so Prometheus scrapes it on a listener of its
+	// own. Set METRICS_ADDR=off to disable.
+	if addr := config.String("METRICS_ADDR", ":9090"); addr != "off" {
+		metricsServer := &http.Server{
+			Addr:         addr,
+			Handler:      http.HandlerFunc(metrics.Handler),
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("⚠️  Metrics listener error: %v", err)
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			metricsServer.Close()
+		}()
+		log.Printf("✅ Metrics listening on %s", addr)
+	}
+
 	// 7. Register for SIGTERM / SIGINT. main() is the sole receiver — see
 	// the detailed comment in the issue #1 fix for why having two receivers
 	// on this channel causes a deadlock.
@@ -111,8 +217,18 @@ func main() {
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 
 	// 8. Start the worker pool. It blocks until all goroutines exit, which
-	// happens after ctx is cancelled below.
-	go worker.Start(ctx, concurrency)
+	// happens after ctx is cancelled below; done is closed when it returns.
+	done := make(chan struct{})
+	go func() {
+		worker.Start(ctx, concurrency)
+		// The last jobs' completion callbacks may still be queued.
+		webhook.Close()
+		// Every worker has returned, so nothing writes to the cache any more.
+		if snapshotPath != "" {
+			cache.SaveSnapshot(snapshotPath)
+		}
+		close(done)
+	}()
 
 	// 9. Block until the OS sends a shutdown signal.
 	<-quit
@@ -123,9 +239,15 @@ func main() {
 	// into the cache cleanup goroutine (exits cleanly).
 	cancel()
 
-	const drainTimeout = 30 * time.Second
+	// Wait for the pool to finish its in-flight tasks and flush pending
+	// results, but no longer than DRAIN_TIMEOUT: a probe stuck in a call
+	// that ignores cancellation must not hold up the exit forever.
+	drainTimeout := config.Duration("DRAIN_TIMEOUT", 30*time.Second)
 	log.Printf("⏳ Waiting up to %s for in-flight jobs to complete...", drainTimeout)
-	time.Sleep(drainTimeout)
-
-	log.Println("✅ Worker shut down cleanly.")
+	select {
+	case <-done:
+		log.Println("✅ Worker shut down cleanly.")
+	case <-time.After(drainTimeout):
+		log.Printf("⚠️  Workers still busy after %s; exiting anyway.", drainTimeout)
+	}
 }
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Register records a concrete value type so it can be written to and read
+// back from a snapshot. Packages that cache their own types call this from
+// init; entries of unregistered types are skipped when saving.
+func Register(value interface{}) {
+	gob.Register(value)
+}
+
+// snapshotEntry is one cache item as stored on disk. Value holds the item
+// gob-encoded on its own, so a single unencodable value cannot corrupt the
+// rest of the snapshot.
+type snapshotEntry struct {
+	Key        string
+	Expiration int64
+	Value      []byte
+}
+
+// boxed wraps a value so gob records its concrete type.
+type boxed struct {
+	V interface{}
+}
+
+// Save writes every unexpired item to w. It returns the number of items
+// written; items whose type was never passed to Register are skipped.
+func (s *Store) Save(w io.Writer) (int, error) {
+	now := time.Now().UnixNano()
+
+	s.mu.RLock()
+	entries := make([]snapshotEntry, 0, len(s.items))
+	for k, item := range s.items {
+		if now > item.Expiration {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(boxed{V: item.Value}); err != nil {
+			continue
+		}
+		entries = append(entries, snapshotEntry{Key: k, Expiration: item.Expiration, Value: buf.Bytes()})
+	}
+	s.mu.RUnlock()
+
+	if err := gob.NewEncoder(w).Encode(entries); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// Load reads a snapshot written by Save and restores every item that has not
+// expired in the meantime, keeping its original expiry. It returns the number
+// of items restored.
+func (s *Store) Load(r io.Reader) (int, error) {
+	var entries []snapshotEntry
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UnixNano()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	restored := 0
+	for _, e := range entries {
+		if now > e.Expiration {
+			continue
+		}
+		var b boxed
+		if err := gob.NewDecoder(bytes.NewReader(e.Value)).Decode(&b); err != nil {
+			continue
+		}
+		s.items[e.Key] = Item{Value: b.V, Expiration: e.Expiration}
+		restored++
+	}
+	return restored, nil
+}
+
+// SaveFile writes a snapshot to path atomically, via a temporary file in the
+// same directory, so a crash mid-write never leaves a truncated snapshot.
+func (s *Store) SaveFile(path string) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cache-snapshot-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := s.Save(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmp.Name(), path)
+}
+
+// LoadFile restores a snapshot from path. A missing file is not an error.
+func (s *Store) LoadFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return s.Load(f)
+}
+
+// SaveSnapshot writes DomainCache to path, logging the outcome. A process
+// calls it once more on shutdown, after its last writer to the cache has
+// stopped, so the final snapshot holds everything learned before exit.
+func SaveSnapshot(path string) {
+	if n, err := DomainCache.SaveFile(path); err != nil {
+		log.Printf("[cache] snapshot to %s failed: %v", path, err)
+	} else {
+		log.Printf("[cache] snapshot wrote %d entries to %s", n, path)
+	}
+}
+
+// StartSnapshots writes DomainCache to path on the given interval until ctx
+// is cancelled, so a restarted process can reload it with LoadFile. Call this
+// once during process initialisation.
+func StartSnapshots(ctx context.Context, path string, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				SaveSnapshot(path)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
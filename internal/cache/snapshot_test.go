@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type snapshotValue struct {
+	Provider string
+	Age      int
+}
+
+func init() {
+	Register(snapshotValue{})
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	src := New()
+	src.Set("infra:live", snapshotValue{Provider: "google", Age: 4000}, time.Hour)
+	src.Set("flag:live", true, time.Hour)
+	src.Set("infra:expired", snapshotValue{Provider: "old"}, -time.Second)
+	src.Set("unregistered", struct{ X int }{1}, time.Hour)
+
+	var buf bytes.Buffer
+	n, err := src.Save(&buf)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("saved %d entries, expected 2 (expired and unregistered skipped)", n)
+	}
+
+	dst := New()
+	if n, err := dst.Load(&buf); err != nil || n != 2 {
+		t.Fatalf("Load: restored %d, err %v", n, err)
+	}
+
+	val, ok := dst.Get("infra:live")
+	if !ok || val.(snapshotValue) != (snapshotValue{Provider: "google", Age: 4000}) {
+		t.Errorf("infra:live = %v, %v", val, ok)
+	}
+	if val, ok := dst.Get("flag:live"); !ok || val.(bool) != true {
+		t.Errorf("flag:live = %v, %v", val, ok)
+	}
+	if _, ok := dst.Get("infra:expired"); ok {
+		t.Errorf("expired entry must not be restored")
+	}
+
+	// The original expiry is kept rather than reset on load.
+	if got, want := dst.items["infra:live"].Expiration, src.items["infra:live"].Expiration; got != want {
+		t.Errorf("expiration %d != original %d", got, want)
+	}
+}
+
+func TestSnapshotFileDropsEntriesExpiredSinceSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	src := New()
+	src.Set("short", snapshotValue{Provider: "a"}, 20*time.Millisecond)
+	src.Set("long", snapshotValue{Provider: "b"}, time.Hour)
+	if _, err := src.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	dst := New()
+	n, err := dst.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if n != 1 || dst.Len() != 1 {
+		t.Errorf("restored %d entries, expected only the unexpired one", n)
+	}
+
+	if n, err := New().LoadFile(filepath.Join(t.TempDir(), "missing")); n != 0 || err != nil {
+		t.Errorf("missing snapshot: %d, %v", n, err)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"mailvetter/internal/metrics"
 )
 
 // Item represents a cached value with an expiration time.
@@ -17,6 +20,8 @@ type Item struct {
 type Store struct {
 	items map[string]Item
 	mu    sync.RWMutex
+
+	hits, misses atomic.Uint64
 }
 
 // DomainCache is the package-level singleton used by all lookup functions.
@@ -48,15 +53,39 @@ func (s *Store) Get(key string) (interface{}, bool) {
 	defer s.mu.RUnlock()
 
 	item, found := s.items[key]
-	if !found {
+	if !found || time.Now().UnixNano() > item.Expiration {
+		metrics.CacheRequests.Inc("miss")
+		s.misses.Add(1)
 		return nil, false
 	}
 
-	if time.Now().UnixNano() > item.Expiration {
-		return nil, false
+	metrics.CacheRequests.Inc("hit")
+	s.hits.Add(1)
+	return item.Value, true
+}
+
+// TTL returns how long key has left before it expires. ok is false on a miss
+// or if the item has already expired.
+func (s *Store) TTL(key string) (remaining time.Duration, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, found := s.items[key]
+	if !found {
+		return 0, false
+	}
+	remaining = time.Duration(item.Expiration - time.Now().UnixNano())
+	if remaining <= 0 {
+		return 0, false
 	}
+	return remaining, true
+}
 
-	return item.Value, true
+// Delete removes key from the cache. It is a no-op if the key is absent.
+func (s *Store) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
 }
 
 // Len returns the number of items currently in the cache, including expired
@@ -67,6 +96,24 @@ func (s *Store) Len() int {
 	return len(s.items)
 }
 
+// Stats is a snapshot of a Store's size and Get outcomes since start-up.
+type Stats struct {
+	Entries  int     `json:"entries"`
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// Stats returns the store's current size and hit/miss counts, for tuning
+// TTLs.
+func (s *Store) Stats() Stats {
+	st := Stats{Entries: s.Len(), Hits: s.hits.Load(), Misses: s.misses.Load()}
+	if total := st.Hits + st.Misses; total > 0 {
+		st.HitRatio = float64(st.Hits) / float64(total)
+	}
+	return st
+}
+
 // Cleanup removes all expired items. It acquires a full write lock for the
 // duration of the sweep, so it should only be called from the background
 // goroutine managed by StartCleanup — not inline on the hot path.
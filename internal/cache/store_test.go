@@ -0,0 +1,23 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	s := New()
+	s.Set("a", 1, time.Minute)
+	s.Set("b", 2, -time.Second) // already expired
+
+	s.Get("a")
+	s.Get("a")
+	s.Get("b")
+	s.Get("missing")
+
+	got := s.Stats()
+	want := Stats{Entries: 2, Hits: 2, Misses: 2, HitRatio: 0.5}
+	if got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,181 @@
+// Package calibration runs a sample of verifications through an external
+// reference verifier and records whether it agrees with this engine. It is a
+// QA tool for measuring accuracy, not part of the production verdict.
+package calibration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/models"
+	"mailvetter/internal/store"
+)
+
+// Enabled gates the comparison. Off by default; enable with
+// CALIBRATION_ENABLED=true and point CALIBRATION_PROVIDER_URL at the
+// reference verifier.
+var Enabled = config.Bool("CALIBRATION_ENABLED", false)
+
+// SampleRate is the fraction (0–1) of verifications that are also sent to the
+// reference verifier. Set via CALIBRATION_SAMPLE_RATE.
+var SampleRate = config.Float("CALIBRATION_SAMPLE_RATE", 0.01)
+
+// Provider is an external verifier whose verdict is used as the reference.
+type Provider interface {
+	Verify(ctx context.Context, email string) (models.VerificationStatus, error)
+}
+
+// Default is the configured reference provider, or nil if none is set.
+var Default Provider = newHTTPProvider(
+	config.String("CALIBRATION_PROVIDER_URL", ""),
+	config.String("CALIBRATION_PROVIDER_KEY", ""),
+)
+
+// Record is one engine-versus-reference comparison.
+type Record struct {
+	Email     string
+	Ours      models.VerificationStatus
+	Theirs    models.VerificationStatus
+	Agree     bool
+	CheckedAt time.Time
+}
+
+// recordFn persists a comparison. It is a variable so tests can capture
+// records without a database.
+var recordFn = func(ctx context.Context, r Record) error {
+	return store.RecordCalibration(ctx, r.Email, string(r.Ours), string(r.Theirs), r.Agree)
+}
+
+// sampleFn decides whether this verification is sampled.
+var sampleFn = func() bool { return rand.Float64() < SampleRate }
+
+// providerTimeout bounds a single reference lookup so a slow provider cannot
+// hold a worker slot.
+const providerTimeout = 15 * time.Second
+
+// MaybeCompare sends a sample of verifications to the reference provider and
+// records the outcome. It is a no-op unless Enabled is set and a provider is
+// configured; failures are logged and never affect the caller.
+func MaybeCompare(ctx context.Context, email string, ours models.VerificationStatus) {
+	if !Enabled || Default == nil || !sampleFn() {
+		return
+	}
+	if _, err := Compare(ctx, Default, email, ours); err != nil {
+		log.Printf("⚠️  Calibration comparison failed for %s: %v", email, err)
+	}
+}
+
+// Compare asks p for its verdict on email, records whether it matches ours,
+// and returns the record.
+func Compare(ctx context.Context, p Provider, email string, ours models.VerificationStatus) (Record, error) {
+	pctx, cancel := context.WithTimeout(ctx, providerTimeout)
+	defer cancel()
+
+	theirs, err := p.Verify(pctx, email)
+	if err != nil {
+		return Record{}, fmt.Errorf("reference provider: %w", err)
+	}
+
+	rec := Record{
+		Email:     email,
+		Ours:      ours,
+		Theirs:    theirs,
+		Agree:     Agrees(ours, theirs),
+		CheckedAt: time.Now(),
+	}
+	if err := recordFn(ctx, rec); err != nil {
+		return rec, fmt.Errorf("record: %w", err)
+	}
+	return rec, nil
+}
+
+// Agrees reports whether two verdicts match. StatusRisky and StatusCatchAll
+// both mean "accepted but unconfirmed", so they are treated as equivalent.
+func Agrees(ours, theirs models.VerificationStatus) bool {
+	return bucket(ours) == bucket(theirs)
+}
+
+func bucket(s models.VerificationStatus) models.VerificationStatus {
+	if s == models.StatusCatchAll {
+		return models.StatusRisky
+	}
+	return s
+}
+
+// NormalizeStatus maps the vocabularies common verification APIs use onto
+// ours. Unrecognised values map to StatusUnknown.
+func NormalizeStatus(s string) models.VerificationStatus {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "valid", "deliverable", "ok":
+		return models.StatusValid
+	case "invalid", "undeliverable", "bounce", "rejected":
+		return models.StatusInvalid
+	case "catch_all", "catch-all", "catchall", "accept_all", "accept-all":
+		return models.StatusCatchAll
+	case "risky", "do_not_mail":
+		return models.StatusRisky
+	}
+	return models.StatusUnknown
+}
+
+// httpProvider queries a reference API with GET <url>?email=<addr>, expecting
+// a JSON body with a "status" (or "result") field.
+type httpProvider struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func newHTTPProvider(endpoint, apiKey string) Provider {
+	if endpoint == "" {
+		return nil
+	}
+	return &httpProvider{endpoint: endpoint, apiKey: apiKey, client: &http.Client{Timeout: providerTimeout}}
+}
+
+func (p *httpProvider) Verify(ctx context.Context, email string) (models.VerificationStatus, error) {
+	u, err := url.Parse(p.endpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("email", email)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Status string `json:"status"`
+		Result string `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Status == "" {
+		body.Status = body.Result
+	}
+	return NormalizeStatus(body.Status), nil
+}
@@ -0,0 +1,131 @@
+package calibration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mailvetter/internal/models"
+)
+
+type stubProvider struct {
+	verdicts map[string]models.VerificationStatus
+	err      error
+	calls    int
+}
+
+func (s *stubProvider) Verify(ctx context.Context, email string) (models.VerificationStatus, error) {
+	s.calls++
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.verdicts[email], nil
+}
+
+func captureRecords(t *testing.T) *[]Record {
+	t.Helper()
+	saved := recordFn
+	t.Cleanup(func() { recordFn = saved })
+	var got []Record
+	recordFn = func(ctx context.Context, r Record) error {
+		got = append(got, r)
+		return nil
+	}
+	return &got
+}
+
+func TestCompareRecordsAgreement(t *testing.T) {
+	got := captureRecords(t)
+	p := &stubProvider{verdicts: map[string]models.VerificationStatus{
+		"same@example.com":     models.StatusValid,
+		"differ@example.com":   models.StatusInvalid,
+		"acceptor@example.com": models.StatusCatchAll,
+	}}
+	ctx := context.Background()
+
+	cases := []struct {
+		email string
+		ours  models.VerificationStatus
+		agree bool
+	}{
+		{"same@example.com", models.StatusValid, true},
+		{"differ@example.com", models.StatusValid, false},
+		{"acceptor@example.com", models.StatusRisky, true},
+	}
+	for _, c := range cases {
+		rec, err := Compare(ctx, p, c.email, c.ours)
+		if err != nil {
+			t.Fatalf("%s: %v", c.email, err)
+		}
+		if rec.Agree != c.agree {
+			t.Errorf("%s: agree %v != expected %v", c.email, rec.Agree, c.agree)
+		}
+	}
+	if len(*got) != len(cases) {
+		t.Fatalf("recorded %d comparisons, expected %d", len(*got), len(cases))
+	}
+	if r := (*got)[1]; r.Ours != models.StatusValid || r.Theirs != models.StatusInvalid {
+		t.Errorf("recorded %+v, expected ours=valid theirs=invalid", r)
+	}
+}
+
+func TestCompareProviderErrorRecordsNothing(t *testing.T) {
+	got := captureRecords(t)
+	p := &stubProvider{err: errors.New("quota exceeded")}
+
+	if _, err := Compare(context.Background(), p, "x@example.com", models.StatusValid); err == nil {
+		t.Fatalf("expected provider error")
+	}
+	if len(*got) != 0 {
+		t.Errorf("a failed reference lookup must not be recorded")
+	}
+}
+
+func TestMaybeCompareGating(t *testing.T) {
+	got := captureRecords(t)
+	savedEnabled, savedDefault, savedSample := Enabled, Default, sampleFn
+	defer func() { Enabled, Default, sampleFn = savedEnabled, savedDefault, savedSample }()
+
+	p := &stubProvider{verdicts: map[string]models.VerificationStatus{}}
+	Default = p
+	sampled := false
+	sampleFn = func() bool { return sampled }
+
+	Enabled = false
+	sampled = true
+	MaybeCompare(context.Background(), "a@example.com", models.StatusValid)
+
+	Enabled = true
+	sampled = false
+	MaybeCompare(context.Background(), "b@example.com", models.StatusValid)
+	if p.calls != 0 || len(*got) != 0 {
+		t.Fatalf("disabled or unsampled verifications must not reach the provider")
+	}
+
+	sampled = true
+	MaybeCompare(context.Background(), "c@example.com", models.StatusValid)
+	if p.calls != 1 || len(*got) != 1 {
+		t.Errorf("sampled verification: %d calls, %d records", p.calls, len(*got))
+	}
+}
+
+func TestHTTPProviderNormalizesStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("email") != "a@example.com" || r.Header.Get("Authorization") != "Bearer k" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"result":"deliverable"}`))
+	}))
+	defer srv.Close()
+
+	status, err := newHTTPProvider(srv.URL, "k").Verify(context.Background(), "a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != models.StatusValid {
+		t.Errorf("status %q != expected %q", status, models.StatusValid)
+	}
+}
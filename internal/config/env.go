@@ -0,0 +1,94 @@
+// Package config provides small helpers for reading optional settings from
+// environment variables with a typed fallback.
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the value of the named variable, or def if it is unset or
+// empty.
+func String(name, def string) string {
+	if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+		return v
+	}
+	return def
+}
+
+// Bool returns true for "true"/"1"/"yes", false for "false"/"0"/"no", and def
+// for anything else (including unset).
+func Bool(name string, def bool) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(name))) {
+	case "true", "1", "yes":
+		return true
+	case "false", "0", "no":
+		return false
+	}
+	return def
+}
+
+// Int returns the named variable parsed as a positive integer, or def if it is
+// unset, malformed, or not positive.
+func Int(name string, def int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name))); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// NonNegativeInt is Int for settings where 0 means "off": it returns the
+// named variable parsed as an integer >= 0, or def if it is unset, malformed,
+// or negative.
+func NonNegativeInt(name string, def int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name))); err == nil && n >= 0 {
+		return n
+	}
+	return def
+}
+
+// Float returns the named variable parsed as a float, or def if it is unset or
+// malformed.
+func Float(name string, def float64) float64 {
+	if f, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(name)), 64); err == nil {
+		return f
+	}
+	return def
+}
+
+// Duration returns the named variable parsed with time.ParseDuration (e.g.
+// "30s", "15m"), or def if it is unset, malformed, or not positive.
+func Duration(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv(name))); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// NonNegativeDuration is Duration for settings where 0 means "off": it
+// returns the named variable parsed with time.ParseDuration, or def if it is
+// unset, malformed, or negative.
+func NonNegativeDuration(name string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv(name))); err == nil && d >= 0 {
+		return d
+	}
+	return def
+}
+
+// List returns the named variable split on commas with blank entries removed,
+// or nil if it is unset.
+func List(name string) []string {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+// Package export writes a finished job's results to an external object store.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/outbound"
+)
+
+// Format is the serialisation used for an export.
+type Format string
+
+const (
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+// ParseFormat returns the Format named by s, defaulting to NDJSON.
+func ParseFormat(s string) Format {
+	if Format(s) == FormatCSV {
+		return FormatCSV
+	}
+	return FormatNDJSON
+}
+
+// Row is one verification result as stored in the results table.
+type Row struct {
+	Email string
+	Score int
+	Data  json.RawMessage
+}
+
+// RowSource streams rows to yield, stopping at the first error yield returns.
+type RowSource func(yield func(Row) error) error
+
+// AllowedHosts, when set, limits export URLs to these hosts and their
+// subdomains (e.g. "s3.amazonaws.com"). Set via EXPORT_URL_HOSTS.
+var AllowedHosts = config.List("EXPORT_URL_HOSTS")
+
+// client is used for uploads. It refuses non-public destinations (see
+// outbound.Client). The timeout is generous because a large job can produce
+// an export of several hundred megabytes.
+var client = outbound.Client(10 * time.Minute)
+
+// Upload serialises every row from src and PUTs it to putURL, which is
+// expected to be a presigned object-store URL (S3, GCS, R2, MinIO, …).
+//
+// Rows are spooled to a temporary file rather than held in memory, so memory
+// use is constant regardless of job size. Spooling (instead of streaming the
+// request body directly) is required because S3 rejects PUT requests without
+// a Content-Length.
+func Upload(ctx context.Context, putURL string, format Format, src RowSource) error {
+	tmp, err := os.CreateTemp("", "mailvetter-export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create spool file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := Write(tmp, format, src); err != nil {
+		return err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to size spool file: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind spool file: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, putURL, tmp)
+	if err != nil {
+		return fmt.Errorf("invalid export URL: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType(format))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("export upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("export upload rejected: %s: %s", resp.Status, body)
+	}
+	return nil
+}
+
+// Write serialises every row from src to w in the given format.
+func Write(w io.Writer, format Format, src RowSource) error {
+	if format == FormatCSV {
+		return writeCSV(w, src)
+	}
+	return writeNDJSON(w, src)
+}
+
+func writeNDJSON(w io.Writer, src RowSource) error {
+	return src(func(r Row) error {
+		if _, err := w.Write(r.Data); err != nil {
+			return err
+		}
+		_, err := w.Write([]byte("\n"))
+		return err
+	})
+}
+
+// writeCSV emits the headline fields only; the full analysis, where it was
+// stored, is available in the NDJSON format. Both stored shapes carry status
+// and reachability.
+func writeCSV(w io.Writer, src RowSource) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"email", "score", "status", "reachability"}); err != nil {
+		return err
+	}
+
+	err := src(func(r Row) error {
+		var summary struct {
+			Status       string `json:"status"`
+			Reachability string `json:"reachability"`
+		}
+		json.Unmarshal(r.Data, &summary)
+		return cw.Write([]string{r.Email, strconv.Itoa(r.Score), summary.Status, summary.Reachability})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func contentType(format Format) string {
+	if format == FormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mailvetter/internal/outbound"
+)
+
+// allowLoopback lets the export client reach the local test server.
+func allowLoopback(t *testing.T) {
+	saved := outbound.AllowPrivate
+	t.Cleanup(func() { outbound.AllowPrivate = saved })
+	outbound.AllowPrivate = true
+}
+
+func sampleSource(rows []Row) RowSource {
+	return func(yield func(Row) error) error {
+		for _, r := range rows {
+			if err := yield(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestUploadToMockObjectStore(t *testing.T) {
+	allowLoopback(t)
+	var gotBody, gotType string
+	var gotLength int64
+
+	store := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("expected PUT, got %s", r.Method)
+		}
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotType = r.Header.Get("Content-Type")
+		gotLength = r.ContentLength
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer store.Close()
+
+	rows := []Row{
+		{Email: "a@example.com", Score: 95, Data: json.RawMessage(`{"email":"a@example.com","status":"valid","reachability":"safe"}`)},
+		{Email: "b@example.com", Score: 0, Data: json.RawMessage(`{"email":"b@example.com","status":"invalid","reachability":"bad"}`)},
+	}
+
+	if err := Upload(context.Background(), store.URL+"/bucket/job.ndjson", FormatNDJSON, sampleSource(rows)); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(gotBody), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 NDJSON lines, got %d: %q", len(lines), gotBody)
+	}
+	if gotType != "application/x-ndjson" {
+		t.Errorf("Content-Type %q != application/x-ndjson", gotType)
+	}
+	if gotLength != int64(len(gotBody)) {
+		t.Errorf("Content-Length %d != body length %d", gotLength, len(gotBody))
+	}
+
+	if err := Upload(context.Background(), store.URL+"/bucket/job.csv", FormatCSV, sampleSource(rows)); err != nil {
+		t.Fatalf("CSV upload failed: %v", err)
+	}
+	want := "email,score,status,reachability\na@example.com,95,valid,safe\nb@example.com,0,invalid,bad\n"
+	if gotBody != want {
+		t.Errorf("CSV body %q != expected %q", gotBody, want)
+	}
+}
+
+func TestUploadRejected(t *testing.T) {
+	allowLoopback(t)
+	store := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+	}))
+	defer store.Close()
+
+	err := Upload(context.Background(), store.URL, FormatNDJSON, sampleSource(nil))
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a 403 upload error, got %v", err)
+	}
+}
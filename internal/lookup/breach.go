@@ -2,14 +2,72 @@ package lookup
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/metrics"
+	"mailvetter/internal/queue"
 )
 
-const hibpURL = "https://haveibeenpwned.com/api/v3/breachedaccount/"
+// hibpURL is the breachedaccount endpoint. It is a variable so tests can
+// point it at a local server.
+var hibpURL = "https://haveibeenpwned.com/api/v3/breachedaccount/"
+
+// HIBPCacheTTL is how long a breach count is cached in Redis. Breach data
+// barely changes, so a multi-day TTL spares the rate-limited API repeat
+// lookups of addresses that recur across jobs. Configured by HIBP_CACHE_TTL;
+// 0 disables the cache.
+var HIBPCacheTTL = config.NonNegativeDuration("HIBP_CACHE_TTL", 7*24*time.Hour)
+
+// breachStore caches breach counts across processes.
+type breachStore interface {
+	Get(ctx context.Context, key string) (int, bool)
+	Set(ctx context.Context, key string, count int, ttl time.Duration)
+}
+
+// breachCache is the store CheckHIBP reads through. It is a variable so tests
+// can substitute an in-memory store.
+var breachCache breachStore = redisBreachStore{}
+
+// redisBreachStore keeps counts in the queue's Redis instance. It is a no-op
+// until queue.Init has connected.
+type redisBreachStore struct{}
+
+func (redisBreachStore) Get(ctx context.Context, key string) (int, bool) {
+	if queue.Client == nil {
+		return 0, false
+	}
+	raw, err := queue.Client.Get(ctx, key).Result()
+	if err != nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(raw)
+	return n, err == nil
+}
+
+func (redisBreachStore) Set(ctx context.Context, key string, count int, ttl time.Duration) {
+	if queue.Client == nil {
+		return
+	}
+	if err := queue.Client.Set(ctx, key, count, ttl).Err(); err != nil {
+		log.Printf("[DEBUG] HIBP: failed to cache breach count: %v", err)
+	}
+}
+
+// breachCacheKey hashes the address so no plaintext email ends up in a
+// Redis key.
+func breachCacheKey(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return "hibp:" + hex.EncodeToString(sum[:])
+}
 
 type hibpBreach struct {
 	Name string `json:"Name"`
@@ -18,7 +76,31 @@ type hibpBreach struct {
 // CheckHIBP queries the HaveIBeenPwned v3 API and returns the number of
 // breaches the given email address has appeared in. Returns 0 if the API
 // key is absent, the address is clean, or any unrecoverable error occurs.
+// Definitive answers are cached for HIBPCacheTTL; a cache hit makes no
+// network call at all.
+func CheckHIBP(ctx context.Context, email, apiKey string, pURL *url.URL) int {
+	if apiKey == "" {
+		return 0
+	}
+
+	key := breachCacheKey(email)
+	if HIBPCacheTTL > 0 {
+		if n, ok := breachCache.Get(ctx, key); ok {
+			return n
+		}
+	}
+
+	n, ok := queryHIBP(ctx, email, apiKey, pURL)
+	// Errors and rate limits are not answers; only a 200 or 404 is cached.
+	if ok && HIBPCacheTTL > 0 {
+		breachCache.Set(ctx, key, n, HIBPCacheTTL)
+	}
+	return n
+}
 
+// queryHIBP makes the API call for CheckHIBP. ok reports whether the API
+// gave a definitive answer.
+//
 // Email local parts are permitted by RFC 5321 to contain characters that are
 // not safe in a URL path segment — most commonly `+` (valid in a local part,
 // means space in a query string) and `%` (valid in a local part, begins a
@@ -31,11 +113,7 @@ type hibpBreach struct {
 // PathEscape encodes everything that is not a valid path character, including
 // `+`, `%`, `?`, `#`, and space, while leaving the `@` sign and alphanumerics
 // untouched — which is exactly what the HIBP API path segment requires.
-func CheckHIBP(ctx context.Context, email, apiKey string, pURL *url.URL) int {
-	if apiKey == "" {
-		return 0
-	}
-
+func queryHIBP(ctx context.Context, email, apiKey string, pURL *url.URL) (count int, ok bool) {
 	// PathEscape rather than QueryEscape: the email sits in the URL *path*,
 	// not in a query parameter. QueryEscape would encode `@` as `%40` which
 	// some WAFs and API gateways reject when it appears in a path segment.
@@ -49,7 +127,7 @@ func CheckHIBP(ctx context.Context, email, apiKey string, pURL *url.URL) int {
 			// With a correctly encoded URL this branch should never be reached
 			// under normal operation. Log it so any future edge-case is visible.
 			log.Printf("[DEBUG] HIBP: failed to build request for %s (attempt %d): %v", email, attempt, err)
-			return 0
+			return 0, false
 		}
 
 		req.Header.Set("hibp-api-key", apiKey)
@@ -66,7 +144,7 @@ func CheckHIBP(ctx context.Context, email, apiKey string, pURL *url.URL) int {
 				time.Sleep(500 * time.Millisecond)
 				continue
 			}
-			return 0
+			return 0, false
 		}
 
 		switch resp.StatusCode {
@@ -74,28 +152,29 @@ func CheckHIBP(ctx context.Context, email, apiKey string, pURL *url.URL) int {
 			var breaches []hibpBreach
 			if err := json.NewDecoder(resp.Body).Decode(&breaches); err != nil {
 				resp.Body.Close()
-				return 0
+				return 0, false
 			}
 			resp.Body.Close()
-			return len(breaches)
+			return len(breaches), true
 
 		case 404:
 			// 404 means the address exists but has no recorded breaches — clean.
 			resp.Body.Close()
-			return 0
+			return 0, true
 
 		case 429:
 			resp.Body.Close()
+			metrics.HIBPRateLimited.Inc()
 			if attempt == 1 {
 				log.Printf("[DEBUG] HIBP rate limit hit for %s, backing off and retrying", email)
 				select {
 				case <-time.After(1600 * time.Millisecond):
 				case <-ctx.Done():
-					return 0
+					return 0, false
 				}
 				continue
 			}
-			return 0
+			return 0, false
 
 		default:
 			resp.Body.Close()
@@ -103,8 +182,8 @@ func CheckHIBP(ctx context.Context, email, apiKey string, pURL *url.URL) int {
 				time.Sleep(500 * time.Millisecond)
 				continue
 			}
-			return 0
+			return 0, false
 		}
 	}
-	return 0
+	return 0, false
 }
@@ -0,0 +1,87 @@
+package lookup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memBreachStore struct {
+	mu sync.Mutex
+	m  map[string]int
+}
+
+func (s *memBreachStore) Get(ctx context.Context, key string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.m[key]
+	return n, ok
+}
+
+func (s *memBreachStore) Set(ctx context.Context, key string, count int, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[key] = count
+}
+
+func TestCheckHIBPCachesBreachCount(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if strings.Contains(r.URL.Path, "clean") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if strings.Contains(r.URL.Path, "limited") {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`[{"Name":"Adobe"},{"Name":"LinkedIn"}]`))
+	}))
+	defer srv.Close()
+
+	store := &memBreachStore{m: map[string]int{}}
+	savedURL, savedCache, savedTTL := hibpURL, breachCache, HIBPCacheTTL
+	defer func() { hibpURL, breachCache, HIBPCacheTTL = savedURL, savedCache, savedTTL }()
+	hibpURL, breachCache, HIBPCacheTTL = srv.URL+"/", store, time.Hour
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if n := CheckHIBP(ctx, "Jane@Example.com", "key", nil); n != 2 {
+			t.Fatalf("call %d: breach count = %d, want 2", i, n)
+		}
+	}
+	if calls := atomic.LoadInt32(&hits); calls != 1 {
+		t.Errorf("expected one API call for a repeated address, got %d", calls)
+	}
+	if n := CheckHIBP(ctx, "jane@example.com", "key", nil); n != 2 || atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("case-folded address missed the cache: count=%d", n)
+	}
+
+	CheckHIBP(ctx, "clean@example.com", "key", nil)
+	CheckHIBP(ctx, "clean@example.com", "key", nil)
+	if calls := atomic.LoadInt32(&hits); calls != 2 {
+		t.Errorf("a clean (404) answer should be cached too, got %d API calls", calls)
+	}
+
+	for key := range store.m {
+		if strings.Contains(key, "@") || strings.Contains(key, "example") {
+			t.Errorf("cache key %q leaks the address", key)
+		}
+	}
+
+	// A rate-limited lookup is not an answer and must not be cached.
+	before := atomic.LoadInt32(&hits)
+	CheckHIBP(ctx, "limited@example.com", "key", nil)
+	if _, ok := store.Get(ctx, breachCacheKey("limited@example.com")); ok {
+		t.Error("a 429 result was cached")
+	}
+	if calls := atomic.LoadInt32(&hits) - before; calls != 2 {
+		t.Errorf("expected the 429 retry to reach the API, got %d calls", calls)
+	}
+}
@@ -0,0 +1,113 @@
+package lookup
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// disposableMu guards disposableDomains.
+var disposableMu sync.RWMutex
+
+// IsDisposableDomain checks if the domain is a known burner provider.
+func IsDisposableDomain(domain string) bool {
+	disposableMu.RLock()
+	defer disposableMu.RUnlock()
+	_, exists := disposableDomains[strings.ToLower(domain)]
+	return exists
+}
+
+// LoadDisposableList merges the newline-delimited domains in the file at path
+// into the disposable list. Blank lines and "#" comments are ignored.
+func LoadDisposableList(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := mergeDisposableList(f)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	log.Printf("[disposable] loaded %d domains from %s", n, path)
+	return nil
+}
+
+// RefreshDisposableList fetches a newline-delimited domain list from url and
+// merges it into the disposable list. Entries are only ever added, so a
+// truncated or failed download cannot shrink the list.
+func RefreshDisposableList(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := sharedClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch %s: status %d", url, resp.StatusCode)
+	}
+
+	n, err := mergeDisposableList(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return fmt.Errorf("read %s: %w", url, err)
+	}
+	log.Printf("[disposable] merged %d domains from %s", n, url)
+	return nil
+}
+
+// StartDisposableRefresh calls RefreshDisposableList immediately and then on
+// the given interval until ctx is cancelled. Failures are logged and the
+// current list is kept.
+func StartDisposableRefresh(ctx context.Context, url string, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := RefreshDisposableList(ctx, url); err != nil {
+				log.Printf("[disposable] refresh failed, keeping current list: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// mergeDisposableList adds every domain in r to the disposable list and
+// returns how many lines named a domain. The list is parsed in full before
+// the lock is taken, so lookups never wait on I/O.
+func mergeDisposableList(r io.Reader) (int, error) {
+	var domains []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, strings.ToLower(line))
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+
+	disposableMu.Lock()
+	defer disposableMu.Unlock()
+	for _, d := range domains {
+		disposableDomains[d] = struct{}{}
+	}
+	return len(domains), nil
+}
@@ -0,0 +1,79 @@
+package lookup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withDisposableList restores the built-in list when the test ends.
+func withDisposableList(t *testing.T) {
+	t.Helper()
+	disposableMu.Lock()
+	saved := make(map[string]struct{}, len(disposableDomains))
+	for d := range disposableDomains {
+		saved[d] = struct{}{}
+	}
+	disposableMu.Unlock()
+	t.Cleanup(func() {
+		disposableMu.Lock()
+		disposableDomains = saved
+		disposableMu.Unlock()
+	})
+}
+
+func TestLoadDisposableList(t *testing.T) {
+	withDisposableList(t)
+
+	path := filepath.Join(t.TempDir(), "disposable.txt")
+	list := "# burner providers\nBurner.Example\n\n  trashmail.example  \n"
+	if err := os.WriteFile(path, []byte(list), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDisposableList(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []string{"burner.example", "TRASHMAIL.example", "mailinator.com"} {
+		if !IsDisposableDomain(d) {
+			t.Errorf("%s should be disposable after loading", d)
+		}
+	}
+	if IsDisposableDomain("# burner providers") {
+		t.Errorf("comment lines must be skipped")
+	}
+
+	if err := LoadDisposableList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestRefreshDisposableList(t *testing.T) {
+	withDisposableList(t)
+
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		io.WriteString(w, "fresh-burner.example\n")
+	}))
+	defer srv.Close()
+
+	if err := RefreshDisposableList(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if !IsDisposableDomain("fresh-burner.example") {
+		t.Errorf("refreshed domain not merged")
+	}
+
+	status = http.StatusInternalServerError
+	if err := RefreshDisposableList(context.Background(), srv.URL); err == nil {
+		t.Errorf("expected an error for a failed download")
+	}
+	if !IsDisposableDomain("fresh-burner.example") || !IsDisposableDomain("mailinator.com") {
+		t.Errorf("a failed refresh must keep the current list")
+	}
+}
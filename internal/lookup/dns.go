@@ -2,12 +2,23 @@ package lookup
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"time"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/config"
 )
 
+// NegativeMXCacheTTL is how long CheckDNS remembers that a domain does not
+// exist or has neither MX nor address records, so junk domains repeated
+// through a list are not looked up again for every address. It should stay
+// well below the positive cache TTLs, since a fixed domain must not stay
+// dead for long. Set via NEGATIVE_CACHE_TTL; 0 disables it.
+var NegativeMXCacheTTL = config.NonNegativeDuration("NEGATIVE_CACHE_TTL", 2*time.Minute)
+
 // MXRecord holds the simplified result of an MX lookup.
 // Using a value type rather than *net.MX avoids mutating structs that
 // Go's internal resolver may have cached.
@@ -18,6 +29,8 @@ type MXRecord struct {
 
 // CheckDNS performs the initial domain validation and MX lookup.
 // Returns a slice of MXRecord values sorted by preference (lowest = highest priority).
+// A domain with no MX records but an A/AAAA record yields the domain itself as
+// its implicit MX.
 //
 // BUG FIXED (issue #10): The previous fallback dialer hardcoded "udp" as the
 // network protocol regardless of what the resolver originally requested:
@@ -39,7 +52,72 @@ type MXRecord struct {
 // requested, preserving the protocol contract. The Google DNS address is still
 // used as the fallback *destination*, but over the correct transport.
 func CheckDNS(ctx context.Context, domain string) ([]MXRecord, error) {
-	r := &net.Resolver{
+	negKey := "mx_negative:" + strings.ToLower(strings.TrimSuffix(domain, "."))
+	if v, ok := cache.DomainCache.Get(negKey); ok {
+		return nil, v.(error)
+	}
+
+	r := newResolver()
+
+	rawRecords, err := r.LookupMX(ctx, domain)
+	var dnsErr *net.DNSError
+	// noMail is set once both lookups have definitively come back empty, as
+	// opposed to failing; only that answer is cached.
+	noMail := false
+	if (err == nil && len(rawRecords) == 0) || (errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		// RFC 5321 §5.1: mail for a domain without MX records is delivered
+		// to its address record. Small self-hosted domains rely on this.
+		addrs, aErr := r.LookupIPAddr(ctx, domain)
+		if aErr == nil && len(addrs) > 0 {
+			return []MXRecord{{Host: strings.TrimSuffix(domain, "."), Pref: 0}}, nil
+		}
+		var aDNSErr *net.DNSError
+		noMail = aErr == nil || (errors.As(aErr, &aDNSErr) && aDNSErr.IsNotFound)
+	}
+	if err != nil {
+		err = fmt.Errorf("DNS lookup failed: %w", err)
+	} else if len(rawRecords) == 0 {
+		err = fmt.Errorf("no MX records found for domain")
+	}
+	if err != nil {
+		if noMail && NegativeMXCacheTTL > 0 {
+			cache.DomainCache.Set(negKey, err, NegativeMXCacheTTL)
+		}
+		return nil, err
+	}
+
+	// Copy into our own value-typed MXRecord slice rather than mutating
+	// the *net.MX pointers returned by LookupMX. Go's resolver may cache those
+	// structs internally, and stripping the trailing dot in-place would corrupt
+	// any subsequent lookup that reuses the same cached pointer.
+	records := make([]MXRecord, 0, len(rawRecords))
+	for _, mx := range rawRecords {
+		records = append(records, MXRecord{
+			// Strip the trailing dot from Go's FQDN format.
+			// SOCKS5 proxies will fail to resolve hostnames ending in a dot.
+			Host: strings.TrimSuffix(mx.Host, "."),
+			Pref: mx.Pref,
+		})
+	}
+
+	return records, nil
+}
+
+// dnsResolver is the subset of *net.Resolver CheckDNS and the TXT checks use.
+type dnsResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// newResolver builds the resolver CheckDNS queries: DNS-over-HTTPS when
+// DNSMode is "doh", otherwise the Go resolver. It is a variable so tests can
+// avoid real DNS.
+var newResolver = func() dnsResolver {
+	if strings.EqualFold(DNSMode, "doh") {
+		return &dohResolver{endpoint: DoHURL, client: dohClient}
+	}
+	return &net.Resolver{
 		PreferGo: true,
 		Dial: func(dialCtx context.Context, network, address string) (net.Conn, error) {
 			// Respect the caller's context deadline rather than always using a
@@ -71,29 +149,4 @@ func CheckDNS(ctx context.Context, domain string) ([]MXRecord, error) {
 			return conn, err
 		},
 	}
-
-	rawRecords, err := r.LookupMX(ctx, domain)
-	if err != nil {
-		return nil, fmt.Errorf("DNS lookup failed: %w", err)
-	}
-
-	if len(rawRecords) == 0 {
-		return nil, fmt.Errorf("no MX records found for domain")
-	}
-
-	// Copy into our own value-typed MXRecord slice rather than mutating
-	// the *net.MX pointers returned by LookupMX. Go's resolver may cache those
-	// structs internally, and stripping the trailing dot in-place would corrupt
-	// any subsequent lookup that reuses the same cached pointer.
-	records := make([]MXRecord, 0, len(rawRecords))
-	for _, mx := range rawRecords {
-		records = append(records, MXRecord{
-			// Strip the trailing dot from Go's FQDN format.
-			// SOCKS5 proxies will fail to resolve hostnames ending in a dot.
-			Host: strings.TrimSuffix(mx.Host, "."),
-			Pref: mx.Pref,
-		})
-	}
-
-	return records, nil
 }
@@ -0,0 +1,114 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+type fakeResolver struct {
+	mx    []*net.MX
+	mxErr error
+	addrs []net.IPAddr
+}
+
+func (f fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return f.mx, f.mxErr
+}
+
+func (f fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if len(f.addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return f.addrs, nil
+}
+
+func (f fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func withResolver(t *testing.T, r dnsResolver) {
+	t.Helper()
+	saved := newResolver
+	t.Cleanup(func() { newResolver = saved })
+	newResolver = func() dnsResolver { return r }
+}
+
+func TestCheckDNSImplicitMX(t *testing.T) {
+	notFound := &net.DNSError{Err: "no such host", Name: "selfhosted.example", IsNotFound: true}
+	withResolver(t, fakeResolver{
+		mxErr: notFound,
+		addrs: []net.IPAddr{{IP: net.ParseIP("192.0.2.10")}},
+	})
+
+	records, err := CheckDNS(context.Background(), "selfhosted.example")
+	if err != nil {
+		t.Fatalf("expected implicit MX, got error: %v", err)
+	}
+	if len(records) != 1 || records[0].Host != "selfhosted.example" {
+		t.Errorf("records = %+v, expected the domain itself as implicit MX", records)
+	}
+}
+
+func TestCheckDNSNoMXNoAddress(t *testing.T) {
+	notFound := &net.DNSError{Err: "no such host", Name: "nothing.example", IsNotFound: true}
+	withResolver(t, fakeResolver{mxErr: notFound})
+
+	if _, err := CheckDNS(context.Background(), "nothing.example"); err == nil {
+		t.Errorf("expected an error for a domain with neither MX nor address records")
+	}
+}
+
+func TestCheckDNSPrefersExplicitMX(t *testing.T) {
+	withResolver(t, fakeResolver{
+		mx:    []*net.MX{{Host: "mx1.example.com.", Pref: 10}},
+		addrs: []net.IPAddr{{IP: net.ParseIP("192.0.2.10")}},
+	})
+
+	records, err := CheckDNS(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Host != "mx1.example.com" {
+		t.Errorf("records = %+v, expected the explicit MX", records)
+	}
+}
+
+// countingResolver counts MX lookups made through it.
+type countingResolver struct {
+	fakeResolver
+	mxLookups *int
+}
+
+func (c countingResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	*c.mxLookups++
+	return c.fakeResolver.LookupMX(ctx, name)
+}
+
+func TestCheckDNSNegativeCache(t *testing.T) {
+	var lookups int
+	notFound := &net.DNSError{Err: "no such host", Name: "junk-negative.example", IsNotFound: true}
+	withResolver(t, countingResolver{fakeResolver{mxErr: notFound}, &lookups})
+
+	for i := 0; i < 3; i++ {
+		_, err := CheckDNS(context.Background(), "junk-negative.example")
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			t.Fatalf("lookup %d: want a not-found DNS error, got %v", i, err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("%d MX lookups for a cached NXDOMAIN, want 1", lookups)
+	}
+
+	// A failed lookup is not an answer and is never cached.
+	lookups = 0
+	timeout := &net.DNSError{Err: "i/o timeout", Name: "flaky-negative.example", IsTimeout: true}
+	withResolver(t, countingResolver{fakeResolver{mxErr: timeout}, &lookups})
+	CheckDNS(context.Background(), "flaky-negative.example")
+	CheckDNS(context.Background(), "flaky-negative.example")
+	if lookups != 2 {
+		t.Errorf("%d MX lookups after a timeout, want 2", lookups)
+	}
+}
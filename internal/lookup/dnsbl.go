@@ -0,0 +1,235 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/proxy"
+)
+
+// DNSBLZones are the blocklists CheckDNSBL queries. Set via DNSBL_ZONES;
+// Spamhaus ZEN, Barracuda and SpamCop by default.
+var DNSBLZones = dnsblZones(config.List("DNSBL_ZONES"))
+
+// PublicIPURL answers a GET with the caller's public IP as plain text. The
+// DNSBL self-check uses it to find the address direct probes leave from when
+// SMTP_SOURCE_IPS is unset, and each proxy's exit IP. Set via PUBLIC_IP_URL.
+var PublicIPURL = config.String("PUBLIC_IP_URL", "https://api.ipify.org")
+
+func dnsblZones(zones []string) []string {
+	if len(zones) == 0 {
+		return []string{"zen.spamhaus.org", "b.barracudacentral.org", "bl.spamcop.net"}
+	}
+	return zones
+}
+
+// CheckDNSBL returns the zones in DNSBLZones that list ip, in DNSBLZones
+// order. A zone that cannot be queried counts as not listing it.
+func CheckDNSBL(ctx context.Context, ip string) []string {
+	name, ok := dnsblName(ip)
+	if !ok {
+		return nil
+	}
+
+	r := newResolver()
+	zones := DNSBLZones
+	listed := make([]bool, len(zones))
+	var wg sync.WaitGroup
+	for i, zone := range zones {
+		wg.Add(1)
+		go func(i int, zone string) {
+			defer wg.Done()
+			listed[i] = dnsblListed(ctx, r, name+"."+zone, zone)
+		}(i, zone)
+	}
+	wg.Wait()
+
+	var out []string
+	for i, zone := range zones {
+		if listed[i] {
+			out = append(out, zone)
+		}
+	}
+	return out
+}
+
+// dnsblName is ip in the reversed form DNSBLs are queried by: octets for
+// IPv4, nibbles for IPv6.
+func dnsblName(ip string) (string, bool) {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return "", false
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", v4[3], v4[2], v4[1], v4[0]), true
+	}
+	const hexDigits = "0123456789abcdef"
+	nibbles := make([]string, 0, 32)
+	for i := len(parsed) - 1; i >= 0; i-- {
+		b := parsed[i]
+		nibbles = append(nibbles, string(hexDigits[b&0x0f]), string(hexDigits[b>>4]))
+	}
+	return strings.Join(nibbles, "."), true
+}
+
+// dnsblListed reports whether query resolves to a listing answer, which
+// DNSBLs give in 127.0.0.0/8.
+func dnsblListed(ctx context.Context, r dnsResolver, query, zone string) bool {
+	addrs, err := r.LookupIPAddr(ctx, query)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		v4 := a.IP.To4()
+		if v4 == nil || v4[0] != 127 {
+			continue
+		}
+		// Spamhaus answers 127.255.255.x when it refuses the query — most
+		// often because it came through a public resolver — which says
+		// nothing about the IP.
+		if v4[1] == 255 && v4[2] == 255 {
+			log.Printf("⚠️  DNSBL %s refused the query (%s); query it through a private resolver", zone, v4)
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// DNSBLReport is one outbound IP's standing on DNSBLZones.
+type DNSBLReport struct {
+	// Proxy is the proxy, password redacted, whose exit IP this is. It is
+	// empty for the addresses direct probes leave from.
+	Proxy  string   `json:"proxy,omitempty"`
+	IP     string   `json:"ip,omitempty"`
+	Listed []string `json:"listed"`
+	// Error says why a proxy's exit IP could not be found.
+	Error string `json:"error,omitempty"`
+}
+
+// OutboundIPs returns the addresses direct SMTP probes leave from: the
+// SMTP_SOURCE_IPS pool when configured, otherwise the public address
+// PublicIPURL reports.
+func OutboundIPs(ctx context.Context) ([]string, error) {
+	if len(SourceIPs) > 0 {
+		ips := make([]string, len(SourceIPs))
+		for i, ip := range SourceIPs {
+			ips[i] = ip.String()
+		}
+		return ips, nil
+	}
+
+	ip, err := publicIP(ctx, http.DefaultClient)
+	if err != nil {
+		return nil, err
+	}
+	return []string{ip}, nil
+}
+
+// ProxyExitIP returns the public address traffic sent through u leaves from,
+// as PublicIPURL sees it. That is the address a mail server or DNSBL judges,
+// not the proxy's own host.
+func ProxyExitIP(ctx context.Context, u *url.URL) (string, error) {
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(u)}}
+	defer client.CloseIdleConnections()
+	return publicIP(ctx, client)
+}
+
+// publicIP asks PublicIPURL, through client, for the caller's public IP.
+func publicIP(ctx context.Context, client *http.Client) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, PublicIPURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("public IP lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", fmt.Errorf("public IP lookup failed: %w", err)
+	}
+	ip := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK || net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("public IP lookup returned %d %q", resp.StatusCode, ip)
+	}
+	return ip, nil
+}
+
+// SelfCheckDNSBL checks every outbound IP against DNSBLZones: the addresses
+// direct probes leave from, then the exit IP of every loaded proxy. A listed
+// IP is the usual explanation for SMTP servers rejecting probes wholesale.
+func SelfCheckDNSBL(ctx context.Context) ([]DNSBLReport, error) {
+	ips, err := OutboundIPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]DNSBLReport, len(ips))
+	for i, ip := range ips {
+		reports[i] = DNSBLReport{IP: ip, Listed: listedOn(ctx, ip)}
+	}
+
+	proxies := proxy.Global.Proxies()
+	viaProxy := make([]DNSBLReport, len(proxies))
+	var wg sync.WaitGroup
+	for i, u := range proxies {
+		wg.Add(1)
+		go func(i int, u *url.URL) {
+			defer wg.Done()
+			r := DNSBLReport{Proxy: u.Redacted(), Listed: []string{}}
+			if ip, err := ProxyExitIP(ctx, u); err != nil {
+				r.Error = err.Error()
+			} else {
+				r.IP, r.Listed = ip, listedOn(ctx, ip)
+			}
+			viaProxy[i] = r
+		}(i, u)
+	}
+	wg.Wait()
+	return append(reports, viaProxy...), nil
+}
+
+// listedOn is CheckDNSBL with an empty slice rather than nil for an IP no
+// zone lists, so reports encode it as [].
+func listedOn(ctx context.Context, ip string) []string {
+	if listed := CheckDNSBL(ctx, ip); listed != nil {
+		return listed
+	}
+	return []string{}
+}
+
+// LogDNSBLSelfCheck runs SelfCheckDNSBL and logs the outcome for each IP.
+// It is meant to run once in the background at startup.
+func LogDNSBLSelfCheck(ctx context.Context) {
+	reports, err := SelfCheckDNSBL(ctx)
+	if err != nil {
+		log.Printf("⚠️  DNSBL self-check skipped: %v", err)
+		return
+	}
+	for _, r := range reports {
+		what := "Outbound IP " + r.IP
+		if r.Proxy != "" {
+			what = fmt.Sprintf("Proxy %s exit IP %s", r.Proxy, r.IP)
+		}
+		switch {
+		case r.Error != "":
+			log.Printf("⚠️  Proxy %s exit IP unknown, not checked: %s", r.Proxy, r.Error)
+		case len(r.Listed) > 0:
+			log.Printf("🚨 %s is listed on %s — expect SMTP rejections", what, strings.Join(r.Listed, ", "))
+		default:
+			log.Printf("✅ %s is not listed on %d DNSBLs", what, len(DNSBLZones))
+		}
+	}
+}
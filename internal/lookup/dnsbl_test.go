@@ -0,0 +1,124 @@
+package lookup
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"mailvetter/internal/proxy"
+)
+
+// dnsblResolver answers address lookups from a fixed table.
+type dnsblResolver struct {
+	fakeResolver
+	answers map[string]string
+}
+
+func (r dnsblResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if a, ok := r.answers[host]; ok {
+		return []net.IPAddr{{IP: net.ParseIP(a)}}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestCheckDNSBL(t *testing.T) {
+	saved := DNSBLZones
+	t.Cleanup(func() { DNSBLZones = saved })
+	DNSBLZones = []string{"zen.example", "refusing.example", "clean.example", "other.example"}
+
+	withResolver(t, dnsblResolver{answers: map[string]string{
+		"10.113.0.203.zen.example":      "127.0.0.2",
+		"10.113.0.203.refusing.example": "127.255.255.254",
+		"10.113.0.203.other.example":    "127.0.0.4",
+		"11.113.0.203.zen.example":      "127.0.0.2",
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.zen.example": "127.0.0.3",
+	}})
+
+	if got, want := CheckDNSBL(context.Background(), "203.0.113.10"), []string{"zen.example", "other.example"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("listed on %v, want %v", got, want)
+	}
+	if got := CheckDNSBL(context.Background(), "2001:db8::1"); !reflect.DeepEqual(got, []string{"zen.example"}) {
+		t.Errorf("IPv6 listed on %v, want [zen.example]", got)
+	}
+	if got := CheckDNSBL(context.Background(), "203.0.113.12"); got != nil {
+		t.Errorf("clean IP listed on %v", got)
+	}
+	if got := CheckDNSBL(context.Background(), "not-an-ip"); got != nil {
+		t.Errorf("invalid IP listed on %v", got)
+	}
+}
+
+func TestSelfCheckDNSBLDiscoversPublicIP(t *testing.T) {
+	savedZones, savedURL, savedIPs := DNSBLZones, PublicIPURL, SourceIPs
+	t.Cleanup(func() { DNSBLZones, PublicIPURL, SourceIPs = savedZones, savedURL, savedIPs })
+	DNSBLZones = []string{"zen.example"}
+	SourceIPs = nil
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("203.0.113.10\n"))
+	}))
+	defer srv.Close()
+	PublicIPURL = srv.URL
+
+	withResolver(t, dnsblResolver{answers: map[string]string{"10.113.0.203.zen.example": "127.0.0.2"}})
+
+	reports, err := SelfCheckDNSBL(context.Background())
+	if err != nil {
+		t.Fatalf("SelfCheckDNSBL: %v", err)
+	}
+	want := []DNSBLReport{{IP: "203.0.113.10", Listed: []string{"zen.example"}}}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("reports = %+v, want %+v", reports, want)
+	}
+}
+
+func TestSelfCheckDNSBLChecksProxyExitIPs(t *testing.T) {
+	savedZones, savedURL, savedIPs := DNSBLZones, PublicIPURL, SourceIPs
+	savedGlobal, savedSem, savedSMTP := proxy.Global, proxy.Semaphore, proxy.SMTPEnabled
+	t.Cleanup(func() {
+		DNSBLZones, PublicIPURL, SourceIPs = savedZones, savedURL, savedIPs
+		proxy.Global, proxy.Semaphore, proxy.SMTPEnabled = savedGlobal, savedSem, savedSMTP
+	})
+	DNSBLZones = []string{"zen.example"}
+	SourceIPs = []net.IP{net.ParseIP("192.0.2.1")}
+	PublicIPURL = "http://ip.example/"
+
+	// An HTTP proxy is sent the absolute URL; answering it directly stands
+	// in for forwarding the request and relaying the reply.
+	exit := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() != PublicIPURL {
+			http.Error(w, "unexpected target", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("198.51.100.7"))
+	}))
+	defer exit.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	if err := proxy.Init([]string{exit.URL, dead.URL}, 0, false); err != nil {
+		t.Fatalf("proxy.Init: %v", err)
+	}
+	withResolver(t, dnsblResolver{answers: map[string]string{"7.100.51.198.zen.example": "127.0.0.2"}})
+
+	reports, err := SelfCheckDNSBL(context.Background())
+	if err != nil {
+		t.Fatalf("SelfCheckDNSBL: %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("expected the direct IP and both proxies, got %+v", reports)
+	}
+	if r := reports[0]; r.Proxy != "" || r.IP != "192.0.2.1" || len(r.Listed) != 0 {
+		t.Errorf("direct report = %+v", r)
+	}
+	want := DNSBLReport{Proxy: exit.URL, IP: "198.51.100.7", Listed: []string{"zen.example"}}
+	if !reflect.DeepEqual(reports[1], want) {
+		t.Errorf("proxy report = %+v, want %+v", reports[1], want)
+	}
+	if r := reports[2]; r.Proxy != dead.URL || r.IP != "" || r.Error == "" {
+		t.Errorf("unreachable proxy should report an error, got %+v", r)
+	}
+}
@@ -0,0 +1,241 @@
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/config"
+)
+
+// DNSMode selects the resolver behind CheckDNS and the TXT checks: "system"
+// (default) uses the Go resolver with its 8.8.8.8 fallback, "doh" sends every
+// query to DoHURL over HTTPS, for locked-down networks and to sidestep ISP
+// tampering with MX answers. Set via DNS_MODE.
+var DNSMode = config.String("DNS_MODE", "system")
+
+// DoHURL is a DNS-over-HTTPS endpoint speaking the JSON API shared by
+// Cloudflare (https://cloudflare-dns.com/dns-query) and Google
+// (https://dns.google/resolve). Set via DOH_URL.
+var DoHURL = config.String("DOH_URL", "https://cloudflare-dns.com/dns-query")
+
+// DNS record types used with the DoH JSON API.
+const (
+	dnsTypeA    = 1
+	dnsTypeMX   = 15
+	dnsTypeTXT  = 16
+	dnsTypeAAAA = 28
+)
+
+// dohNegativeTTL is how long a NXDOMAIN or empty answer is cached when the
+// response carries no SOA to take a TTL from.
+const dohNegativeTTL = 5 * time.Minute
+
+var dohClient = &http.Client{Timeout: 5 * time.Second}
+
+// dohResolver implements dnsResolver over the DoH JSON API. Answers are cached
+// in cache.DomainCache per query name and type for the record TTL.
+type dohResolver struct {
+	endpoint string
+	client   *http.Client
+}
+
+type dohResponse struct {
+	Status    int         `json:"Status"`
+	TC        bool        `json:"TC"`
+	Answer    []dohRecord `json:"Answer"`
+	Authority []dohRecord `json:"Authority"`
+}
+
+type dohRecord struct {
+	Name string `json:"name"`
+	Type int    `json:"type"`
+	TTL  int    `json:"TTL"`
+	Data string `json:"data"`
+}
+
+// query returns the data of every answer of type qtype for name.
+func (r *dohResolver) query(ctx context.Context, name string, qtype int) ([]string, error) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	key := "doh:" + name + ":" + strconv.Itoa(qtype)
+	if v, ok := cache.DomainCache.Get(key); ok {
+		if _, nx := v.(dohNXDomain); nx {
+			return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+		}
+		return v.([]string), nil
+	}
+
+	q := url.Values{"name": {name}, "type": {strconv.Itoa(qtype)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: name, IsTemporary: true}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &net.DNSError{Err: "DoH server returned " + resp.Status, Name: name, IsTemporary: true}
+	}
+
+	var body dohResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, &net.DNSError{Err: "malformed DoH response: " + err.Error(), Name: name, IsTemporary: true}
+	}
+
+	// Over HTTPS the upstream resolver is responsible for retrying a
+	// truncated UDP answer over TCP. If it still reports TC, the record set
+	// is incomplete; fail rather than return a partial MX list.
+	if body.TC {
+		return nil, &net.DNSError{Err: "truncated DoH response", Name: name, IsTemporary: true}
+	}
+
+	switch body.Status {
+	case 0: // NOERROR
+	case 3: // NXDOMAIN
+		cache.DomainCache.Set(key, dohNXDomain{}, negativeTTL(body))
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	default:
+		return nil, &net.DNSError{Err: fmt.Sprintf("DoH rcode %d", body.Status), Name: name, IsTemporary: true}
+	}
+
+	var data []string
+	ttl := 0
+	for _, a := range body.Answer {
+		if a.Type != qtype {
+			continue // CNAME hops on the way to the answer
+		}
+		data = append(data, a.Data)
+		if ttl == 0 || a.TTL < ttl {
+			ttl = a.TTL
+		}
+	}
+	if len(data) == 0 {
+		cache.DomainCache.Set(key, []string(nil), negativeTTL(body))
+	} else if ttl > 0 {
+		cache.DomainCache.Set(key, data, time.Duration(ttl)*time.Second)
+	}
+	return data, nil
+}
+
+// dohNXDomain is cached for a name that does not exist, so a cache hit
+// reports not-found again rather than an empty answer.
+type dohNXDomain struct{}
+
+// negativeTTL follows RFC 2308: a negative answer is cached for the SOA TTL
+// in the authority section, falling back to dohNegativeTTL.
+func negativeTTL(body dohResponse) time.Duration {
+	for _, a := range body.Authority {
+		if a.TTL > 0 {
+			return time.Duration(a.TTL) * time.Second
+		}
+	}
+	return dohNegativeTTL
+}
+
+func (r *dohResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	data, err := r.query(ctx, name, dnsTypeMX)
+	if err != nil {
+		return nil, err
+	}
+	var mx []*net.MX
+	for _, d := range data {
+		prefStr, host, ok := strings.Cut(strings.TrimSpace(d), " ")
+		pref, perr := strconv.ParseUint(prefStr, 10, 16)
+		if !ok || perr != nil {
+			continue
+		}
+		mx = append(mx, &net.MX{Host: strings.TrimSpace(host), Pref: uint16(pref)})
+	}
+	return mx, nil
+}
+
+func (r *dohResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	var addrs []net.IPAddr
+	var firstErr error
+	for _, qtype := range []int{dnsTypeA, dnsTypeAAAA} {
+		data, err := r.query(ctx, host, qtype)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, d := range data {
+			if ip := net.ParseIP(strings.TrimSpace(d)); ip != nil {
+				addrs = append(addrs, net.IPAddr{IP: ip})
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func (r *dohResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	data, err := r.query(ctx, name, dnsTypeTXT)
+	if err != nil {
+		return nil, err
+	}
+	txts := make([]string, 0, len(data))
+	for _, d := range data {
+		txts = append(txts, unquoteTXT(d))
+	}
+	return txts, nil
+}
+
+// unquoteTXT joins the character-strings of one TXT record. Cloudflare
+// presents them quoted ("v=spf1 " "include:…"), Google as plain text. Quoted
+// strings use zone-file escapes (RFC 1035 §5.1): \DDD is a decimal byte
+// value and a backslash before any other character stands for it literally.
+func unquoteTXT(data string) string {
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, `"`) {
+		return data
+	}
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case !quoted:
+			// Whitespace between character-strings.
+		case c == '\\' && i+3 < len(data) && isDigits(data[i+1:i+4]):
+			if n, _ := strconv.Atoi(data[i+1 : i+4]); n <= 255 {
+				b.WriteByte(byte(n))
+			}
+			i += 3
+		case c == '\\' && i+1 < len(data):
+			i++
+			b.WriteByte(data[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// dohServer serves canned DoH JSON answers keyed by "name type" and counts
+// the queries that reach it.
+func dohServer(t *testing.T, answers map[string]string) (*dohResolver, *int32) {
+	t.Helper()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.Header.Get("Accept") != "application/dns-json" {
+			t.Errorf("Accept = %q", r.Header.Get("Accept"))
+		}
+		body, ok := answers[r.URL.Query().Get("name")+" "+r.URL.Query().Get("type")]
+		if !ok {
+			body = `{"Status":3,"Authority":[{"type":6,"TTL":60}]}`
+		}
+		w.Header().Set("Content-Type", "application/dns-json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return &dohResolver{endpoint: srv.URL, client: srv.Client()}, &hits
+}
+
+func TestDoHLookupMXCached(t *testing.T) {
+	r, hits := dohServer(t, map[string]string{
+		"doh-mx.example 15": `{"Status":0,"Answer":[
+			{"name":"doh-mx.example.","type":5,"TTL":300,"data":"alias.example."},
+			{"name":"doh-mx.example.","type":15,"TTL":300,"data":"20 mx2.doh-mx.example."},
+			{"name":"doh-mx.example.","type":15,"TTL":120,"data":"10 mx1.doh-mx.example."}]}`,
+	})
+	withResolver(t, r)
+
+	for i := 0; i < 2; i++ {
+		records, err := CheckDNS(context.Background(), "doh-mx.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || records[1] != (MXRecord{Host: "mx1.doh-mx.example", Pref: 10}) {
+			t.Fatalf("records = %+v", records)
+		}
+	}
+	if got := atomic.LoadInt32(hits); got != 1 {
+		t.Errorf("%d DoH queries, expected the second lookup to be served from cache", got)
+	}
+}
+
+func TestDoHNXDomain(t *testing.T) {
+	r, hits := dohServer(t, nil)
+
+	// The second lookup is served from cache and must still report the
+	// name missing, not an empty answer.
+	for i := 0; i < 2; i++ {
+		_, err := r.LookupMX(context.Background(), "doh-missing.example")
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			t.Errorf("lookup %d: expected a not-found DNSError, got %v", i+1, err)
+		}
+	}
+	if got := atomic.LoadInt32(hits); got != 1 {
+		t.Errorf("%d DoH queries, expected the NXDOMAIN to be cached", got)
+	}
+}
+
+func TestUnquoteTXT(t *testing.T) {
+	for in, want := range map[string]string{
+		`"v=spf1 " "~all"`:       "v=spf1 ~all",
+		`"a\059b"`:               "a;b",
+		`"k=v\;p=\"x\""`:         `k=v;p="x"`,
+		`"back\\slash"`:          `back\slash`,
+		`"100\0370"`:             "100%0",
+		`plain text, not quoted`: "plain text, not quoted",
+	} {
+		if got := unquoteTXT(in); got != want {
+			t.Errorf("unquoteTXT(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDoHTruncatedResponseFails(t *testing.T) {
+	r, _ := dohServer(t, map[string]string{
+		"doh-tc.example 15": `{"Status":0,"TC":true,"Answer":[
+			{"name":"doh-tc.example.","type":15,"TTL":300,"data":"10 mx1.doh-tc.example."}]}`,
+	})
+
+	mx, err := r.LookupMX(context.Background(), "doh-tc.example")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsTemporary || mx != nil {
+		t.Errorf("expected a temporary error instead of a partial MX set, got %v, %v", mx, err)
+	}
+}
+
+func TestDoHLookupTXT(t *testing.T) {
+	r, _ := dohServer(t, map[string]string{
+		// Cloudflare quotes each character-string; Google does not.
+		"doh-txt.example 16": `{"Status":0,"Answer":[
+			{"name":"doh-txt.example.","type":16,"TTL":300,"data":"\"v=spf1 include:_spf.google.com \" \"~all\""},
+			{"name":"doh-txt.example.","type":16,"TTL":300,"data":"stripe-verification=abc"}]}`,
+	})
+	withResolver(t, r)
+
+	txts, err := r.LookupTXT(context.Background(), "doh-txt.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txts) != 2 || txts[0] != "v=spf1 include:_spf.google.com ~all" || txts[1] != "stripe-verification=abc" {
+		t.Errorf("txts = %q", txts)
+	}
+	if !CheckSPF(context.Background(), "doh-txt.example") || !CheckSaaSTokens(context.Background(), "doh-txt.example") {
+		t.Error("SPF and SaaS checks should read TXT records through the DoH resolver")
+	}
+}
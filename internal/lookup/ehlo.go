@@ -0,0 +1,100 @@
+package lookup
+
+import (
+	"errors"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mailvetter/internal/config"
+)
+
+// AlwaysEHLO greets every server with EHLO, not only those whose banner
+// mentions ESMTP, so capabilities are learned from servers with terse
+// banners too. A server that rejects EHLO is still greeted again with HELO.
+// Set via SMTP_ALWAYS_EHLO.
+var AlwaysEHLO = config.Bool("SMTP_ALWAYS_EHLO", false)
+
+// recordedExtensions are the EHLO keywords SMTPCapabilities keeps; the rest
+// say nothing about how a server treats probes.
+var recordedExtensions = map[string]bool{
+	"8BITMIME":            true,
+	"BINARYMIME":          true,
+	"CHUNKING":            true,
+	"DSN":                 true,
+	"ENHANCEDSTATUSCODES": true,
+	"PIPELINING":          true,
+	"REQUIRETLS":          true,
+	"SMTPUTF8":            true,
+	"STARTTLS":            true,
+}
+
+// SMTPCapabilities is what a server advertised in reply to EHLO.
+type SMTPCapabilities struct {
+	// Extensions are the recorded keywords advertised, upper-case and sorted.
+	Extensions []string
+	// Size is the SIZE limit in bytes; 0 when not advertised or unlimited.
+	Size int64
+	// Auth lists the AUTH mechanisms offered.
+	Auth []string
+}
+
+// parseEHLO reads an EHLO reply as ReadResponse returns it: the greeting
+// line, then one extension per line.
+func parseEHLO(reply string) SMTPCapabilities {
+	var c SMTPCapabilities
+	lines := strings.Split(reply, "\n")
+	for _, line := range lines[1:] {
+		// Pre-RFC 2554 servers advertise "AUTH=LOGIN PLAIN".
+		fields := strings.Fields(strings.Replace(line, "=", " ", 1))
+		if len(fields) == 0 {
+			continue
+		}
+		keyword := strings.ToUpper(fields[0])
+		switch {
+		case keyword == "SIZE":
+			if len(fields) > 1 {
+				c.Size, _ = strconv.ParseInt(fields[1], 10, 64)
+			}
+		case keyword == "AUTH":
+			for _, mech := range fields[1:] {
+				c.addAuth(strings.ToUpper(mech))
+			}
+		case recordedExtensions[keyword]:
+			c.addExtension(keyword)
+		}
+	}
+	return c
+}
+
+func (c *SMTPCapabilities) addExtension(ext string) {
+	for _, e := range c.Extensions {
+		if e == ext {
+			return
+		}
+	}
+	c.Extensions = append(c.Extensions, ext)
+	sort.Strings(c.Extensions)
+}
+
+func (c *SMTPCapabilities) addAuth(mech string) {
+	for _, m := range c.Auth {
+		if m == mech {
+			return
+		}
+	}
+	c.Auth = append(c.Auth, mech)
+}
+
+// IsAuthRequired reports whether err is the server demanding authentication
+// (530, RFC 4954) before it will take MAIL FROM or RCPT TO. Such a server
+// never answers an anonymous probe about a mailbox.
+func IsAuthRequired(err error) bool {
+	var textErr *textproto.Error
+	if !errors.As(err, &textErr) {
+		return false
+	}
+	return textErr.Code == 530 ||
+		strings.Contains(strings.ToLower(textErr.Msg), "authentication required")
+}
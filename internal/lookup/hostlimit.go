@@ -0,0 +1,100 @@
+package lookup
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"mailvetter/internal/config"
+)
+
+// SMTPPerHostLimit caps concurrent SMTP connections to any one MX host, so a
+// run of addresses at one provider cannot take every SMTPSemaphore slot and
+// get the egress IP throttled by that host. Set via SMTP_PER_HOST_LIMIT; 0
+// disables the cap.
+var SMTPPerHostLimit = config.NonNegativeInt("SMTP_PER_HOST_LIMIT", 3)
+
+// smtpHostSlots is the per-host limiter CheckSMTPAs and CheckVRFY acquire
+// before the global SMTPSemaphore. Taking the host slot first keeps probes
+// queued behind a busy host from sitting on global slots other hosts could
+// use.
+var smtpHostSlots = newHostLimiter(SMTPPerHostLimit)
+
+const (
+	// hostLimiterIdle is how long a host's entry outlives its last
+	// connection; hostLimiterSweep how often idle entries are looked for.
+	hostLimiterIdle  = 10 * time.Minute
+	hostLimiterSweep = time.Minute
+)
+
+// hostLimiter is a lazily populated map of per-host semaphores. Entries for
+// hosts not contacted in hostLimiterIdle are dropped on later acquires, so a
+// long-running worker does not accumulate one per MX it has ever seen.
+type hostLimiter struct {
+	limit int
+
+	mu        sync.Mutex
+	hosts     map[string]*hostSlot
+	lastSweep time.Time
+}
+
+type hostSlot struct {
+	sem      chan struct{}
+	users    int // holders and waiters; the entry is kept while non-zero
+	lastUsed time.Time
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, hosts: make(map[string]*hostSlot)}
+}
+
+// acquire waits for a connection slot to host. The returned release must be
+// called once the connection is closed.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (release func(), err error) {
+	if l.limit <= 0 {
+		return func() {}, nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	l.mu.Lock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= hostLimiterSweep {
+		l.sweep(now)
+	}
+	s := l.hosts[host]
+	if s == nil {
+		s = &hostSlot{sem: make(chan struct{}, l.limit)}
+		l.hosts[host] = s
+	}
+	s.users++
+	l.mu.Unlock()
+
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		l.leave(s)
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-s.sem
+		l.leave(s)
+	}, nil
+}
+
+func (l *hostLimiter) leave(s *hostSlot) {
+	l.mu.Lock()
+	s.users--
+	s.lastUsed = time.Now()
+	l.mu.Unlock()
+}
+
+// sweep drops idle entries. l.mu must be held.
+func (l *hostLimiter) sweep(now time.Time) {
+	l.lastSweep = now
+	for host, s := range l.hosts {
+		if s.users == 0 && now.Sub(s.lastUsed) >= hostLimiterIdle {
+			delete(l.hosts, host)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package lookup
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	l := newHostLimiter(2)
+	ctx := context.Background()
+
+	r1, err := l.acquire(ctx, "mx1.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := l.acquire(ctx, "MX1.example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The host is full; a third probe waits until its context gives up.
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(short, "mx1.example.com"); err == nil {
+		t.Fatal("third acquire on a full host succeeded")
+	}
+
+	// Other hosts are unaffected.
+	r3, err := l.acquire(ctx, "mx2.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r3()
+
+	r1()
+	r4, err := l.acquire(ctx, "mx1.example.com")
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	r2()
+	r4()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.hosts) != 2 {
+		t.Fatalf("hosts = %d, want 2", len(l.hosts))
+	}
+	l.hosts["mx2.example.com"].lastUsed = time.Now().Add(-2 * hostLimiterIdle)
+	l.hosts["mx1.example.com"].users = 1 // still in use, however old
+	l.hosts["mx1.example.com"].lastUsed = time.Now().Add(-2 * hostLimiterIdle)
+	l.sweep(time.Now())
+	if _, ok := l.hosts["mx2.example.com"]; ok {
+		t.Error("idle host was not swept")
+	}
+	if _, ok := l.hosts["mx1.example.com"]; !ok {
+		t.Error("host in use was swept")
+	}
+}
+
+func TestHostLimiterDisabled(t *testing.T) {
+	l := newHostLimiter(0)
+	for i := 0; i < 5; i++ {
+		if _, err := l.acquire(context.Background(), "mx.example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(l.hosts) != 0 {
+		t.Errorf("disabled limiter tracked %d hosts", len(l.hosts))
+	}
+}
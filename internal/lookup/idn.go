@@ -0,0 +1,86 @@
+package lookup
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// ToASCIIDomain converts an internationalized domain to its ASCII (punycode)
+// form for DNS and SMTP, e.g. "münchen.de" → "xn--mnchen-3ya.de". ASCII
+// domains are returned lowercased and otherwise unchanged.
+func ToASCIIDomain(domain string) (string, error) {
+	return idna.Lookup.ToASCII(domain)
+}
+
+// scriptCombos are the multi-script mixes legitimate in a single label, after
+// the Unicode "Highly Restrictive" profile (UTS #39 §5.2): Japanese, Chinese
+// and Korean text routinely mixes Han with kana or Hangul and with Latin.
+var scriptCombos = [][]*unicode.RangeTable{
+	{unicode.Latin, unicode.Han, unicode.Hiragana, unicode.Katakana},
+	{unicode.Latin, unicode.Han, unicode.Bopomofo},
+	{unicode.Latin, unicode.Han, unicode.Hangul},
+}
+
+// checkedScripts are the scripts IsMixedScriptDomain tells apart.
+var checkedScripts = []*unicode.RangeTable{
+	unicode.Latin, unicode.Cyrillic, unicode.Greek, unicode.Armenian,
+	unicode.Hebrew, unicode.Arabic, unicode.Han, unicode.Hiragana,
+	unicode.Katakana, unicode.Bopomofo, unicode.Hangul, unicode.Thai,
+	unicode.Devanagari, unicode.Georgian, unicode.Cherokee,
+}
+
+// IsMixedScriptDomain reports whether any label of domain mixes scripts in a
+// way no legitimate name does — the homograph trick behind lookalikes such
+// as "pаypal.com" with a Cyrillic "а". domain may be in Unicode or punycode
+// form. Digits, hyphens and marks shared between scripts are ignored.
+func IsMixedScriptDomain(domain string) bool {
+	if u, err := idna.Lookup.ToUnicode(domain); err == nil {
+		domain = u
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if mixedScriptLabel(label) {
+			return true
+		}
+	}
+	return false
+}
+
+func mixedScriptLabel(label string) bool {
+	var seen []*unicode.RangeTable
+	for _, r := range label {
+		for _, script := range checkedScripts {
+			if unicode.Is(script, r) && !containsTable(seen, script) {
+				seen = append(seen, script)
+			}
+		}
+	}
+	if len(seen) <= 1 {
+		return false
+	}
+	for _, combo := range scriptCombos {
+		if allIn(seen, combo) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTable(tables []*unicode.RangeTable, t *unicode.RangeTable) bool {
+	for _, x := range tables {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
+
+func allIn(tables, set []*unicode.RangeTable) bool {
+	for _, t := range tables {
+		if !containsTable(set, t) {
+			return false
+		}
+	}
+	return true
+}
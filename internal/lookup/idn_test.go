@@ -0,0 +1,42 @@
+package lookup
+
+import "testing"
+
+func TestToASCIIDomain(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"münchen.de", "xn--mnchen-3ya.de"},
+		{"例子.广告", "xn--fsqu00a.xn--4rr70v"},
+		{"Example.COM", "example.com"},
+	}
+	for _, tt := range tests {
+		got, err := ToASCIIDomain(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ToASCIIDomain(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestIsMixedScriptDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"example.com", false},
+		{"münchen.de", false},
+		{"пример.рф", false},
+		{"例子.广告", false},
+		// Japanese mixes kanji, kana and Latin legitimately.
+		{"ソニー東京tokyo.jp", false},
+		// Cyrillic "а" (U+0430) inside a Latin label.
+		{"pаypal.com", true},
+		// The same lookalike in punycode form.
+		{"xn--pypal-4ve.com", true},
+		// Greek omicron among Latin letters.
+		{"gοogle.com", true},
+	}
+	for _, tt := range tests {
+		if got := IsMixedScriptDomain(tt.domain); got != tt.want {
+			t.Errorf("IsMixedScriptDomain(%q) = %v, want %v", tt.domain, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package lookup
+
+import "strings"
+
+// gmailDomains are the domains whose local parts ignore dots.
+var gmailDomains = map[string]bool{"gmail.com": true, "googlemail.com": true}
+
+// NormalizeEmail returns the canonical form of email's mailbox: the domain is
+// lowercased and any "+tag" subaddress is dropped, and for Gmail addresses the
+// local part is also lowercased with its dots removed, since Gmail delivers
+// john.doe+news@gmail.com and johndoe@gmail.com to the same inbox. Quoted
+// local parts and anything that is not a single local@domain are returned
+// unchanged.
+func NormalizeEmail(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 || strings.HasPrefix(email, `"`) {
+		return email
+	}
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+
+	if plus := strings.IndexByte(local, '+'); plus > 0 {
+		local = local[:plus]
+	}
+	if gmailDomains[domain] {
+		if stripped := strings.ReplaceAll(strings.ToLower(local), ".", ""); stripped != "" {
+			local = stripped
+		}
+	}
+	return local + "@" + domain
+}
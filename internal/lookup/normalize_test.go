@@ -0,0 +1,26 @@
+package lookup
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"john.doe+news@gmail.com", "johndoe@gmail.com"},
+		{"John.Doe@GMail.com", "johndoe@gmail.com"},
+		{"j.o.h.n@googlemail.com", "john@googlemail.com"},
+		{"jane+billing@Example.com", "jane@example.com"},
+		// Dots are significant outside Gmail.
+		{"jane.doe@example.com", "jane.doe@example.com"},
+		// A leading "+" is the whole local part, not a tag.
+		{"+tag@example.com", "+tag@example.com"},
+		{`"john+doe"@example.com`, `"john+doe"@example.com`},
+		{"not-an-email", "not-an-email"},
+		{"jane@", "jane@"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeEmail(tt.in); got != tt.want {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package lookup
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/metrics"
+)
+
+// Each OSINT probe already retries a proxied 403 once without the proxy. When
+// the provider blocks the whole proxy pool (common with datacenter IPs) that
+// doubles every probe's cost, and without the retry every signal quietly
+// reads false. The egress switch below is the coarse companion: once enough
+// proxied OSINT requests are refused, OSINT goes direct for a while and SMTP
+// stays on the proxies. The decision is per process; each worker sees the
+// same blocks and reaches it independently.
+var (
+	// OSINTDirectFallback enables the switch. It is off by default: going
+	// direct exposes the host's own IP to the providers the proxies were
+	// meant to hide it from. Configured by OSINT_DIRECT_FALLBACK.
+	OSINTDirectFallback = config.Bool("OSINT_DIRECT_FALLBACK", false)
+
+	// OSINTBlockThreshold is the share of proxied OSINT responses that must
+	// be 403 to switch. Configured by OSINT_BLOCK_THRESHOLD.
+	OSINTBlockThreshold = config.Float("OSINT_BLOCK_THRESHOLD", 0.5)
+
+	// OSINTBlockMinSamples is how many proxied OSINT responses make up one
+	// evaluation window. Configured by OSINT_BLOCK_MIN_SAMPLES.
+	OSINTBlockMinSamples = config.Int("OSINT_BLOCK_MIN_SAMPLES", 50)
+
+	// OSINTDirectFor is how long OSINT stays direct before the proxies are
+	// given another window. Configured by OSINT_DIRECT_DURATION.
+	OSINTDirectFor = config.Duration("OSINT_DIRECT_DURATION", 15*time.Minute)
+)
+
+// egressTracker counts proxied OSINT responses over tumbling windows of
+// OSINTBlockMinSamples and decides when OSINT should bypass the proxies.
+type egressTracker struct {
+	mu          sync.Mutex
+	total       int
+	blocked     int
+	directUntil time.Time
+}
+
+var osintEgress = &egressTracker{}
+
+// observe records one proxied OSINT response and, at the end of a window,
+// switches to direct egress if the 403 share reached OSINTBlockThreshold.
+func (t *egressTracker) observe(status int, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.total++
+	if status == http.StatusForbidden {
+		t.blocked++
+	}
+	if t.total < OSINTBlockMinSamples {
+		return
+	}
+
+	rate := float64(t.blocked) / float64(t.total)
+	t.total, t.blocked = 0, 0
+	if rate >= OSINTBlockThreshold && OSINTDirectFallback && !now.Before(t.directUntil) {
+		t.directUntil = now.Add(OSINTDirectFor)
+		metrics.OSINTEgressSwitches.Inc("direct")
+		log.Printf("⚠️  %.0f%% of proxied OSINT requests returned 403 — routing OSINT direct for %s (SMTP stays proxied)", rate*100, OSINTDirectFor)
+	}
+}
+
+// direct reports whether OSINT should currently skip the proxies. The first
+// call after the direct period ends logs the return to proxied egress.
+func (t *egressTracker) direct(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.directUntil.IsZero() {
+		return false
+	}
+	if now.Before(t.directUntil) {
+		return true
+	}
+	t.directUntil = time.Time{}
+	t.total, t.blocked = 0, 0
+	metrics.OSINTEgressSwitches.Inc("proxy")
+	log.Println("✅ OSINT direct-egress period over — routing OSINT through proxies again")
+	return false
+}
+
+// OSINTDirect reports whether the OSINT collector should currently send its
+// probes direct rather than through the pinned proxy.
+func OSINTDirect() bool {
+	return osintEgress.direct(time.Now())
+}
+
+type osintCtxKey struct{}
+
+// WithOSINTTracking marks ctx so proxied responses to requests made with it
+// feed the OSINT egress decision.
+func WithOSINTTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, osintCtxKey{}, true)
+}
+
+// observeOSINTEgress feeds a proxied response to the egress tracker if the
+// request was made by the OSINT collector.
+func observeOSINTEgress(ctx context.Context, pURL *url.URL, resp *http.Response) {
+	if pURL == nil || resp == nil {
+		return
+	}
+	if tracked, _ := ctx.Value(osintCtxKey{}).(bool); tracked {
+		osintEgress.observe(resp.StatusCode, time.Now())
+	}
+}
@@ -0,0 +1,103 @@
+package lookup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func withEgressConfig(t *testing.T, threshold float64, samples int, dur time.Duration) {
+	t.Helper()
+	savedFallback, savedThreshold, savedSamples, savedFor := OSINTDirectFallback, OSINTBlockThreshold, OSINTBlockMinSamples, OSINTDirectFor
+	savedTracker := osintEgress
+	t.Cleanup(func() {
+		OSINTDirectFallback, OSINTBlockThreshold, OSINTBlockMinSamples, OSINTDirectFor = savedFallback, savedThreshold, savedSamples, savedFor
+		osintEgress = savedTracker
+	})
+	OSINTDirectFallback, OSINTBlockThreshold, OSINTBlockMinSamples, OSINTDirectFor = true, threshold, samples, dur
+	osintEgress = &egressTracker{}
+}
+
+func TestOSINTEgressSwitchesOnBlockRate(t *testing.T) {
+	withEgressConfig(t, 0.5, 10, time.Minute)
+	now := time.Now()
+
+	// A window under the threshold keeps OSINT on the proxies.
+	for i := 0; i < 10; i++ {
+		status := http.StatusOK
+		if i < 4 {
+			status = http.StatusForbidden
+		}
+		osintEgress.observe(status, now)
+	}
+	if osintEgress.direct(now) {
+		t.Fatal("switched to direct at a 40% block rate")
+	}
+
+	// A window at the threshold switches.
+	for i := 0; i < 10; i++ {
+		status := http.StatusForbidden
+		if i%2 == 0 {
+			status = http.StatusOK
+		}
+		osintEgress.observe(status, now)
+	}
+	if !osintEgress.direct(now) {
+		t.Fatal("expected direct egress at a 50% block rate")
+	}
+	if !osintEgress.direct(now.Add(59 * time.Second)) {
+		t.Error("direct egress ended before OSINTDirectFor elapsed")
+	}
+	if osintEgress.direct(now.Add(time.Minute)) {
+		t.Error("expected OSINT back on the proxies after OSINTDirectFor")
+	}
+}
+
+func TestOSINTEgressFallbackDisabled(t *testing.T) {
+	withEgressConfig(t, 0.5, 4, time.Minute)
+	OSINTDirectFallback = false
+
+	for i := 0; i < 4; i++ {
+		osintEgress.observe(http.StatusForbidden, time.Now())
+	}
+	if osintEgress.direct(time.Now()) {
+		t.Error("switched to direct with OSINT_DIRECT_FALLBACK off")
+	}
+}
+
+func TestProxiedOSINTRequestsFeedTheTracker(t *testing.T) {
+	withEgressConfig(t, 0.5, 2, time.Minute)
+
+	// The "proxy" answers every request itself, as a blocked exit would.
+	blocker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer blocker.Close()
+	pURL, _ := url.Parse(blocker.URL)
+
+	send := func(ctx context.Context) {
+		req, _ := http.NewRequestWithContext(ctx, "GET", "http://osint.example/probe", nil)
+		resp, err := DoProxiedRequest(req, pURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// Untracked requests, such as the infra web check, do not count.
+	send(context.Background())
+	send(context.Background())
+	if OSINTDirect() {
+		t.Fatal("untracked requests triggered the switch")
+	}
+
+	ctx := WithOSINTTracking(context.Background())
+	send(ctx)
+	send(ctx)
+	if !OSINTDirect() {
+		t.Error("expected a fully blocked OSINT window to switch to direct egress")
+	}
+}
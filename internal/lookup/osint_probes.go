@@ -0,0 +1,115 @@
+package lookup
+
+import (
+	"context"
+	"net/url"
+	"sync"
+)
+
+// Probe is one mailbox-level OSINT check. Run reports whether email was
+// found and, if so, which signal the hit sets on the analysis; the signal
+// keys are the Signal* constants. A probe that needs the verification's
+// proxy reads it with ProbeProxy.
+type Probe interface {
+	Name() string
+	Run(ctx context.Context, email, domain string) (signalKey string, found bool)
+}
+
+// Signal keys returned by the built-in probes. They match the JSON names of
+// the RiskAnalysis fields they set.
+const (
+	SignalGoogleCalendar = "has_google_calendar"
+	SignalTeams          = "has_teams_presence"
+	SignalSharePoint     = "has_sharepoint"
+	SignalAdobe          = "has_adobe"
+	SignalGravatar       = "has_gravatar"
+	SignalGitHub         = "has_github"
+	SignalLinkedIn       = "has_linkedin"
+	SignalSlack          = "has_slack"
+	SignalTwitter        = "has_twitter"
+	SignalSpotify        = "has_spotify"
+)
+
+var (
+	probesMu sync.RWMutex
+	probes   []Probe
+)
+
+// RegisterProbe adds p to the probes the OSINT collector runs. It is meant to
+// be called from init; registering a name twice panics.
+func RegisterProbe(p Probe) {
+	probesMu.Lock()
+	defer probesMu.Unlock()
+	for _, existing := range probes {
+		if existing.Name() == p.Name() {
+			panic("lookup: probe " + p.Name() + " registered twice")
+		}
+	}
+	probes = append(probes, p)
+}
+
+// Probes returns the registered probes in registration order.
+func Probes() []Probe {
+	probesMu.RLock()
+	defer probesMu.RUnlock()
+	return append([]Probe(nil), probes...)
+}
+
+const probeProxyKey contextKey = "probeProxy"
+
+// WithProbeProxy returns ctx carrying pURL for probes to send through.
+func WithProbeProxy(ctx context.Context, pURL *url.URL) context.Context {
+	return context.WithValue(ctx, probeProxyKey, pURL)
+}
+
+// ProbeProxy returns the proxy set by WithProbeProxy, or nil to go direct.
+func ProbeProxy(ctx context.Context) *url.URL {
+	p, _ := ctx.Value(probeProxyKey).(*url.URL)
+	return p
+}
+
+// checkProbe adapts a Check function to Probe.
+type checkProbe struct {
+	name   string
+	signal string
+	check  func(ctx context.Context, email, domain string, pURL *url.URL) bool
+}
+
+func (p checkProbe) Name() string { return p.name }
+
+func (p checkProbe) Run(ctx context.Context, email, domain string) (string, bool) {
+	return p.signal, p.check(ctx, email, domain, ProbeProxy(ctx))
+}
+
+func init() {
+	for _, p := range []checkProbe{
+		{"google_calendar", SignalGoogleCalendar, func(ctx context.Context, email, _ string, p *url.URL) bool {
+			return CheckGoogleCalendar(ctx, email, p)
+		}},
+		{"teams", SignalTeams, CheckTeamsPresence},
+		{"sharepoint", SignalSharePoint, func(ctx context.Context, email, _ string, p *url.URL) bool {
+			return CheckSharePoint(ctx, email, p)
+		}},
+		{"adobe", SignalAdobe, func(ctx context.Context, email, _ string, p *url.URL) bool {
+			return CheckAdobe(ctx, email, p)
+		}},
+		{"gravatar", SignalGravatar, func(ctx context.Context, email, _ string, p *url.URL) bool {
+			return CheckGravatar(ctx, email, p)
+		}},
+		{"github", SignalGitHub, func(ctx context.Context, email, _ string, p *url.URL) bool {
+			return CheckGitHub(ctx, email, p)
+		}},
+		{"linkedin", SignalLinkedIn, func(ctx context.Context, email, _ string, p *url.URL) bool {
+			return CheckLinkedIn(ctx, email, p)
+		}},
+		{"slack", SignalSlack, CheckSlack},
+		{"twitter", SignalTwitter, func(ctx context.Context, email, _ string, p *url.URL) bool {
+			return CheckTwitter(ctx, email, p)
+		}},
+		{"spotify", SignalSpotify, func(ctx context.Context, email, _ string, p *url.URL) bool {
+			return CheckSpotify(ctx, email, p)
+		}},
+	} {
+		RegisterProbe(p)
+	}
+}
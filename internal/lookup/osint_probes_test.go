@@ -0,0 +1,75 @@
+package lookup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func registeredProbe(t *testing.T, name string) Probe {
+	t.Helper()
+	for _, p := range Probes() {
+		if p.Name() == name {
+			return p
+		}
+	}
+	t.Fatalf("probe %q not registered", name)
+	return nil
+}
+
+func TestLinkedInProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("userName") == "jane@example.com" {
+			io.WriteString(w, "<h1>Check your email</h1>")
+			return
+		}
+		io.WriteString(w, "We couldn't find an account associated with that email.")
+	}))
+	defer srv.Close()
+
+	saved := linkedInResetURL
+	defer func() { linkedInResetURL = saved }()
+	linkedInResetURL = srv.URL
+
+	p := registeredProbe(t, "linkedin")
+	if signal, ok := p.Run(context.Background(), "jane@example.com", "example.com"); !ok || signal != SignalLinkedIn {
+		t.Errorf("Run(jane) = %q, %v; want %q, true", signal, ok, SignalLinkedIn)
+	}
+	if _, ok := p.Run(context.Background(), "ghost@example.com", "example.com"); ok {
+		t.Error("Run(ghost) found an account")
+	}
+}
+
+func TestTwitterProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("email") == "jane@example.com" {
+			io.WriteString(w, `{"taken": true}`)
+			return
+		}
+		io.WriteString(w, `{"taken": false}`)
+	}))
+	defer srv.Close()
+
+	saved := twitterAvailableURL
+	defer func() { twitterAvailableURL = saved }()
+	twitterAvailableURL = srv.URL
+
+	p := registeredProbe(t, "twitter")
+	if signal, ok := p.Run(context.Background(), "jane@example.com", "example.com"); !ok || signal != SignalTwitter {
+		t.Errorf("Run(jane) = %q, %v; want %q, true", signal, ok, SignalTwitter)
+	}
+	if _, ok := p.Run(context.Background(), "ghost@example.com", "example.com"); ok {
+		t.Error("Run(ghost) found an account")
+	}
+}
+
+func TestRegisterProbeRejectsDuplicates(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a probe name twice did not panic")
+		}
+	}()
+	RegisterProbe(checkProbe{name: "linkedin"})
+}
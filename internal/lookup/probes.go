@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"mailvetter/internal/metrics"
 	"mailvetter/internal/proxy"
 )
 
@@ -56,35 +57,49 @@ func getRandomUserAgent() string {
 }
 
 func DoProxiedRequest(req *http.Request, pURL *url.URL) (*http.Response, error) {
+	if pURL != nil && proxy.Bypass(req.URL.Hostname()) {
+		pURL = nil
+	}
 	reqCtx := context.WithValue(req.Context(), proxyCtxKey, pURL)
 	req = req.WithContext(reqCtx)
 
 	if pURL != nil && proxy.Enabled() {
+		waitStart := time.Now()
 		select {
 		case proxy.Semaphore <- struct{}{}:
+			metrics.ProxySlotWait.Observe(time.Since(waitStart).Seconds())
 		case <-req.Context().Done():
 			return nil, req.Context().Err()
 		}
 		defer func() { <-proxy.Semaphore }()
 	}
-	return sharedClient.Do(req)
+	resp, err := sharedClient.Do(req)
+	observeOSINTEgress(reqCtx, pURL, resp)
+	return resp, err
 }
 
 // doProxiedNoRedirectRequest is identical to DoProxiedRequest but uses
 // sharedNoRedirectClient so that HTTP redirects are not followed.
 func doProxiedNoRedirectRequest(req *http.Request, pURL *url.URL) (*http.Response, error) {
+	if pURL != nil && proxy.Bypass(req.URL.Hostname()) {
+		pURL = nil
+	}
 	reqCtx := context.WithValue(req.Context(), proxyCtxKey, pURL)
 	req = req.WithContext(reqCtx)
 
 	if pURL != nil && proxy.Enabled() {
+		waitStart := time.Now()
 		select {
 		case proxy.Semaphore <- struct{}{}:
+			metrics.ProxySlotWait.Observe(time.Since(waitStart).Seconds())
 		case <-req.Context().Done():
 			return nil, req.Context().Err()
 		}
 		defer func() { <-proxy.Semaphore }()
 	}
-	return sharedNoRedirectClient.Do(req)
+	resp, err := sharedNoRedirectClient.Do(req)
+	observeOSINTEgress(reqCtx, pURL, resp)
+	return resp, err
 }
 
 func CheckOffice365(ctx context.Context, domain string) bool {
@@ -376,3 +391,142 @@ func CheckMicrosoftLogin(ctx context.Context, email string, pURL *url.URL) bool
 	}
 	return false
 }
+
+// slackWorkspaceURL and slackCheckEmailURL are Slack's public workspace
+// sign-in page and its sign-up address check, formatted with the workspace
+// name. They are variables so tests can point them at a local server.
+var (
+	slackWorkspaceURL  = "https://%s.slack.com/"
+	slackCheckEmailURL = "https://%s.slack.com/api/signup.checkEmail"
+)
+
+// slackTeam guesses a domain's Slack workspace name the way most companies
+// pick it: the domain's first label, e.g. "acme" for acme.co.uk. Free-mail
+// and disposable domains get "": gmail.slack.com or yahoo.slack.com, if
+// they exist, say nothing about their users.
+func slackTeam(domain string) string {
+	if IsFreeProvider(domain) || IsDisposableDomain(domain) {
+		return ""
+	}
+	label, _, _ := strings.Cut(strings.ToLower(domain), ".")
+	team := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			return r
+		}
+		return -1
+	}, label)
+	return strings.Trim(team, "-")
+}
+
+// CheckSlackWorkspace reports whether domain has a Slack workspace under its
+// own name. Slack serves an existing workspace's sign-in page and redirects
+// for an unknown one. It describes the domain, not any mailbox.
+func CheckSlackWorkspace(ctx context.Context, domain string, pURL *url.URL) bool {
+	team := slackTeam(domain)
+	if team == "" {
+		return false
+	}
+	target := fmt.Sprintf(slackWorkspaceURL, team)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+		if err != nil {
+			return false
+		}
+		req.Header.Set("User-Agent", getRandomUserAgent())
+
+		currentProxy := pURL
+		if attempt == 2 {
+			currentProxy = nil
+		}
+
+		resp, err := doProxiedNoRedirectRequest(req, currentProxy)
+		if err != nil {
+			if attempt == 1 {
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+			return false
+		}
+
+		// Slack rate limits unauthenticated traffic hard; back off longer
+		// than the other probes before the direct retry.
+		if resp.StatusCode == 429 || resp.StatusCode >= 500 {
+			resp.Body.Close()
+			if attempt == 1 {
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			return false
+		}
+
+		isOk := resp.StatusCode == 200
+		resp.Body.Close()
+		return isOk
+	}
+	return false
+}
+
+// CheckSlack reports whether email already belongs to the Slack workspace
+// named after domain, via the sign-up flow's address check. A missing
+// workspace answers like an unknown address, so it needs no separate guard.
+func CheckSlack(ctx context.Context, email, domain string, pURL *url.URL) bool {
+	team := slackTeam(domain)
+	if team == "" {
+		return false
+	}
+	target := fmt.Sprintf(slackCheckEmailURL, team)
+	form := url.Values{"email": {email}}.Encode()
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", target, strings.NewReader(form))
+		if err != nil {
+			return false
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", getRandomUserAgent())
+
+		currentProxy := pURL
+		if attempt == 2 {
+			currentProxy = nil
+		}
+
+		resp, err := doProxiedNoRedirectRequest(req, currentProxy)
+		if err != nil {
+			if attempt == 1 {
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+			return false
+		}
+
+		if resp.StatusCode == 429 || resp.StatusCode >= 500 {
+			resp.Body.Close()
+			if attempt == 1 {
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			return false
+		}
+
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return false
+		}
+
+		var result struct {
+			OK    bool   `json:"ok"`
+			Found bool   `json:"found"`
+			Error string `json:"error"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return false
+		}
+		// Slack reports a taken address either as found or by refusing the
+		// sign-up because the user is already a member.
+		return (result.OK && result.Found) || result.Error == "already_in_team"
+	}
+	return false
+}
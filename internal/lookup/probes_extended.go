@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -72,15 +73,149 @@ func CheckAdobe(ctx context.Context, email string, pURL *url.URL) bool {
 	return false
 }
 
-func CheckDomainAge(ctx context.Context, domain string, pURL *url.URL) int {
-	target := "https://rdap.org/domain/" + domain
+// linkedInResetURL is LinkedIn's public password-reset submission endpoint.
+// It is a variable so tests can point it at a local server.
+var linkedInResetURL = "https://www.linkedin.com/checkpoint/rp/request-password-reset-submit"
+
+// CheckLinkedIn reports whether email is registered to a LinkedIn account,
+// using the password-reset flow, which confirms a reset for known addresses
+// and reports the account missing otherwise.
+func CheckLinkedIn(ctx context.Context, email string, pURL *url.URL) bool {
+	form := url.Values{"userName": {email}}.Encode()
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", linkedInResetURL, strings.NewReader(form))
+		if err != nil {
+			return false
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", getRandomUserAgent())
+
+		currentProxy := pURL
+		if attempt == 2 {
+			currentProxy = nil
+		}
+
+		resp, err := DoProxiedRequest(req, currentProxy)
+		if err != nil {
+			if attempt == 1 {
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+			return false
+		}
+
+		if resp.StatusCode == 429 || resp.StatusCode >= 500 {
+			resp.Body.Close()
+			if attempt == 1 {
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+			return false
+		}
+
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return false
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 256*1024))
+		resp.Body.Close()
+		if err != nil {
+			if attempt == 1 {
+				continue
+			}
+			return false
+		}
+		return linkedInAccountExists(string(body))
+	}
+	return false
+}
+
+// linkedInAccountExists classifies a password-reset response page. Only an
+// explicit confirmation counts: a login wall or challenge page says nothing
+// about the account, so it is treated like a miss.
+func linkedInAccountExists(body string) bool {
+	lower := strings.ToLower(body)
+	if strings.Contains(lower, "couldn't find an account") || strings.Contains(lower, "could not find an account") {
+		return false
+	}
+	return strings.Contains(lower, "check your email") || strings.Contains(lower, "we've sent")
+}
+
+// twitterAvailableURL and spotifyValidateURL are the sign-up forms' address
+// availability checks. They are variables so tests can point them at a local
+// server.
+var (
+	twitterAvailableURL = "https://api.twitter.com/i/users/email_available.json"
+	spotifyValidateURL  = "https://spclient.wg.spotify.com/signup/public/v1/account"
+)
+
+// spotifyEmailTaken is the status Spotify's sign-up validation returns for an
+// address that already has an account.
+const spotifyEmailTaken = 20
+
+// CheckTwitter reports whether email is registered to a Twitter/X account,
+// via the sign-up form's check that the address is still available.
+func CheckTwitter(ctx context.Context, email string, pURL *url.URL) bool {
+	target := twitterAvailableURL + "?" + url.Values{"email": {email}}.Encode()
 
 	for attempt := 1; attempt <= 2; attempt++ {
 		req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
 		if err != nil {
-			return 0
+			return false
+		}
+		req.Header.Set("User-Agent", getRandomUserAgent())
+
+		currentProxy := pURL
+		if attempt == 2 {
+			currentProxy = nil
+		}
+
+		resp, err := DoProxiedRequest(req, currentProxy)
+		if err != nil {
+			if attempt == 1 {
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+			return false
+		}
+
+		if resp.StatusCode == 429 || resp.StatusCode >= 500 {
+			resp.Body.Close()
+			if attempt == 1 {
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+			return false
+		}
+
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return false
 		}
-		req.Header.Set("Accept", "application/rdap+json")
+
+		var result struct {
+			Taken bool `json:"taken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		return err == nil && result.Taken
+	}
+	return false
+}
+
+// CheckSpotify reports whether email is registered to a Spotify account, via
+// the sign-up form's address validation.
+func CheckSpotify(ctx context.Context, email string, pURL *url.URL) bool {
+	target := spotifyValidateURL + "?" + url.Values{"validate": {"1"}, "email": {email}}.Encode()
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+		if err != nil {
+			return false
+		}
+		req.Header.Set("User-Agent", getRandomUserAgent())
 
 		currentProxy := pURL
 		if attempt == 2 {
@@ -93,7 +228,7 @@ func CheckDomainAge(ctx context.Context, domain string, pURL *url.URL) int {
 				time.Sleep(500 * time.Millisecond)
 				continue
 			}
-			return 0
+			return false
 		}
 
 		if resp.StatusCode == 429 || resp.StatusCode >= 500 {
@@ -102,45 +237,168 @@ func CheckDomainAge(ctx context.Context, domain string, pURL *url.URL) int {
 				time.Sleep(500 * time.Millisecond)
 				continue
 			}
-			return 0
+			return false
 		}
 
 		if resp.StatusCode != 200 {
 			resp.Body.Close()
-			return 0
+			return false
+		}
+
+		var result struct {
+			Status int `json:"status"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		return err == nil && result.Status == spotifyEmailTaken
+	}
+	return false
+}
+
+// RDAPInfo holds the fields extracted from a domain's RDAP record.
+type RDAPInfo struct {
+	AgeDays   int
+	Registrar string
+}
+
+// CheckDomainAge returns the domain's age in days from its RDAP record, or 0
+// if it could not be determined.
+func CheckDomainAge(ctx context.Context, domain string, pURL *url.URL) int {
+	return CheckRDAP(ctx, domain, pURL).AgeDays
+}
+
+// CheckRDAP fetches the domain's RDAP record and extracts its registration
+// age and registrar name. Missing fields are left at their zero value.
+func CheckRDAP(ctx context.Context, domain string, pURL *url.URL) RDAPInfo {
+	target := "https://rdap.org/domain/" + domain
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+		if err != nil {
+			return RDAPInfo{}
+		}
+		req.Header.Set("Accept", "application/rdap+json")
+
+		currentProxy := pURL
+		if attempt == 2 {
+			currentProxy = nil
 		}
 
-		var rdap struct {
-			Events []struct {
-				Action string `json:"eventAction"`
-				Date   string `json:"eventDate"`
-			} `json:"events"`
+		resp, err := DoProxiedRequest(req, currentProxy)
+		if err != nil {
+			if attempt == 1 {
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+			return RDAPInfo{}
 		}
 
-		if err := json.NewDecoder(resp.Body).Decode(&rdap); err != nil {
+		if resp.StatusCode == 429 || resp.StatusCode >= 500 {
 			resp.Body.Close()
-			return 0
+			if attempt == 1 {
+				time.Sleep(500 * time.Millisecond)
+				continue
+			}
+			return RDAPInfo{}
+		}
+
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return RDAPInfo{}
 		}
+
+		info, err := parseRDAP(resp.Body, time.Now())
 		resp.Body.Close()
+		if err != nil {
+			return RDAPInfo{}
+		}
+		return info
+	}
+	return RDAPInfo{}
+}
 
-		var created time.Time
-		for _, event := range rdap.Events {
-			if event.Action == "registration" || event.Action == "creation" {
-				t, err := time.Parse(time.RFC3339, event.Date)
-				if err != nil {
-					continue
-				}
-				if created.IsZero() || t.Before(created) {
-					created = t
-				}
+// parseRDAP decodes an RDAP domain response. The registration date comes from
+// the earliest "registration"/"creation" event; the registrar name comes from
+// the "fn" property of the vCard on the entity holding the "registrar" role.
+func parseRDAP(body io.Reader, now time.Time) (RDAPInfo, error) {
+	var rdap struct {
+		Events []struct {
+			Action string `json:"eventAction"`
+			Date   string `json:"eventDate"`
+		} `json:"events"`
+		Entities []struct {
+			Roles      []string        `json:"roles"`
+			VCardArray json.RawMessage `json:"vcardArray"`
+		} `json:"entities"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&rdap); err != nil {
+		return RDAPInfo{}, err
+	}
+
+	var info RDAPInfo
+
+	var created time.Time
+	for _, event := range rdap.Events {
+		if event.Action == "registration" || event.Action == "creation" {
+			t, err := time.Parse(time.RFC3339, event.Date)
+			if err != nil {
+				continue
+			}
+			if created.IsZero() || t.Before(created) {
+				created = t
 			}
 		}
+	}
+	if !created.IsZero() {
+		info.AgeDays = int(now.Sub(created).Hours() / 24)
+	}
 
-		if created.IsZero() {
-			return 0
+	for _, ent := range rdap.Entities {
+		isRegistrar := false
+		for _, role := range ent.Roles {
+			if role == "registrar" {
+				isRegistrar = true
+				break
+			}
+		}
+		if !isRegistrar {
+			continue
 		}
+		if name := vcardFullName(ent.VCardArray); name != "" {
+			info.Registrar = name
+			break
+		}
+	}
 
-		return int(time.Since(created).Hours() / 24)
+	return info, nil
+}
+
+// vcardFullName extracts the "fn" value from a jCard (RFC 7095) array of the
+// form ["vcard", [["fn", {}, "text", "Example Registrar, Inc."], ...]].
+func vcardFullName(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var card []json.RawMessage
+	if err := json.Unmarshal(raw, &card); err != nil || len(card) < 2 {
+		return ""
+	}
+	var props [][]json.RawMessage
+	if err := json.Unmarshal(card[1], &props); err != nil {
+		return ""
+	}
+	for _, prop := range props {
+		if len(prop) < 4 {
+			continue
+		}
+		var name, value string
+		if json.Unmarshal(prop[0], &name) != nil || name != "fn" {
+			continue
+		}
+		if json.Unmarshal(prop[3], &value) == nil {
+			return strings.TrimSpace(value)
+		}
 	}
-	return 0
+	return ""
 }
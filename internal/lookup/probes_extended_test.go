@@ -0,0 +1,149 @@
+package lookup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleRDAP = `{
+  "objectClassName": "domain",
+  "ldhName": "EXAMPLE.COM",
+  "events": [
+    {"eventAction": "registration", "eventDate": "2015-03-01T00:00:00Z"},
+    {"eventAction": "last changed", "eventDate": "2024-01-01T00:00:00Z"}
+  ],
+  "entities": [
+    {
+      "roles": ["technical"],
+      "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Tech Contact"]]]
+    },
+    {
+      "roles": ["registrar"],
+      "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "MarkMonitor Inc."]]]
+    }
+  ]
+}`
+
+func TestParseRDAP(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	info, err := parseRDAP(strings.NewReader(sampleRDAP), now)
+	if err != nil {
+		t.Fatalf("parseRDAP failed: %v", err)
+	}
+	if info.Registrar != "MarkMonitor Inc." {
+		t.Errorf("Registrar %q != expected %q", info.Registrar, "MarkMonitor Inc.")
+	}
+	if info.AgeDays < 3650 || info.AgeDays > 3655 {
+		t.Errorf("AgeDays %d not ~10 years", info.AgeDays)
+	}
+}
+
+func TestParseRDAPWithoutRegistrar(t *testing.T) {
+	info, err := parseRDAP(strings.NewReader(`{"events": []}`), time.Now())
+	if err != nil {
+		t.Fatalf("parseRDAP failed: %v", err)
+	}
+	if info.Registrar != "" || info.AgeDays != 0 {
+		t.Errorf("expected empty RDAPInfo, got %+v", info)
+	}
+}
+
+func TestCheckLinkedIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		body     string
+		want     bool
+		wantHits int
+	}{
+		{"account confirmed", []int{200}, "<h1>Check your email</h1><p>We've sent a link to reset your password.</p>", true, 1},
+		{"no account", []int{200}, "<p>We couldn't find an account associated with that email.</p>", false, 1},
+		{"challenge page is not proof", []int{200}, "<title>Security Verification</title>", false, 1},
+		{"rate limited then confirmed", []int{429, 200}, "Check your email", true, 2},
+		{"5xx twice is unknown", []int{503, 503}, "Check your email", false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[min(hits, len(tt.statuses)-1)]
+				hits++
+				if r.FormValue("userName") != "jane@example.com" {
+					t.Errorf("unexpected userName %q", r.FormValue("userName"))
+				}
+				w.WriteHeader(status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			saved := linkedInResetURL
+			defer func() { linkedInResetURL = saved }()
+			linkedInResetURL = srv.URL
+
+			if got := CheckLinkedIn(context.Background(), "jane@example.com", nil); got != tt.want {
+				t.Errorf("CheckLinkedIn() = %v, want %v", got, tt.want)
+			}
+			if hits != tt.wantHits {
+				t.Errorf("%d requests, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestCheckTwitterAndSpotify(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		taken := r.URL.Query().Get("email") == "jane@example.com"
+		switch r.URL.Path {
+		case "/twitter":
+			if hits == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			if taken {
+				io.WriteString(w, `{"valid":false,"taken":true}`)
+			} else {
+				io.WriteString(w, `{"valid":true,"taken":false}`)
+			}
+		case "/spotify":
+			if r.URL.Query().Get("validate") != "1" {
+				t.Errorf("spotify request without validate=1: %s", r.URL)
+			}
+			if taken {
+				io.WriteString(w, `{"status":20,"errors":{"email":"That email is already registered to an account."}}`)
+			} else {
+				io.WriteString(w, `{"status":1}`)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	savedTwitter, savedSpotify := twitterAvailableURL, spotifyValidateURL
+	defer func() { twitterAvailableURL, spotifyValidateURL = savedTwitter, savedSpotify }()
+	twitterAvailableURL, spotifyValidateURL = srv.URL+"/twitter", srv.URL+"/spotify"
+
+	ctx := context.Background()
+	if !CheckTwitter(ctx, "jane@example.com", nil) {
+		t.Error("CheckTwitter missed a taken address after a 429")
+	}
+	if hits != 2 {
+		t.Errorf("%d Twitter requests, want 2", hits)
+	}
+	if CheckTwitter(ctx, "nobody@example.com", nil) {
+		t.Error("CheckTwitter matched an available address")
+	}
+	if !CheckSpotify(ctx, "jane@example.com", nil) {
+		t.Error("CheckSpotify missed a registered address")
+	}
+	if CheckSpotify(ctx, "nobody@example.com", nil) {
+		t.Error("CheckSpotify matched an unregistered address")
+	}
+}
@@ -0,0 +1,93 @@
+package lookup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSlackTeam(t *testing.T) {
+	for domain, want := range map[string]string{
+		"acme.com":       "acme",
+		"Acme.co.uk":     "acme",
+		"my-shop.io":     "my-shop",
+		"-odd_name.com":  "oddname",
+		"":               "",
+		"gmail.com":      "",
+		"Yahoo.com":      "",
+		"mailinator.com": "",
+	} {
+		if got := slackTeam(domain); got != want {
+			t.Errorf("slackTeam(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
+
+func TestCheckSlack(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		body     string
+		want     bool
+		wantHits int
+	}{
+		{"member", []int{200}, `{"ok":true,"found":true}`, true, 1},
+		{"already in team", []int{200}, `{"ok":false,"error":"already_in_team"}`, true, 1},
+		{"unknown address", []int{200}, `{"ok":true,"found":false}`, false, 1},
+		{"no workspace", []int{404}, `{"ok":false,"error":"team_not_found"}`, false, 1},
+		{"rate limited then member", []int{429, 200}, `{"ok":true,"found":true}`, true, 2},
+		{"rate limited twice is unknown", []int{429, 429}, `{"ok":true,"found":true}`, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[min(hits, len(tt.statuses)-1)]
+				hits++
+				if r.URL.Path != "/acme" || r.FormValue("email") != "jane@acme.com" {
+					t.Errorf("unexpected request %s email=%q", r.URL.Path, r.FormValue("email"))
+				}
+				w.WriteHeader(status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			saved := slackCheckEmailURL
+			defer func() { slackCheckEmailURL = saved }()
+			slackCheckEmailURL = srv.URL + "/%s"
+
+			if got := CheckSlack(context.Background(), "jane@acme.com", "acme.com", nil); got != tt.want {
+				t.Errorf("CheckSlack() = %v, want %v", got, tt.want)
+			}
+			if hits != tt.wantHits {
+				t.Errorf("%d requests, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestCheckSlackWorkspace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.URL.Path, "/") == "acme" {
+			io.WriteString(w, "<title>Sign in to Acme | Slack</title>")
+			return
+		}
+		http.Redirect(w, r, "https://slack.com/get-started", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	saved := slackWorkspaceURL
+	defer func() { slackWorkspaceURL = saved }()
+	slackWorkspaceURL = srv.URL + "/%s"
+
+	if !CheckSlackWorkspace(context.Background(), "acme.com", nil) {
+		t.Error("existing workspace not found")
+	}
+	if CheckSlackWorkspace(context.Background(), "nobody.com", nil) {
+		t.Error("redirect counted as a workspace")
+	}
+}
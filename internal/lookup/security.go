@@ -2,13 +2,12 @@ package lookup
 
 import (
 	"context"
-	"net"
 	"strings"
 )
 
 // CheckSPF looks for a valid SPF record in TXT entries.
 func CheckSPF(ctx context.Context, domain string) bool {
-	txts, err := net.DefaultResolver.LookupTXT(ctx, domain)
+	txts, err := lookupTXT(ctx, domain)
 	if err != nil {
 		return false
 	}
@@ -20,10 +19,92 @@ func CheckSPF(ctx context.Context, domain string) bool {
 	return false
 }
 
+// SPFLookupLimit is the RFC 7208 §4.6.4 cap on DNS-querying terms evaluated
+// for one SPF check. Receivers return permerror past it, so a record that
+// exceeds it fails SPF for the domain's own mail.
+const SPFLookupLimit = 10
+
+// maxSPFDepth bounds include/redirect nesting independently of the lookup
+// count, as a guard against pathological chains.
+const maxSPFDepth = 10
+
+// lookupTXT resolves TXT records through the resolver selected by DNSMode. It
+// is a variable so tests can serve SPF chains without DNS.
+var lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+	return newResolver().LookupTXT(ctx, name)
+}
+
+// CheckSPFOverLimit reports whether domain's SPF record, with its includes
+// and redirect expanded, needs more than SPFLookupLimit DNS lookups. A domain
+// without SPF, or one whose chain cannot be resolved, is not over the limit.
+func CheckSPFOverLimit(ctx context.Context, domain string) bool {
+	count := 0
+	countSPFLookups(ctx, domain, 0, map[string]bool{}, &count)
+	return count > SPFLookupLimit
+}
+
+// countSPFLookups adds the lookups domain's SPF record costs to count,
+// stopping as soon as the limit is exceeded. Every include is expanded and
+// counted, even one already reached along another branch, as receivers do;
+// seen holds only the domains on the current include path, so it stops loops.
+func countSPFLookups(ctx context.Context, domain string, depth int, seen map[string]bool, count *int) {
+	if depth > maxSPFDepth || *count > SPFLookupLimit || ctx.Err() != nil {
+		return
+	}
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if seen[domain] {
+		return
+	}
+	seen[domain] = true
+	defer delete(seen, domain)
+
+	terms := strings.Fields(spfRecord(ctx, domain))
+	if len(terms) == 0 {
+		return
+	}
+	for _, term := range terms[1:] {
+		term = strings.ToLower(strings.TrimLeft(term, "+-~?"))
+		name, arg, _ := strings.Cut(term, ":")
+		if name == term {
+			name, arg, _ = strings.Cut(term, "=")
+		}
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			name = name[:i]
+		}
+
+		switch name {
+		case "a", "mx", "ptr", "exists":
+			*count++
+		case "include", "redirect":
+			*count++
+			if arg != "" {
+				countSPFLookups(ctx, arg, depth+1, seen, count)
+			}
+		}
+		if *count > SPFLookupLimit {
+			return
+		}
+	}
+}
+
+// spfRecord returns domain's "v=spf1" TXT record, or "" if it has none.
+func spfRecord(ctx context.Context, domain string) string {
+	txts, err := lookupTXT(ctx, domain)
+	if err != nil {
+		return ""
+	}
+	for _, txt := range txts {
+		if strings.HasPrefix(txt, "v=spf1") {
+			return txt
+		}
+	}
+	return ""
+}
+
 // CheckDMARC looks for a DMARC policy record.
 // Presence of DMARC implies active IT management of the domain.
 func CheckDMARC(ctx context.Context, domain string) bool {
-	txts, err := net.DefaultResolver.LookupTXT(ctx, "_dmarc."+domain)
+	txts, err := lookupTXT(ctx, "_dmarc."+domain)
 	if err != nil {
 		return false
 	}
@@ -39,7 +120,7 @@ func CheckDMARC(ctx context.Context, domain string) bool {
 // Finding tokens for tools like Salesforce or Zendesk proves the domain is
 // actively used for business operations, not just registered and parked.
 func CheckSaaSTokens(ctx context.Context, domain string) bool {
-	txts, err := net.DefaultResolver.LookupTXT(ctx, domain)
+	txts, err := lookupTXT(ctx, domain)
 	if err != nil {
 		return false
 	}
@@ -98,39 +179,71 @@ func IdentifyProvider(ctx context.Context, domain string) (string, error) {
 		return "generic", err
 	}
 
+	return ProviderForMXRecords(mxRecords), nil
+}
+
+// ProviderForMXRecords returns the provider of the first MX record that maps
+// to a known provider, or "generic". Use it instead of IdentifyProvider when
+// the MX records have already been fetched.
+func ProviderForMXRecords(mxRecords []MXRecord) string {
 	for _, mx := range mxRecords {
-		host := strings.ToLower(mx.Host)
-
-		// ── Enterprise security gateways ─────────────────────────────────────
-		// Checked first because some organisations route through a gateway
-		// in front of Google or Microsoft, and the gateway is the more
-		// meaningful signal for scoring purposes.
-		if strings.Contains(host, "pphosted.com") {
-			return "proofpoint", nil
-		}
-		if strings.Contains(host, "mimecast.com") {
-			return "mimecast", nil
-		}
-		if strings.Contains(host, "barracudanetworks.com") {
-			return "barracuda", nil
-		}
-		// iphmx.com is the MX hostname pattern for Cisco IronPort /
-		// Cisco Secure Email Gateway (formerly IronPort Systems, acquired
-		// by Cisco in 2007). It is a paid enterprise product deployed
-		// exclusively by mid-to-large organisations — the same signal
-		// strength as Proofpoint or Mimecast.
-		if strings.Contains(host, "iphmx.com") {
-			return "ironport", nil
+		if provider := ProviderForMX(mx.Host); provider != "generic" {
+			return provider
 		}
+	}
+	return "generic"
+}
 
-		// ── Major hosted providers ────────────────────────────────────────────
-		if strings.Contains(host, "google.com") || strings.Contains(host, "googlemail.com") {
-			return "google", nil
-		}
-		if strings.Contains(host, "outlook.com") || strings.Contains(host, "protection.outlook.com") {
-			return "office365", nil
+// ProvidersForMXRecords returns the distinct providers (see IdentifyProvider)
+// behind mxRecords, in MX order, so a domain with a Google primary and a
+// Mimecast backup yields ["google", "mimecast"].
+func ProvidersForMXRecords(mxRecords []MXRecord) []string {
+	var providers []string
+	seen := make(map[string]bool, len(mxRecords))
+	for _, mx := range mxRecords {
+		provider := ProviderForMX(mx.Host)
+		if !seen[provider] {
+			seen[provider] = true
+			providers = append(providers, provider)
 		}
 	}
+	return providers
+}
+
+// ProviderForMX maps a single MX hostname to its canonical provider name (see
+// IdentifyProvider), or "generic" if it matches no known provider.
+func ProviderForMX(mxHost string) string {
+	host := strings.ToLower(mxHost)
+
+	// ── Enterprise security gateways ─────────────────────────────────────
+	// Checked first because some organisations route through a gateway
+	// in front of Google or Microsoft, and the gateway is the more
+	// meaningful signal for scoring purposes.
+	if strings.Contains(host, "pphosted.com") {
+		return "proofpoint"
+	}
+	if strings.Contains(host, "mimecast.com") {
+		return "mimecast"
+	}
+	if strings.Contains(host, "barracudanetworks.com") {
+		return "barracuda"
+	}
+	// iphmx.com is the MX hostname pattern for Cisco IronPort /
+	// Cisco Secure Email Gateway (formerly IronPort Systems, acquired
+	// by Cisco in 2007). It is a paid enterprise product deployed
+	// exclusively by mid-to-large organisations — the same signal
+	// strength as Proofpoint or Mimecast.
+	if strings.Contains(host, "iphmx.com") {
+		return "ironport"
+	}
+
+	// ── Major hosted providers ────────────────────────────────────────────
+	if strings.Contains(host, "google.com") || strings.Contains(host, "googlemail.com") {
+		return "google"
+	}
+	if strings.Contains(host, "outlook.com") || strings.Contains(host, "protection.outlook.com") {
+		return "office365"
+	}
 
-	return "generic", nil
+	return "generic"
 }
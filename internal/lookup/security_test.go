@@ -0,0 +1,77 @@
+package lookup
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func withTXT(t *testing.T, records map[string]string) {
+	t.Helper()
+	saved := lookupTXT
+	t.Cleanup(func() { lookupTXT = saved })
+	lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		if r, ok := records[name]; ok {
+			return []string{"google-site-verification=abc", r}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+}
+
+func TestCheckSPFOverLimit(t *testing.T) {
+	t.Run("over-limit include chain", func(t *testing.T) {
+		// 2 + (1 + 4) + (1 + 3) = 11 lookups.
+		withTXT(t, map[string]string{
+			"sprawl.example":       "v=spf1 a mx include:_spf1.sprawl.example include:_spf2.sprawl.example -all",
+			"_spf1.sprawl.example": "v=spf1 a mx exists:%{i}.bl.example include:_spf3.sprawl.example ~all",
+			"_spf2.sprawl.example": "v=spf1 ip4:192.0.2.0/24 a/24 mx:mail.example ptr ~all",
+			"_spf3.sprawl.example": "v=spf1 ip6:2001:db8::/32 -all",
+		})
+		if !CheckSPFOverLimit(context.Background(), "sprawl.example") {
+			t.Errorf("expected an 11-lookup chain to exceed the limit")
+		}
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		withTXT(t, map[string]string{
+			"tidy.example":      "v=spf1 mx include:_spf.tidy.example redirect=_r.tidy.example",
+			"_spf.tidy.example": "v=spf1 ip4:192.0.2.1 -all",
+			"_r.tidy.example":   "v=spf1 a -all",
+		})
+		if CheckSPFOverLimit(context.Background(), "tidy.example") {
+			t.Errorf("expected a 4-lookup chain to be within the limit")
+		}
+	})
+
+	t.Run("repeated include counts each time", func(t *testing.T) {
+		// 2 + (1 + 1 + 4) + (1 + 1 + 4) = 14 lookups; _shared is expanded twice.
+		withTXT(t, map[string]string{
+			"twice.example":         "v=spf1 a mx include:_a.twice.example include:_b.twice.example -all",
+			"_a.twice.example":      "v=spf1 include:_shared.twice.example -all",
+			"_b.twice.example":      "v=spf1 include:_shared.twice.example -all",
+			"_shared.twice.example": "v=spf1 a mx ptr exists:%{i}.bl.example -all",
+		})
+		if !CheckSPFOverLimit(context.Background(), "twice.example") {
+			t.Errorf("expected a repeated include to be counted on each branch")
+		}
+	})
+
+	t.Run("include loop terminates", func(t *testing.T) {
+		withTXT(t, map[string]string{
+			"loop.example":  "v=spf1 include:loop2.example -all",
+			"loop2.example": "v=spf1 include:loop.example -all",
+		})
+		if CheckSPFOverLimit(context.Background(), "loop.example") {
+			t.Errorf("a two-domain loop counts each include once")
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		withTXT(t, map[string]string{"sprawl.example": "v=spf1 a a a a a a a a a a a -all"})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if CheckSPFOverLimit(ctx, "sprawl.example") {
+			t.Errorf("expected no verdict once the context is cancelled")
+		}
+	})
+}
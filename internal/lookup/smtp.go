@@ -4,22 +4,272 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"mailvetter/internal/config"
+	"mailvetter/internal/metrics"
 	"mailvetter/internal/proxy"
+	"mailvetter/internal/ratelimit"
 	"net"
 	"net/textproto"
 	"net/url"
+	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
-const (
-	HeloHost = "mta1.mailvetter.com"
-	MailFrom = ""
-)
+const defaultHeloHost = "mta1.mailvetter.com"
+
+// HeloHost is the hostname sent in EHLO/HELO. Strict receivers reject a name
+// that does not match the probing IP's PTR record or the sender's SPF, so
+// operators should set it to a name of their own. Set via SMTP_HELO_HOST; a
+// value that is not a fully qualified domain name is ignored with a warning.
+var HeloHost = parseHeloHost(config.String("SMTP_HELO_HOST", defaultHeloHost))
+
+// MailFrom is the MAIL FROM reverse-path; empty sends the null sender (<>).
+// Set via SMTP_MAIL_FROM; an invalid address is ignored with a warning.
+var MailFrom = parseMailFrom(config.String("SMTP_MAIL_FROM", ""))
+
+func parseHeloHost(host string) string {
+	host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+	if err := validateDomainPart(host); err != nil || !isASCII(host) {
+		log.Printf("⚠️  Ignoring SMTP_HELO_HOST %q: not a fully qualified domain name, using %s", host, defaultHeloHost)
+		return defaultHeloHost
+	}
+	return host
+}
+
+func parseMailFrom(from string) string {
+	from = strings.Trim(strings.TrimSpace(from), "<>")
+	if from == "" {
+		return ""
+	}
+	if err := ValidateSyntax(from); err != nil {
+		log.Printf("⚠️  Ignoring SMTP_MAIL_FROM %q: %v; sending the null sender", from, err)
+		return ""
+	}
+	return from
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
 
 var SMTPSemaphore = make(chan struct{}, 15)
 
+// SenderIdentity is the HELO hostname and MAIL FROM address presented to the
+// receiving server. An empty MailFrom sends the null reverse-path (<>).
+type SenderIdentity struct {
+	Helo     string
+	MailFrom string
+}
+
+func (s SenderIdentity) String() string {
+	return fmt.Sprintf("%s <%s>", s.Helo, s.MailFrom)
+}
+
+// DefaultIdentity is used when no alternative identities are configured.
+var DefaultIdentity = SenderIdentity{Helo: HeloHost, MailFrom: MailFrom}
+
+// SenderIdentities is the ordered list of identities CheckSMTPRotating works
+// through. Configured via SMTP_SENDER_IDENTITIES as a comma-separated list of
+// "helo|mailfrom" pairs, e.g.
+//
+//	SMTP_SENDER_IDENTITIES=mta1.a.com|verify@a.com,mta1.b.com|
+//
+// Strict receivers sometimes accept one sender domain and reject another on
+// reputation grounds; trying the next identity on a policy rejection turns
+// those inconsistent verdicts into answers.
+var SenderIdentities = parseSenderIdentities(config.List("SMTP_SENDER_IDENTITIES"))
+
+func parseSenderIdentities(entries []string) []SenderIdentity {
+	var ids []SenderIdentity
+	for _, e := range entries {
+		helo, from, _ := strings.Cut(e, "|")
+		helo = strings.TrimSpace(helo)
+		if helo == "" {
+			continue
+		}
+		ids = append(ids, SenderIdentity{Helo: helo, MailFrom: strings.TrimSpace(from)})
+	}
+	if len(ids) == 0 {
+		ids = []SenderIdentity{DefaultIdentity}
+	}
+	return ids
+}
+
+// SourceIPs is the pool of local IPv4 addresses direct (non-proxied) SMTP
+// connections are bound to, rotating round-robin to spread reputation load
+// across several clean outbound IPs. Every address must be bound to a local
+// interface. Empty means the OS picks the source address.
+//
+// Set via SMTP_SOURCE_IPS, e.g. "203.0.113.10,203.0.113.11".
+var SourceIPs = parseSourceIPs(config.List("SMTP_SOURCE_IPS"))
+
+var sourceIPCounter uint64
+
+func parseSourceIPs(entries []string) []net.IP {
+	var ips []net.IP
+	for _, e := range entries {
+		ip := net.ParseIP(e)
+		if ip == nil || ip.To4() == nil {
+			log.Printf("⚠️  Ignoring SMTP_SOURCE_IPS entry %q: not an IPv4 address", e)
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	return ips
+}
+
+// directSMTPDialer returns the dialer for a direct SMTP connection, bound to
+// the next source IP in the pool when one is configured.
+func directSMTPDialer() *net.Dialer {
+	d := &net.Dialer{Timeout: 10 * time.Second}
+	if len(SourceIPs) > 0 {
+		n := atomic.AddUint64(&sourceIPCounter, 1)
+		d.LocalAddr = &net.TCPAddr{IP: SourceIPs[(n-1)%uint64(len(SourceIPs))]}
+	}
+	return d
+}
+
+// dialSMTP connects to mxHost's SMTP port: through pURL when SMTP proxying is
+// on, otherwise directly over IPv4 from the source IP pool. Every probe dials
+// through here so they all leave from the same kind of address.
+func dialSMTP(ctx context.Context, mxHost string, pURL *url.URL) (net.Conn, error) {
+	if proxy.SMTPEnabled && pURL != nil {
+		return proxy.DialContext(ctx, "tcp", mxHost+":25", 10*time.Second, pURL)
+	}
+	return directSMTPDialer().DialContext(ctx, "tcp4", mxHost+":25")
+}
+
+// PolicyRejectRetry controls what happens when a server refuses the session at
+// HELO or MAIL FROM. When true (the default) the rejection is treated like any
+// other transient failure and the probe is retried over direct egress, since
+// these refusals are usually about the connecting IP or HELO name. When false
+// the probe gives up immediately and the address is reported as unknown —
+// cheaper against servers that are known to reject every unfamiliar sender.
+//
+// Set via SMTP_POLICY_REJECT_RETRY.
+var PolicyRejectRetry = config.Bool("SMTP_POLICY_REJECT_RETRY", true)
+
+// PolicyError reports that the receiving server refused the SMTP session
+// before the recipient was evaluated (HELO or MAIL FROM rejected). It says
+// something about our sending infrastructure, never about the mailbox, so it
+// must not be read as a mailbox verdict even when it carries a 5xx code.
+type PolicyError struct {
+	Stage string // "HELO" or "MAIL FROM"
+	Err   error
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s rejected: %v", e.Stage, e.Err)
+}
+
+func (e *PolicyError) Unwrap() error { return e.Err }
+
+// IsPolicyError reports whether err is an infrastructure/policy rejection
+// that occurred before RCPT TO.
+func IsPolicyError(err error) bool {
+	var pe *PolicyError
+	return errors.As(err, &pe)
+}
+
+// GreylistError reports a 450/451 deferral of RCPT TO. Greylisting servers
+// temporarily refuse unfamiliar sender/recipient pairs and accept the same
+// pair on a later attempt, so the reply is transient, not a mailbox verdict.
+type GreylistError struct {
+	Err error
+}
+
+func (e *GreylistError) Error() string { return "greylisted: " + e.Err.Error() }
+
+func (e *GreylistError) Unwrap() error { return e.Err }
+
+// IsGreylistError reports whether err is a greylisting deferral at RCPT TO.
+func IsGreylistError(err error) bool {
+	var ge *GreylistError
+	return errors.As(err, &ge)
+}
+
+// CheckSMTPRotating probes targetEmail with each identity in SenderIdentities
+// in order, moving on to the next only when the server refused the previous
+// one for policy or reputation reasons. Mailbox verdicts and connection
+// failures are returned immediately — a different sender cannot change those.
+func CheckSMTPRotating(ctx context.Context, mxHost string, targetEmail string, pURL *url.URL) (bool, time.Duration, error) {
+	return probeWithIdentities(SenderIdentities, func(id SenderIdentity) (bool, time.Duration, error) {
+		return CheckSMTPAs(ctx, mxHost, targetEmail, pURL, id)
+	}, func(id SenderIdentity, valid bool, err error) {
+		log.Printf("[DEBUG] SMTP probe for %s via %s settled with sender identity %s: %s", targetEmail, mxHost, id, smtpOutcome(valid, err))
+	})
+}
+
+// probeWithIdentities runs probe with each of ids until one gets past the
+// server's sender checks. onRotated, if set, is told the identity and result
+// the probe settled on when that was not the first identity.
+func probeWithIdentities(ids []SenderIdentity, probe func(SenderIdentity) (bool, time.Duration, error), onRotated func(SenderIdentity, bool, error)) (bool, time.Duration, error) {
+	var valid bool
+	var elapsed time.Duration
+	var err error
+
+	for i, id := range ids {
+		valid, elapsed, err = probe(id)
+		if !shouldRotateSender(err) {
+			if i > 0 && onRotated != nil {
+				onRotated(id, valid, err)
+			}
+			break
+		}
+	}
+	return valid, elapsed, err
+}
+
+// shouldRotateSender reports whether err is a server refusal that another
+// sender identity might get past: a HELO/MAIL FROM policy rejection, or an
+// SMTP reply at RCPT that is not a mailbox verdict. Greylisting and rate
+// limits are not: the server wants us to back off, and a new sender would
+// reset the greylist triplet so the retry could never pass.
+func shouldRotateSender(err error) bool {
+	if err == nil || IsGreylistError(err) || IsRateLimitError(err) {
+		return false
+	}
+	if IsPolicyError(err) {
+		return true
+	}
+	var textErr *textproto.Error
+	return errors.As(err, &textErr) && !IsNoSuchUserError(err)
+}
+
+// CheckSMTP probes targetEmail using DefaultIdentity.
 func CheckSMTP(ctx context.Context, mxHost string, targetEmail string, pURL *url.URL) (bool, time.Duration, error) {
+	return CheckSMTPAs(ctx, mxHost, targetEmail, pURL, DefaultIdentity)
+}
+
+// CheckSMTPAs runs a single RCPT TO probe presenting the given sender identity.
+func CheckSMTPAs(ctx context.Context, mxHost string, targetEmail string, pURL *url.URL, id SenderIdentity) (accepted bool, latency time.Duration, err error) {
+	defer func(start time.Time) {
+		metrics.SMTPProbeDuration.Observe(time.Since(start).Seconds(), smtpOutcome(accepted, err))
+	}(time.Now())
+
+	releaseHost, err := smtpHostSlots.acquire(ctx, mxHost)
+	if err != nil {
+		return false, 0, err
+	}
+	defer releaseHost()
+
+	// The fleet-wide slot is taken before the process-wide one, so a
+	// probe waiting on a saturated provider does not sit on an
+	// SMTPSemaphore slot other providers could use.
+	releaseFleet, err := ratelimit.Fleet.Acquire(ctx, ProviderForMX(mxHost))
+	if err != nil {
+		return false, 0, err
+	}
+	defer releaseFleet()
+
 	select {
 	case SMTPSemaphore <- struct{}{}:
 	case <-ctx.Done():
@@ -27,98 +277,327 @@ func CheckSMTP(ctx context.Context, mxHost string, targetEmail string, pURL *url
 	}
 	defer func() { <-SMTPSemaphore }()
 
-	var conn net.Conn
-	var err error
+	isStrictEnterprise := isStrictGateway(mxHost)
 
-	if proxy.SMTPEnabled && pURL != nil {
-		conn, err = proxy.DialContext(ctx, "tcp", mxHost+":25", 10*time.Second, pURL)
-	} else {
-		d := net.Dialer{Timeout: 10 * time.Second}
-		conn, err = d.DialContext(ctx, "tcp4", mxHost+":25")
+	deadlineOffset := 12 * time.Second
+	if isStrictEnterprise {
+		deadlineOffset = 16 * time.Second
 	}
 
-	if err != nil {
-		return false, 0, fmt.Errorf("connection failed: %w", err)
+	delay := commandDelay(mxHost)
+	deadlineOffset += sessionDelays * delay
+
+	dial := func() (net.Conn, error) {
+		conn, err := dialSMTP(ctx, mxHost, pURL)
+		if err != nil {
+			return nil, fmt.Errorf("connection failed: %w", err)
+		}
+		conn = TranscriptFrom(ctx).wrap(conn, mxHost)
+
+		deadline := time.Now().Add(deadlineOffset)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		conn.SetDeadline(deadline)
+		return conn, nil
 	}
 
-	start := time.Now()
-	mxLower := strings.ToLower(mxHost)
-	isStrictEnterprise := false
+	return rcptOverDial(ctx, mxHost, dial, targetEmail, id, delay)
+}
+
+// rcptOverDial runs rcptSession over a connection from dial. If the STARTTLS
+// handshake fails, the connection is unusable, so it dials again and repeats
+// the probe in plaintext, as a sending MTA would.
+func rcptOverDial(ctx context.Context, mxHost string, dial func() (net.Conn, error), targetEmail string, id SenderIdentity, delay time.Duration) (bool, time.Duration, error) {
+	conn, err := dial()
+	if err != nil {
+		return false, 0, err
+	}
+	accepted, latency, err := rcptSession(ctx, conn, targetEmail, id, delay, true)
+	if errors.Is(err, ErrSTARTTLSFailed) {
+		log.Printf("[DEBUG] %s: %v; probing again in plaintext", mxHost, err)
+		if conn, err = dial(); err != nil {
+			return false, 0, err
+		}
+		return rcptSession(ctx, conn, targetEmail, id, delay, false)
+	}
+	return accepted, latency, err
+}
 
-	strictGateways := []string{
-		"mimecast.com", "pphosted.com", "barracudanetworks.com", "messagelabs.com",
-		"iphmx.com", "trendmicro.com", "trendmicro.eu", "sophos.com",
-		"mailcontrol.com", "mxlogic.net", "fireeye.com", "mx.cloudflare.net",
+// smtpOutcome labels a finished probe for the SMTP duration histogram.
+func smtpOutcome(accepted bool, err error) string {
+	switch {
+	case accepted:
+		return "accepted"
+	case IsNoSuchUserError(err):
+		return "rejected"
+	default:
+		return "error"
 	}
+}
 
+// strictGateways are enterprise gateways that penalise fast or bursty
+// senders; sessions with them get a longer deadline and paced commands.
+var strictGateways = []string{
+	"mimecast.com", "pphosted.com", "barracudanetworks.com", "messagelabs.com",
+	"iphmx.com", "trendmicro.com", "trendmicro.eu", "sophos.com",
+	"mailcontrol.com", "mxlogic.net", "fireeye.com", "mx.cloudflare.net",
+}
+
+func isStrictGateway(mxHost string) bool {
+	host := strings.ToLower(mxHost)
 	for _, gw := range strictGateways {
-		if strings.Contains(mxLower, gw) {
-			isStrictEnterprise = true
-			break
+		if strings.Contains(host, gw) {
+			return true
 		}
 	}
+	return false
+}
 
-	deadlineOffset := 12 * time.Second
-	if isStrictEnterprise {
-		deadlineOffset = 16 * time.Second
+// strictGatewayDelay is the inter-command pause for strict gateways that
+// SMTPCommandDelays does not cover.
+const strictGatewayDelay = 1 * time.Second
+
+// SMTPCommandDelays overrides the pause before each SMTP command, per
+// provider. Keys are canonical provider names (see ProviderForMX) or MX host
+// fragments; hosts matching neither keep the default of strictGatewayDelay
+// for strict gateways and no pause otherwise. Set via SMTP_COMMAND_DELAYS:
+//
+//	SMTP_COMMAND_DELAYS="google=0ms,mimecast=1500ms,ironport=2s"
+var SMTPCommandDelays = parseCommandDelays(config.List("SMTP_COMMAND_DELAYS"))
+
+func parseCommandDelays(entries []string) map[string]time.Duration {
+	delays := make(map[string]time.Duration)
+	for _, e := range entries {
+		name, val, ok := strings.Cut(e, "=")
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(val))
+		if err != nil || d < 0 {
+			continue
+		}
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			delays[name] = d
+		}
 	}
+	return delays
+}
 
-	deadline := time.Now().Add(deadlineOffset)
-	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
-		deadline = ctxDeadline
+// commandDelay returns the pause before each SMTP command to mxHost. A
+// provider name wins over host fragments; among fragments the longest match
+// wins, so "mx1.eu.example" beats "example" whatever the map order.
+func commandDelay(mxHost string) time.Duration {
+	if d, ok := SMTPCommandDelays[ProviderForMX(mxHost)]; ok {
+		return d
+	}
+	host := strings.ToLower(mxHost)
+	best := ""
+	for name := range SMTPCommandDelays {
+		if !strings.Contains(host, name) {
+			continue
+		}
+		if len(name) > len(best) || (len(name) == len(best) && name < best) {
+			best = name
+		}
 	}
-	conn.SetDeadline(deadline)
+	if best != "" {
+		return SMTPCommandDelays[best]
+	}
+	if isStrictGateway(mxHost) {
+		return strictGatewayDelay
+	}
+	return 0
+}
 
-	tp := textproto.NewConn(conn)
-	defer tp.Close()
+// sessionDelays is how many commands of a single-probe session are preceded
+// by the command delay: EHLO, a HELO retry, STARTTLS and the EHLO after it,
+// MAIL FROM and RCPT TO. The connection deadline is extended by that many
+// delays so a slow-paced session is not cut off by its own pacing.
+const sessionDelays = 6
+
+// ErrSMTPUTF8Unsupported reports that the target has a non-ASCII local part
+// but the server does not advertise SMTPUTF8 (RFC 6531), so the address
+// cannot be verified over SMTP at all. It says nothing about the mailbox.
+var ErrSMTPUTF8Unsupported = errors.New("server does not support SMTPUTF8 for internationalized address")
+
+// needsSMTPUTF8 reports whether email has a non-ASCII local part.
+func needsSMTPUTF8(email string) bool {
+	local := email
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		local = email[:at]
+	}
+	for i := 0; i < len(local); i++ {
+		if local[i] >= 0x80 {
+			return true
+		}
+	}
+	return false
+}
+
+// rcptSession runs the SMTP dialogue up to RCPT TO over an established
+// connection. The session greets with EHLO when the banner advertises ESMTP
+// or AlwaysEHLO is set (plain HELO otherwise) and, with tryTLS, upgrades to
+// TLS if STARTTLS is offered. An internationalized local part always uses
+// EHLO and requires SMTPUTF8.
+func rcptSession(ctx context.Context, conn net.Conn, targetEmail string, id SenderIdentity, delay time.Duration, tryTLS bool) (bool, time.Duration, error) {
+	s, err := openSMTPSession(ctx, conn, id, delay, needsSMTPUTF8(targetEmail), tryTLS)
+	if err != nil {
+		return false, s.elapsed(), err
+	}
+	defer s.close()
+
+	accepted, elapsed, err := s.rcpt(targetEmail)
+	if s.replied || errors.Is(err, ErrSMTPUTF8Unsupported) {
+		s.tp.Cmd("QUIT")
+	}
+	return accepted, elapsed, err
+}
+
+// smtpSession is an SMTP conversation past the greeting (and STARTTLS),
+// ready for MAIL FROM.
+type smtpSession struct {
+	ctx   context.Context
+	tp    *textproto.Conn
+	caps  string
+	id    SenderIdentity
+	delay time.Duration
+	start time.Time
+
+	replied bool // the transaction got an answer to RCPT TO
+}
+
+// openSMTPSession reads the banner and greets the server, upgrading to TLS
+// if it is offered and tryTLS is set. The returned session is non-nil even on
+// error, so the caller can report how long the attempt took.
+func openSMTPSession(ctx context.Context, conn net.Conn, id SenderIdentity, delay time.Duration, utf8, tryTLS bool) (*smtpSession, error) {
+	s := &smtpSession{ctx: ctx, tp: textproto.NewConn(conn), id: id, delay: delay, start: time.Now()}
+
+	_, banner, err := s.tp.ReadResponse(220)
+	if err != nil {
+		s.close()
+		return s, fmt.Errorf("banner timeout/rejected: %w", err)
+	}
+
+	greeting := "HELO"
+	if utf8 || AlwaysEHLO || strings.Contains(strings.ToUpper(banner), "ESMTP") {
+		greeting = "EHLO"
+	}
+
+	caps, err := s.hello(greeting)
+	if err != nil && greeting == "EHLO" && !utf8 && isCommandUnrecognized(err) {
+		// The banner oversold the server; plain SMTP still works.
+		greeting = "HELO"
+		caps, err = s.hello(greeting)
+	}
+	if err != nil {
+		s.close()
+		return s, err
+	}
 
-	smartDelay := func() error {
-		if !isStrictEnterprise {
-			return nil
+	var advertised SMTPCapabilities
+	if greeting == "EHLO" {
+		advertised = parseEHLO(caps)
+	}
+
+	if greeting == "EHLO" && StartTLS && tryTLS && hasExtension(caps, "STARTTLS") {
+		if err := s.smartDelay(); err != nil {
+			s.close()
+			return s, err
+		}
+		upgraded, ok, err := startTLS(ctx, s.tp, conn)
+		if err != nil {
+			s.close()
+			return s, err
 		}
-		select {
-		case <-time.After(1 * time.Second):
-			return nil
-		case <-ctx.Done():
-			return ctx.Err()
+		if ok {
+			// RFC 3207 §4.2: the client must discard what it knew and
+			// greet the server again over the encrypted channel.
+			s.tp = textproto.NewConn(upgraded)
+			if caps, err = s.hello("EHLO"); err != nil {
+				s.close()
+				return s, err
+			}
+			// Servers often offer AUTH only once the channel is encrypted,
+			// and stop listing STARTTLS.
+			advertised = parseEHLO(caps)
+			advertised.addExtension("STARTTLS")
 		}
 	}
+	s.caps = caps
+	SessionInfoFrom(ctx).setCapabilities(advertised)
+	return s, nil
+}
 
-	if _, _, err := tp.ReadResponse(220); err != nil {
-		return false, time.Since(start), fmt.Errorf("banner timeout/rejected: %w", err)
+func (s *smtpSession) smartDelay() error {
+	if s.delay <= 0 {
+		return nil
 	}
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
 
-	if err := smartDelay(); err != nil {
-		return false, time.Since(start), err
+func (s *smtpSession) hello(verb string) (string, error) {
+	if err := s.smartDelay(); err != nil {
+		return "", err
 	}
-	if _, err := tp.Cmd("HELO %s", HeloHost); err != nil {
-		return false, time.Since(start), err
+	if _, err := s.tp.Cmd("%s %s", verb, s.id.Helo); err != nil {
+		return "", err
 	}
-	if _, _, err := tp.ReadResponse(250); err != nil {
-		return false, time.Since(start), fmt.Errorf("HELO rejected: %w", err)
+	_, caps, err := s.tp.ReadResponse(250)
+	if err != nil {
+		return "", &PolicyError{Stage: "HELO", Err: err}
 	}
+	return caps, nil
+}
 
-	if err := smartDelay(); err != nil {
+// elapsed is the time since the session began; zero for a nil session.
+func (s *smtpSession) elapsed() time.Duration {
+	if s == nil {
+		return 0
+	}
+	return time.Since(s.start)
+}
+
+func (s *smtpSession) close() { s.tp.Close() }
+
+// rcpt runs the MAIL FROM / RCPT TO transaction. Its latency is measured
+// from the start of the session, as the timing signal always has been.
+func (s *smtpSession) rcpt(email string) (bool, time.Duration, error) {
+	start := s.start
+
+	mailParams := ""
+	if needsSMTPUTF8(email) {
+		if !hasExtension(s.caps, "SMTPUTF8") {
+			return false, time.Since(start), ErrSMTPUTF8Unsupported
+		}
+		mailParams = " SMTPUTF8"
+	}
+
+	if err := s.smartDelay(); err != nil {
 		return false, time.Since(start), err
 	}
-	if _, err := tp.Cmd("MAIL FROM:<%s>", MailFrom); err != nil {
+	if _, err := s.tp.Cmd("MAIL FROM:<%s>%s", s.id.MailFrom, mailParams); err != nil {
 		return false, time.Since(start), err
 	}
-	if _, _, err := tp.ReadResponse(250); err != nil {
-		return false, time.Since(start), fmt.Errorf("MAIL FROM rejected: %w", err)
+	if _, _, err := s.tp.ReadResponse(250); err != nil {
+		return false, time.Since(start), &PolicyError{Stage: "MAIL FROM", Err: err}
 	}
 
-	if err := smartDelay(); err != nil {
+	if err := s.smartDelay(); err != nil {
 		return false, time.Since(start), err
 	}
-	if _, err := tp.Cmd("RCPT TO:<%s>", targetEmail); err != nil {
+	if _, err := s.tp.Cmd("RCPT TO:<%s>", email); err != nil {
 		return false, time.Since(start), err
 	}
 
-	code, msg, err := tp.ReadResponse(0)
+	code, msg, err := s.tp.ReadResponse(0)
 	elapsed := time.Since(start)
-
-	tp.Cmd("QUIT")
+	s.replied = true
 
 	if err != nil {
 		return false, elapsed, fmt.Errorf("network read error: %w", err)
@@ -127,10 +606,32 @@ func CheckSMTP(ctx context.Context, mxHost string, targetEmail string, pURL *url
 	if code == 250 || code == 251 {
 		return true, elapsed, nil
 	}
+	if code == 450 || code == 451 {
+		return false, elapsed, &GreylistError{Err: &textproto.Error{Code: code, Msg: msg}}
+	}
 
 	return false, elapsed, &textproto.Error{Code: code, Msg: msg}
 }
 
+// isCommandUnrecognized reports whether err is a 500–504 reply: the server
+// does not implement the command, as opposed to refusing the client.
+func isCommandUnrecognized(err error) bool {
+	var textErr *textproto.Error
+	return errors.As(err, &textErr) && textErr.Code >= 500 && textErr.Code <= 504
+}
+
+// hasExtension reports whether an EHLO reply (as returned by ReadResponse,
+// one line per extension after the greeting) advertises ext.
+func hasExtension(ehloReply, ext string) bool {
+	lines := strings.Split(ehloReply, "\n")
+	for _, line := range lines[1:] {
+		if f := strings.Fields(line); len(f) > 0 && strings.EqualFold(f[0], ext) {
+			return true
+		}
+	}
+	return false
+}
+
 func CheckPostmaster(ctx context.Context, mxHost, domain string, pURL *url.URL) bool {
 	success, _, err := CheckSMTP(ctx, mxHost, "postmaster@"+domain, pURL)
 	if success {
@@ -143,6 +644,24 @@ func CheckPostmaster(ctx context.Context, mxHost, domain string, pURL *url.URL)
 }
 
 func CheckVRFY(ctx context.Context, mxHost string, targetEmail string, pURL *url.URL) bool {
+	// VRFY has no SMTPUTF8 form worth relying on; leave these to RCPT TO.
+	if needsSMTPUTF8(targetEmail) {
+		return false
+	}
+
+	releaseHost, err := smtpHostSlots.acquire(ctx, mxHost)
+	if err != nil {
+		return false
+	}
+	defer releaseHost()
+
+	// Slots in the same order as CheckSMTPAs.
+	releaseFleet, err := ratelimit.Fleet.Acquire(ctx, ProviderForMX(mxHost))
+	if err != nil {
+		return false
+	}
+	defer releaseFleet()
+
 	select {
 	case SMTPSemaphore <- struct{}{}:
 	case <-ctx.Done():
@@ -150,19 +669,11 @@ func CheckVRFY(ctx context.Context, mxHost string, targetEmail string, pURL *url
 	}
 	defer func() { <-SMTPSemaphore }()
 
-	var conn net.Conn
-	var err error
-
-	if proxy.SMTPEnabled && pURL != nil {
-		conn, err = proxy.DialContext(ctx, "tcp", mxHost+":25", 10*time.Second, pURL)
-	} else {
-		d := net.Dialer{Timeout: 10 * time.Second}
-		conn, err = d.DialContext(ctx, "tcp", mxHost+":25")
-	}
-
+	conn, err := dialSMTP(ctx, mxHost, pURL)
 	if err != nil {
 		return false
 	}
+	conn = TranscriptFrom(ctx).wrap(conn, mxHost)
 	defer conn.Close()
 
 	deadline := time.Now().Add(10 * time.Second)
@@ -194,14 +705,88 @@ func CheckVRFY(ctx context.Context, mxHost string, targetEmail string, pURL *url
 	return err == nil && (code == 250 || code == 251)
 }
 
+// ResponseText returns the SMTP reply carried by err as "<code> <message>"
+// (prefixed with the stage for policy rejections), or "" if err does not carry
+// a server reply — e.g. a dial failure or timeout.
+func ResponseText(err error) string {
+	var textErr *textproto.Error
+	if !errors.As(err, &textErr) {
+		return ""
+	}
+	text := fmt.Sprintf("%d %s", textErr.Code, textErr.Msg)
+
+	var pe *PolicyError
+	if errors.As(err, &pe) {
+		return pe.Stage + " rejected: " + text
+	}
+	return text
+}
+
+// enhancedStatusRe matches an RFC 3463 enhanced status code (class.subject.detail)
+// in a reply text, e.g. "5.1.1" in "550 5.1.1 <x@example.com>: user unknown".
+var enhancedStatusRe = regexp.MustCompile(`(?:^|[^\d.])([245])\.(\d{1,3})\.(\d{1,3})(?:[^\d.]|$)`)
+
+// enhancedStatus returns the first enhanced status code in text as
+// "class.subject.detail", or "" if there is none.
+func enhancedStatus(text string) string {
+	m := enhancedStatusRe.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	return m[1] + "." + m[2] + "." + m[3]
+}
+
+// noSuchMailboxCodes are the enhanced status codes that speak to the recipient
+// mailbox itself: bad mailbox (5.1.1), other address status (5.1.0), mailbox
+// moved without forwarding (5.1.6), mailbox disabled (5.2.1), and the 5.4.1
+// Exchange Online returns for recipients its directory does not know.
+var noSuchMailboxCodes = map[string]bool{
+	"5.1.0": true, "5.1.1": true, "5.1.6": true, "5.2.1": true, "5.4.1": true,
+}
+
+// IsNoSuchUserError reports whether err is a permanent verdict that the
+// recipient mailbox does not exist.
+//
+// Classification is driven by the reply codes first and falls back to keyword
+// matching only when they are inconclusive:
+//
+//  1. Policy rejections and greylisting deferrals are never mailbox verdicts.
+//  2. An enhanced status code decides on its own: 4.x.x is transient, a
+//     mailbox code (see noSuchMailboxCodes) is a verdict, and any other
+//     specific 5.x.x (5.7.1 blocked, 5.2.2 mailbox full, …) is not. The
+//     generic 5.0.0 and 5.5.x codes carry no subject, so they fall through.
+//  3. A 4xx basic reply code is transient whatever its wording.
+//  4. Keywords, then a bare 550/551, as a last resort.
 func IsNoSuchUserError(err error) bool {
 	if err == nil {
 		return false
 	}
+	// A session-level refusal can carry 550 and even "address rejected"
+	// (referring to the sender), but it never speaks to the recipient.
+	if IsPolicyError(err) {
+		return false
+	}
+	// A 4xx deferral is never final, whatever its wording.
+	if IsGreylistError(err) {
+		return false
+	}
 	errStr := strings.ToLower(err.Error())
 
-	if strings.Contains(errStr, "5.1.1") || strings.Contains(errStr, "5.1.0") || strings.Contains(errStr, "5.4.1") {
-		return true
+	if code := enhancedStatus(errStr); code != "" {
+		switch {
+		case code[0] != '5':
+			return false
+		case noSuchMailboxCodes[code]:
+			return true
+		case code != "5.0.0" && !strings.HasPrefix(code, "5.5."):
+			return false
+		}
+	}
+
+	var textErr *textproto.Error
+	hasCode := errors.As(err, &textErr)
+	if hasCode && textErr.Code >= 400 && textErr.Code < 500 {
+		return false
 	}
 
 	blockKeywords := []string{
@@ -231,14 +816,7 @@ func IsNoSuchUserError(err error) bool {
 		}
 	}
 
-	var textErr *textproto.Error
-	if errors.As(err, &textErr) {
-		if textErr.Code == 550 || textErr.Code == 551 {
-			return true
-		}
-	}
-
-	return false
+	return hasCode && (textErr.Code == 550 || textErr.Code == 551)
 }
 
 func IsRateLimitError(err error) bool {
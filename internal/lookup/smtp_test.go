@@ -0,0 +1,621 @@
+package lookup
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicyErrorClassification(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantPolicy bool
+		wantNoUser bool
+	}{
+		{
+			name:       "HELO rejected with 5xx",
+			err:        &PolicyError{Stage: "HELO", Err: &textproto.Error{Code: 550, Msg: "5.7.1 unknown host"}},
+			wantPolicy: true,
+			wantNoUser: false,
+		},
+		{
+			name:       "HELO rejected with mailbox-like wording",
+			err:        &PolicyError{Stage: "HELO", Err: &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}},
+			wantPolicy: true,
+			wantNoUser: false,
+		},
+		{
+			name:       "MAIL FROM rejected as address rejected",
+			err:        &PolicyError{Stage: "MAIL FROM", Err: &textproto.Error{Code: 553, Msg: "address rejected"}},
+			wantPolicy: true,
+			wantNoUser: false,
+		},
+		{
+			name:       "RCPT rejected — user unknown",
+			err:        &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"},
+			wantPolicy: false,
+			wantNoUser: true,
+		},
+		{
+			name:       "RCPT greylisted with mailbox-like wording",
+			err:        &GreylistError{Err: &textproto.Error{Code: 450, Msg: "4.2.0 mailbox unavailable, try again later"}},
+			wantPolicy: false,
+			wantNoUser: false,
+		},
+		{
+			name:       "Plain connection failure",
+			err:        errors.New("connection failed: dial tcp: i/o timeout"),
+			wantPolicy: false,
+			wantNoUser: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPolicyError(tt.err); got != tt.wantPolicy {
+				t.Errorf("IsPolicyError() = %v, want %v", got, tt.wantPolicy)
+			}
+			if got := IsNoSuchUserError(tt.err); got != tt.wantNoUser {
+				t.Errorf("IsNoSuchUserError() = %v, want %v", got, tt.wantNoUser)
+			}
+		})
+	}
+}
+
+func TestPolicyErrorMessage(t *testing.T) {
+	inner := &textproto.Error{Code: 554, Msg: "go away"}
+	err := &PolicyError{Stage: "HELO", Err: inner}
+	if got, want := err.Error(), "HELO rejected: "+inner.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	var textErr *textproto.Error
+	if !errors.As(err, &textErr) || textErr.Code != 554 {
+		t.Errorf("expected PolicyError to unwrap to the underlying textproto.Error")
+	}
+}
+
+func TestProbeWithIdentitiesRotatesOnRejection(t *testing.T) {
+	a := SenderIdentity{Helo: "mta.a.example", MailFrom: "verify@a.example"}
+	b := SenderIdentity{Helo: "mta.b.example", MailFrom: "verify@b.example"}
+
+	var tried []SenderIdentity
+	var rotatedTo SenderIdentity
+	var rotatedValid bool
+
+	valid, _, err := probeWithIdentities([]SenderIdentity{a, b}, func(id SenderIdentity) (bool, time.Duration, error) {
+		tried = append(tried, id)
+		if id == a {
+			return false, 0, &PolicyError{Stage: "MAIL FROM", Err: &textproto.Error{Code: 550, Msg: "sender reputation too low"}}
+		}
+		return true, 0, nil
+	}, func(id SenderIdentity, valid bool, err error) { rotatedTo, rotatedValid = id, valid })
+
+	if !valid || err != nil {
+		t.Fatalf("expected identity B to be accepted, got valid=%v err=%v", valid, err)
+	}
+	if len(tried) != 2 || tried[0] != a || tried[1] != b {
+		t.Errorf("expected identities tried in order [A, B], got %v", tried)
+	}
+	if rotatedTo != b || !rotatedValid {
+		t.Errorf("expected rotation to be reported for B with its result, got %v (valid=%v)", rotatedTo, rotatedValid)
+	}
+}
+
+func TestProbeWithIdentitiesStopsOnBackoff(t *testing.T) {
+	a := SenderIdentity{Helo: "mta.a.example"}
+	b := SenderIdentity{Helo: "mta.b.example"}
+
+	for name, deferral := range map[string]error{
+		"greylist":   &GreylistError{Err: &textproto.Error{Code: 451, Msg: "4.7.1 greylisted, try again later"}},
+		"rate limit": &textproto.Error{Code: 450, Msg: "4.7.0 too many connections"},
+	} {
+		calls := 0
+		_, _, err := probeWithIdentities([]SenderIdentity{a, b}, func(id SenderIdentity) (bool, time.Duration, error) {
+			calls++
+			return false, 0, deferral
+		}, nil)
+		if calls != 1 || err != deferral {
+			t.Errorf("%s: expected no rotation after a deferral, got %d calls (err %v)", name, calls, err)
+		}
+	}
+}
+
+func TestProbeWithIdentitiesStopsOnMailboxVerdict(t *testing.T) {
+	a := SenderIdentity{Helo: "mta.a.example"}
+	b := SenderIdentity{Helo: "mta.b.example"}
+
+	calls := 0
+	_, _, err := probeWithIdentities([]SenderIdentity{a, b}, func(id SenderIdentity) (bool, time.Duration, error) {
+		calls++
+		return false, 0, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+	}, nil)
+
+	if calls != 1 {
+		t.Errorf("expected a mailbox verdict to stop rotation after 1 call, got %d", calls)
+	}
+	if !IsNoSuchUserError(err) {
+		t.Errorf("expected the mailbox verdict to be returned, got %v", err)
+	}
+}
+
+func TestParseSenderIdentities(t *testing.T) {
+	ids := parseSenderIdentities([]string{"mta.a.example|verify@a.example", "mta.b.example|", "|orphan@c.example"})
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 identities, got %d", len(ids))
+	}
+	if ids[0].Helo != "mta.a.example" || ids[0].MailFrom != "verify@a.example" {
+		t.Errorf("unexpected first identity %v", ids[0])
+	}
+	if ids[1].MailFrom != "" {
+		t.Errorf("expected null sender for second identity, got %q", ids[1].MailFrom)
+	}
+
+	if def := parseSenderIdentities(nil); len(def) != 1 || def[0] != DefaultIdentity {
+		t.Errorf("expected fallback to DefaultIdentity, got %v", def)
+	}
+}
+
+func TestParseHeloHostAndMailFrom(t *testing.T) {
+	for in, want := range map[string]string{
+		"mx.mydomain.example.": "mx.mydomain.example",
+		" mta1.example.org ":   "mta1.example.org",
+		"localhost":            defaultHeloHost,
+		"mta_1.example.org":    defaultHeloHost,
+		"192.0.2.1":            defaultHeloHost,
+		"mtä.example.org":      defaultHeloHost,
+	} {
+		if got := parseHeloHost(in); got != want {
+			t.Errorf("parseHeloHost(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	for in, want := range map[string]string{
+		"":                          "",
+		"verify@mydomain.example":   "verify@mydomain.example",
+		"<bounce@mydomain.example>": "bounce@mydomain.example",
+		"not an address":            "",
+	} {
+		if got := parseMailFrom(in); got != want {
+			t.Errorf("parseMailFrom(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDirectSMTPDialerUsesSourceIPPool(t *testing.T) {
+	saved := SourceIPs
+	defer func() { SourceIPs = saved }()
+
+	SourceIPs = parseSourceIPs([]string{"203.0.113.10", "not-an-ip", "2001:db8::1", "203.0.113.11"})
+	if len(SourceIPs) != 2 {
+		t.Fatalf("expected 2 usable IPv4 source IPs, got %d", len(SourceIPs))
+	}
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		d := directSMTPDialer()
+		addr, ok := d.LocalAddr.(*net.TCPAddr)
+		if !ok || addr == nil {
+			t.Fatalf("expected LocalAddr to be a *net.TCPAddr, got %T", d.LocalAddr)
+		}
+		seen[addr.IP.String()]++
+	}
+	if seen["203.0.113.10"] != 2 || seen["203.0.113.11"] != 2 {
+		t.Errorf("expected even rotation across the pool, got %v", seen)
+	}
+
+	SourceIPs = nil
+	if d := directSMTPDialer(); d.LocalAddr != nil {
+		t.Errorf("expected no LocalAddr with an empty pool, got %v", d.LocalAddr)
+	}
+}
+
+// fakeSMTPServer answers one session on conn with the given banner and
+// EHLO/HELO reply and records every command it receives. With a non-nil
+// tlsConfig it accepts STARTTLS and continues the session over TLS.
+func fakeSMTPServer(conn net.Conn, banner, greetingReply string, tlsConfig *tls.Config) <-chan []string {
+	done := make(chan []string, 1)
+	go func() {
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var cmds []string
+		tp.PrintfLine("220 %s", banner)
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				break
+			}
+			cmds = append(cmds, line)
+			switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+			case "EHLO", "HELO":
+				tp.PrintfLine("%s", greetingReply)
+			case "STARTTLS":
+				if tlsConfig == nil {
+					tp.PrintfLine("502 not implemented")
+					continue
+				}
+				tp.PrintfLine("220 ready to start TLS")
+				tlsConn := tls.Server(conn, tlsConfig)
+				if err := tlsConn.Handshake(); err != nil {
+					done <- cmds
+					return
+				}
+				tp = textproto.NewConn(tlsConn)
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				done <- cmds
+				return
+			default:
+				tp.PrintfLine("250 OK")
+			}
+		}
+		done <- cmds
+	}()
+	return done
+}
+
+func TestRCPTSessionSMTPUTF8(t *testing.T) {
+	const target = "用户@example.com"
+
+	client, server := net.Pipe()
+	cmds := fakeSMTPServer(server, "mx.example.com ESMTP", "250-mx.example.com\r\n250-8BITMIME\r\n250 SMTPUTF8", nil)
+
+	ok, _, err := rcptSession(context.Background(), client, target, DefaultIdentity, 0, true)
+	if err != nil || !ok {
+		t.Fatalf("expected acceptance via SMTPUTF8, got ok=%v err=%v", ok, err)
+	}
+
+	got := <-cmds
+	want := []string{
+		"EHLO " + HeloHost,
+		"MAIL FROM:<> SMTPUTF8",
+		"RCPT TO:<" + target + ">",
+		"QUIT",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestRCPTSessionSMTPUTF8Unsupported(t *testing.T) {
+	client, server := net.Pipe()
+	cmds := fakeSMTPServer(server, "mx.example.com ESMTP", "250-mx.example.com\r\n250 8BITMIME", nil)
+
+	_, _, err := rcptSession(context.Background(), client, "用户@example.com", DefaultIdentity, 0, true)
+	if !errors.Is(err, ErrSMTPUTF8Unsupported) {
+		t.Fatalf("expected ErrSMTPUTF8Unsupported, got %v", err)
+	}
+	if IsNoSuchUserError(err) {
+		t.Errorf("an unsupported extension must not read as a mailbox verdict")
+	}
+	for _, c := range <-cmds {
+		if strings.HasPrefix(c, "RCPT") {
+			t.Errorf("RCPT TO must not be sent without SMTPUTF8, got %q", c)
+		}
+	}
+}
+
+func TestRCPTSessionASCIIUsesHELO(t *testing.T) {
+	client, server := net.Pipe()
+	cmds := fakeSMTPServer(server, "mx.example.com Service ready", "250 mx.example.com", nil)
+
+	if ok, _, err := rcptSession(context.Background(), client, "jane@example.com", DefaultIdentity, 0, true); !ok || err != nil {
+		t.Fatalf("ok=%v err=%v", ok, err)
+	}
+	if got := <-cmds; got[0] != "HELO "+HeloHost || got[1] != "MAIL FROM:<>" {
+		t.Errorf("ASCII session changed: %q", got)
+	}
+}
+
+func TestRCPTSessionESMTPWithoutSTARTTLS(t *testing.T) {
+	client, server := net.Pipe()
+	cmds := fakeSMTPServer(server, "mx.example.com ESMTP", "250-mx.example.com\r\n250 8BITMIME", nil)
+
+	info := &SessionInfo{}
+	ctx := WithSessionInfo(context.Background(), info)
+	if ok, _, err := rcptSession(ctx, client, "jane@example.com", DefaultIdentity, 0, true); !ok || err != nil {
+		t.Fatalf("ok=%v err=%v", ok, err)
+	}
+	if got := <-cmds; got[0] != "EHLO "+HeloHost || got[1] != "MAIL FROM:<>" {
+		t.Errorf("expected EHLO then MAIL FROM, got %q", got)
+	}
+	if info.TLSVersion() != 0 {
+		t.Errorf("no TLS was offered, got version %x", info.TLSVersion())
+	}
+}
+
+func TestRCPTSessionSTARTTLS(t *testing.T) {
+	// A TCP loopback pair rather than net.Pipe: the TLS close_notify and the
+	// server's QUIT reply are written concurrently and need socket buffering.
+	client, server := tcpPair(t)
+	cmds := fakeSMTPServer(server, "mx.example.com ESMTP", "250-mx.example.com\r\n250 STARTTLS", &tls.Config{
+		Certificates: []tls.Certificate{selfSignedCert(t)},
+		MinVersion:   tls.VersionTLS13,
+	})
+
+	info := &SessionInfo{}
+	ctx := WithSessionInfo(context.Background(), info)
+	if ok, _, err := rcptSession(ctx, client, "jane@example.com", DefaultIdentity, 0, true); !ok || err != nil {
+		t.Fatalf("ok=%v err=%v", ok, err)
+	}
+
+	want := []string{
+		"EHLO " + HeloHost,
+		"STARTTLS",
+		"EHLO " + HeloHost,
+		"MAIL FROM:<>",
+		"RCPT TO:<jane@example.com>",
+		"QUIT",
+	}
+	if got := <-cmds; !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	if info.TLSVersion() != tls.VersionTLS13 {
+		t.Errorf("expected TLS 1.3 to be recorded, got %x", info.TLSVersion())
+	}
+}
+
+func TestRCPTOverDialSTARTTLSFailure(t *testing.T) {
+	client, server := tcpPair(t)
+	// The server only speaks TLS 1.1, which the client refuses.
+	first := fakeSMTPServer(server, "mx.example.com ESMTP", "250-mx.example.com\r\n250 STARTTLS", &tls.Config{
+		Certificates: []tls.Certificate{selfSignedCert(t)},
+		MaxVersion:   tls.VersionTLS11,
+	})
+
+	var second <-chan []string
+	dial := func() (net.Conn, error) {
+		if client != nil {
+			c := client
+			client = nil
+			return c, nil
+		}
+		c, s := net.Pipe()
+		second = fakeSMTPServer(s, "mx.example.com ESMTP", "250-mx.example.com\r\n250 STARTTLS", nil)
+		return c, nil
+	}
+
+	accepted, _, err := rcptOverDial(context.Background(), "mx.example.com", dial, "jane@example.com", DefaultIdentity, 0)
+	if !accepted || err != nil {
+		t.Fatalf("accepted=%v err=%v, expected the plaintext retry to be accepted", accepted, err)
+	}
+	if got := <-first; got[len(got)-1] != "STARTTLS" {
+		t.Errorf("first connection should end at STARTTLS, got %q", got)
+	}
+	if second == nil {
+		t.Fatal("expected a redial after the handshake failed")
+	}
+	want := []string{"EHLO " + HeloHost, "MAIL FROM:<>", "RCPT TO:<jane@example.com>", "QUIT"}
+	if got := <-second; !reflect.DeepEqual(got, want) {
+		t.Errorf("plaintext connection commands = %q, want %q", got, want)
+	}
+}
+
+func TestRCPTSessionEHLOFallsBackToHELO(t *testing.T) {
+	client, server := net.Pipe()
+	// The server advertises ESMTP but rejects EHLO as unrecognised; HELO
+	// gets the same reply, so the fallback must surface it as a policy error.
+	cmds := fakeSMTPServer(server, "mx.example.com ESMTP", "502 command not recognized", nil)
+
+	_, _, err := rcptSession(context.Background(), client, "jane@example.com", DefaultIdentity, 0, true)
+	if !IsPolicyError(err) {
+		t.Fatalf("expected a HELO policy error, got %v", err)
+	}
+	client.Close()
+	if got := <-cmds; len(got) < 2 || got[0] != "EHLO "+HeloHost || got[1] != "HELO "+HeloHost {
+		t.Errorf("expected EHLO then HELO, got %q", got)
+	}
+}
+
+func TestParseEHLO(t *testing.T) {
+	reply := "mx.example.com Hello\nSIZE 35882577\nPIPELINING\nAUTH=LOGIN PLAIN\nauth login plain xoauth2\nETRN\n8bitmime\nSMTPUTF8"
+	got := parseEHLO(reply)
+	want := SMTPCapabilities{
+		Extensions: []string{"8BITMIME", "PIPELINING", "SMTPUTF8"},
+		Size:       35882577,
+		Auth:       []string{"LOGIN", "PLAIN", "XOAUTH2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEHLO = %+v, want %+v", got, want)
+	}
+	if got := parseEHLO("mx.example.com"); !reflect.DeepEqual(got, SMTPCapabilities{}) {
+		t.Errorf("bare greeting parsed as %+v", got)
+	}
+}
+
+func TestRCPTSessionRecordsCapabilities(t *testing.T) {
+	saved := AlwaysEHLO
+	defer func() { AlwaysEHLO = saved }()
+	AlwaysEHLO = true
+
+	client, server := net.Pipe()
+	// The banner does not mention ESMTP, so only AlwaysEHLO sends EHLO.
+	cmds := fakeSMTPServer(server, "mx.example.com Service ready", "250-mx.example.com\r\n250-SIZE 10240000\r\n250-AUTH PLAIN\r\n250 8BITMIME", nil)
+
+	info := &SessionInfo{}
+	ctx := WithSessionInfo(context.Background(), info)
+	if ok, _, err := rcptSession(ctx, client, "jane@example.com", DefaultIdentity, 0, true); !ok || err != nil {
+		t.Fatalf("ok=%v err=%v", ok, err)
+	}
+	if got := <-cmds; got[0] != "EHLO "+HeloHost {
+		t.Errorf("expected EHLO with AlwaysEHLO set, got %q", got)
+	}
+	want := SMTPCapabilities{Extensions: []string{"8BITMIME"}, Size: 10240000, Auth: []string{"PLAIN"}}
+	if got := info.Capabilities(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Capabilities = %+v, want %+v", got, want)
+	}
+}
+
+func TestIsAuthRequired(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{&PolicyError{Stage: "MAIL FROM", Err: &textproto.Error{Code: 530, Msg: "5.7.0 Must issue a STARTTLS command first"}}, true},
+		{&textproto.Error{Code: 550, Msg: "5.7.1 Client host rejected: Authentication required"}, true},
+		{&textproto.Error{Code: 550, Msg: "5.1.1 User unknown"}, false},
+		{errors.New("connection reset"), false},
+	} {
+		if got := IsAuthRequired(tc.err); got != tc.want {
+			t.Errorf("IsAuthRequired(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+// tcpPair returns the two ends of a loopback TCP connection.
+func tcpPair(t *testing.T) (client, server net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	client, err = net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = <-accepted
+	if server == nil {
+		t.Fatal("accept failed")
+	}
+	return client, server
+}
+
+// selfSignedCert returns a throwaway ECDSA certificate for TLS tests.
+func selfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mx.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestNoSuchUserRealWorldReplies(t *testing.T) {
+	tests := []struct {
+		code int
+		msg  string
+		want bool
+	}{
+		// Mailbox verdicts.
+		{550, "5.1.1 The email account that you tried to reach does not exist. Please try double-checking the recipient's email address for typos", true},
+		{550, "5.4.1 Recipient address rejected: Access denied. [AM0PR01MB1234.eurprd01.prod.exchangelabs.com]", true},
+		{550, "5.1.1 <jane@example.com>: Recipient address rejected: mailbox temporarily disabled", true},
+		{550, "5.2.1 The email account that you tried to reach is disabled", true},
+		{550, "5.0.0 <jane@example.com>... User unknown", true},
+		{550, "Requested action not taken: mailbox unavailable", true},
+		{550, "No such user here", true},
+
+		// Transient: an enhanced 4.x.x or a 4xx reply, whatever the wording.
+		{450, "4.1.1 <jane@example.com>: Recipient address rejected: User unknown in local recipient table", false},
+		{550, "4.2.0 <jane@example.com>: Recipient address rejected: user does not exist (retry later)", false},
+		{451, "Requested action aborted: local error in processing, user not found in cache", false},
+		{421, "4.7.0 Try again later, closing connection", false},
+
+		// Permanent, but about the sender or the mailbox's state, not its existence.
+		{550, "5.7.1 Service unavailable, Client host [192.0.2.1] blocked using Spamhaus", false},
+		{554, "5.7.1 <jane@example.com>: Relay access denied", false},
+		{552, "5.2.2 The email account that you tried to reach is over quota", false},
+		{550, "5.7.606 Access denied, banned sending IP [192.0.2.1]", false},
+		{550, "5.7.1 Recipient address rejected: user unknown to this gateway policy", false},
+	}
+
+	for _, tt := range tests {
+		err := &textproto.Error{Code: tt.code, Msg: tt.msg}
+		if got := IsNoSuchUserError(err); got != tt.want {
+			t.Errorf("IsNoSuchUserError(%d %s) = %v, want %v", tt.code, tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestEnhancedStatus(t *testing.T) {
+	tests := map[string]string{
+		"550 5.1.1 user unknown":                   "5.1.1",
+		"550 5.7.606 access denied":                "5.7.606",
+		"550 #5.1.0 Address rejected":              "5.1.0",
+		"connection failed: dial 10.5.1.1:25":      "",
+		"550 user unknown":                         "",
+		"network read error: 192.0.2.25:25 closed": "",
+	}
+	for in, want := range tests {
+		if got := enhancedStatus(in); got != want {
+			t.Errorf("enhancedStatus(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCommandDelay(t *testing.T) {
+	saved := SMTPCommandDelays
+	defer func() { SMTPCommandDelays = saved }()
+
+	SMTPCommandDelays = parseCommandDelays([]string{"google=0ms", "mimecast=1500ms", "ironport=2s", "slowhost.example=250ms", "host.example=5s", "bogus", "neg=-1s"})
+
+	tests := []struct {
+		mx   string
+		want time.Duration
+	}{
+		{"aspmx.l.google.com", 0},
+		{"eu-smtp-inbound-1.mimecast.com", 1500 * time.Millisecond},
+		{"mx1.acme.iphmx.com", 2 * time.Second},
+		{"mx.slowhost.example", 250 * time.Millisecond},
+		// Unconfigured strict gateways keep the built-in pacing.
+		{"mx0a-001.pphosted.com", strictGatewayDelay},
+		{"mx.example.com", 0},
+	}
+	// Map iteration order varies; the longest fragment must win every time.
+	for i := 0; i < 20; i++ {
+		for _, tt := range tests {
+			if got := commandDelay(tt.mx); got != tt.want {
+				t.Fatalf("commandDelay(%q) = %s, want %s", tt.mx, got, tt.want)
+			}
+		}
+	}
+	if _, ok := SMTPCommandDelays["neg"]; ok {
+		t.Errorf("negative delays must be rejected")
+	}
+}
+
+func TestRCPTSessionAppliesCommandDelay(t *testing.T) {
+	const delay = 40 * time.Millisecond
+
+	client, server := net.Pipe()
+	fakeSMTPServer(server, "mx.example.com ESMTP", "250 mx.example.com", nil)
+
+	start := time.Now()
+	if ok, _, err := rcptSession(context.Background(), client, "jane@example.com", DefaultIdentity, delay, true); !ok || err != nil {
+		t.Fatalf("ok=%v err=%v", ok, err)
+	}
+	// EHLO, MAIL FROM and RCPT TO are each preceded by the delay.
+	if elapsed := time.Since(start); elapsed < 3*delay {
+		t.Errorf("session took %s, want at least %s of pacing", elapsed, 3*delay)
+	}
+}
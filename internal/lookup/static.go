@@ -5,19 +5,43 @@ import (
 	"unicode"
 )
 
-// Common disposable domains
+// Common disposable domains. The built-in list is a floor; LoadDisposableList
+// and RefreshDisposableList merge larger lists into it at runtime, so every
+// access goes through disposableMu.
 var disposableDomains = map[string]struct{}{
 	"temp-mail.org": {}, "10minutemail.com": {}, "guerrillamail.com": {},
 	"mailinator.com": {}, "yopmail.com": {}, "throwawaymail.com": {},
 	"tempmail.net": {}, "sharklasers.com": {}, "dispostable.com": {},
 }
 
-// MX servers that indicate the domain is inactive/parked
+// Consumer free-mail domains. Their MX hosts do not honour RCPT verification,
+// so SMTP probing tells us nothing about individual mailboxes.
+var freeMailDomains = map[string]struct{}{
+	"gmail.com": {}, "googlemail.com": {}, "outlook.com": {}, "hotmail.com": {},
+	"live.com": {}, "msn.com": {}, "yahoo.com": {}, "ymail.com": {},
+	"aol.com": {}, "icloud.com": {}, "me.com": {}, "mac.com": {},
+}
+
+// Consumer mailbox providers beyond freeMailDomains: personal accounts
+// anyone can sign up for, whether or not the provider honours RCPT probes.
+var freeProviderDomains = map[string]struct{}{
+	"yahoo.co.uk": {}, "yahoo.fr": {}, "yahoo.de": {}, "yahoo.co.in": {},
+	"yahoo.co.jp": {}, "hotmail.co.uk": {}, "hotmail.fr": {}, "live.co.uk": {},
+	"outlook.fr": {}, "outlook.de": {}, "aim.com": {},
+	"proton.me": {}, "protonmail.com": {}, "protonmail.ch": {}, "pm.me": {},
+	"gmx.com": {}, "gmx.net": {}, "gmx.de": {}, "web.de": {}, "mail.com": {},
+	"zoho.com": {}, "zohomail.com": {}, "tutanota.com": {}, "tuta.io": {},
+	"yandex.com": {}, "yandex.ru": {}, "mail.ru": {}, "inbox.ru": {},
+	"qq.com": {}, "163.com": {}, "126.com": {}, "naver.com": {},
+	"rediffmail.com": {}, "libero.it": {}, "seznam.cz": {}, "hey.com": {},
+}
+
+// MX servers that indicate the domain is inactive/parked. Registrars'
+// hosted-mail MX (GoDaddy's secureserver.net, for one) carry real mailboxes
+// and must not be listed here.
 var parkedMXHosts = []string{
-	"secureserver.net",  // GoDaddy Parking
-	"parking.reg.ru",    // Registrar Parking
-	"namecheap.com",     // Namecheap Parking
-	"domaincontrol.com", // GoDaddy
+	"parking.reg.ru", // Registrar Parking
+	"namecheap.com",  // Namecheap Parking
 }
 
 // Common role-based prefixes (Upgraded to Map for performance)
@@ -29,9 +53,19 @@ var roleAccounts = map[string]bool{
 	"hr": true,
 }
 
-// IsDisposableDomain checks if the domain is a known burner provider.
-func IsDisposableDomain(domain string) bool {
-	_, exists := disposableDomains[strings.ToLower(domain)]
+// IsFreeMailDomain checks if the domain is a consumer free-mail provider.
+func IsFreeMailDomain(domain string) bool {
+	_, exists := freeMailDomains[strings.ToLower(domain)]
+	return exists
+}
+
+// IsFreeProvider reports whether domain is a consumer mailbox provider, i.e.
+// the address is most likely a personal account rather than a business one.
+func IsFreeProvider(domain string) bool {
+	if IsFreeMailDomain(domain) {
+		return true
+	}
+	_, exists := freeProviderDomains[strings.ToLower(domain)]
 	return exists
 }
 
@@ -0,0 +1,21 @@
+package lookup
+
+import "testing"
+
+func TestIsFreeProvider(t *testing.T) {
+	cases := map[string]bool{
+		"gmail.com":      true,
+		"Outlook.com":    true,
+		"proton.me":      true,
+		"gmx.de":         true,
+		"icloud.com":     true,
+		"acme.com":       false,
+		"mail.acme.com":  false,
+		"protonmail.org": false,
+	}
+	for domain, want := range cases {
+		if got := IsFreeProvider(domain); got != want {
+			t.Errorf("IsFreeProvider(%q) = %v, want %v", domain, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package lookup
+
+import "strings"
+
+// suggestionDomains are the high-volume consumer and business domains that
+// SuggestDomain corrects typos towards.
+var suggestionDomains = []string{
+	"gmail.com", "googlemail.com", "yahoo.com", "ymail.com", "hotmail.com",
+	"outlook.com", "live.com", "msn.com", "aol.com", "icloud.com",
+	"protonmail.com", "proton.me", "gmx.com", "gmx.de", "web.de",
+	"yandex.com", "mail.ru", "zoho.com", "comcast.net", "verizon.net",
+	"att.net", "sbcglobal.net", "btinternet.com", "yahoo.co.uk",
+	"hotmail.co.uk", "orange.fr",
+}
+
+// SuggestDomain returns the well-known domain domain is most likely a typo
+// of, e.g. "gmial.com" → "gmail.com". It is deliberately conservative so a
+// legitimate rare domain is not "corrected": domains shorter than 9
+// characters may differ by one edit, longer ones by at most two, and a tie
+// between two candidates yields no suggestion. A domain that is itself in
+// the list is never corrected.
+func SuggestDomain(domain string) (string, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	if domain == "" {
+		return "", false
+	}
+	maxDist := 1
+	if len(domain) >= 9 {
+		maxDist = 2
+	}
+
+	best, bestDist, tied := "", maxDist+1, false
+	for _, candidate := range suggestionDomains {
+		d := levenshtein(domain, candidate)
+		if d == 0 {
+			return "", false
+		}
+		if d < bestDist {
+			best, bestDist, tied = candidate, d, false
+		} else if d == bestDist {
+			tied = true
+		}
+	}
+	if best == "" || tied {
+		return "", false
+	}
+	return best, true
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
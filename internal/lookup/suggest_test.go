@@ -0,0 +1,49 @@
+package lookup
+
+import "testing"
+
+func TestSuggestDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+		ok     bool
+	}{
+		{"gmial.com", "gmail.com", true},
+		{"hotnail.com", "hotmail.com", true},
+		{"yahooo.com", "yahoo.com", true},
+		{"GMAIL.CON", "gmail.com", true},
+		{"outlok.com", "outlook.com", true},
+
+		// Exact matches and unrelated domains are left alone.
+		{"gmail.com", "", false},
+		{"acme-corp.com", "", false},
+		// Short domains only tolerate a single edit.
+		{"mxl.ru", "", false},
+		// Equally close to gmail.com and ymail.com: ambiguous, no suggestion.
+		{"mail.com", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := SuggestDomain(tt.domain)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("SuggestDomain(%q) = %q, %v; want %q, %v", tt.domain, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"gmail.com", "gmail.com", 0},
+		{"gmial.com", "gmail.com", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
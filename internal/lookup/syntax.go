@@ -0,0 +1,147 @@
+package lookup
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidSyntax is wrapped by every error ValidateSyntax returns.
+var ErrInvalidSyntax = errors.New("invalid email syntax")
+
+// RFC 5321 §4.5.3.1 size limits, in octets.
+const (
+	MaxLocalPartLength = 64
+	MaxDomainLength    = 255
+	MaxAddressLength   = 254 // forward-path limit of 256 minus the angle brackets
+	MaxLabelLength     = 63
+)
+
+// ValidateSyntax checks email against the RFC 5322 addr-spec grammar as
+// restricted by RFC 5321 for use on the wire: a dot-atom or quoted-string
+// local part, a hostname domain, and the SMTP length limits. Non-ASCII
+// letters are accepted in both parts (RFC 6531/IDN) since the SMTP layer
+// negotiates SMTPUTF8 for them. Address literals such as user@[192.0.2.1]
+// are rejected: they have no MX to verify against. Comments and folding
+// whitespace are not supported; no real list entry uses them.
+func ValidateSyntax(email string) error {
+	if !utf8.ValidString(email) {
+		return fmt.Errorf("%w: not valid UTF-8", ErrInvalidSyntax)
+	}
+	if len(email) > MaxAddressLength {
+		return fmt.Errorf("%w: address longer than %d octets", ErrInvalidSyntax, MaxAddressLength)
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return fmt.Errorf("%w: missing @", ErrInvalidSyntax)
+	}
+	if err := validateLocalPart(email[:at]); err != nil {
+		return err
+	}
+	return validateDomainPart(email[at+1:])
+}
+
+func validateLocalPart(local string) error {
+	switch {
+	case local == "":
+		return fmt.Errorf("%w: empty local part", ErrInvalidSyntax)
+	case len(local) > MaxLocalPartLength:
+		return fmt.Errorf("%w: local part longer than %d octets", ErrInvalidSyntax, MaxLocalPartLength)
+	case strings.HasPrefix(local, `"`):
+		return validateQuotedLocal(local)
+	case strings.HasPrefix(local, ".") || strings.HasSuffix(local, "."):
+		return fmt.Errorf("%w: local part starts or ends with a dot", ErrInvalidSyntax)
+	case strings.Contains(local, ".."):
+		return fmt.Errorf("%w: consecutive dots in local part", ErrInvalidSyntax)
+	}
+	for _, r := range local {
+		if r != '.' && !isAtext(r) {
+			return fmt.Errorf("%w: invalid character %q in local part", ErrInvalidSyntax, r)
+		}
+	}
+	return nil
+}
+
+// validateQuotedLocal checks an RFC 5322 quoted-string: printable characters
+// and spaces between double quotes, with `"` and `\` only as quoted pairs.
+func validateQuotedLocal(local string) error {
+	if len(local) < 2 || !strings.HasSuffix(local, `"`) {
+		return fmt.Errorf("%w: unterminated quoted local part", ErrInvalidSyntax)
+	}
+	inner := local[1 : len(local)-1]
+	escaped := false
+	for _, r := range inner {
+		switch {
+		case isControl(r):
+			return fmt.Errorf("%w: control character in quoted local part", ErrInvalidSyntax)
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			return fmt.Errorf("%w: unescaped quote in quoted local part", ErrInvalidSyntax)
+		}
+	}
+	if escaped {
+		return fmt.Errorf("%w: dangling escape in quoted local part", ErrInvalidSyntax)
+	}
+	return nil
+}
+
+func validateDomainPart(domain string) error {
+	switch {
+	case domain == "":
+		return fmt.Errorf("%w: empty domain", ErrInvalidSyntax)
+	case len(domain) > MaxDomainLength:
+		return fmt.Errorf("%w: domain longer than %d octets", ErrInvalidSyntax, MaxDomainLength)
+	case strings.HasPrefix(domain, "["):
+		return fmt.Errorf("%w: address literals are not supported", ErrInvalidSyntax)
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("%w: domain has no top-level domain", ErrInvalidSyntax)
+	}
+	for _, label := range labels {
+		if label == "" {
+			return fmt.Errorf("%w: empty label in domain", ErrInvalidSyntax)
+		}
+		if len(label) > MaxLabelLength {
+			return fmt.Errorf("%w: domain label longer than %d octets", ErrInvalidSyntax, MaxLabelLength)
+		}
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("%w: domain label starts or ends with a hyphen", ErrInvalidSyntax)
+		}
+		for _, r := range label {
+			if r != '-' && !isLetterOrDigit(r) {
+				return fmt.Errorf("%w: invalid character %q in domain", ErrInvalidSyntax, r)
+			}
+		}
+	}
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return fmt.Errorf("%w: numeric top-level domain", ErrInvalidSyntax)
+	}
+	return nil
+}
+
+// isAtext reports whether r may appear in a dot-atom: RFC 5322 atext, plus
+// non-ASCII letters and digits per RFC 6531.
+func isAtext(r rune) bool {
+	if r < utf8.RuneSelf {
+		return isLetterOrDigit(r) || strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r)
+	}
+	return isLetterOrDigit(r)
+}
+
+func isLetterOrDigit(r rune) bool {
+	if r < utf8.RuneSelf {
+		return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f || unicode.IsControl(r)
+}
@@ -0,0 +1,62 @@
+package lookup
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateSyntax(t *testing.T) {
+	valid := []string{
+		"jane@example.com",
+		"jane.doe+news@example.co.uk",
+		"o'brien@example.ie",
+		"x@sub-domain.example.org",
+		`"john doe"@example.com`,
+		`"quoted\"quote"@example.com`,
+		"用户@例子.广告",
+		"müller@bücher.de",
+		strings.Repeat("a", 64) + "@example.com",
+	}
+	for _, email := range valid {
+		if err := ValidateSyntax(email); err != nil {
+			t.Errorf("ValidateSyntax(%q) = %v, want nil", email, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"plainaddress",
+		"jane@@example.com",
+		"jane@example@com",
+		"jane doe@example.com",
+		" jane@example.com",
+		"jane@example.com,",
+		"jane@example.com\n",
+		"ja\x00ne@example.com",
+		".jane@example.com",
+		"jane.@example.com",
+		"ja..ne@example.com",
+		"@example.com",
+		"jane@",
+		"jane@localhost",
+		"jane@example..com",
+		"jane@-example.com",
+		"jane@example-.com",
+		"jane@example.123",
+		"jane@[192.0.2.1]",
+		`"unterminated@example.com`,
+		`"bad"quote"@example.com`,
+		strings.Repeat("a", 65) + "@example.com",
+		"jane@" + strings.Repeat("a", 64) + ".com",
+		"jane@\xff.com",
+	}
+	for _, email := range invalid {
+		err := ValidateSyntax(email)
+		if err == nil {
+			t.Errorf("ValidateSyntax(%q) = nil, want an error", email)
+		} else if !errors.Is(err, ErrInvalidSyntax) {
+			t.Errorf("ValidateSyntax(%q) = %v, want it to wrap ErrInvalidSyntax", email, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package lookup
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"sync"
+
+	"mailvetter/internal/config"
+)
+
+// StartTLS upgrades probe sessions to TLS when the server advertises
+// STARTTLS, so the negotiated version can be recorded. A server whose
+// handshake fails is probed again in plaintext. Disable with
+// SMTP_STARTTLS=false.
+var StartTLS = config.Bool("SMTP_STARTTLS", true)
+
+// ErrSTARTTLSFailed reports a STARTTLS handshake that failed after the
+// server agreed to it. The connection is unusable; the probe should be
+// repeated in plaintext on a fresh one, as a sending MTA would.
+var ErrSTARTTLSFailed = errors.New("STARTTLS handshake failed")
+
+// SessionInfo collects what a probe learned about the server itself, as
+// opposed to the mailbox. Like Transcript it travels in the context, so the
+// probe signature stays the same for callers that do not need it.
+type SessionInfo struct {
+	mu         sync.Mutex
+	tlsVersion uint16
+	caps       SMTPCapabilities
+}
+
+type sessionInfoKey struct{}
+
+// WithSessionInfo returns a context whose SMTP sessions report into info.
+func WithSessionInfo(ctx context.Context, info *SessionInfo) context.Context {
+	return context.WithValue(ctx, sessionInfoKey{}, info)
+}
+
+// SessionInfoFrom returns the SessionInfo carried by ctx, or nil.
+func SessionInfoFrom(ctx context.Context) *SessionInfo {
+	info, _ := ctx.Value(sessionInfoKey{}).(*SessionInfo)
+	return info
+}
+
+// TLSVersion returns the version negotiated by the most recent session, or 0
+// if none upgraded to TLS. It is safe to call on a nil SessionInfo.
+func (s *SessionInfo) TLSVersion() uint16 {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tlsVersion
+}
+
+func (s *SessionInfo) setTLSVersion(v uint16) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.tlsVersion = v
+	s.mu.Unlock()
+}
+
+// Capabilities returns what the most recent session's server advertised in
+// reply to EHLO; the zero value if it was greeted with HELO. It is safe to
+// call on a nil SessionInfo.
+func (s *SessionInfo) Capabilities() SMTPCapabilities {
+	if s == nil {
+		return SMTPCapabilities{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.caps
+}
+
+func (s *SessionInfo) setCapabilities(c SMTPCapabilities) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.caps = c
+	s.mu.Unlock()
+}
+
+// startTLS issues STARTTLS on tp and performs the client handshake over conn.
+// ok is false if the server declined the command, in which case the session
+// continues in plaintext. A failed handshake leaves the connection unusable
+// and is returned as ErrSTARTTLSFailed.
+func startTLS(ctx context.Context, tp *textproto.Conn, conn net.Conn) (upgraded net.Conn, ok bool, err error) {
+	if _, err := tp.Cmd("STARTTLS"); err != nil {
+		return nil, false, err
+	}
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return nil, false, nil
+	}
+
+	// Recording happens on the plaintext side of the TLS connection so the
+	// transcript stays readable.
+	raw, t := conn, (*Transcript)(nil)
+	if tc, isRecorded := conn.(*transcriptConn); isRecorded {
+		raw, t = tc.Conn, tc.t
+	}
+
+	// We only want to learn which protocol version the server speaks, not to
+	// authenticate it: MX certificates routinely fail hostname checks, and no
+	// message content is sent over this session.
+	tlsConn := tls.Client(raw, &tls.Config{InsecureSkipVerify: true})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrSTARTTLSFailed, err)
+	}
+
+	version := tlsConn.ConnectionState().Version
+	SessionInfoFrom(ctx).setTLSVersion(version)
+	if t != nil {
+		t.Add("--- TLS negotiated: " + tls.VersionName(version))
+		return &transcriptConn{Conn: tlsConn, t: t}, true, nil
+	}
+	return tlsConn, true, nil
+}
@@ -0,0 +1,92 @@
+package lookup
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Transcript records the raw SMTP exchange of every session opened under a
+// context carrying it. It exists for diagnostics: production probes never
+// carry one, so the wrapping below costs nothing on the hot path.
+type Transcript struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+type transcriptKey struct{}
+
+// WithTranscript returns a context whose SMTP sessions are recorded into t.
+func WithTranscript(ctx context.Context, t *Transcript) context.Context {
+	return context.WithValue(ctx, transcriptKey{}, t)
+}
+
+// TranscriptFrom returns the transcript carried by ctx, or nil.
+func TranscriptFrom(ctx context.Context) *Transcript {
+	t, _ := ctx.Value(transcriptKey{}).(*Transcript)
+	return t
+}
+
+// Add appends one line. It is a no-op on a nil Transcript.
+func (t *Transcript) Add(line string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.lines = append(t.lines, line)
+	t.mu.Unlock()
+}
+
+// Lines returns a copy of the recorded lines.
+func (t *Transcript) Lines() []string {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.lines...)
+}
+
+// wrap returns conn with its traffic recorded line by line: "C:" for what we
+// send, "S:" for what the server replies. A nil Transcript returns conn as-is.
+func (t *Transcript) wrap(conn net.Conn, mxHost string) net.Conn {
+	if t == nil {
+		return conn
+	}
+	t.Add("--- connected to " + mxHost + ":25")
+	return &transcriptConn{Conn: conn, t: t}
+}
+
+type transcriptConn struct {
+	net.Conn
+	t          *Transcript
+	rbuf, wbuf []byte
+}
+
+func (c *transcriptConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.rbuf = c.t.consume(c.rbuf, p[:n], "S: ")
+	return n, err
+}
+
+func (c *transcriptConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.wbuf = c.t.consume(c.wbuf, p[:n], "C: ")
+	return n, err
+}
+
+// consume appends data to buf, records every complete line with prefix, and
+// returns the unfinished remainder.
+func (t *Transcript) consume(buf, data []byte, prefix string) []byte {
+	buf = append(buf, data...)
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			return buf
+		}
+		t.Add(prefix + strings.TrimRight(string(buf[:i]), "\r"))
+		buf = buf[i+1:]
+	}
+}
@@ -0,0 +1,46 @@
+package lookup
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestTranscriptRecordsSMTPExchange(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	tr := &Transcript{}
+	conn := TranscriptFrom(WithTranscript(context.Background(), tr)).wrap(client, "mx.example.com")
+	defer conn.Close()
+
+	go func() {
+		r := bufio.NewReader(server)
+		server.Write([]byte("220 mx.example.com ESMTP\r\n"))
+		r.ReadString('\n')
+		// Reply in two writes to exercise partial-line buffering.
+		server.Write([]byte("250 mx.exa"))
+		server.Write([]byte("mple.com\r\n"))
+	}()
+
+	r := bufio.NewReader(conn)
+	r.ReadString('\n')
+	conn.Write([]byte("HELO mta1.mailvetter.com\r\n"))
+	r.ReadString('\n')
+
+	want := []string{
+		"--- connected to mx.example.com:25",
+		"S: 220 mx.example.com ESMTP",
+		"C: HELO mta1.mailvetter.com",
+		"S: 250 mx.example.com",
+	}
+	if got := tr.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("transcript = %q, want %q", got, want)
+	}
+
+	if got := TranscriptFrom(context.Background()).wrap(client, "x"); got != client {
+		t.Errorf("a context without a transcript must leave the conn unwrapped")
+	}
+}
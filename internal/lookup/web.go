@@ -0,0 +1,66 @@
+package lookup
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// webPresenceTimeout bounds each site check; a slow site is not worth
+// holding up the infra collector for.
+const webPresenceTimeout = 5 * time.Second
+
+// webPresenceURLs returns the URLs CheckWebPresence tries for domain. It is a
+// variable so tests can point it at a local server.
+var webPresenceURLs = func(domain string) []string {
+	return []string{"https://" + domain, "https://www." + domain}
+}
+
+// parkingHosts are domain-parking and for-sale landing services. A site that
+// redirects to one of them is a parked domain, not a live one.
+var parkingHosts = []string{
+	"sedoparking.com", "parkingcrew.net", "bodis.com", "dan.com",
+	"afternic.com", "hugedomains.com", "parklogic.com", "above.com",
+}
+
+// CheckWebPresence reports whether domain serves a live website on its apex
+// or www host. Any answer short of a server error counts — a 403 from a WAF
+// is still a site — except 404/410 and redirects onto a parking service.
+func CheckWebPresence(ctx context.Context, domain string, pURL *url.URL) bool {
+	for _, target := range webPresenceURLs(domain) {
+		if siteIsLive(ctx, target, pURL) {
+			return true
+		}
+	}
+	return false
+}
+
+func siteIsLive(ctx context.Context, target string, pURL *url.URL) bool {
+	ctx, cancel := context.WithTimeout(ctx, webPresenceTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", target, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("User-Agent", getRandomUserAgent())
+
+	resp, err := DoProxiedRequest(req, pURL)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == 404 || resp.StatusCode == 410 {
+		return false
+	}
+	host := strings.ToLower(resp.Request.URL.Hostname())
+	for _, parked := range parkingHosts {
+		if host == parked || strings.HasSuffix(host, "."+parked) {
+			return false
+		}
+	}
+	return true
+}
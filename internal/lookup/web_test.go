@@ -0,0 +1,66 @@
+package lookup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckWebPresence(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    bool
+	}{
+		{"live site", func(w http.ResponseWriter, r *http.Request) {}, true},
+		{"WAF challenge is still a site", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusForbidden) }, true},
+		{"not found", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) }, false},
+		{"server error", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			saved := webPresenceURLs
+			defer func() { webPresenceURLs = saved }()
+			webPresenceURLs = func(string) []string { return []string{srv.URL} }
+
+			if got := CheckWebPresence(context.Background(), "example.com", nil); got != tt.want {
+				t.Errorf("CheckWebPresence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckWebPresenceNoSite(t *testing.T) {
+	saved := webPresenceURLs
+	defer func() { webPresenceURLs = saved }()
+
+	// A closed port on both hosts: nothing answers.
+	srv := httptest.NewServer(http.NotFoundHandler())
+	addr := srv.URL
+	srv.Close()
+	webPresenceURLs = func(string) []string { return []string{addr, addr} }
+
+	if CheckWebPresence(context.Background(), "example.com", nil) {
+		t.Errorf("expected no web presence when neither host answers")
+	}
+}
+
+func TestCheckWebPresenceFallsBackToWWW(t *testing.T) {
+	saved := webPresenceURLs
+	defer func() { webPresenceURLs = saved }()
+
+	apex := httptest.NewServer(http.NotFoundHandler())
+	defer apex.Close()
+	www := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer www.Close()
+	webPresenceURLs = func(string) []string { return []string{apex.URL, www.URL} }
+
+	if !CheckWebPresence(context.Background(), "example.com", nil) {
+		t.Errorf("expected the www host to count as web presence")
+	}
+}
@@ -0,0 +1,219 @@
+// Package metrics exposes process metrics in the Prometheus text format. It
+// implements only the counters and histograms the engine needs, so the
+// binaries do not pull in the full Prometheus client.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The engine's metrics. Label values are kept to small fixed sets so series
+// cardinality stays bounded.
+var (
+	// VerificationsTotal counts finished verifications by final status.
+	VerificationsTotal = NewCounter("mailvetter_verifications_total",
+		"Verifications completed, by final status.", "status")
+
+	// SMTPProbeDuration times each RCPT TO probe session, by outcome
+	// ("accepted", "rejected" or "error").
+	SMTPProbeDuration = NewHistogram("mailvetter_smtp_probe_duration_seconds",
+		"Duration of one SMTP RCPT probe session.", DefaultBuckets, "outcome")
+
+	// ProxySlotWait times how long proxied HTTP requests wait for a proxy
+	// concurrency slot.
+	ProxySlotWait = NewHistogram("mailvetter_proxy_slot_wait_seconds",
+		"Time spent waiting for a proxy concurrency slot.", DefaultBuckets)
+
+	// CacheRequests counts cache lookups by result ("hit" or "miss").
+	CacheRequests = NewCounter("mailvetter_cache_requests_total",
+		"Domain cache lookups, by result.", "result")
+
+	// HIBPRateLimited counts 429 responses from the HIBP API.
+	HIBPRateLimited = NewCounter("mailvetter_hibp_rate_limited_total",
+		"HIBP API responses with status 429.")
+
+	// OSINTEgressSwitches counts OSINT egress mode switches, by the mode
+	// switched to ("direct" when the proxies are blocked, "proxy" after).
+	OSINTEgressSwitches = NewCounter("mailvetter_osint_egress_switches_total",
+		"OSINT egress mode switches, by new mode.", "mode")
+)
+
+// DefaultBuckets are latency buckets, in seconds, spanning a cache-warm HTTP
+// probe to a tarpitting SMTP server.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60}
+
+// collector is a metric family that can write itself out.
+type collector interface {
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// WriteTo writes every registered metric to w in the Prometheus text format.
+func WriteTo(w io.Writer) {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler serves the registered metrics for a Prometheus scrape.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	WriteTo(w)
+}
+
+// Counter is a monotonically increasing value per label-value set.
+type Counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter creates and registers a counter with the given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: map[string]float64{}}
+	register(c)
+	return c
+}
+
+// Inc adds 1 to the series for labelValues.
+func (c *Counter) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Add adds v, which must not be negative, to the series for labelValues.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	key := labelPairs(c.labels, labelValues)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Value returns the current value of the series for labelValues.
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := labelPairs(c.labels, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *Counter) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %g\n", c.name, braced(key), c.values[key])
+	}
+}
+
+// Histogram counts observations into cumulative buckets per label-value set.
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, non-cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates and registers a histogram with the given upper bucket
+// bounds, which must be sorted ascending, and label names.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogramSeries{}}
+	register(h)
+	return h
+}
+
+// Observe records v in the series for labelValues.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := labelPairs(h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, braced(joinPairs(key, fmt.Sprintf("le=%q", fmt.Sprint(le)))), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, braced(joinPairs(key, `le="+Inf"`)), s.count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", h.name, braced(key), s.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, braced(key), s.count)
+	}
+}
+
+// labelPairs renders name="value" pairs; missing values are empty strings.
+func labelPairs(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		pairs[i] = fmt.Sprintf("%s=%q", name, v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func joinPairs(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func braced(pairs string) string {
+	if pairs == "" {
+		return ""
+	}
+	return "{" + pairs + "}"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCounterExposition(t *testing.T) {
+	c := &Counter{name: "test_total", help: "A test counter.", labels: []string{"status"}, values: map[string]float64{}}
+	c.Inc("valid")
+	c.Inc("valid")
+	c.Add(3, "invalid")
+
+	var buf bytes.Buffer
+	c.write(&buf)
+	want := `# HELP test_total A test counter.
+# TYPE test_total counter
+test_total{status="invalid"} 3
+test_total{status="valid"} 2
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if c.Value("valid") != 2 {
+		t.Errorf("Value(valid) = %v", c.Value("valid"))
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	h := &Histogram{name: "probe_seconds", help: "A test histogram.", buckets: []float64{0.5, 1}, series: map[string]*histogramSeries{}}
+	h.Observe(0.2)
+	h.Observe(0.5)
+	h.Observe(0.8)
+	h.Observe(3)
+
+	var buf bytes.Buffer
+	h.write(&buf)
+	for _, line := range []string{
+		`probe_seconds_bucket{le="0.5"} 2`,
+		`probe_seconds_bucket{le="1"} 3`,
+		`probe_seconds_bucket{le="+Inf"} 4`,
+		`probe_seconds_sum 4.5`,
+		`probe_seconds_count 4`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestRegistryIncludesEngineMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	WriteTo(&buf)
+	for _, name := range []string{
+		"mailvetter_verifications_total", "mailvetter_smtp_probe_duration_seconds",
+		"mailvetter_proxy_slot_wait_seconds", "mailvetter_cache_requests_total",
+		"mailvetter_hibp_rate_limited_total",
+	} {
+		if !strings.Contains(buf.String(), "# TYPE "+name+" ") {
+			t.Errorf("%s not registered", name)
+		}
+	}
+}
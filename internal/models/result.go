@@ -3,6 +3,13 @@ package models
 type VerificationStatus string
 type Reachability string
 
+// Recommendation is the single sending decision a verdict maps to.
+type Recommendation string
+
+// UndeliverableCause says whether an address fails at the domain (fix the
+// domain, often a typo) or at the mailbox (fix the local part).
+type UndeliverableCause string
+
 const (
 	StatusValid    VerificationStatus = "valid"
 	StatusInvalid  VerificationStatus = "invalid"
@@ -14,14 +21,38 @@ const (
 	ReachabilityRisky   Reachability = "risky"
 	ReachabilityBad     Reachability = "bad"
 	ReachabilityUnknown Reachability = "unknown"
+
+	RecommendSend            Recommendation = "send"
+	RecommendSendWithCaution Recommendation = "send_with_caution"
+	RecommendDoNotSend       Recommendation = "do_not_send"
+	RecommendVerifyLater     Recommendation = "verify_later"
+
+	// CauseDomainNoMX: the domain has no MX records or does not exist.
+	CauseDomainNoMX UndeliverableCause = "domain_no_mx"
+	// CauseDomainParked: the domain's mail goes to a parking service.
+	CauseDomainParked UndeliverableCause = "domain_parked"
+	// CauseMailboxNotFound: the domain accepts mail but rejected this
+	// mailbox outright.
+	CauseMailboxNotFound UndeliverableCause = "mailbox_not_found"
 )
 
 type RiskAnalysis struct {
 	// P0: Critical
-	SmtpStatus        int  `json:"smtp_status"`
-	HasTeamsPresence  bool `json:"has_teams_presence"`
-	HasGoogleCalendar bool `json:"has_google_calendar"`
-	HasSharePoint     bool `json:"has_sharepoint"`
+	SmtpStatus        int    `json:"smtp_status"`
+	SmtpMessage       string `json:"smtp_message,omitempty"`
+	SmtpSkipped       bool   `json:"smtp_skipped,omitempty"`
+	SmtpHost          string `json:"smtp_host,omitempty"`
+	HasTeamsPresence  bool   `json:"has_teams_presence"`
+	HasGoogleCalendar bool   `json:"has_google_calendar"`
+	HasSharePoint     bool   `json:"has_sharepoint"`
+
+	// SMTP server capabilities, advertised in reply to EHLO and kept for
+	// diagnostics. SmtpAuthRequired marks a server that demanded
+	// authentication before RCPT TO; it never answers an anonymous probe.
+	SmtpExtensions     []string `json:"smtp_extensions,omitempty"`
+	SmtpMaxSize        int64    `json:"smtp_max_size,omitempty"`
+	SmtpAuthMechanisms []string `json:"smtp_auth_mechanisms,omitempty"`
+	SmtpAuthRequired   bool     `json:"smtp_auth_required,omitempty"`
 
 	// Golden Tickets
 	HasVRFY bool `json:"has_vrfy"`
@@ -29,39 +60,99 @@ type RiskAnalysis struct {
 	// P1: High Value
 	IsCatchAll    bool   `json:"is_catch_all"`
 	MxProvider    string `json:"mx_provider"`
+	IsParked      bool   `json:"is_parked"`
 	HasSaaSTokens bool   `json:"has_saas_tokens"`
+	// CatchAllLowConfidence marks a catch-all verdict resting on a ghost
+	// probe answered exactly like the target — which is also what a ghost
+	// that happens to hit a real mailbox looks like.
+	CatchAllLowConfidence bool `json:"catch_all_low_confidence,omitempty"`
+	// MxCount is the number of MX records; MxProviders the distinct
+	// providers behind them, in MX order. More than one provider means a
+	// deliberately redundant setup, e.g. a Google primary with a Mimecast
+	// backup.
+	MxCount     int      `json:"mx_count"`
+	MxProviders []string `json:"mx_providers,omitempty"`
 
 	// Extended Socials
 	HasAdobe bool `json:"has_adobe"`
+	HasSlack bool `json:"has_slack"`
 
 	// Social & History
 	HasGitHub   bool `json:"has_github"`
 	HasGravatar bool `json:"has_gravatar"`
+	HasLinkedIn bool `json:"has_linkedin"`
+	HasTwitter  bool `json:"has_twitter"`
+	HasSpotify  bool `json:"has_spotify"`
 	BreachCount int  `json:"breach_count"`
 
 	// Syntax / Hygiene
 	IsRoleAccount      bool    `json:"is_role_account"`
 	EntropyScore       float64 `json:"entropy_score"`
 	IsPostmasterBroken bool    `json:"is_postmaster_broken"`
+	// IsFreeProvider flags a personal account at a consumer provider
+	// (gmail.com, proton.me, …). Informational only; it does not affect the
+	// score.
+	IsFreeProvider bool `json:"is_free_provider"`
+	// IsMixedScriptDomain flags a homograph lookalike domain, one whose
+	// labels mix scripts (e.g. Latin with a Cyrillic "а").
+	IsMixedScriptDomain bool `json:"is_mixed_script_domain"`
 
 	// P2: Medium
 	TimingDeltaMs int64 `json:"timing_delta_ms"`
 	HasDMARC      bool  `json:"has_dmarc"`
 	HasSPF        bool  `json:"has_spf"`
+	SPFOverLimit  bool  `json:"spf_over_limit"`
 	IsGreylisted  bool  `json:"is_greylisted"`
 
 	// P3: Low
-	DomainAgeDays int  `json:"domain_age_days"`
-	HasTLS13      bool `json:"has_tls13"`
+	DomainAgeDays int    `json:"domain_age_days"`
+	Registrar     string `json:"registrar,omitempty"`
+	TLD           string `json:"tld,omitempty"`
+	HasTLS13      bool   `json:"has_tls13"`
+	HasWebsite    bool   `json:"has_website"`
+
+	// HasSlackWorkspace marks a Slack workspace named after the domain: a
+	// sign of an active business, though it says nothing about the mailbox.
+	HasSlackWorkspace bool `json:"has_slack_workspace"`
+
+	// OsintTimeouts names the OSINT probes cut off by their own timeout.
+	// Their signals read as absent, which may not be true.
+	OsintTimeouts []string `json:"osint_timeouts,omitempty"`
 }
 
 type ValidationResult struct {
-	Email          string             `json:"email"`
-	Score          int                `json:"score"`
-	ScoreBreakdown map[string]float64 `json:"score_details"`
-	Status         VerificationStatus `json:"status"`
-	Reachability   Reachability       `json:"reachability"`
-	Analysis       RiskAnalysis       `json:"analysis"`
-	Duration       string             `json:"duration"`
-	Error          string             `json:"error,omitempty"`
+	Email string `json:"email"`
+	// NormalizedEmail is the canonical address that was actually probed,
+	// e.g. johndoe@gmail.com for john.doe+news@gmail.com.
+	NormalizedEmail string             `json:"normalized_email,omitempty"`
+	Score           int                `json:"score"`
+	ScoreBreakdown  map[string]float64 `json:"score_details"`
+	Explanation     []string           `json:"explanation,omitempty"`
+	Status          VerificationStatus `json:"status"`
+	// NuancedStatus keeps the scored status when the invalid_below scoring
+	// threshold is on; Status then reads invalid for any score under it.
+	NuancedStatus VerificationStatus `json:"nuanced_status,omitempty"`
+	Reachability  Reachability       `json:"reachability"`
+	// Recommendation condenses Status and Reachability into the sending
+	// decision; see validator.Recommend.
+	Recommendation Recommendation `json:"recommendation,omitempty"`
+	ConfirmedBy    string         `json:"confirmed_by,omitempty"`
+	Reason         string         `json:"reason,omitempty"`
+	// Undeliverable says whether the domain or the mailbox is what cannot
+	// receive mail; empty when neither was shown.
+	Undeliverable UndeliverableCause `json:"undeliverable,omitempty"`
+	// DomainUnicode and DomainASCII are set for internationalized domains:
+	// the domain as given, and the punycode form DNS and SMTP were run on.
+	DomainUnicode string `json:"domain_unicode,omitempty"`
+	DomainASCII   string `json:"domain_ascii,omitempty"`
+	// SuggestedEmail is the address with a likely-typo domain corrected
+	// (gmial.com → gmail.com), set only when the domain has no MX.
+	SuggestedEmail string       `json:"suggested_email,omitempty"`
+	Analysis       RiskAnalysis `json:"analysis"`
+	Duration       string       `json:"duration"`
+	Error          string       `json:"error,omitempty"`
+
+	// Cached marks a result served from the result cache rather than
+	// verified now; it is not a new observation for history or calibration.
+	Cached bool `json:"cached,omitempty"`
 }
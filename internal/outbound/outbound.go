@@ -0,0 +1,112 @@
+// Package outbound guards requests the service makes to URLs its API clients
+// supply (export destinations, completion webhooks), so a client cannot point
+// them at the service's own network.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"mailvetter/internal/config"
+)
+
+// AllowPrivate lets client-supplied URLs reach loopback, private and
+// link-local addresses, for deployments whose object store or webhook
+// receiver lives on the internal network. Set via OUTBOUND_ALLOW_PRIVATE.
+var AllowPrivate = config.Bool("OUTBOUND_ALLOW_PRIVATE", false)
+
+// ErrPrivateAddress is returned for a destination on a non-public address.
+var ErrPrivateAddress = errors.New("destination is not a public address")
+
+// ValidateURL checks a client-supplied URL before it is stored. It must be
+// https with a host; when allow is non-empty the host must be one of its
+// entries or a subdomain of one. A literal IP must be public unless
+// AllowPrivate is set. Names are checked again when dialled, see Client.
+func ValidateURL(raw string, allow []string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("scheme %q not allowed, use https", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return errors.New("missing host")
+	}
+	if len(allow) > 0 && !hostAllowed(host, allow) {
+		return fmt.Errorf("host %q is not in the allowlist", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) && !AllowPrivate {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+func hostAllowed(host string, allow []string) bool {
+	for _, a := range allow {
+		a = strings.ToLower(strings.TrimPrefix(a, "."))
+		if host == a || strings.HasSuffix(host, "."+a) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicIP reports whether ip is routable on the public internet.
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast())
+}
+
+// Client returns an HTTP client whose connections are refused when the
+// destination resolves to a non-public address, closing the gap a DNS name
+// leaves between ValidateURL and the request. Redirects are not followed.
+func Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if AllowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Location is u without its query and fragment: where an object lives,
+// without the presigned credentials a PUT URL carries.
+func Location(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u.RawQuery, u.Fragment, u.User = "", "", nil
+	return u.String()
+}
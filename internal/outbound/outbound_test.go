@@ -0,0 +1,69 @@
+package outbound
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateURL(t *testing.T) {
+	saved := AllowPrivate
+	defer func() { AllowPrivate = saved }()
+	AllowPrivate = false
+
+	tests := []struct {
+		raw   string
+		allow []string
+		ok    bool
+	}{
+		{"https://bucket.s3.amazonaws.com/job.ndjson?X-Amz-Signature=abc", nil, true},
+		{"http://bucket.s3.amazonaws.com/job.ndjson", nil, false},
+		{"file:///etc/passwd", nil, false},
+		{"https:///nohost", nil, false},
+		{"https://127.0.0.1/x", nil, false},
+		{"https://169.254.169.254/latest/meta-data", nil, false},
+		{"https://[::1]/x", nil, false},
+		{"https://10.0.0.5/x", nil, false},
+		{"https://bucket.s3.amazonaws.com/x", []string{"amazonaws.com"}, true},
+		{"https://amazonaws.com.evil.example/x", []string{"amazonaws.com"}, false},
+		{"https://storage.googleapis.com/x", []string{"amazonaws.com"}, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateURL(tt.raw, tt.allow); (err == nil) != tt.ok {
+			t.Errorf("ValidateURL(%q, %v) = %v, want ok=%v", tt.raw, tt.allow, err, tt.ok)
+		}
+	}
+
+	AllowPrivate = true
+	if err := ValidateURL("https://10.0.0.5/x", nil); err != nil {
+		t.Errorf("OUTBOUND_ALLOW_PRIVATE should admit private addresses: %v", err)
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	saved := AllowPrivate
+	defer func() { AllowPrivate = saved }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	AllowPrivate = false
+	if _, err := Client(0).Get(srv.URL); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("request to loopback: got %v, want ErrPrivateAddress", err)
+	}
+
+	AllowPrivate = true
+	resp, err := Client(0).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with OUTBOUND_ALLOW_PRIVATE: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestLocation(t *testing.T) {
+	got := Location("https://user:pw@bucket.s3.amazonaws.com/jobs/1.ndjson?X-Amz-Signature=abc#frag")
+	if want := "https://bucket.s3.amazonaws.com/jobs/1.ndjson"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+}
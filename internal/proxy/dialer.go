@@ -6,9 +6,12 @@ import (
 	"log"
 	"net"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
+	"mailvetter/internal/config"
+
 	netproxy "golang.org/x/net/proxy"
 )
 
@@ -26,21 +29,96 @@ func (pc *proxyConn) Close() error {
 	return pc.Conn.Close()
 }
 
+// DialAttempts is how many different proxies DialContext tries before giving
+// up on the proxy pool, so one flaky exit does not cost a whole probe.
+// Configured by PROXY_DIAL_ATTEMPTS.
+var DialAttempts = config.Int("PROXY_DIAL_ATTEMPTS", 3)
+
+// DirectFallback lets DialContext connect directly once every proxy it tried
+// has failed. Off by default: a probe that silently leaves from the host's
+// own IP no longer shares its egress with the pinned proxy, which catch-all
+// detection relies on. Configured by SMTP_PROXY_DIRECT_FALLBACK.
+var DirectFallback = config.Bool("SMTP_PROXY_DIRECT_FALLBACK", false)
+
+// targetReplies are the SOCKS5 CONNECT replies that report on the target
+// rather than the proxy: the proxy worked, and another one would get the
+// same answer.
+var targetReplies = []string{"connection refused", "host unreachable", "network unreachable", "TTL expired"}
+
+// isTargetError reports whether err is the proxy relaying a failure to reach
+// the target, as opposed to the proxy itself being unreachable or failing the
+// handshake.
+func isTargetError(err error) bool {
+	msg := err.Error()
+	for _, reply := range targetReplies {
+		// x/net/proxy reports a CONNECT reply as "unknown error <reply>".
+		if strings.HasSuffix(msg, "unknown error "+reply) {
+			return true
+		}
+	}
+	return false
+}
+
+// DialContext connects to addr through pURL. If the proxy itself fails —
+// unreachable, or the SOCKS handshake fails — up to DialAttempts proxies in
+// all are tried from the rotation, and then, if DirectFallback allows, a
+// direct connection. A failure the proxy reports about the target is
+// returned as is.
 func DialContext(ctx context.Context, network, addr string, timeout time.Duration, pURL *url.URL) (net.Conn, error) {
 	directDialer := &net.Dialer{Timeout: timeout}
 
-	if !Enabled() || pURL == nil {
+	if !Enabled() || pURL == nil || bypassAddr(addr) {
+		return directDialer.DialContext(ctx, network, addr)
+	}
+
+	attempts := DialAttempts
+	if n := len(Global.proxies); attempts > n {
+		attempts = n
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			next := Global.Next()
+			if next == nil || next.String() == pURL.String() {
+				break
+			}
+			log.Printf("[DEBUG-PROXY] Retrying %s via next proxy %s (attempt %d/%d)", addr, next.Host, attempt, attempts)
+			pURL = next
+		}
+
+		var conn net.Conn
+		conn, err = dialVia(ctx, network, addr, directDialer, pURL)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil || isTargetError(err) {
+			return nil, err
+		}
+	}
+
+	if DirectFallback {
+		log.Printf("[DEBUG-PROXY] All proxies failed for %s, dialing direct. Last error: %v", addr, err)
 		return directDialer.DialContext(ctx, network, addr)
 	}
+	return nil, err
+}
 
+// dialVia makes one connection attempt through pURL. It holds a Semaphore
+// slot for the life of the returned connection and releases it on failure.
+func dialVia(ctx context.Context, network, addr string, directDialer *net.Dialer, pURL *url.URL) (net.Conn, error) {
 	select {
 	case Semaphore <- struct{}{}:
 	case <-ctx.Done():
 		return nil, fmt.Errorf("timeout waiting for proxy slot: %w", ctx.Err())
 	}
 
+	// socks5h asks the proxy to resolve the target, so leave it a name.
 	host, port, err := net.SplitHostPort(addr)
-	if err == nil {
+	if err == nil && pURL.Scheme != "socks5h" {
 		if net.ParseIP(host) == nil {
 			ips, lookupErr := net.LookupIP(host)
 			if lookupErr == nil && len(ips) > 0 {
@@ -84,3 +162,12 @@ func DialContext(ctx context.Context, network, addr string, timeout time.Duratio
 
 	return &proxyConn{Conn: conn}, nil
 }
+
+// bypassAddr applies Bypass to the host part of a host:port address.
+func bypassAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return Bypass(host)
+}
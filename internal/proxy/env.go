@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ListFromEnv returns the proxy list to pass to Init. PROXY_LIST
+// (comma-separated) wins; without it the conventional HTTPS_PROXY,
+// HTTP_PROXY and ALL_PROXY variables (either case) are used, so a standard
+// single-proxy setup needs no extra configuration.
+func ListFromEnv() []string {
+	if list := splitList(os.Getenv("PROXY_LIST")); len(list) > 0 {
+		return list
+	}
+
+	var list []string
+	seen := make(map[string]bool)
+	for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY", "ALL_PROXY"} {
+		p := getenvAnyCase(name)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		list = append(list, p)
+	}
+	return list
+}
+
+// getenvAnyCase reads name, falling back to its lowercase form as curl and
+// the Go standard library do.
+func getenvAnyCase(name string) string {
+	if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+		return v
+	}
+	return strings.TrimSpace(os.Getenv(strings.ToLower(name)))
+}
+
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+var (
+	noProxyOnce sync.Once
+	noProxy     []string
+)
+
+// Bypass reports whether host should be reached directly, per NO_PROXY (or
+// no_proxy). Entries are "*" for every host, a domain matching itself and
+// its subdomains (a leading dot is optional), an IP address, or a CIDR
+// range. Ports in entries are ignored.
+func Bypass(host string) bool {
+	noProxyOnce.Do(func() { noProxy = splitList(getenvAnyCase("NO_PROXY")) })
+	return matchNoProxy(noProxy, host)
+}
+
+func matchNoProxy(entries []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+
+	for _, e := range entries {
+		e = strings.ToLower(e)
+		if e == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(e); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, _, err := net.SplitHostPort(e); err == nil {
+			e = h
+		}
+		if eip := net.ParseIP(e); eip != nil {
+			if ip != nil && eip.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		e = strings.TrimPrefix(e, ".")
+		if e != "" && (host == e || strings.HasSuffix(host, "."+e)) {
+			return true
+		}
+	}
+	return false
+}
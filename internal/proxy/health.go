@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"mailvetter/internal/config"
+)
+
+// HealthCheckInterval is how often StartHealthChecks probes every proxy.
+// Configured by PROXY_HEALTH_INTERVAL.
+var HealthCheckInterval = config.Duration("PROXY_HEALTH_INTERVAL", 1*time.Minute)
+
+// HealthCheckTimeout bounds a single proxy probe.
+const HealthCheckTimeout = 5 * time.Second
+
+// checkProxy probes one proxy. The default opens and closes a TCP connection
+// to it, which catches the common failure (a dead or unreachable proxy)
+// without spending a request through it. It is a variable so tests can fail
+// chosen proxies without a network.
+var checkProxy = func(ctx context.Context, u *url.URL) error {
+	d := net.Dialer{Timeout: HealthCheckTimeout}
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr(u))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// proxyAddr is u's host:port, filling in the scheme's default port.
+func proxyAddr(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	switch u.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// CheckAll probes every proxy concurrently and records each result through
+// ReportSuccess / ReportFailure, so failing proxies leave rotation and
+// recovered ones rejoin it.
+func (m *Manager) CheckAll(ctx context.Context) {
+	if m == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, u := range m.proxies {
+		wg.Add(1)
+		go func(u *url.URL) {
+			defer wg.Done()
+			if err := checkProxy(ctx, u); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if m.reportFailure(u, err) {
+					log.Printf("[proxy] ⚠️  %s failed health check, out of rotation: %v", u.Redacted(), err)
+				}
+				return
+			}
+			m.ReportSuccess(u)
+		}(u)
+	}
+	wg.Wait()
+}
+
+// StartHealthChecks runs CheckAll on Global immediately and then on the given
+// interval until ctx is cancelled. It does nothing if no proxies are loaded.
+func StartHealthChecks(ctx context.Context, interval time.Duration) {
+	if !Enabled() {
+		return
+	}
+	m := Global
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			m.CheckAll(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Status is one proxy's entry in the pool report.
+type Status struct {
+	// Proxy is the entry as configured, with any password redacted.
+	Proxy   string `json:"proxy"`
+	Trusted bool   `json:"trusted"`
+	Healthy bool   `json:"healthy"`
+	// ConsecutiveFailures counts failed checks since the last pass.
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Successes           uint64     `json:"successes"`
+	Failures            uint64     `json:"failures"`
+	LastChecked         *time.Time `json:"last_checked,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// Statuses reports the health of every proxy, in rotation order.
+func (m *Manager) Statuses() []Status {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Status, len(m.proxies))
+	for i, st := range m.state {
+		s := Status{
+			Proxy:               redact(st.raw),
+			Trusted:             st.trusted,
+			Healthy:             !st.ejected,
+			ConsecutiveFailures: st.failures,
+			Successes:           st.checksOK,
+			Failures:            st.checksFailed,
+			LastError:           st.lastError,
+		}
+		if !st.lastChecked.IsZero() {
+			checked := st.lastChecked
+			s.LastChecked = &checked
+		}
+		out[i] = s
+	}
+	return out
+}
+
+// redact hides the password in a configured proxy entry.
+func redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "<unparseable>"
+	}
+	return u.Redacted()
+}
@@ -4,21 +4,61 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// Consecutive health-check failures after which a proxy is ejected from
+// rotation. Trusted proxies are given more room so a single blip on a known
+// stable (usually premium) proxy does not flap it out of rotation.
+const (
+	EjectAfterFailures        = 1
+	TrustedEjectAfterFailures = 5
+)
+
+// proxyState is the per-proxy metadata kept alongside the rotation list.
+type proxyState struct {
+	raw      string // entry as configured in PROXY_LIST, before pre-resolution
+	trusted  bool
+	failures int // consecutive; reset by a passing check
+	ejected  bool
+
+	// Lifetime health-check totals, for the /proxies report.
+	checksOK     uint64
+	checksFailed uint64
+	lastChecked  time.Time
+	lastError    string
+}
+
 type Manager struct {
 	proxies []*url.URL
 	counter uint64
+
+	mu    sync.Mutex
+	state []proxyState // parallel to proxies
 }
 
 var Global *Manager
 var Semaphore chan struct{}
 var SMTPEnabled bool
 
-// Init loads the proxies and sets the dynamic concurrency limit
+// Init loads the proxies and sets the dynamic concurrency limit.
+//
+// Supported schemes:
+//
+//	http://, https://     HTTP probes only (OSINT, RDAP, web checks)
+//	socks5://, socks5h:// HTTP probes and SMTP; socks5h resolves the
+//	                      target on the proxy rather than locally
+//
+// Credentials go in the URL as user:pass@host:port and are sent as HTTP
+// Basic proxy auth or SOCKS5 username/password auth (RFC 1929). Characters
+// such as '@' or ':' in a password must be percent-encoded. Pre-resolving
+// the proxy host below only rewrites u.Host, so u.User is kept.
 func Init(proxyList []string, limit int, enableSMTP bool) error {
 	var parsed []*url.URL
+	var state []proxyState
 
 	for _, p := range proxyList {
 		if p == "" {
@@ -57,6 +97,7 @@ func Init(proxyList []string, limit int, enableSMTP bool) error {
 		}
 
 		parsed = append(parsed, u)
+		state = append(state, proxyState{raw: strings.TrimSpace(p)})
 	}
 
 	if limit <= 0 {
@@ -72,16 +113,136 @@ func Init(proxyList []string, limit int, enableSMTP bool) error {
 	Global = &Manager{
 		proxies: parsed,
 		counter: 0,
+		state:   state,
 	}
 	return nil
 }
 
+// Next returns the next proxy in round-robin order, skipping any that have
+// been ejected by health checking. If every proxy is ejected it falls back to
+// plain rotation over the full list rather than silently going direct.
 func (m *Manager) Next() *url.URL {
 	if m == nil || len(m.proxies) == 0 {
 		return nil
 	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := uint64(len(m.proxies))
+	for i := uint64(0); i < total; i++ {
+		n := atomic.AddUint64(&m.counter, 1)
+		idx := (n - 1) % total
+		if !m.state[idx].ejected {
+			return m.proxies[idx]
+		}
+	}
+
 	n := atomic.AddUint64(&m.counter, 1)
-	return m.proxies[(n-1)%uint64(len(m.proxies))]
+	return m.proxies[(n-1)%total]
+}
+
+// SetTrusted marks the given proxies (matched against the entries originally
+// passed to Init) as trusted. Returns the number of proxies matched.
+func (m *Manager) SetTrusted(raw []string) int {
+	if m == nil {
+		return 0
+	}
+
+	want := make(map[string]bool, len(raw))
+	for _, r := range raw {
+		want[strings.TrimSpace(r)] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matched := 0
+	for i := range m.state {
+		if want[m.state[i].raw] {
+			m.state[i].trusted = true
+			matched++
+		}
+	}
+	return matched
+}
+
+// ReportFailure records a failed health check for u and ejects it from
+// rotation once it reaches its failure threshold. Returns true if u is
+// ejected after this call.
+func (m *Manager) ReportFailure(u *url.URL) bool {
+	return m.reportFailure(u, nil)
+}
+
+func (m *Manager) reportFailure(u *url.URL, cause error) bool {
+	if m == nil || u == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := m.indexOf(u)
+	if idx < 0 {
+		return false
+	}
+
+	st := &m.state[idx]
+	st.failures++
+	st.checksFailed++
+	st.lastChecked = time.Now()
+	if cause != nil {
+		st.lastError = cause.Error()
+	}
+
+	limit := EjectAfterFailures
+	if st.trusted {
+		limit = TrustedEjectAfterFailures
+	}
+	if st.failures >= limit {
+		st.ejected = true
+	}
+	return st.ejected
+}
+
+// ReportSuccess records a passing health check for u, resetting its failure
+// count and returning it to rotation if it had been ejected.
+func (m *Manager) ReportSuccess(u *url.URL) {
+	if m == nil || u == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if idx := m.indexOf(u); idx >= 0 {
+		st := &m.state[idx]
+		st.failures = 0
+		st.ejected = false
+		st.checksOK++
+		st.lastChecked = time.Now()
+		st.lastError = ""
+	}
+}
+
+// Proxies returns every loaded proxy in rotation order, including any that
+// health checking has ejected.
+func (m *Manager) Proxies() []*url.URL {
+	if m == nil {
+		return nil
+	}
+	return append([]*url.URL(nil), m.proxies...)
+}
+
+// indexOf returns the position of u in the rotation list, or -1. Callers must
+// hold m.mu.
+func (m *Manager) indexOf(u *url.URL) int {
+	for i, p := range m.proxies {
+		if p == u || p.String() == u.String() {
+			return i
+		}
+	}
+	return -1
 }
 
 func Enabled() bool {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -22,10 +23,28 @@ var ErrNil = redis.Nil
 type Task struct {
 	JobID string `json:"job_id"`
 	Email string `json:"email"`
+	// Fleet is the worker fleet the task was routed to; see Route.
+	Fleet string `json:"fleet,omitempty"`
+	// Attempts counts failed verifications so far; see Retry.
+	Attempts int `json:"attempts,omitempty"`
+	// RetryAt is when a retried task becomes due (Unix nanoseconds). It also
+	// keeps two retries of the same address distinct in RetryQueueName.
+	RetryAt int64 `json:"retry_at,omitempty"`
+	// LastError is the failure that sent the task to DeadQueueName.
+	LastError string `json:"last_error,omitempty"`
 }
 
 const QueueName = "tasks:verify"
 
+// RetryQueueName is a sorted set of tasks waiting out their retry backoff,
+// scored by RetryAt in milliseconds. PromoteDue moves them back onto QueueName.
+// Named fleets have their own (see RetryQueueFor).
+const RetryQueueName = "tasks:retry"
+
+// DeadQueueName collects tasks that exhausted their retries, and payloads
+// that could not be decoded, for inspection.
+const DeadQueueName = "tasks:dead"
+
 // Init connects to Redis.
 func Init(addr string) error {
 	Client = redis.NewClient(&redis.Options{
@@ -45,8 +64,9 @@ func Init(addr string) error {
 	return nil
 }
 
-// EnqueueBatch pushes a list of emails to the Redis queue in one go.
-func EnqueueBatch(ctx context.Context, jobID string, emails []string) error {
+// EnqueueBatch pushes a list of emails onto fleet's queue in one go. Use ""
+// for the default fleet.
+func EnqueueBatch(ctx context.Context, jobID string, emails []string, fleet string) error {
 	if len(emails) == 0 {
 		return nil
 	}
@@ -62,7 +82,7 @@ func EnqueueBatch(ctx context.Context, jobID string, emails []string) error {
 		// 1. Convert emails to JSON tasks
 		var values []interface{}
 		for _, email := range emails[i:end] {
-			task := Task{JobID: jobID, Email: email}
+			task := Task{JobID: jobID, Email: email, Fleet: fleet}
 			data, err := json.Marshal(task)
 			if err != nil {
 				return err
@@ -71,10 +91,49 @@ func EnqueueBatch(ctx context.Context, jobID string, emails []string) error {
 		}
 
 		// 2. Push to Redis
-		if err := Client.RPush(ctx, QueueName, values...).Err(); err != nil {
+		if err := Client.RPush(ctx, QueueFor(fleet), values...).Err(); err != nil {
 			return fmt.Errorf("failed to enqueue batch: %w", err)
 		}
 	}
 
 	return nil
 }
+
+// Retry schedules task to be re-queued onto its fleet's queue after delay.
+func Retry(ctx context.Context, task Task, delay time.Duration) error {
+	task.RetryAt = time.Now().Add(delay).UnixNano()
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return Client.ZAdd(ctx, RetryQueueFor(task.Fleet), redis.Z{Score: float64(task.RetryAt / int64(time.Millisecond)), Member: data}).Err()
+}
+
+// promoteScript moves due members of the retry set onto the work queue in one
+// step, so a task is never lost or duplicated between the two.
+var promoteScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, m in ipairs(due) do
+	redis.call('ZREM', KEYS[1], m)
+	redis.call('RPUSH', KEYS[2], m)
+end
+return #due
+`)
+
+// PromoteDue moves up to limit of fleet's retried tasks whose backoff has
+// elapsed back onto its queue and returns how many it moved.
+func PromoteDue(ctx context.Context, fleet string, limit int) (int, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return promoteScript.Run(ctx, Client, []string{RetryQueueFor(fleet), QueueFor(fleet)}, now, limit).Int()
+}
+
+// DeadLetter appends payload (an encoded Task, or an undecodable raw
+// payload) to DeadQueueName.
+func DeadLetter(ctx context.Context, payload []byte) error {
+	return Client.RPush(ctx, DeadQueueName, payload).Err()
+}
+
+// DeadDepth returns the number of entries in DeadQueueName.
+func DeadDepth(ctx context.Context) (int64, error) {
+	return Client.LLen(ctx, DeadQueueName).Result()
+}
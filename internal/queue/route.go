@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"strings"
+
+	"mailvetter/internal/config"
+)
+
+// Fleets let separate worker deployments consume different job classes. A
+// task routed to fleet "slow" is pushed onto tasks:verify:slow and popped only
+// by workers started with WORKER_FLEET=slow; the unnamed default fleet keeps
+// using QueueName, so single-pool deployments need no configuration.
+
+// Routes maps a job class (the "class" upload field) to the fleet that
+// processes it. Set via TASK_ROUTES:
+//
+//	TASK_ROUTES="bulk=slow,priority=fast"
+var Routes = ParseRoutes(config.List("TASK_ROUTES"))
+
+// ParseRoutes parses "class=fleet" entries into a route map. Malformed
+// entries are skipped.
+func ParseRoutes(entries []string) map[string]string {
+	routes := make(map[string]string)
+	for _, e := range entries {
+		class, fleet, ok := strings.Cut(e, "=")
+		if !ok {
+			continue
+		}
+		class = strings.ToLower(strings.TrimSpace(class))
+		fleet = strings.ToLower(strings.TrimSpace(fleet))
+		if class != "" && fleet != "" {
+			routes[class] = fleet
+		}
+	}
+	return routes
+}
+
+// Route returns the fleet for a job class. The empty class goes to the
+// default fleet; ok is false for a class Routes does not know.
+func Route(class string) (fleet string, ok bool) {
+	class = strings.ToLower(strings.TrimSpace(class))
+	if class == "" {
+		return "", true
+	}
+	fleet, ok = Routes[class]
+	return fleet, ok
+}
+
+// QueueFor returns the work list fleet consumes.
+func QueueFor(fleet string) string {
+	if fleet == "" {
+		return QueueName
+	}
+	return QueueName + ":" + fleet
+}
+
+// RetryQueueFor returns the retry set for fleet's tasks.
+func RetryQueueFor(fleet string) string {
+	if fleet == "" {
+		return RetryQueueName
+	}
+	return RetryQueueName + ":" + fleet
+}
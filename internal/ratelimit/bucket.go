@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenBucket is an in-process request-rate limiter: it holds up to one
+// minute's worth of tokens and refills continuously at the per-minute rate.
+type TokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	perSec   float64
+	tokens   float64
+	last     time.Time
+}
+
+// NewTokenBucket returns a full bucket allowing perMinute requests a minute.
+// A non-positive perMinute returns nil, which allows everything.
+func NewTokenBucket(perMinute int) *TokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &TokenBucket{
+		capacity: float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		tokens:   float64(perMinute),
+	}
+}
+
+// Allow takes a token at now. When none is left it returns false and how long
+// until one will be.
+func (b *TokenBucket) Allow(now time.Time) (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.perSec
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.perSec * float64(time.Second))
+	return false, wait
+}
+
+// ParseKeyLimits parses credential entries (e.g. from API_KEYS) of the form
+// "key" or "key=N", where N is a requests-per-minute limit. Keys without a
+// limit, or with N=0, map to 0. Only an all-digit suffix is read as a limit,
+// so keys that themselves contain '=' (base64 padding) are kept whole.
+func ParseKeyLimits(entries []string) map[string]int {
+	keys := make(map[string]int)
+	for _, e := range entries {
+		key, limit := e, 0
+		if i := strings.LastIndexByte(e, '='); i > 0 {
+			if n, err := strconv.Atoi(strings.TrimSpace(e[i+1:])); err == nil && n >= 0 {
+				key, limit = strings.TrimSpace(e[:i]), n
+			}
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			keys[key] = limit
+		}
+	}
+	return keys
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := NewTokenBucket(60)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 60; i++ {
+		if ok, _ := b.Allow(now); !ok {
+			t.Fatalf("request %d of a full bucket was refused", i+1)
+		}
+	}
+	ok, wait := b.Allow(now)
+	if ok {
+		t.Fatal("61st request in the same instant was allowed")
+	}
+	if wait != time.Second {
+		t.Errorf("retry after %s, expected 1s at 60/min", wait)
+	}
+
+	if ok, _ := b.Allow(now.Add(time.Second)); !ok {
+		t.Error("a token should have refilled after one second")
+	}
+	if ok, _ := b.Allow(now.Add(time.Second)); ok {
+		t.Error("only one token should have refilled")
+	}
+
+	// Idle time refills to capacity, not beyond.
+	later := now.Add(time.Hour)
+	for i := 0; i < 60; i++ {
+		if ok, _ := b.Allow(later); !ok {
+			t.Fatalf("request %d after an idle hour was refused", i+1)
+		}
+	}
+	if ok, _ := b.Allow(later); ok {
+		t.Error("bucket refilled beyond capacity")
+	}
+}
+
+func TestTokenBucketUnlimited(t *testing.T) {
+	b := NewTokenBucket(0)
+	for i := 0; i < 1000; i++ {
+		if ok, _ := b.Allow(time.Now()); !ok {
+			t.Fatal("unlimited bucket refused a request")
+		}
+	}
+}
+
+func TestParseKeyLimits(t *testing.T) {
+	got := ParseKeyLimits([]string{"alpha", "beta=120", "c2VjcmV0==", "gamma=0", " delta = 30 ", "=5"})
+	want := map[string]int{
+		"alpha":      0,
+		"beta":       120,
+		"c2VjcmV0==": 0,
+		"gamma":      0,
+		"delta":      30,
+		"=5":         0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseKeyLimits = %v, want %v", got, want)
+	}
+}
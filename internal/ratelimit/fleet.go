@@ -0,0 +1,165 @@
+// Package ratelimit provides a Redis-backed concurrency limiter shared by every
+// worker process, so per-provider SMTP caps hold across the whole fleet rather
+// than per process, and an in-process token bucket for request rates.
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultLease bounds how long a slot can be held. It comfortably exceeds the
+// longest SMTP session (16 s deadline plus command delays) and guarantees that
+// slots held by a crashed process are reclaimed rather than leaked forever.
+const DefaultLease = 60 * time.Second
+
+// pollInterval is how often a waiting caller re-checks for a free slot.
+const pollInterval = 200 * time.Millisecond
+
+// slotBackend is the storage behind the limiter. The Redis implementation is
+// used in production; tests substitute an in-memory one.
+type slotBackend interface {
+	tryAcquire(ctx context.Context, key, token string, limit int, lease time.Duration) (bool, error)
+	release(ctx context.Context, key, token string) error
+}
+
+// FleetLimiter caps concurrent SMTP sessions per provider across all processes
+// sharing the same Redis instance.
+type FleetLimiter struct {
+	backend slotBackend
+	caps    map[string]int
+	lease   time.Duration
+}
+
+// Fleet is the process-wide limiter. It is nil (and every Acquire is a no-op)
+// until InitFleet is called with at least one cap.
+var Fleet *FleetLimiter
+
+// InitFleet configures the process-wide limiter from caps (provider → max
+// concurrent sessions fleet-wide). Providers without a cap are not limited.
+func InitFleet(client *redis.Client, caps map[string]int) {
+	if client == nil || len(caps) == 0 {
+		Fleet = nil
+		return
+	}
+	Fleet = &FleetLimiter{
+		backend: &redisBackend{client: client},
+		caps:    caps,
+		lease:   DefaultLease,
+	}
+}
+
+// ParseCaps parses "provider=N" entries (e.g. from SMTP_PROVIDER_CAPS) into a
+// cap map. Malformed or non-positive entries are skipped.
+func ParseCaps(entries []string) map[string]int {
+	caps := make(map[string]int)
+	for _, e := range entries {
+		name, val, ok := strings.Cut(e, "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil || n <= 0 {
+			continue
+		}
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			caps[name] = n
+		}
+	}
+	return caps
+}
+
+// Acquire blocks until a fleet-wide slot for provider is free or ctx is done.
+// It returns a release function that must be called when the session ends.
+//
+// If Redis is unreachable the limiter fails open: the session proceeds without
+// a slot rather than halting verification on a Redis outage.
+func (f *FleetLimiter) Acquire(ctx context.Context, provider string) (func(), error) {
+	noop := func() {}
+	if f == nil {
+		return noop, nil
+	}
+	limit, ok := f.caps[provider]
+	if !ok {
+		return noop, nil
+	}
+
+	key := "smtp:slots:" + provider
+	token := newToken()
+
+	for {
+		acquired, err := f.backend.tryAcquire(ctx, key, token, limit, f.lease)
+		if err != nil {
+			if ctx.Err() != nil {
+				return noop, ctx.Err()
+			}
+			log.Printf("[ratelimit] ⚠️  fleet limiter unavailable for %s, proceeding without slot: %v", provider, err)
+			return noop, nil
+		}
+		if acquired {
+			return func() {
+				// Use a fresh context: the caller's may already be cancelled,
+				// and leaving the slot held until lease expiry would throttle
+				// the rest of the fleet for no reason.
+				relCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+				f.backend.release(relCtx, key, token)
+			}, nil
+		}
+
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return noop, ctx.Err()
+		}
+	}
+}
+
+func newToken() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// acquireScript implements a leased counting semaphore on a sorted set:
+// members are slot tokens scored by acquisition time (Redis server clock, so
+// worker clock skew is irrelevant). Expired leases are pruned before counting.
+//
+// KEYS[1] = slot set, ARGV[1] = token, ARGV[2] = limit, ARGV[3] = lease (ms)
+var acquireScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local lease = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - lease)
+if redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('ZADD', KEYS[1], now, ARGV[1])
+	redis.call('PEXPIRE', KEYS[1], lease)
+	return 1
+end
+return 0
+`)
+
+type redisBackend struct {
+	client *redis.Client
+}
+
+func (r *redisBackend) tryAcquire(ctx context.Context, key, token string, limit int, lease time.Duration) (bool, error) {
+	n, err := acquireScript.Run(ctx, r.client, []string{key}, token, limit, lease.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *redisBackend) release(ctx context.Context, key, token string) error {
+	return r.client.ZRem(ctx, key, token).Err()
+}
@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBackend mirrors the Redis script's semantics in-process.
+type memoryBackend struct {
+	mu    sync.Mutex
+	slots map[string]map[string]time.Time
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{slots: make(map[string]map[string]time.Time)}
+}
+
+func (m *memoryBackend) tryAcquire(ctx context.Context, key, token string, limit int, lease time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, ok := m.slots[key]
+	if !ok {
+		set = make(map[string]time.Time)
+		m.slots[key] = set
+	}
+	now := time.Now()
+	for tok, at := range set {
+		if now.Sub(at) > lease {
+			delete(set, tok)
+		}
+	}
+	if len(set) >= limit {
+		return false, nil
+	}
+	set[token] = now
+	return true, nil
+}
+
+func (m *memoryBackend) release(ctx context.Context, key, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.slots[key], token)
+	return nil
+}
+
+func (m *memoryBackend) held(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.slots[key])
+}
+
+func TestFleetLimiterAcquireRelease(t *testing.T) {
+	backend := newMemoryBackend()
+	f := &FleetLimiter{backend: backend, caps: map[string]int{"google": 2}, lease: DefaultLease}
+	ctx := context.Background()
+
+	rel1, err := f.Acquire(ctx, "google")
+	if err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+	rel2, err := f.Acquire(ctx, "google")
+	if err != nil {
+		t.Fatalf("second acquire failed: %v", err)
+	}
+	if n := backend.held("smtp:slots:google"); n != 2 {
+		t.Fatalf("expected 2 held slots, got %d", n)
+	}
+
+	// A third caller must block until a slot is released.
+	blockedCtx, cancel := context.WithTimeout(ctx, 3*pollInterval)
+	defer cancel()
+	if _, err := f.Acquire(blockedCtx, "google"); err == nil {
+		t.Fatalf("expected third acquire to block until ctx expired")
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		rel3, err := f.Acquire(ctx, "google")
+		if err == nil {
+			defer rel3()
+			close(acquired)
+		}
+	}()
+
+	rel1()
+	select {
+	case <-acquired:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("waiting caller did not acquire the released slot")
+	}
+
+	rel2()
+}
+
+func TestFleetLimiterUncappedProvider(t *testing.T) {
+	backend := newMemoryBackend()
+	f := &FleetLimiter{backend: backend, caps: map[string]int{"google": 1}, lease: DefaultLease}
+
+	for i := 0; i < 5; i++ {
+		if _, err := f.Acquire(context.Background(), "generic"); err != nil {
+			t.Fatalf("uncapped provider should never block: %v", err)
+		}
+	}
+	if n := backend.held("smtp:slots:generic"); n != 0 {
+		t.Errorf("uncapped provider should not consume slots, got %d", n)
+	}
+}
+
+func TestFleetLimiterExpiredLeaseIsReclaimed(t *testing.T) {
+	backend := newMemoryBackend()
+	f := &FleetLimiter{backend: backend, caps: map[string]int{"office365": 1}, lease: 50 * time.Millisecond}
+
+	// Acquire and never release, simulating a crashed process.
+	if _, err := f.Acquire(context.Background(), "office365"); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := f.Acquire(ctx, "office365"); err != nil {
+		t.Errorf("expected the leaked slot to be reclaimed after lease expiry: %v", err)
+	}
+}
+
+func TestNilFleetLimiterIsNoop(t *testing.T) {
+	var f *FleetLimiter
+	release, err := f.Acquire(context.Background(), "google")
+	if err != nil {
+		t.Fatalf("nil limiter should not fail: %v", err)
+	}
+	release()
+}
+
+func TestParseCaps(t *testing.T) {
+	caps := ParseCaps([]string{"Google=20", "office365=30", "bad", "yahoo=0", "x=abc"})
+	if len(caps) != 2 || caps["google"] != 20 || caps["office365"] != 30 {
+		t.Errorf("unexpected caps %v", caps)
+	}
+}
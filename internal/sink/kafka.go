@@ -0,0 +1,141 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mailvetter/internal/config"
+)
+
+// KafkaRESTURL is the base URL of a Kafka REST Proxy (Confluent-compatible v2
+// API). When set together with KafkaTopic, every completed result is produced
+// to the topic. Set via KAFKA_REST_URL.
+var KafkaRESTURL = config.String("KAFKA_REST_URL", "")
+
+// KafkaTopic is the topic results are produced to. Set via KAFKA_TOPIC.
+var KafkaTopic = config.String("KAFKA_TOPIC", "")
+
+// KafkaKeyBy selects the message key: "email" (default) keys each record by
+// address, "job" keys by job ID so a job's results share a partition. Set via
+// KAFKA_KEY_BY.
+var KafkaKeyBy = config.String("KAFKA_KEY_BY", "email")
+
+// Message is one record to produce.
+type Message struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// Producer writes messages to a Kafka topic.
+type Producer interface {
+	Produce(ctx context.Context, topic string, msgs ...Message) error
+}
+
+// Kafka is a Sink that produces each result to a topic.
+//
+// Each message value is an envelope of job ID, email and the result. Because
+// results are published after the Postgres commit, a retried task can produce
+// the same result twice; consumers should deduplicate on (job_id, email), the
+// same pair that identifies a row in the results table.
+type Kafka struct {
+	producer Producer
+	topic    string
+	keyBy    string
+}
+
+// NewKafka returns a sink producing to topic through p. keyBy is "email" or
+// "job"; anything else is treated as "email".
+func NewKafka(p Producer, topic, keyBy string) *Kafka {
+	return &Kafka{producer: p, topic: topic, keyBy: keyBy}
+}
+
+// KafkaFromEnv returns the sink configured by KAFKA_REST_URL and KAFKA_TOPIC,
+// or nil if either is unset.
+func KafkaFromEnv() *Kafka {
+	if KafkaRESTURL == "" || KafkaTopic == "" {
+		return nil
+	}
+	return NewKafka(NewRESTProducer(KafkaRESTURL), KafkaTopic, KafkaKeyBy)
+}
+
+func (k *Kafka) Name() string { return "kafka:" + k.topic }
+
+func (k *Kafka) Publish(ctx context.Context, r Result) error {
+	key := r.Email
+	if k.keyBy == "job" {
+		key = r.JobID
+	}
+	value, err := json.Marshal(envelope{JobID: r.JobID, Email: r.Email, Score: r.Score, Result: r.Data})
+	if err != nil {
+		return err
+	}
+	return k.producer.Produce(ctx, k.topic, Message{Key: key, Value: value})
+}
+
+// envelope is the JSON value of each produced message.
+type envelope struct {
+	JobID  string          `json:"job_id"`
+	Email  string          `json:"email"`
+	Score  int             `json:"score"`
+	Result json.RawMessage `json:"result"`
+}
+
+// restTimeout bounds a single produce request so a slow proxy cannot hold a
+// worker slot.
+const restTimeout = 10 * time.Second
+
+// restProducer produces through a Kafka REST Proxy using the v2 JSON embedded
+// format.
+type restProducer struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewRESTProducer returns a Producer that POSTs to baseURL/topics/<topic>.
+func NewRESTProducer(baseURL string) Producer {
+	return &restProducer{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: restTimeout},
+	}
+}
+
+type restRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (p *restProducer) Produce(ctx context.Context, topic string, msgs ...Message) error {
+	records := make([]restRecord, 0, len(msgs))
+	for _, m := range msgs {
+		records = append(records, restRecord{Key: m.Key, Value: m.Value})
+	}
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return err
+	}
+
+	endpoint := p.baseURL + "/topics/" + url.PathEscape(topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("produce rejected: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
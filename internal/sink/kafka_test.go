@@ -0,0 +1,148 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockProducer struct {
+	mu     sync.Mutex
+	topics []string
+	msgs   []Message
+	err    error
+	fails  int // calls to fail before succeeding
+	calls  int
+}
+
+func (m *mockProducer) Produce(_ context.Context, topic string, msgs ...Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if m.err != nil {
+		return m.err
+	}
+	if m.calls <= m.fails {
+		return errors.New("transient failure")
+	}
+	for _, msg := range msgs {
+		m.topics = append(m.topics, topic)
+		m.msgs = append(m.msgs, msg)
+	}
+	return nil
+}
+
+func resetSinks(t *testing.T) {
+	mu.Lock()
+	saved, savedDelay := sinks, retryDelay
+	sinks, retryDelay = nil, time.Millisecond
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		sinks, retryDelay = saved, savedDelay
+		mu.Unlock()
+	})
+}
+
+func TestKafkaPublishesCompletedResults(t *testing.T) {
+	resetSinks(t)
+
+	mock := &mockProducer{}
+	Register(NewKafka(mock, "verifications", "email"))
+
+	data := json.RawMessage(`{"email":"jane@example.com","score":95,"status":"valid"}`)
+	Publish(context.Background(), Result{JobID: "job-1", Email: "jane@example.com", Score: 95, Data: data})
+	Wait()
+
+	if len(mock.msgs) != 1 {
+		t.Fatalf("expected 1 produced message, got %d", len(mock.msgs))
+	}
+	if mock.topics[0] != "verifications" || mock.msgs[0].Key != "jane@example.com" {
+		t.Errorf("unexpected topic/key: %q %q", mock.topics[0], mock.msgs[0].Key)
+	}
+
+	var got envelope
+	if err := json.Unmarshal(mock.msgs[0].Value, &got); err != nil {
+		t.Fatalf("value is not a JSON envelope: %v", err)
+	}
+	if got.JobID != "job-1" || got.Email != "jane@example.com" || got.Score != 95 || string(got.Result) != string(data) {
+		t.Errorf("unexpected envelope %+v", got)
+	}
+}
+
+func TestKafkaKeyByJob(t *testing.T) {
+	mock := &mockProducer{}
+	if err := NewKafka(mock, "t", "job").Publish(context.Background(), Result{JobID: "job-7", Email: "a@b.c", Data: json.RawMessage(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if mock.msgs[0].Key != "job-7" {
+		t.Errorf("expected job key, got %q", mock.msgs[0].Key)
+	}
+}
+
+func TestPublishContinuesPastFailingSink(t *testing.T) {
+	resetSinks(t)
+
+	failing := &mockProducer{err: errors.New("broker down")}
+	healthy := &mockProducer{}
+	Register(NewKafka(failing, "a", "email"))
+	Register(NewKafka(healthy, "b", "email"))
+
+	Publish(context.Background(), Result{JobID: "j", Email: "x@y.z", Data: json.RawMessage(`{}`)})
+	Wait()
+
+	if len(healthy.msgs) != 1 {
+		t.Errorf("expected the healthy sink to receive the result despite the failing one")
+	}
+	if failing.calls != PublishAttempts {
+		t.Errorf("failing sink tried %d times, want %d", failing.calls, PublishAttempts)
+	}
+}
+
+func TestPublishRetriesTransientFailures(t *testing.T) {
+	resetSinks(t)
+
+	flaky := &mockProducer{fails: 1}
+	Register(NewKafka(flaky, "a", "email"))
+
+	Publish(context.Background(), Result{JobID: "j", Email: "x@y.z", Data: json.RawMessage(`{}`)})
+	Wait()
+
+	if len(flaky.msgs) != 1 || flaky.calls != 2 {
+		t.Errorf("expected delivery on the second attempt, got %d messages after %d calls", len(flaky.msgs), flaky.calls)
+	}
+}
+
+func TestRESTProducer(t *testing.T) {
+	var gotPath, gotType string
+	var gotBody struct {
+		Records []struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		} `json:"records"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotType = r.URL.Path, r.Header.Get("Content-Type")
+		raw, _ := io.ReadAll(r.Body)
+		json.Unmarshal(raw, &gotBody)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p := NewRESTProducer(srv.URL + "/")
+	if err := p.Produce(context.Background(), "verifications", Message{Key: "k", Value: json.RawMessage(`{"a":1}`)}); err != nil {
+		t.Fatalf("produce: %v", err)
+	}
+	if gotPath != "/topics/verifications" || gotType != "application/vnd.kafka.json.v2+json" {
+		t.Errorf("unexpected request %s %s", gotPath, gotType)
+	}
+	if len(gotBody.Records) != 1 || gotBody.Records[0].Key != "k" || string(gotBody.Records[0].Value) != `{"a":1}` {
+		t.Errorf("unexpected body %+v", gotBody)
+	}
+}
@@ -0,0 +1,102 @@
+// Package sink streams completed verification results to external systems in
+// addition to the Postgres results table, for consumers that prefer
+// event-driven delivery over polling the API or database.
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"mailvetter/internal/config"
+)
+
+// Result is one completed verification as handed to a sink. Data is
+// identical to what is stored in results.data: the serialised
+// ValidationResult, or its validator.Verdict with STORE_FULL_ANALYSIS off.
+type Result struct {
+	JobID string
+	Email string
+	Score int
+	Data  json.RawMessage
+}
+
+// Sink receives completed results. Delivery is best effort: a failed publish
+// is retried PublishAttempts times and then dropped with a log line, so a
+// consumer that must see every result should reconcile against the results
+// table. A result may also be published twice if a worker retries its task,
+// so implementations should give consumers a stable key to deduplicate on.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, r Result) error
+}
+
+var (
+	mu    sync.RWMutex
+	sinks []Sink
+)
+
+// Register adds s to the set of sinks every result is published to.
+func Register(s Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+	sinks = append(sinks, s)
+}
+
+// PublishAttempts is how many times a result is offered to a failing sink
+// before it is dropped. Set via SINK_PUBLISH_ATTEMPTS.
+var PublishAttempts = config.Int("SINK_PUBLISH_ATTEMPTS", 3)
+
+// retryDelay is the pause before the second attempt; it grows linearly
+// after that.
+var retryDelay = time.Second
+
+// maxInFlight bounds the publishes running at once. Beyond it Publish
+// blocks, so a sink that is down slows the workers instead of piling up
+// goroutines.
+const maxInFlight = 64
+
+var (
+	slots    = make(chan struct{}, maxInFlight)
+	inflight sync.WaitGroup
+)
+
+// Publish hands r to every registered sink. Delivery runs in the background,
+// so a slow or failing sink does not hold up the caller or the other sinks;
+// call Wait before exiting.
+func Publish(ctx context.Context, r Result) {
+	mu.RLock()
+	registered := sinks
+	mu.RUnlock()
+
+	// The result is stored already; a worker shutting down still delivers it.
+	ctx = context.WithoutCancel(ctx)
+	for _, s := range registered {
+		slots <- struct{}{}
+		inflight.Add(1)
+		go func() {
+			defer func() { <-slots; inflight.Done() }()
+			deliver(ctx, s, r)
+		}()
+	}
+}
+
+func deliver(ctx context.Context, s Sink, r Result) {
+	var err error
+	for attempt := 1; attempt <= PublishAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * retryDelay)
+		}
+		if err = s.Publish(ctx, r); err == nil {
+			return
+		}
+	}
+	log.Printf("⚠️  Sink %s dropped %s after %d attempts: %v", s.Name(), r.Email, PublishAttempts, err)
+}
+
+// Wait blocks until every publish started by Publish has finished.
+func Wait() {
+	inflight.Wait()
+}
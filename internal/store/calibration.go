@@ -0,0 +1,12 @@
+package store
+
+import "context"
+
+// RecordCalibration appends one engine-versus-reference comparison.
+func RecordCalibration(ctx context.Context, email, ours, theirs string, agree bool) error {
+	_, err := DB.Exec(ctx, `
+		INSERT INTO calibration_results (email, ours, theirs, agree, checked_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, NormalizeEmail(email), ours, theirs, agree)
+	return err
+}
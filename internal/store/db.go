@@ -69,6 +69,90 @@ func runMigrations(ctx context.Context) error {
 	CREATE INDEX IF NOT EXISTS idx_results_job_id_id
 		ON results (job_id, id);`
 
+	// Optional per-job export destination (a presigned PUT URL). When set,
+	// the worker that completes the job uploads the results there and
+	// records the outcome in export_status.
+	queryJobsExport := `
+	ALTER TABLE jobs
+		ADD COLUMN IF NOT EXISTS export_url    TEXT,
+		ADD COLUMN IF NOT EXISTS export_format TEXT,
+		ADD COLUMN IF NOT EXISTS export_status TEXT;`
+
+	// Table: email_history — append-only verdict log per normalized email,
+	// written only when HISTORY_ENABLED is set. Lets users see whether an
+	// address has been consistently valid or only just became so.
+	queryHistory := `
+	CREATE TABLE IF NOT EXISTS email_history (
+		id         BIGSERIAL PRIMARY KEY,
+		email      TEXT      NOT NULL,
+		status     TEXT      NOT NULL,
+		score      INT       NOT NULL,
+		checked_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);`
+
+	queryIdxHistoryEmail := `
+	CREATE INDEX IF NOT EXISTS idx_email_history_email_checked_at
+		ON email_history (email, checked_at);`
+
+	// Table: calibration_results — sampled comparisons against an external
+	// reference verifier, written only when CALIBRATION_ENABLED is set.
+	queryCalibration := `
+	CREATE TABLE IF NOT EXISTS calibration_results (
+		id         BIGSERIAL PRIMARY KEY,
+		email      TEXT      NOT NULL,
+		ours       TEXT      NOT NULL,
+		theirs     TEXT      NOT NULL,
+		agree      BOOLEAN   NOT NULL,
+		checked_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);`
+
+	// Verdict columns lifted out of results.data so status filters are
+	// plain indexed comparisons rather than JSONB operators on every row.
+	// Rows written before this migration leave them NULL.
+	queryResultsVerdict := `
+	ALTER TABLE results
+		ADD COLUMN IF NOT EXISTS status       TEXT,
+		ADD COLUMN IF NOT EXISTS reachability TEXT;`
+
+	// Index 4: serves the /results status filter with the same id ordering
+	// as idx_results_job_id_id; min_score is applied to the rows it yields.
+	queryIdxResultsJobStatus := `
+	CREATE INDEX IF NOT EXISTS idx_results_job_id_status_id
+		ON results (job_id, status, id);`
+
+	// Index 5: serves /results/lookup, which fetches a handful of addresses
+	// from a job. Not unique — an upload may list the same address twice.
+	queryIdxResultsJobEmail := `
+	CREATE INDEX IF NOT EXISTS idx_results_job_id_email
+		ON results (job_id, lower(email));`
+
+	// Optional per-job completion webhook, POSTed by the worker that
+	// finishes the job.
+	queryJobsCallback := `
+	ALTER TABLE jobs
+		ADD COLUMN IF NOT EXISTS callback_url TEXT;`
+
+	// Optional client-supplied Idempotency-Key from /upload, so a retried
+	// upload returns the job it already created. A key is scoped to the
+	// API key that sent it (api_key holds a digest, never the secret), and
+	// total_rows keeps the upload's row count for the replayed response.
+	// CreateJob releases a key once its TTL window has passed.
+	queryJobsIdempotency := `
+	ALTER TABLE jobs
+		ADD COLUMN IF NOT EXISTS idempotency_key TEXT,
+		ADD COLUMN IF NOT EXISTS api_key         TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS total_rows      INT;`
+
+	queryIdxJobsIdempotency := `
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_api_key_idempotency_key
+		ON jobs (api_key, idempotency_key) WHERE idempotency_key IS NOT NULL;`
+
+	// Index 6: serves /result, the latest result for one address across
+	// every job, matched case-insensitively.
+	queryIdxResultsEmail := `
+	CREATE INDEX IF NOT EXISTS idx_results_lower_email_id
+		ON results (lower(email), id);`
+
 	migrations := []struct {
 		name  string
 		query string
@@ -78,6 +162,17 @@ func runMigrations(ctx context.Context) error {
 		{"create index idx_results_job_id", queryIdxResultsJobID},
 		{"create index idx_jobs_status", queryIdxJobsStatus},
 		{"create index idx_results_job_id_id", queryIdxResultsJobIDID},
+		{"add jobs export columns", queryJobsExport},
+		{"create table email_history", queryHistory},
+		{"create index idx_email_history_email_checked_at", queryIdxHistoryEmail},
+		{"create table calibration_results", queryCalibration},
+		{"add results verdict columns", queryResultsVerdict},
+		{"create index idx_results_job_id_status_id", queryIdxResultsJobStatus},
+		{"create index idx_results_job_id_email", queryIdxResultsJobEmail},
+		{"add jobs callback column", queryJobsCallback},
+		{"add jobs idempotency column", queryJobsIdempotency},
+		{"create index idx_jobs_api_key_idempotency_key", queryIdxJobsIdempotency},
+		{"create index idx_results_lower_email_id", queryIdxResultsEmail},
 	}
 
 	for _, m := range migrations {
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"mailvetter/internal/config"
+)
+
+// HistoryEnabled gates writes to the append-only email_history table. Off by
+// default; enable with HISTORY_ENABLED=true.
+var HistoryEnabled = config.Bool("HISTORY_ENABLED", false)
+
+// HistoryEntry is one recorded verdict for an address.
+type HistoryEntry struct {
+	Status    string    `json:"status"`
+	Score     int       `json:"score"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// HistorySummary condenses an address's verdict history into the answers
+// list-hygiene users actually ask: how long has it been seen, and how long has
+// it been consistently valid.
+type HistorySummary struct {
+	Checks     int        `json:"checks"`
+	FirstSeen  *time.Time `json:"first_seen,omitempty"`
+	LastSeen   *time.Time `json:"last_seen,omitempty"`
+	ValidSince *time.Time `json:"valid_since,omitempty"`
+}
+
+// NormalizeEmail is the key history rows are stored under.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// RecordHistory appends a verdict for email. It is a no-op unless
+// HistoryEnabled is set.
+func RecordHistory(ctx context.Context, email, status string, score int) error {
+	if !HistoryEnabled {
+		return nil
+	}
+	_, err := DB.Exec(ctx, `
+		INSERT INTO email_history (email, status, score, checked_at)
+		VALUES ($1, $2, $3, NOW())
+	`, NormalizeEmail(email), status, score)
+	return err
+}
+
+// FetchHistory returns up to limit verdicts for email, oldest first.
+func FetchHistory(ctx context.Context, email string, limit int) ([]HistoryEntry, error) {
+	rows, err := DB.Query(ctx, `
+		SELECT status, score, checked_at
+		FROM (
+			SELECT status, score, checked_at
+			FROM   email_history
+			WHERE  email = $1
+			ORDER  BY checked_at DESC
+			LIMIT  $2
+		) recent
+		ORDER BY checked_at ASC
+	`, NormalizeEmail(email), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]HistoryEntry, 0)
+	for rows.Next() {
+		var e HistoryEntry
+		if err := rows.Scan(&e.Status, &e.Score, &e.CheckedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// SummarizeHistory condenses email's entire history, however long, in the
+// database; FetchHistory's limit does not apply. ValidSince is the first check
+// after the latest non-valid verdict, so it is nil when the latest verdict is
+// not valid.
+func SummarizeHistory(ctx context.Context, email string) (HistorySummary, error) {
+	var sum HistorySummary
+	err := DB.QueryRow(ctx, `
+		SELECT COUNT(*),
+		       MIN(checked_at),
+		       MAX(checked_at),
+		       MIN(checked_at) FILTER (
+		           WHERE checked_at > COALESCE(
+		               (SELECT MAX(checked_at)
+		                FROM   email_history
+		                WHERE  email = $1 AND status <> 'valid'),
+		               '-infinity'::timestamp)
+		       )
+		FROM   email_history
+		WHERE  email = $1
+	`, NormalizeEmail(email)).Scan(&sum.Checks, &sum.FirstSeen, &sum.LastSeen, &sum.ValidSince)
+	return sum, err
+}
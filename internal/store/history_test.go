@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestSummarizeHistoryAccumulates needs a disposable Postgres named by
+// MAILVETTER_TEST_DB_URL and is skipped without one.
+func TestSummarizeHistoryAccumulates(t *testing.T) {
+	dbURL := os.Getenv("MAILVETTER_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("MAILVETTER_TEST_DB_URL not set")
+	}
+	if err := Init(dbURL); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer DB.Close()
+
+	ctx := context.Background()
+	email := fmt.Sprintf("history-test-%d@example.com", time.Now().UnixNano())
+	t.Cleanup(func() {
+		DB.Exec(ctx, `DELETE FROM email_history WHERE email = $1`, email)
+	})
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	month := 30 * 24 * time.Hour
+	record := func(status string, at time.Time) {
+		t.Helper()
+		if _, err := DB.Exec(ctx,
+			`INSERT INTO email_history (email, status, score, checked_at) VALUES ($1, $2, 90, $3)`,
+			email, status, at,
+		); err != nil {
+			t.Fatalf("insert history: %v", err)
+		}
+	}
+
+	record("unknown", start)
+	sum, err := SummarizeHistory(ctx, email)
+	if err != nil || sum.Checks != 1 || sum.ValidSince != nil {
+		t.Fatalf("after one unknown check: got %+v, err %v", sum, err)
+	}
+
+	record("valid", start.Add(month))
+	record("valid", start.Add(3*month))
+	record("valid", start.Add(6*month))
+
+	// The summary spans the whole history, not FetchHistory's window.
+	if entries, err := FetchHistory(ctx, email, 2); err != nil || len(entries) != 2 {
+		t.Fatalf("FetchHistory: %d entries, err %v", len(entries), err)
+	}
+	if sum, err = SummarizeHistory(ctx, email); err != nil {
+		t.Fatalf("SummarizeHistory: %v", err)
+	}
+	if sum.Checks != 4 {
+		t.Errorf("Checks %d != 4", sum.Checks)
+	}
+	if sum.FirstSeen == nil || !sum.FirstSeen.Equal(start) {
+		t.Errorf("FirstSeen %v != %v", sum.FirstSeen, start)
+	}
+	if sum.LastSeen == nil || !sum.LastSeen.Equal(start.Add(6*month)) {
+		t.Errorf("LastSeen %v != %v", sum.LastSeen, start.Add(6*month))
+	}
+	if sum.ValidSince == nil || !sum.ValidSince.Equal(start.Add(month)) {
+		t.Errorf("ValidSince %v != %v", sum.ValidSince, start.Add(month))
+	}
+
+	record("invalid", start.Add(7*month))
+	if sum, err = SummarizeHistory(ctx, email); err != nil || sum.ValidSince != nil {
+		t.Errorf("ValidSince should reset after an invalid verdict, got %v (err %v)", sum.ValidSince, err)
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	if got := NormalizeEmail("  Jane.Doe@Example.COM "); got != "jane.doe@example.com" {
+		t.Errorf("NormalizeEmail = %q", got)
+	}
+}
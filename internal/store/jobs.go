@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"mailvetter/internal/config"
+)
+
+// JobStatusCancelled marks a job whose queued tasks must be skipped.
+const JobStatusCancelled = "cancelled"
+
+var (
+	// ErrJobNotFound is returned for an unknown job ID.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling a job that already completed.
+	ErrJobFinished = errors.New("job already completed")
+)
+
+// IdempotencyTTL is how long an upload's Idempotency-Key stays bound to the
+// job it created; a retry inside the window gets that job back instead of a
+// new one. Set via UPLOAD_IDEMPOTENCY_TTL.
+var IdempotencyTTL = config.Duration("UPLOAD_IDEMPOTENCY_TTL", 24*time.Hour)
+
+// NewJob is a pending job about to be created from an upload.
+type NewJob struct {
+	ID             string
+	TotalRows      int // addresses in the file, before dropping repeats
+	TotalCount     int // addresses to verify
+	ExportURL      *string
+	ExportFormat   *string
+	CallbackURL    *string
+	APIKey         string // digest of the uploading API key; scopes IdempotencyKey
+	IdempotencyKey string // optional
+}
+
+// CreateJob inserts job and returns it. When job carries an IdempotencyKey
+// the same API key already used for a job created within IdempotencyTTL,
+// nothing is inserted and that stored job is returned with existing set.
+func CreateJob(ctx context.Context, job NewJob) (created NewJob, existing bool, err error) {
+	var key *string
+	cutoff := time.Now().Add(-IdempotencyTTL)
+	if job.IdempotencyKey != "" {
+		key = &job.IdempotencyKey
+		if stored, ok, err := jobForIdempotencyKey(ctx, job.APIKey, job.IdempotencyKey, cutoff); err != nil || ok {
+			return stored, ok, err
+		}
+		// The key is unique per API key, so one whose window has passed
+		// must be released before a new job can take it.
+		if _, err := DB.Exec(ctx, `
+			UPDATE jobs SET idempotency_key = NULL
+			WHERE  api_key = $1 AND idempotency_key = $2 AND created_at <= $3
+		`, job.APIKey, job.IdempotencyKey, cutoff); err != nil {
+			return NewJob{}, false, err
+		}
+	}
+
+	_, err = DB.Exec(ctx, `
+		INSERT INTO jobs (id, status, total_count, total_rows, created_at, export_url, export_format, callback_url, api_key, idempotency_key)
+		VALUES ($1, 'pending', $2, $3, $4, $5, $6, $7, $8, $9)
+	`, job.ID, job.TotalCount, job.TotalRows, time.Now(), job.ExportURL, job.ExportFormat, job.CallbackURL, job.APIKey, key)
+	var pgErr *pgconn.PgError
+	if key != nil && errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		// A concurrent retry of the same upload claimed the key first.
+		if stored, ok, lookupErr := jobForIdempotencyKey(ctx, job.APIKey, job.IdempotencyKey, cutoff); lookupErr == nil && ok {
+			return stored, true, nil
+		}
+	}
+	if err != nil {
+		return NewJob{}, false, err
+	}
+	return job, false, nil
+}
+
+func jobForIdempotencyKey(ctx context.Context, apiKey, key string, cutoff time.Time) (NewJob, bool, error) {
+	job := NewJob{APIKey: apiKey, IdempotencyKey: key}
+	var totalRows *int
+	err := DB.QueryRow(ctx, `
+		SELECT id, total_count, total_rows, export_url, export_format, callback_url
+		FROM   jobs
+		WHERE  api_key = $1 AND idempotency_key = $2 AND created_at > $3
+	`, apiKey, key, cutoff).Scan(&job.ID, &job.TotalCount, &totalRows, &job.ExportURL, &job.ExportFormat, &job.CallbackURL)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return NewJob{}, false, nil
+	}
+	if err != nil {
+		return NewJob{}, false, err
+	}
+	// Jobs created before total_rows existed only know their unique count.
+	job.TotalRows = job.TotalCount
+	if totalRows != nil {
+		job.TotalRows = *totalRows
+	}
+	return job, true, nil
+}
+
+// AbandonJob cancels a job whose tasks could not all be queued and releases
+// its Idempotency-Key, so a retry of the upload starts a fresh job instead of
+// getting this one back. Tasks that did reach the queue are skipped as for
+// any cancelled job.
+func AbandonJob(ctx context.Context, jobID string) error {
+	_, err := DB.Exec(ctx, `
+		UPDATE jobs
+		SET    status = $2, idempotency_key = NULL
+		WHERE  id = $1
+	`, jobID, JobStatusCancelled)
+	return err
+}
+
+// CancelJob marks a job cancelled and returns how many of its emails had not
+// been processed yet. Cancelling an already-cancelled job is not an error.
+func CancelJob(ctx context.Context, jobID string) (remaining int, err error) {
+	err = DB.QueryRow(ctx, `
+		UPDATE jobs
+		SET    status = $2
+		WHERE  id = $1 AND status <> 'completed'
+		RETURNING total_count - processed_count
+	`, jobID, JobStatusCancelled).Scan(&remaining)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return remaining, err
+	}
+
+	var exists bool
+	if err := DB.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM jobs WHERE id = $1)`, jobID).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrJobNotFound
+	}
+	return 0, ErrJobFinished
+}
+
+// JobCancelled reports whether jobID has been cancelled.
+func JobCancelled(ctx context.Context, jobID string) (bool, error) {
+	var status string
+	if err := DB.QueryRow(ctx, `SELECT status FROM jobs WHERE id = $1`, jobID).Scan(&status); err != nil {
+		return false, err
+	}
+	return status == JobStatusCancelled, nil
+}
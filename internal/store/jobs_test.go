@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestCreateJobIdempotencyKey needs a disposable Postgres named by
+// MAILVETTER_TEST_DB_URL and is skipped without one.
+func TestCreateJobIdempotencyKey(t *testing.T) {
+	dbURL := os.Getenv("MAILVETTER_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("MAILVETTER_TEST_DB_URL not set")
+	}
+	if err := Init(dbURL); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer DB.Close()
+
+	ctx := context.Background()
+	prefix := fmt.Sprintf("idem-test-%d", time.Now().UnixNano())
+	key := prefix + "-key"
+	t.Cleanup(func() {
+		DB.Exec(ctx, `DELETE FROM jobs WHERE id LIKE $1`, prefix+"%")
+	})
+
+	first, existing, err := CreateJob(ctx, NewJob{ID: prefix + "-1", TotalRows: 5, TotalCount: 3, APIKey: "tenant-a", IdempotencyKey: key})
+	if err != nil || existing || first.ID != prefix+"-1" {
+		t.Fatalf("first upload: job=%+v existing=%v err=%v", first, existing, err)
+	}
+
+	// The retry gets the stored job back, counts included, not its own.
+	got, existing, err := CreateJob(ctx, NewJob{ID: prefix + "-2", TotalRows: 9, TotalCount: 8, APIKey: "tenant-a", IdempotencyKey: key})
+	if err != nil || !existing || got.ID != prefix+"-1" || got.TotalRows != 5 || got.TotalCount != 3 {
+		t.Fatalf("retry: job=%+v existing=%v err=%v, want the first job back", got, existing, err)
+	}
+
+	// Another API key may use the same Idempotency-Key independently.
+	got, existing, err = CreateJob(ctx, NewJob{ID: prefix + "-b", TotalCount: 3, APIKey: "tenant-b", IdempotencyKey: key})
+	if err != nil || existing || got.ID != prefix+"-b" {
+		t.Fatalf("other API key: job=%+v existing=%v err=%v, want a new job", got, existing, err)
+	}
+
+	// An abandoned job releases its key.
+	if err := AbandonJob(ctx, prefix+"-b"); err != nil {
+		t.Fatalf("AbandonJob: %v", err)
+	}
+	got, existing, err = CreateJob(ctx, NewJob{ID: prefix + "-b2", TotalCount: 3, APIKey: "tenant-b", IdempotencyKey: key})
+	if err != nil || existing || got.ID != prefix+"-b2" {
+		t.Fatalf("after abandon: job=%+v existing=%v err=%v, want a new job", got, existing, err)
+	}
+
+	// Once the window has passed the key starts a new job.
+	if _, err := DB.Exec(ctx, `UPDATE jobs SET created_at = $2 WHERE id = $1`, prefix+"-1", time.Now().Add(-IdempotencyTTL-time.Minute)); err != nil {
+		t.Fatalf("age job: %v", err)
+	}
+	got, existing, err = CreateJob(ctx, NewJob{ID: prefix + "-3", TotalCount: 3, APIKey: "tenant-a", IdempotencyKey: key})
+	if err != nil || existing || got.ID != prefix+"-3" {
+		t.Fatalf("after TTL: job=%+v existing=%v err=%v, want a new job", got, existing, err)
+	}
+}
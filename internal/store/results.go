@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ResultRow is one stored verification result. ID is the cursor for keyset
+// pagination and is reported separately, as next_after_id. Data is the full
+// ValidationResult, or only its verdict (marked "verdict_only": true) for
+// rows written with STORE_FULL_ANALYSIS off.
+type ResultRow struct {
+	ID    int64           `json:"-"`
+	Email string          `json:"email"`
+	Score int             `json:"score"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// ResultsQuery selects one page of a job's results in id order. A non-zero
+// AfterID starts the page after that result (keyset pagination); otherwise
+// Offset rows are skipped.
+type ResultsQuery struct {
+	JobID    string
+	Status   string // only rows with this verdict; empty for all
+	MinScore *int   // only rows scoring at least this much; nil for all
+	AfterID  int64
+	Offset   int
+	Limit    int
+}
+
+// sql builds the page query. The keyset form seeks straight to AfterID via
+// idx_results_job_id_id (or idx_results_job_id_status_id), so deep pages cost
+// the same as the first; OFFSET still walks and discards every skipped row.
+func (q ResultsQuery) sql() (string, []any) {
+	where := "job_id = $1"
+	args := []any{q.JobID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if q.Status != "" {
+		where += " AND status = " + arg(q.Status)
+	}
+	if q.MinScore != nil {
+		where += " AND score >= " + arg(*q.MinScore)
+	}
+	if q.AfterID > 0 {
+		where += " AND id > " + arg(q.AfterID)
+	}
+
+	query := `
+		SELECT id, email, score, data
+		FROM   results
+		WHERE  ` + where + `
+		ORDER  BY id ASC
+		LIMIT  ` + arg(q.Limit)
+	if q.AfterID <= 0 && q.Offset > 0 {
+		query += `
+		OFFSET ` + arg(q.Offset)
+	}
+	return query, args
+}
+
+// FetchResults returns the page of results selected by q.
+func FetchResults(ctx context.Context, q ResultsQuery) ([]ResultRow, error) {
+	query, args := q.sql()
+	rows, err := DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]ResultRow, 0, q.Limit)
+	for rows.Next() {
+		var row ResultRow
+		if err := rows.Scan(&row.ID, &row.Email, &row.Score, &row.Data); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// LookupRow is one requested address in a LookupResults answer. Found is
+// false when the job has no result for the address.
+type LookupRow struct {
+	Email string          `json:"email"`
+	Found bool            `json:"found"`
+	Score int             `json:"score"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// LookupResults returns jobID's results for emails, one row per requested
+// address in the order given. If a job verified an address more than once,
+// the latest result wins.
+func LookupResults(ctx context.Context, jobID string, emails []string) ([]LookupRow, error) {
+	wanted := make([]string, len(emails))
+	keys := make([]string, len(emails))
+	for i, e := range emails {
+		wanted[i] = strings.TrimSpace(e)
+		keys[i] = strings.ToLower(wanted[i])
+	}
+
+	rows, err := DB.Query(ctx, `
+		SELECT email, score, data
+		FROM   results
+		WHERE  job_id = $1 AND lower(email) = ANY($2)
+		ORDER  BY id ASC
+	`, jobID, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []LookupRow
+	for rows.Next() {
+		row := LookupRow{Found: true}
+		if err := rows.Scan(&row.Email, &row.Score, &row.Data); err != nil {
+			return nil, err
+		}
+		found = append(found, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return orderLookup(wanted, found), nil
+}
+
+// orderLookup arranges found (in insertion order) to match requested, filling
+// a not-found row for each address without a result. Addresses match
+// case-insensitively, as DedupeEmails and LatestResult compare them.
+func orderLookup(requested []string, found []LookupRow) []LookupRow {
+	byEmail := make(map[string]LookupRow, len(found))
+	for _, row := range found {
+		byEmail[strings.ToLower(row.Email)] = row
+	}
+
+	out := make([]LookupRow, len(requested))
+	for i, email := range requested {
+		if row, ok := byEmail[strings.ToLower(email)]; ok {
+			out[i] = row
+		} else {
+			out[i] = LookupRow{Email: email}
+		}
+	}
+	return out
+}
+
+// ErrResultNotFound is returned by LatestResult for an address no job has
+// verified.
+var ErrResultNotFound = errors.New("no stored result for this address")
+
+// StoredResult is one address's stored verification and the job it came from.
+type StoredResult struct {
+	JobID  string          `json:"job_id"`
+	Email  string          `json:"email"`
+	Score  int             `json:"score"`
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// LatestResult returns the most recently stored result for email across all
+// jobs, matching the address case-insensitively. Result ids grow with
+// insertion, so the highest one is the latest verification.
+func LatestResult(ctx context.Context, email string) (StoredResult, error) {
+	var r StoredResult
+	err := DB.QueryRow(ctx, `
+		SELECT job_id, email, score, COALESCE(status, data->>'status', ''), data
+		FROM   results
+		WHERE  lower(email) = lower($1)
+		ORDER  BY id DESC
+		LIMIT  1
+	`, strings.TrimSpace(email)).Scan(&r.JobID, &r.Email, &r.Score, &r.Status, &r.Data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r, ErrResultNotFound
+	}
+	return r, err
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrderLookup(t *testing.T) {
+	found := []LookupRow{
+		{Email: "b@example.com", Found: true, Score: 40, Data: json.RawMessage(`{"v":1}`)},
+		{Email: "a@example.com", Found: true, Score: 95},
+		// A re-verification of b later in the job supersedes the first.
+		{Email: "b@example.com", Found: true, Score: 55, Data: json.RawMessage(`{"v":2}`)},
+	}
+
+	// Addresses match regardless of case.
+	got := orderLookup([]string{"A@Example.com", "missing@example.com", "b@example.com"}, found)
+	want := []LookupRow{
+		{Email: "a@example.com", Found: true, Score: 95},
+		{Email: "missing@example.com"},
+		{Email: "b@example.com", Found: true, Score: 55, Data: json.RawMessage(`{"v":2}`)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orderLookup:\n got  %+v\n want %+v", got, want)
+	}
+}
+
+func TestResultsQuerySQL(t *testing.T) {
+	minScore := 70
+	keyset, args := ResultsQuery{JobID: "job", Status: "valid", MinScore: &minScore, AfterID: 42, Offset: 500, Limit: 100}.sql()
+	if !strings.Contains(keyset, "id > $4") || strings.Contains(keyset, "OFFSET") {
+		t.Errorf("keyset query should seek past the cursor without OFFSET:\n%s", keyset)
+	}
+	if want := []any{"job", "valid", 70, int64(42), 100}; !reflect.DeepEqual(args, want) {
+		t.Errorf("keyset args %v != %v", args, want)
+	}
+
+	offset, args := ResultsQuery{JobID: "job", Offset: 500, Limit: 100}.sql()
+	if strings.Contains(offset, "id >") || !strings.Contains(offset, "OFFSET $3") {
+		t.Errorf("offset query:\n%s", offset)
+	}
+	if want := []any{"job", 100, 500}; !reflect.DeepEqual(args, want) {
+		t.Errorf("offset args %v != %v", args, want)
+	}
+}
+
+// TestKeysetMatchesOffset walks one job both ways against a real database and
+// expects identical pages. It needs a disposable Postgres named by
+// MAILVETTER_TEST_DB_URL and is skipped without one.
+func TestKeysetMatchesOffset(t *testing.T) {
+	dbURL := os.Getenv("MAILVETTER_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("MAILVETTER_TEST_DB_URL not set")
+	}
+	if err := Init(dbURL); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer DB.Close()
+
+	ctx := context.Background()
+	jobID := fmt.Sprintf("keyset-test-%d", time.Now().UnixNano())
+	if _, err := DB.Exec(ctx, `INSERT INTO jobs (id, status, total_count) VALUES ($1, 'completed', 53)`, jobID); err != nil {
+		t.Fatalf("insert job: %v", err)
+	}
+	t.Cleanup(func() {
+		DB.Exec(ctx, `DELETE FROM results WHERE job_id = $1`, jobID)
+		DB.Exec(ctx, `DELETE FROM jobs WHERE id = $1`, jobID)
+	})
+	for i := 0; i < 53; i++ {
+		status := "valid"
+		if i%3 == 0 {
+			status = "catch_all"
+		}
+		if _, err := DB.Exec(ctx,
+			`INSERT INTO results (job_id, email, score, status, data) VALUES ($1, $2, $3, $4, '{}')`,
+			jobID, fmt.Sprintf("user%02d@example.com", i), i, status,
+		); err != nil {
+			t.Fatalf("insert result: %v", err)
+		}
+	}
+
+	for _, filter := range []ResultsQuery{{}, {Status: "catch_all"}} {
+		const pageSize = 10
+		var byOffset, byKeyset []ResultRow
+
+		for page := 0; ; page++ {
+			rows, err := FetchResults(ctx, ResultsQuery{JobID: jobID, Status: filter.Status, Offset: page * pageSize, Limit: pageSize})
+			if err != nil {
+				t.Fatalf("offset page %d: %v", page, err)
+			}
+			byOffset = append(byOffset, rows...)
+			if len(rows) < pageSize {
+				break
+			}
+		}
+
+		var after int64
+		for {
+			rows, err := FetchResults(ctx, ResultsQuery{JobID: jobID, Status: filter.Status, AfterID: after, Limit: pageSize})
+			if err != nil {
+				t.Fatalf("keyset after %d: %v", after, err)
+			}
+			byKeyset = append(byKeyset, rows...)
+			if len(rows) < pageSize {
+				break
+			}
+			after = rows[len(rows)-1].ID
+		}
+
+		if len(byOffset) == 0 || !reflect.DeepEqual(byKeyset, byOffset) {
+			t.Errorf("status %q: keyset returned %d rows, offset %d, or they differ", filter.Status, len(byKeyset), len(byOffset))
+		}
+	}
+}
+
+// TestLatestResult needs a disposable Postgres named by
+// MAILVETTER_TEST_DB_URL and is skipped without one.
+func TestLatestResult(t *testing.T) {
+	dbURL := os.Getenv("MAILVETTER_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("MAILVETTER_TEST_DB_URL not set")
+	}
+	if err := Init(dbURL); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer DB.Close()
+
+	ctx := context.Background()
+	prefix := fmt.Sprintf("latest-test-%d", time.Now().UnixNano())
+	email := prefix + "@example.com"
+	t.Cleanup(func() {
+		DB.Exec(ctx, `DELETE FROM results WHERE job_id LIKE $1`, prefix+"%")
+		DB.Exec(ctx, `DELETE FROM jobs WHERE id LIKE $1`, prefix+"%")
+	})
+	for i, status := range []string{"unknown", "valid"} {
+		jobID := fmt.Sprintf("%s-%d", prefix, i)
+		if _, err := DB.Exec(ctx, `INSERT INTO jobs (id, status, total_count) VALUES ($1, 'completed', 1)`, jobID); err != nil {
+			t.Fatalf("insert job: %v", err)
+		}
+		if _, err := DB.Exec(ctx,
+			`INSERT INTO results (job_id, email, score, status, data) VALUES ($1, $2, $3, $4, '{}')`,
+			jobID, email, i*90, status,
+		); err != nil {
+			t.Fatalf("insert result: %v", err)
+		}
+	}
+
+	got, err := LatestResult(ctx, strings.ToUpper(email))
+	if err != nil {
+		t.Fatalf("LatestResult: %v", err)
+	}
+	if got.JobID != prefix+"-1" || got.Status != "valid" || got.Score != 90 {
+		t.Errorf("LatestResult = %+v, want the second job's valid result", got)
+	}
+
+	if _, err := LatestResult(ctx, "never-"+email); !errors.Is(err, ErrResultNotFound) {
+		t.Errorf("unverified address: err = %v, want ErrResultNotFound", err)
+	}
+}
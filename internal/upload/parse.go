@@ -0,0 +1,134 @@
+// Package upload reads the address lists submitted to /upload.
+package upload
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the layout of an uploaded list.
+type Format string
+
+const (
+	// FormatCSV has the address in the first column, with an optional
+	// header row.
+	FormatCSV Format = "csv"
+	// FormatJSON is an array of addresses or of {"email": ...} objects.
+	FormatJSON Format = "json"
+	// FormatText has one address per line.
+	FormatText Format = "text"
+)
+
+// DetectFormat picks the Format for an uploaded file from its name and the
+// Content-Type its part was sent with. A recognised extension wins, since
+// clients label files inconsistently (curl sends application/octet-stream
+// for everything); anything unrecognised is read as CSV.
+func DetectFormat(contentType, filename string) Format {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	case ".txt":
+		return FormatText
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		return FormatJSON
+	case "text/plain":
+		return FormatText
+	}
+	return FormatCSV
+}
+
+// ParseEmails reads the addresses in r, laid out as format, skipping blank
+// entries. Addresses are returned as written, in file order.
+func ParseEmails(r io.Reader, format Format) ([]string, error) {
+	switch format {
+	case FormatJSON:
+		return parseJSON(r)
+	case FormatText:
+		return parseText(r)
+	default:
+		return parseCSV(r)
+	}
+}
+
+// parseCSV takes the first column of each row, dropping a leading header.
+func parseCSV(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	var emails []string
+	isFirstRow := true
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) > 0 {
+			val := record[0]
+			// Skip the row if it's the first row and looks like a header
+			if isFirstRow && (val == "email" || val == "Email" || val == "Email Address") {
+				isFirstRow = false
+				continue
+			}
+			isFirstRow = false
+
+			if val != "" {
+				emails = append(emails, val)
+			}
+		}
+	}
+	return emails, nil
+}
+
+func parseJSON(r io.Reader) ([]string, error) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, err
+	}
+
+	emails := make([]string, 0, len(items))
+	for i, item := range items {
+		var email string
+		if err := json.Unmarshal(item, &email); err != nil {
+			var obj struct {
+				Email *string `json:"email"`
+			}
+			if err := json.Unmarshal(item, &obj); err != nil || obj.Email == nil {
+				return nil, fmt.Errorf("item %d is neither an address nor an object with an \"email\" field", i)
+			}
+			email = *obj.Email
+		}
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails, nil
+}
+
+func parseText(r io.Reader) ([]string, error) {
+	var emails []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			emails = append(emails, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading text upload: %w", err)
+	}
+	return emails, nil
+}
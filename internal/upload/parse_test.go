@@ -0,0 +1,57 @@
+package upload
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		contentType, filename string
+		want                  Format
+	}{
+		{"text/csv", "list.csv", FormatCSV},
+		{"application/octet-stream", "list.json", FormatJSON},
+		{"application/octet-stream", "LIST.TXT", FormatText},
+		{"application/json; charset=utf-8", "list", FormatJSON},
+		{"text/plain", "export.dat", FormatText},
+		{"text/plain", "list.csv", FormatCSV},
+		{"", "", FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.contentType, tt.filename); got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %s, want %s", tt.contentType, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestParseEmails(t *testing.T) {
+	want := []string{"a@example.com", "b@example.com"}
+	tests := []struct {
+		name   string
+		format Format
+		body   string
+	}{
+		{"csv with header", FormatCSV, "email,name\na@example.com,A\n,blank\nb@example.com,B\n"},
+		{"json strings", FormatJSON, `["a@example.com", "", "b@example.com"]`},
+		{"json objects", FormatJSON, `[{"email": "a@example.com", "name": "A"}, {"email": " b@example.com "}]`},
+		{"text", FormatText, "a@example.com\r\n\n  b@example.com  \n"},
+	}
+	for _, tt := range tests {
+		got, err := ParseEmails(strings.NewReader(tt.body), tt.format)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, want)
+		}
+	}
+
+	for _, body := range []string{`{"email": "a@example.com"}`, `[42]`, `[{"name": "A"}]`, `["a@example.com"`} {
+		if _, err := ParseEmails(strings.NewReader(body), FormatJSON); err == nil {
+			t.Errorf("ParseEmails(%s) accepted malformed JSON", body)
+		}
+	}
+}
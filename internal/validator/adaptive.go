@@ -0,0 +1,103 @@
+package validator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/lookup"
+)
+
+// AdaptiveTimeouts caps each verification's SMTP phase at a deadline learned
+// from the provider's recent latency, so one tarpitting provider cannot blow
+// out a job's tail latency while fast providers keep a tight bound. Off by
+// default; enable with ADAPTIVE_TIMEOUTS=true. The learned deadline is
+// clamped to [ADAPTIVE_TIMEOUT_MIN, ADAPTIVE_TIMEOUT_MAX].
+var (
+	AdaptiveTimeouts   = config.Bool("ADAPTIVE_TIMEOUTS", false)
+	AdaptiveTimeoutMin = config.Duration("ADAPTIVE_TIMEOUT_MIN", 5*time.Second)
+	AdaptiveTimeoutMax = config.Duration("ADAPTIVE_TIMEOUT_MAX", 90*time.Second)
+)
+
+const (
+	// latencyWindow is how many recent SMTP phases are kept per provider.
+	latencyWindow = 200
+	// minLatencySamples is how many observations a provider needs before
+	// its deadline is trusted over the default.
+	minLatencySamples = 20
+	// adaptiveHeadroom multiplies the provider's p95 latency, leaving room
+	// for an ordinarily slow answer.
+	adaptiveHeadroom = 2
+	// maxLatencyProviders bounds the tracker; generic MX hosts are tracked
+	// individually and would otherwise grow it without limit.
+	maxLatencyProviders = 10000
+)
+
+// latencyTracker keeps a sliding window of SMTP phase durations per provider.
+type latencyTracker struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+	next    map[string]int
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{samples: map[string][]time.Duration{}, next: map[string]int{}}
+}
+
+// providerLatency is the process-wide tracker the SMTP collector feeds.
+var providerLatency = newLatencyTracker()
+
+func (t *latencyTracker) observe(provider string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	buf, ok := t.samples[provider]
+	if !ok && len(t.samples) >= maxLatencyProviders {
+		return
+	}
+	if len(buf) < latencyWindow {
+		t.samples[provider] = append(buf, d)
+		return
+	}
+	buf[t.next[provider]] = d
+	t.next[provider] = (t.next[provider] + 1) % latencyWindow
+}
+
+// timeout returns the adaptive deadline for provider and how many samples it
+// was derived from; the deadline is 0 until there are enough samples.
+func (t *latencyTracker) timeout(provider string) (time.Duration, int) {
+	t.mu.Lock()
+	samples := append([]time.Duration(nil), t.samples[provider]...)
+	t.mu.Unlock()
+	return deriveTimeout(samples), len(samples)
+}
+
+// deriveTimeout turns observed latencies into a deadline: the p95 times
+// adaptiveHeadroom, clamped to the configured bounds. It returns 0 when there
+// are too few samples to trust.
+func deriveTimeout(samples []time.Duration) time.Duration {
+	if len(samples) < minLatencySamples {
+		return 0
+	}
+	d := percentile(samples, 0.95) * adaptiveHeadroom
+	return min(max(d, AdaptiveTimeoutMin), AdaptiveTimeoutMax)
+}
+
+// percentile returns the nearest-rank p-th percentile of samples, which must
+// be non-empty. samples is sorted in place.
+func percentile(samples []time.Duration, p float64) time.Duration {
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	rank := int(p*float64(len(samples)) + 0.999999)
+	return samples[min(max(rank, 1), len(samples))-1]
+}
+
+// latencyProvider is the key SMTP latency is tracked under: the canonical
+// provider for known infrastructure, else the MX host itself.
+func latencyProvider(mxHost string) string {
+	if p := lookup.ProviderForMX(mxHost); p != "generic" {
+		return p
+	}
+	return strings.ToLower(mxHost)
+}
@@ -0,0 +1,64 @@
+package validator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeriveTimeout(t *testing.T) {
+	savedMin, savedMax := AdaptiveTimeoutMin, AdaptiveTimeoutMax
+	defer func() { AdaptiveTimeoutMin, AdaptiveTimeoutMax = savedMin, savedMax }()
+	AdaptiveTimeoutMin, AdaptiveTimeoutMax = 5*time.Second, 90*time.Second
+
+	// series returns n samples of base with the last `slow` of them at slowD.
+	series := func(n int, base time.Duration, slow int, slowD time.Duration) []time.Duration {
+		s := make([]time.Duration, n)
+		for i := range s {
+			s[i] = base
+			if i >= n-slow {
+				s[i] = slowD
+			}
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		want    time.Duration
+	}{
+		{"too few samples", series(minLatencySamples-1, 2*time.Second, 0, 0), 0},
+		{"fast provider", series(100, 3*time.Second, 0, 0), 6 * time.Second},
+		{"rare outliers sit above p95", series(100, 3*time.Second, 4, 60*time.Second), 6 * time.Second},
+		{"slow provider", series(100, 20*time.Second, 10, 30*time.Second), 60 * time.Second},
+		{"clamped to minimum", series(100, 500*time.Millisecond, 0, 0), 5 * time.Second},
+		{"clamped to maximum", series(100, 70*time.Second, 0, 0), 90 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := deriveTimeout(tt.samples); got != tt.want {
+			t.Errorf("%s: deriveTimeout() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyTrackerSlidingWindow(t *testing.T) {
+	tr := newLatencyTracker()
+	for i := 0; i < latencyWindow; i++ {
+		tr.observe("slowmail", 50*time.Second)
+	}
+	if d, n := tr.timeout("slowmail"); n != latencyWindow || d != AdaptiveTimeoutMax {
+		t.Fatalf("after slow history: timeout=%s samples=%d", d, n)
+	}
+
+	// The provider speeds up; a full window of fast samples replaces the old.
+	for i := 0; i < latencyWindow; i++ {
+		tr.observe("slowmail", 3*time.Second)
+	}
+	if d, n := tr.timeout("slowmail"); n != latencyWindow || d != max(6*time.Second, AdaptiveTimeoutMin) {
+		t.Errorf("after recovery: timeout=%s samples=%d", d, n)
+	}
+
+	if d, n := tr.timeout("unseen"); d != 0 || n != 0 {
+		t.Errorf("unseen provider: timeout=%s samples=%d", d, n)
+	}
+}
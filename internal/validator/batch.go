@@ -0,0 +1,111 @@
+package validator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/models"
+)
+
+// BatchConcurrency bounds how many addresses one VerifyBatch call verifies at
+// once. The process-wide MaxInFlight cap still applies on top. Set via
+// BATCH_CONCURRENCY.
+var BatchConcurrency = config.Int("BATCH_CONCURRENCY", 10)
+
+// BatchEmailTimeout bounds the verification of each address in a batch, so
+// BatchDuration can promise when the whole batch is done. Set via
+// BATCH_EMAIL_TIMEOUT.
+var BatchEmailTimeout = config.Duration("BATCH_EMAIL_TIMEOUT", 25*time.Second)
+
+// BatchDuration is the longest VerifyBatch can take for n addresses: one
+// BatchEmailTimeout for every BatchConcurrency of them.
+func BatchDuration(n int) time.Duration {
+	waves := (n + BatchConcurrency - 1) / BatchConcurrency
+	return time.Duration(waves) * BatchEmailTimeout
+}
+
+// ReasonMalformed is reported for a batch entry that is not a local@domain
+// address.
+const ReasonMalformed = "malformed_address"
+
+// verifyOne is the per-address verifier VerifyBatch fans out to. It is a
+// variable so tests can substitute a stub with controlled latency.
+var verifyOne = VerifyEmail
+
+// VerifyBatch verifies emails concurrently and returns one result per input,
+// in input order: results[i] always belongs to emails[i], however the
+// verifications interleave. Clients zip results back to their rows by
+// position, so this ordering is part of the contract.
+func VerifyBatch(ctx context.Context, emails []string) []models.ValidationResult {
+	results := make([]models.ValidationResult, len(emails))
+	sem := make(chan struct{}, BatchConcurrency)
+	var wg sync.WaitGroup
+
+	for i, email := range emails {
+		email = strings.TrimSpace(email)
+		at := strings.LastIndex(email, "@")
+		if at <= 0 || at == len(email)-1 {
+			results[i] = models.ValidationResult{
+				Email:          email,
+				Status:         models.StatusInvalid,
+				Reachability:   models.ReachabilityBad,
+				Recommendation: Recommend(models.StatusInvalid, 0, false),
+				Reason:         ReasonMalformed,
+			}
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = models.ValidationResult{
+				Email:          email,
+				Status:         models.StatusUnknown,
+				Recommendation: Recommend(models.StatusUnknown, 0, false),
+				Error:          ctx.Err().Error(),
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, email, domain string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			emailCtx, cancel := context.WithTimeout(ctx, BatchEmailTimeout)
+			defer cancel()
+
+			start := time.Now()
+			res, err := verifyOne(emailCtx, email, domain)
+			res.Email = email
+			res.Duration = time.Since(start).String()
+			if err != nil && res.Error == "" {
+				res.Error = err.Error()
+			}
+			results[i] = res
+		}(i, email, email[at+1:])
+	}
+
+	wg.Wait()
+	return results
+}
+
+// DedupeEmails trims emails and drops repeats, comparing case-insensitively,
+// keeping each address's first occurrence in its original position.
+func DedupeEmails(emails []string) []string {
+	seen := make(map[string]bool, len(emails))
+	unique := make([]string, 0, len(emails))
+	for _, e := range emails {
+		e = strings.TrimSpace(e)
+		key := strings.ToLower(e)
+		if e == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, e)
+	}
+	return unique
+}
@@ -0,0 +1,80 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"mailvetter/internal/models"
+)
+
+func TestVerifyBatchPreservesInputOrder(t *testing.T) {
+	savedVerify, savedConc := verifyOne, BatchConcurrency
+	defer func() { verifyOne, BatchConcurrency = savedVerify, savedConc }()
+	BatchConcurrency = 8
+
+	// Earlier addresses take longer, so completion order is roughly the
+	// reverse of submission order.
+	var emails []string
+	latency := make(map[string]time.Duration)
+	for i := 0; i < 8; i++ {
+		e := fmt.Sprintf("user%d@example.com", i)
+		emails = append(emails, e)
+		latency[e] = time.Duration(8-i) * 10 * time.Millisecond
+	}
+	emails = append(emails[:3], append([]string{"not-an-address"}, emails[3:]...)...)
+
+	verifyOne = func(ctx context.Context, email, domain string) (models.ValidationResult, error) {
+		time.Sleep(latency[email])
+		return models.ValidationResult{Email: email, Status: models.StatusValid, Score: 90}, nil
+	}
+
+	results := VerifyBatch(context.Background(), emails)
+	if len(results) != len(emails) {
+		t.Fatalf("got %d results for %d inputs", len(results), len(emails))
+	}
+	for i, res := range results {
+		if res.Email != emails[i] {
+			t.Errorf("results[%d].Email = %q, expected %q", i, res.Email, emails[i])
+		}
+	}
+	if r := results[3]; r.Status != models.StatusInvalid || r.Reason != ReasonMalformed {
+		t.Errorf("malformed entry: status %q reason %q", r.Status, r.Reason)
+	}
+}
+
+func TestVerifyBatchBoundsEachAddress(t *testing.T) {
+	savedVerify, savedConc, savedTimeout := verifyOne, BatchConcurrency, BatchEmailTimeout
+	defer func() { verifyOne, BatchConcurrency, BatchEmailTimeout = savedVerify, savedConc, savedTimeout }()
+	BatchConcurrency, BatchEmailTimeout = 2, 20*time.Millisecond
+
+	verifyOne = func(ctx context.Context, email, domain string) (models.ValidationResult, error) {
+		<-ctx.Done()
+		return models.ValidationResult{Status: models.StatusUnknown}, ctx.Err()
+	}
+
+	emails := []string{"a@example.com", "b@example.com", "c@example.com"}
+	start := time.Now()
+	results := VerifyBatch(context.Background(), emails)
+	if elapsed := time.Since(start); elapsed > BatchDuration(len(emails))+50*time.Millisecond {
+		t.Errorf("batch took %s, want at most about %s", elapsed, BatchDuration(len(emails)))
+	}
+	for _, r := range results {
+		if r.Error == "" {
+			t.Errorf("%s: expected a timeout error", r.Email)
+		}
+	}
+	if got := BatchDuration(3); got != 40*time.Millisecond {
+		t.Errorf("BatchDuration(3) = %s, want two waves of 20ms", got)
+	}
+}
+
+func TestDedupeEmails(t *testing.T) {
+	got := DedupeEmails([]string{"b@example.com", " Jane@Example.com", "a@example.com", "jane@example.com ", "B@EXAMPLE.COM", "  "})
+	want := []string{"b@example.com", "Jane@Example.com", "a@example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DedupeEmails = %q, want %q", got, want)
+	}
+}
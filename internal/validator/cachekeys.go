@@ -0,0 +1,31 @@
+package validator
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"mailvetter/internal/lookup"
+)
+
+// mxFingerprint returns a short, order-independent hash of a domain's MX host
+// set, or "none" when there are no records.
+func mxFingerprint(records []lookup.MXRecord) string {
+	if len(records) == 0 {
+		return "none"
+	}
+	hosts := make([]string, 0, len(records))
+	for _, mx := range records {
+		hosts = append(hosts, strings.ToLower(mx.Host))
+	}
+	sort.Strings(hosts)
+
+	sum := sha1.Sum([]byte(strings.Join(hosts, ",")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// infraCacheKey versions the infra cache entry by the domain's current MX set.
+func infraCacheKey(domain string, records []lookup.MXRecord) string {
+	return "infra:" + strings.ToLower(domain) + ":" + mxFingerprint(records)
+}
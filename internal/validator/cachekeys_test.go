@@ -0,0 +1,42 @@
+package validator
+
+import (
+	"testing"
+	"time"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/lookup"
+)
+
+func TestInfraCacheKeyChangesWithMX(t *testing.T) {
+	oldMX := []lookup.MXRecord{
+		{Host: "mx1.mail.example.net", Pref: 10},
+		{Host: "mx2.mail.example.net", Pref: 20},
+	}
+	reordered := []lookup.MXRecord{
+		{Host: "MX2.mail.example.net", Pref: 5},
+		{Host: "mx1.mail.example.net", Pref: 10},
+	}
+	newMX := []lookup.MXRecord{
+		{Host: "example-com.mail.protection.outlook.com", Pref: 0},
+	}
+
+	if infraCacheKey("example.com", oldMX) != infraCacheKey("example.com", reordered) {
+		t.Errorf("key must not depend on MX order, preference or case")
+	}
+
+	cache.DomainCache.Set(infraCacheKey("example.com", oldMX), DomainResult{Provider: "generic"}, time.Minute)
+
+	if _, ok := cache.DomainCache.Get(infraCacheKey("example.com", oldMX)); !ok {
+		t.Fatalf("expected a hit with the unchanged MX set")
+	}
+	if _, ok := cache.DomainCache.Get(infraCacheKey("example.com", newMX)); ok {
+		t.Errorf("changed MX records must miss the old infra cache entry")
+	}
+}
+
+func TestMXFingerprintEmpty(t *testing.T) {
+	if fp := mxFingerprint(nil); fp != "none" {
+		t.Errorf("mxFingerprint(nil) = %q, want \"none\"", fp)
+	}
+}
@@ -0,0 +1,99 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/lookup"
+	"mailvetter/internal/proxy"
+)
+
+// resolveMX looks up a domain's MX records. It is a variable so tests can
+// avoid real DNS.
+var resolveMX = lookup.CheckDNS
+
+// Sources reported in CatchAllReport.Source.
+const (
+	CatchAllSourceCache = "cache"
+	CatchAllSourceProbe = "probe"
+)
+
+// GhostProbe is the raw outcome of one RCPT TO probe for a made-up address.
+type GhostProbe struct {
+	Address    string `json:"address"`
+	Accepted   bool   `json:"accepted"`
+	DurationMs int64  `json:"duration_ms"`
+	Response   string `json:"response,omitempty"`
+}
+
+// CatchAllReport explains a domain's catch-all verdict for debugging.
+type CatchAllReport struct {
+	Domain        string       `json:"domain"`
+	MX            string       `json:"mx"`
+	IsCatchAll    bool         `json:"is_catch_all"`
+	Source        string       `json:"source"`
+	TimingDeltaMs int64        `json:"timing_delta_ms"`
+	Probes        []GhostProbe `json:"probes,omitempty"`
+}
+
+// CheckCatchAll runs the catch-all half of the SMTP collector in isolation.
+//
+// Unless fresh is set, a verdict already in the smtp_host cache is returned
+// as-is with Source "cache". Otherwise two ghost addresses are probed via
+// runSmtpProbes — the first standing in for the target — and both responses
+// are reported. The cache is never written here, so debugging a domain cannot
+// change the verdict production traffic sees.
+func CheckCatchAll(ctx context.Context, domain string, fresh bool) (CatchAllReport, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	report := CatchAllReport{Domain: domain}
+
+	mxRecords, err := resolveMX(ctx, domain)
+	if err != nil || len(mxRecords) == 0 {
+		return report, fmt.Errorf("no usable MX for %s: %v", domain, err)
+	}
+	sort.Slice(mxRecords, func(i, j int) bool { return mxRecords[i].Pref < mxRecords[j].Pref })
+	report.MX = mxRecords[0].Host
+
+	if !fresh {
+		if val, ok := cache.DomainCache.Get("smtp_host:" + report.MX + ":" + domain); ok {
+			report.IsCatchAll = val.(SmtpHostResult).IsCatchAll
+			report.Source = CatchAllSourceCache
+			return report, nil
+		}
+	}
+
+	var pinnedProxy *url.URL
+	if proxy.Enabled() {
+		pinnedProxy = proxy.Global.Next()
+	}
+	first := generateGhostAddress() + "@" + domain
+
+	r := runSmtpProbes(ctx, first, domain, report.MX, pinnedProxy)
+	report.Source = CatchAllSourceProbe
+	report.IsCatchAll = r.IsCatchAll
+	report.TimingDeltaMs = r.Delta
+	report.Probes = append(report.Probes, toGhostProbe(r.Target))
+	if r.Ghost.Address != "" {
+		report.Probes = append(report.Probes, toGhostProbe(r.Ghost))
+	}
+
+	return report, ctx.Err()
+}
+
+func toGhostProbe(o probeOutcome) GhostProbe {
+	gp := GhostProbe{
+		Address:    o.Address,
+		Accepted:   o.Accepted,
+		DurationMs: o.Duration.Milliseconds(),
+	}
+	if o.Err != nil {
+		gp.Response = o.Err.Error()
+	} else if o.Accepted {
+		gp.Response = "250 OK"
+	}
+	return gp
+}
@@ -0,0 +1,126 @@
+package validator
+
+import (
+	"context"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/lookup"
+)
+
+func stubSMTP(t *testing.T, mx string, probe func(email string) (bool, time.Duration, error)) {
+	t.Helper()
+	savedMX, savedProbe := resolveMX, smtpProbe
+	t.Cleanup(func() { resolveMX, smtpProbe = savedMX, savedProbe })
+
+	resolveMX = func(ctx context.Context, domain string) ([]lookup.MXRecord, error) {
+		return []lookup.MXRecord{{Host: mx, Pref: 10}}, nil
+	}
+	smtpProbe = func(ctx context.Context, mxHost, email string, pURL *url.URL) (bool, time.Duration, error) {
+		return probe(email)
+	}
+}
+
+func TestCheckCatchAllProbe(t *testing.T) {
+	stubSMTP(t, "mx.acceptall.example", func(email string) (bool, time.Duration, error) {
+		return true, 120 * time.Millisecond, nil
+	})
+
+	report, err := CheckCatchAll(context.Background(), "acceptall.example", false)
+	if err != nil {
+		t.Fatalf("CheckCatchAll failed: %v", err)
+	}
+	if !report.IsCatchAll {
+		t.Errorf("expected catch-all verdict")
+	}
+	if report.Source != CatchAllSourceProbe {
+		t.Errorf("Source %q != %q", report.Source, CatchAllSourceProbe)
+	}
+	if len(report.Probes) != 2 {
+		t.Fatalf("expected 2 ghost probes, got %d", len(report.Probes))
+	}
+	for _, p := range report.Probes {
+		if !p.Accepted || !strings.HasSuffix(p.Address, "@acceptall.example") {
+			t.Errorf("unexpected probe %+v", p)
+		}
+	}
+}
+
+func TestCheckCatchAllRejectingDomain(t *testing.T) {
+	stubSMTP(t, "mx.strict.example", func(email string) (bool, time.Duration, error) {
+		return false, 80 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+	})
+
+	report, err := CheckCatchAll(context.Background(), "strict.example", false)
+	if err != nil {
+		t.Fatalf("CheckCatchAll failed: %v", err)
+	}
+	if report.IsCatchAll {
+		t.Errorf("expected non-catch-all verdict")
+	}
+	if len(report.Probes) != 1 || !strings.Contains(report.Probes[0].Response, "550") {
+		t.Errorf("expected a single rejected probe with its response, got %+v", report.Probes)
+	}
+}
+
+func TestCheckCatchAllFromCache(t *testing.T) {
+	probed := false
+	stubSMTP(t, "mx.cached.example", func(email string) (bool, time.Duration, error) {
+		probed = true
+		return true, 0, nil
+	})
+
+	cache.DomainCache.Set("smtp_host:mx.cached.example:cached.example", SmtpHostResult{IsCatchAll: true}, time.Minute)
+
+	report, err := CheckCatchAll(context.Background(), "cached.example", false)
+	if err != nil {
+		t.Fatalf("CheckCatchAll failed: %v", err)
+	}
+	if report.Source != CatchAllSourceCache || !report.IsCatchAll {
+		t.Errorf("expected cached catch-all verdict, got %+v", report)
+	}
+	if probed {
+		t.Errorf("cached verdict must not trigger SMTP probes")
+	}
+
+	report, _ = CheckCatchAll(context.Background(), "cached.example", true)
+	if report.Source != CatchAllSourceProbe || !probed {
+		t.Errorf("fresh=true must bypass the cache, got %+v", report)
+	}
+}
+
+func TestSmtpMessagePropagatesFrom550(t *testing.T) {
+	const reply = "5.1.1 User unknown in virtual mailbox table"
+	stubSMTP(t, "mx.example.org", func(email string) (bool, time.Duration, error) {
+		return false, 50 * time.Millisecond, &textproto.Error{Code: 550, Msg: reply}
+	})
+
+	report := runSmtpProbes(context.Background(), "nobody@example.org", "example.org", "mx.example.org", nil)
+	if report.Status != 550 {
+		t.Fatalf("Status %d != 550", report.Status)
+	}
+	if got, want := report.Target.message(), "550 "+reply; got != want {
+		t.Errorf("SMTP message %q != %q", got, want)
+	}
+}
+
+func TestSmtpMessageEmptyWhenAccepted(t *testing.T) {
+	stubSMTP(t, "mx.example.org", func(email string) (bool, time.Duration, error) {
+		if strings.HasPrefix(email, "real@") {
+			return true, 50 * time.Millisecond, nil
+		}
+		return false, 50 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 no such user"}
+	})
+
+	report := runSmtpProbes(context.Background(), "real@example.org", "example.org", "mx.example.org", nil)
+	if report.Status != 250 {
+		t.Fatalf("Status %d != 250", report.Status)
+	}
+	if msg := report.Target.message(); msg != "" {
+		t.Errorf("accepted target should carry no rejection message, got %q", msg)
+	}
+}
@@ -0,0 +1,123 @@
+package validator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"mailvetter/internal/models"
+)
+
+// scoreExplanations phrases each fixed ScoreBreakdown key for a reader who
+// has never seen the key names. Keys whose sentence depends on the analysis
+// are handled in explainSignal.
+var scoreExplanations = map[string]string{
+	"base_smtp_valid":            "Mail server accepted the mailbox",
+	"base_hard_bounce":           "Mail server rejected the mailbox",
+	"base_osint_only":            "Mail server not probed; scored on online footprint only",
+	"base_catch_all":             "Domain accepts mail for any address (catch-all)",
+	"base_unknown":               "Mail server gave no definite answer",
+	"p0_vrfy_verified":           "Mail server confirmed the mailbox exists",
+	"correction_o365_zombie":     "Microsoft 365 account without a mailbox license",
+	"p0_teams_identity":          "Microsoft Teams account found",
+	"p0_sharepoint_license":      "Active SharePoint license found",
+	"p0_calendar":                "Google Calendar found",
+	"p2_adobe":                   "Adobe account found",
+	"p2_github":                  "GitHub account found",
+	"p2_gravatar":                "Gravatar profile found",
+	"p2_linkedin":                "LinkedIn profile found",
+	"p2_slack":                   "Member of the company's Slack workspace",
+	"p3_slack_workspace":         "Domain has a Slack workspace",
+	"p2_twitter":                 "Twitter/X account found",
+	"p2_spotify":                 "Spotify account found",
+	"p1_saas_usage":              "Domain is verified with business software services",
+	"p2_spf":                     "Domain publishes an SPF record",
+	"penalty_spf_over_limit":     "Domain's SPF record is broken (too many lookups)",
+	"p2_dmarc":                   "Domain publishes a DMARC policy",
+	"p2_greylisted":              "Mail server runs greylisting anti-spam",
+	"p3_tls13":                   "Mail server supports TLS 1.3",
+	"p3_website":                 "Domain has a live website",
+	"p2_timing_strong":           "Mail server response timing strongly suggests a real mailbox",
+	"p2_timing_weak":             "Mail server response timing suggests a real mailbox",
+	"penalty_high_entropy":       "Address looks randomly generated",
+	"penalty_role_account":       "Role address rather than a person (e.g. info@, sales@)",
+	"penalty_parked_domain":      "Domain is parked",
+	"penalty_mixed_script":       "Domain mixes alphabets, a sign of a lookalike domain",
+	"resolution_catchall_strong": "Strong evidence the mailbox exists on this catch-all domain",
+	"resolution_catchall_medium": "Some evidence the mailbox exists on this catch-all domain",
+	"resolution_catchall_empty":  "No catch-all evidence",
+	"penalty_o365_ghost":         "No Microsoft 365 footprint for this catch-all address",
+	"resolution_unknown_strong":  "Strong evidence the mailbox exists",
+	"resolution_unknown_medium":  "Some evidence the mailbox exists",
+	"resolution_unknown_infra":   "Domain has strong mail infrastructure",
+}
+
+// ExplainScore turns breakdown into one sentence per signal, with its weight,
+// e.g. "Active SharePoint license found (+60)". Sentences are ordered by
+// absolute weight, largest first, then by key. analysis supplies details such
+// as the breach count or domain age; it should be the analysis breakdown was
+// computed from.
+func ExplainScore(breakdown map[string]float64, analysis models.RiskAnalysis) []string {
+	keys := make([]string, 0, len(breakdown))
+	for k := range breakdown {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		wi, wj := math.Abs(breakdown[keys[i]]), math.Abs(breakdown[keys[j]])
+		if wi != wj {
+			return wi > wj
+		}
+		return keys[i] < keys[j]
+	})
+
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = fmt.Sprintf("%s (%+g)", explainSignal(k, analysis), breakdown[k])
+	}
+	return out
+}
+
+func explainSignal(key string, analysis models.RiskAnalysis) string {
+	switch key {
+	case "p1_historical_breach":
+		if analysis.BreachCount == 1 {
+			return "Address appears in 1 known data breach"
+		}
+		return fmt.Sprintf("Address appears in %d known data breaches", analysis.BreachCount)
+	case "p1_enterprise_sec":
+		if analysis.MxProvider != "" {
+			return fmt.Sprintf("Mail is filtered by an enterprise security gateway (%s)", analysis.MxProvider)
+		}
+		return "Mail is filtered by an enterprise security gateway"
+	case "p3_mx_redundancy":
+		return fmt.Sprintf("Mail is routed through %d providers for redundancy", len(analysis.MxProviders))
+	case "p2_domain_age_vetted", "p2_domain_age_established":
+		return "Domain was registered " + ageAgo(analysis.DomainAgeDays)
+	case "penalty_new_domain":
+		return "Domain was registered only " + ageAgo(analysis.DomainAgeDays)
+	case "penalty_likely_disposable":
+		return "Looks like a throwaway domain: brand new, accepts any address, no footprint"
+	case "p3_registrar_reputation":
+		return fmt.Sprintf("Registrar reputation (%s)", analysis.Registrar)
+	case "p3_tld_reputation":
+		return fmt.Sprintf("Reputation of the .%s domain ending", strings.ToLower(analysis.TLD))
+	}
+	if s, ok := scoreExplanations[key]; ok {
+		return s
+	}
+	return key
+}
+
+// ageAgo phrases a domain age in days as "3 years ago" or "12 days ago".
+func ageAgo(days int) string {
+	switch {
+	case days >= 730:
+		return fmt.Sprintf("%d years ago", days/365)
+	case days >= 365:
+		return "1 year ago"
+	case days == 1:
+		return "1 day ago"
+	}
+	return fmt.Sprintf("%d days ago", days)
+}
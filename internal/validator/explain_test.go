@@ -0,0 +1,59 @@
+package validator
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"mailvetter/internal/models"
+)
+
+func TestExplainScore(t *testing.T) {
+	breakdown := map[string]float64{
+		"base_catch_all":            30,
+		"p0_sharepoint_license":     60,
+		"resolution_catchall_empty": -20,
+		"p1_historical_breach":      40,
+		"p2_spf":                    5,
+	}
+	analysis := models.RiskAnalysis{BreachCount: 3}
+
+	got := ExplainScore(breakdown, analysis)
+	want := []string{
+		"Active SharePoint license found (+60)",
+		"Address appears in 3 known data breaches (+40)",
+		"Domain accepts mail for any address (catch-all) (+30)",
+		"No catch-all evidence (-20)",
+		"Domain publishes an SPF record (+5)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExplainScore =\n%q\nwant\n%q", got, want)
+	}
+}
+
+// Every key CalculateRobustScore can emit should read as a sentence, not fall
+// back to the raw key.
+func TestExplainScoreCoversBreakdownKeys(t *testing.T) {
+	src, err := os.ReadFile("scoring.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := regexp.MustCompile(`"((?:base|p\d|correction|penalty|resolution)_[a-z0-9_]+)"`).FindAllStringSubmatch(string(src), -1)
+	if len(keys) == 0 {
+		t.Fatal("found no breakdown keys in scoring.go")
+	}
+	for _, k := range keys {
+		if got := explainSignal(k[1], models.RiskAnalysis{}); got == k[1] {
+			t.Errorf("no explanation for %q", k[1])
+		}
+	}
+}
+
+func TestAgeAgo(t *testing.T) {
+	for days, want := range map[int]string{1: "1 day ago", 12: "12 days ago", 400: "1 year ago", 2000: "5 years ago"} {
+		if got := ageAgo(days); got != want {
+			t.Errorf("ageAgo(%d) = %q, want %q", days, got, want)
+		}
+	}
+}
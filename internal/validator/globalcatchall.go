@@ -0,0 +1,128 @@
+package validator
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/config"
+	"mailvetter/internal/lookup"
+)
+
+// Some hosting platforms answer 250 to every RCPT regardless of domain. Once
+// an MX host has accepted ghost addresses for GlobalAcceptMinDomains unrelated
+// domains within GlobalAcceptWindow, and for at least GlobalAcceptMinRatio of
+// all the domains probed through it in that window, it is treated as a global
+// catch-all and further addresses behind it skip SMTP probing entirely.
+//
+// The ratio is what keeps shared infrastructure out: a host serving many
+// tenants sees catch-all tenants and strict ones alike, so its rejections
+// outweigh the accepts however many of the latter pile up. Hosts of the named
+// mail providers and gateways (see lookup.ProviderForMX) are multi-tenant by
+// definition and never learned at all.
+//
+// Recognition is not trusted forever: once the newest accept is older than
+// GlobalAcceptRevalidate, the next address is probed normally, which records
+// either a fresh accept or a rejection.
+var (
+	GlobalAcceptMinDomains = config.Int("GLOBAL_CATCHALL_MIN_DOMAINS", 5)
+	GlobalAcceptMinRatio   = config.Float("GLOBAL_CATCHALL_MIN_RATIO", 0.9)
+	GlobalAcceptWindow     = config.Duration("GLOBAL_CATCHALL_WINDOW", 24*time.Hour)
+	GlobalAcceptRevalidate = config.Duration("GLOBAL_CATCHALL_REVALIDATE", 6*time.Hour)
+)
+
+// globalAcceptRecord is the cached observation set for one MX host: for each
+// domain probed through it, whether its ghost address was last accepted, and
+// when.
+type globalAcceptRecord struct {
+	Domains map[string]ghostVerdict
+}
+
+type ghostVerdict struct {
+	At       time.Time
+	Accepted bool
+}
+
+// globalAcceptMu serialises read-modify-write of the cached records.
+var globalAcceptMu sync.Mutex
+
+func globalAcceptKey(mxHost string) string {
+	return "global_accept:" + strings.ToLower(mxHost)
+}
+
+// sharedMXHost reports whether mxHost belongs to a named multi-tenant mail
+// provider or gateway, whose per-tenant policies say nothing about the host.
+func sharedMXHost(mxHost string) bool {
+	return lookup.ProviderForMX(mxHost) != "generic"
+}
+
+// recordGhostAccept notes that mxHost accepted a ghost address for domain.
+func recordGhostAccept(mxHost, domain string, now time.Time) {
+	recordGhostVerdict(mxHost, domain, true, now)
+}
+
+// recordGhostReject notes that mxHost rejected a ghost address for domain,
+// which counts against the host being a global catch-all.
+func recordGhostReject(mxHost, domain string, now time.Time) {
+	recordGhostVerdict(mxHost, domain, false, now)
+}
+
+// recordGhostVerdict stores the latest ghost verdict for domain on mxHost.
+// Observations older than GlobalAcceptWindow decay out of the record, and the
+// whole record expires from the cache once the host goes unprobed.
+func recordGhostVerdict(mxHost, domain string, accepted bool, now time.Time) {
+	if sharedMXHost(mxHost) {
+		return
+	}
+	globalAcceptMu.Lock()
+	defer globalAcceptMu.Unlock()
+
+	key := globalAcceptKey(mxHost)
+	rec := globalAcceptRecord{Domains: make(map[string]ghostVerdict)}
+	if val, ok := cache.DomainCache.Get(key); ok {
+		for d, v := range val.(globalAcceptRecord).Domains {
+			if now.Sub(v.At) <= GlobalAcceptWindow {
+				rec.Domains[d] = v
+			}
+		}
+	}
+	rec.Domains[strings.ToLower(domain)] = ghostVerdict{At: now, Accepted: accepted}
+
+	cache.DomainCache.Set(key, rec, GlobalAcceptWindow)
+}
+
+// isGlobalCatchAll reports whether mxHost has accepted ghost addresses for
+// enough distinct domains, and for a high enough share of the domains probed
+// through it, within the decay window, and was last seen accepting recently
+// enough to be trusted without re-probing.
+func isGlobalCatchAll(mxHost string, now time.Time) bool {
+	if sharedMXHost(mxHost) {
+		return false
+	}
+	globalAcceptMu.Lock()
+	defer globalAcceptMu.Unlock()
+
+	val, ok := cache.DomainCache.Get(globalAcceptKey(mxHost))
+	if !ok {
+		return false
+	}
+
+	fresh, accepted := 0, 0
+	var newest time.Time
+	for _, v := range val.(globalAcceptRecord).Domains {
+		if now.Sub(v.At) > GlobalAcceptWindow {
+			continue
+		}
+		fresh++
+		if v.Accepted {
+			accepted++
+			if v.At.After(newest) {
+				newest = v.At
+			}
+		}
+	}
+	return accepted >= GlobalAcceptMinDomains &&
+		float64(accepted) >= GlobalAcceptMinRatio*float64(fresh) &&
+		now.Sub(newest) <= GlobalAcceptRevalidate
+}
@@ -0,0 +1,155 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mailvetter/internal/cache"
+)
+
+func TestGlobalCatchAllRecognition(t *testing.T) {
+	savedMin, savedWindow := GlobalAcceptMinDomains, GlobalAcceptWindow
+	defer func() { GlobalAcceptMinDomains, GlobalAcceptWindow = savedMin, savedWindow }()
+	GlobalAcceptMinDomains = 3
+	GlobalAcceptWindow = time.Hour
+
+	mx := "mx.acceptall-hosting.example"
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// The same domain accepting repeatedly is per-domain catch-all, not global.
+	for i := 0; i < 5; i++ {
+		recordGhostAccept(mx, "one.example", now)
+	}
+	if isGlobalCatchAll(mx, now) {
+		t.Fatalf("a single domain must not mark the host as global catch-all")
+	}
+
+	recordGhostAccept(mx, "two.example", now)
+	recordGhostAccept(mx, "three.example", now)
+	if !isGlobalCatchAll(mx, now) {
+		t.Fatalf("expected host to be recognised after 3 unrelated domains")
+	}
+
+	if isGlobalCatchAll("mx.other.example", now) {
+		t.Errorf("unrelated host must not be recognised")
+	}
+
+	// Observations decay once they fall outside the window.
+	if isGlobalCatchAll(mx, now.Add(2*time.Hour)) {
+		t.Errorf("stale observations must decay out of the recognition count")
+	}
+}
+
+func TestGlobalCatchAllDecayOnRecord(t *testing.T) {
+	savedMin, savedWindow := GlobalAcceptMinDomains, GlobalAcceptWindow
+	defer func() { GlobalAcceptMinDomains, GlobalAcceptWindow = savedMin, savedWindow }()
+	GlobalAcceptMinDomains = 3
+	GlobalAcceptWindow = time.Hour
+
+	mx := "mx.decay.example"
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		recordGhostAccept(mx, fmt.Sprintf("old%d.example", i), start)
+	}
+	// Two hours later only one fresh observation exists alongside the stale two.
+	later := start.Add(2 * time.Hour)
+	recordGhostAccept(mx, "new.example", later)
+	if isGlobalCatchAll(mx, later) {
+		t.Errorf("stale observations must not count towards recognition")
+	}
+}
+
+func TestGlobalCatchAllRevalidation(t *testing.T) {
+	savedMin, savedWindow, savedReval := GlobalAcceptMinDomains, GlobalAcceptWindow, GlobalAcceptRevalidate
+	defer func() {
+		GlobalAcceptMinDomains, GlobalAcceptWindow, GlobalAcceptRevalidate = savedMin, savedWindow, savedReval
+	}()
+	GlobalAcceptMinDomains = 3
+	GlobalAcceptWindow = 24 * time.Hour
+	GlobalAcceptRevalidate = time.Hour
+
+	const domain = "reval-one.example"
+	mx := "mx." + domain
+	stale := time.Now().Add(-2 * time.Hour)
+	for _, d := range []string{domain, "reval-two.example", "reval-three.example"} {
+		recordGhostAccept(mx, d, stale)
+	}
+	if !isGlobalCatchAll(mx, stale) {
+		t.Fatalf("expected recognition at the time of observation")
+	}
+
+	// The host stopped accepting everything: the target exists, the ghost
+	// hard-bounces.
+	probes := stubCollectors(t, domain, func(email string) (bool, time.Duration, error) {
+		if strings.HasPrefix(email, "jane@") {
+			return true, 20 * time.Millisecond, nil
+		}
+		return false, 20 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+	})
+
+	res, err := VerifyEmail(context.Background(), "jane@"+domain, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(probes) == 0 {
+		t.Fatalf("a stale registry entry must trigger a fresh probe, not be trusted")
+	}
+	if res.Analysis.IsCatchAll {
+		t.Errorf("fresh probe found no catch-all, but the verdict is still catch-all")
+	}
+
+	val, ok := cache.DomainCache.Get(globalAcceptKey(mx))
+	if !ok {
+		t.Fatalf("registry record vanished entirely")
+	}
+	if v := val.(globalAcceptRecord).Domains[domain]; v.Accepted {
+		t.Errorf("domain that no longer accepts ghosts must be recorded as rejecting")
+	}
+}
+
+func TestGlobalCatchAllNeedsHighAcceptRatio(t *testing.T) {
+	savedMin, savedRatio, savedWindow := GlobalAcceptMinDomains, GlobalAcceptMinRatio, GlobalAcceptWindow
+	defer func() {
+		GlobalAcceptMinDomains, GlobalAcceptMinRatio, GlobalAcceptWindow = savedMin, savedRatio, savedWindow
+	}()
+	GlobalAcceptMinDomains = 3
+	GlobalAcceptMinRatio = 0.9
+	GlobalAcceptWindow = time.Hour
+
+	mx := "mx.shared-hosting.example"
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Plenty of catch-all tenants, but most tenants on the host are strict.
+	for i := 0; i < 5; i++ {
+		recordGhostAccept(mx, fmt.Sprintf("loose%d.example", i), now)
+	}
+	for i := 0; i < 20; i++ {
+		recordGhostReject(mx, fmt.Sprintf("strict%d.example", i), now)
+	}
+	if isGlobalCatchAll(mx, now) {
+		t.Errorf("a host that rejects ghosts for most domains must not be a global catch-all")
+	}
+}
+
+func TestGlobalCatchAllIgnoresSharedProviders(t *testing.T) {
+	savedMin, savedWindow := GlobalAcceptMinDomains, GlobalAcceptWindow
+	defer func() { GlobalAcceptMinDomains, GlobalAcceptWindow = savedMin, savedWindow }()
+	GlobalAcceptMinDomains = 3
+	GlobalAcceptWindow = time.Hour
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, mx := range []string{"aspmx.l.google.com", "contoso-com.mail.protection.outlook.com"} {
+		for i := 0; i < 10; i++ {
+			recordGhostAccept(mx, fmt.Sprintf("tenant%d.example", i), now)
+		}
+		if isGlobalCatchAll(mx, now) {
+			t.Errorf("%s is shared infrastructure and must never be a global catch-all", mx)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package validator
+
+import (
+	"context"
+
+	"mailvetter/internal/config"
+)
+
+// MaxInFlight caps how many VerifyEmail calls run at once across the process,
+// independently of worker count. Each in-flight verification holds a full
+// RiskAnalysis, a dozen goroutines and open HTTP/SMTP connections, so this
+// bounds peak memory and FD usage even when WORKER_CONCURRENCY is set high.
+// Set via MAX_INFLIGHT_VERIFICATIONS.
+var MaxInFlight = config.Int("MAX_INFLIGHT_VERIFICATIONS", 200)
+
+// inFlight is the semaphore enforcing MaxInFlight. A nil channel means
+// uncapped.
+var inFlight = newInFlight(MaxInFlight)
+
+func newInFlight(limit int) chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+	return make(chan struct{}, limit)
+}
+
+// acquireSlot blocks until an in-flight slot is free or ctx is done. The
+// returned release func must be called exactly once when err is nil.
+func acquireSlot(ctx context.Context) (release func(), err error) {
+	sem := inFlight
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
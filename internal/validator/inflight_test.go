@@ -0,0 +1,101 @@
+package validator
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mailvetter/internal/models"
+)
+
+func TestInFlightCapLimitsVerifyEmail(t *testing.T) {
+	saved := inFlight
+	defer func() { inFlight = saved }()
+	inFlight = newInFlight(2)
+
+	// Occupy every slot, as two long-running verifications would.
+	ctx := context.Background()
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := acquireSlot(ctx)
+		if err != nil {
+			t.Fatalf("acquire %d: %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+
+	// A disposable address returns without any network I/O once it runs, so
+	// the only thing that can hold it back is the in-flight cap.
+	done := make(chan models.ValidationResult, 1)
+	go func() {
+		res, _ := VerifyEmail(ctx, "someone@mailinator.com", "mailinator.com")
+		done <- res
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("VerifyEmail ran while the in-flight cap was exhausted")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	releases[0]()
+	select {
+	case res := <-done:
+		if res.Status != models.StatusInvalid {
+			t.Errorf("status %q != expected %q", res.Status, models.StatusInvalid)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("VerifyEmail did not run after a slot was released")
+	}
+	releases[1]()
+
+	// With the cap exhausted, a caller whose context expires gives up.
+	for i := 0; i < 2; i++ {
+		release, _ := acquireSlot(ctx)
+		defer release()
+	}
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	res, err := VerifyEmail(short, "other@mailinator.com", "mailinator.com")
+	if err == nil || res.Status != models.StatusUnknown {
+		t.Errorf("expected timeout waiting for a slot, got status %q err %v", res.Status, err)
+	}
+}
+
+func TestInFlightPeakConcurrency(t *testing.T) {
+	saved := inFlight
+	defer func() { inFlight = saved }()
+	inFlight = newInFlight(3)
+
+	var current, peak int64
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := acquireSlot(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+
+			n := atomic.AddInt64(&current, 1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt64(&current, -1)
+		}()
+	}
+	wg.Wait()
+
+	if peak > 3 {
+		t.Errorf("peak concurrency %d exceeded cap 3", peak)
+	}
+}
@@ -3,18 +3,25 @@ package validator
 import (
 	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
 	"math"
+	"net"
 	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"mailvetter/internal/cache"
+	"mailvetter/internal/config"
 	"mailvetter/internal/lookup"
+	"mailvetter/internal/metrics"
 	"mailvetter/internal/models"
 	"mailvetter/internal/proxy"
 )
@@ -22,9 +29,14 @@ import (
 type DomainResult struct {
 	Provider      string
 	HasSPF        bool
+	SPFOverLimit  bool
 	HasDMARC      bool
 	HasSaaSTokens bool
 	DomainAge     int
+	Registrar     string
+	HasWebsite    bool
+
+	HasSlackWorkspace bool
 }
 
 type SmtpHostResult struct {
@@ -32,31 +44,191 @@ type SmtpHostResult struct {
 	IsPostmasterBroken bool
 }
 
+// CatchAllCacheTTL is how long a domain's learned catch-all verdict is trusted
+// before the next address re-probes it. Set via CATCHALL_CACHE_TTL.
+var CatchAllCacheTTL = config.Duration("CATCHALL_CACHE_TTL", 30*time.Minute)
+
+// ConservativeCatchAll requires a catch-all observation to be repeated on a
+// second, independent connection (a fresh ghost address, sent direct) before
+// the verdict is committed. Under proxy load a transiently permissive path
+// can accept both the target and the ghost; an unconfirmed observation is
+// reported as unknown with ReasonCatchAllUnconfirmed instead. Costs one extra
+// RCPT probe per catch-all domain. Off by default; enable with
+// CONSERVATIVE_CATCH_ALL=true.
+var ConservativeCatchAll = config.Bool("CONSERVATIVE_CATCH_ALL", false)
+
+// GhostCollisionMs is the target/ghost latency difference, in milliseconds,
+// at or under which an accepted ghost is treated as suspiciously identical
+// to the target. 0 turns the check off. Configured by GHOST_COLLISION_MS.
+var GhostCollisionMs = int64(config.NonNegativeInt("GHOST_COLLISION_MS", 10))
+
+// GhostCollisionReprobe re-probes a suspiciously identical catch-all with a
+// differently shaped ghost address. A rejection shows the first ghost hit a
+// real mailbox and the domain is not catch-all; an acceptance confirms it.
+// Hosts whose catch-all verdict is already cached are not re-probed.
+// On by default; disable with GHOST_COLLISION_REPROBE=false, which leaves
+// such verdicts marked CatchAllLowConfidence.
+var GhostCollisionReprobe = config.Bool("GHOST_COLLISION_REPROBE", true)
+
+// NeverCatchAllProviders lists MX providers (as named by lookup.ProviderForMX)
+// that never accept mail for unknown recipients. On their hosts the ghost
+// probe is skipped and an accepted target is taken as valid, halving RCPT
+// connections for those domains. Configured by NEVER_CATCH_ALL_PROVIDERS
+// (comma-separated); empty by default, so the ghost probe always runs.
+var NeverCatchAllProviders = stringSet(config.List("NEVER_CATCH_ALL_PROVIDERS"))
+
+func stringSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, s := range list {
+		set[strings.ToLower(s)] = true
+	}
+	return set
+}
+
+// FreeMailOSINTMode auto-selects ModeOSINT for consumer free-mail domains,
+// whose providers do not honour RCPT verification and penalise senders that
+// try. On by default; disable with FREEMAIL_OSINT_MODE=false.
+var FreeMailOSINTMode = config.Bool("FREEMAIL_OSINT_MODE", true)
+
+// modeFor returns the verification mode VerifyEmail uses for domain.
+func modeFor(domain string) Mode {
+	if FreeMailOSINTMode && lookup.IsFreeMailDomain(domain) {
+		return ModeOSINT
+	}
+	return ModeFull
+}
+
+// UnknownGraceRetry, when enabled, re-runs a verification once if it came back
+// unknown because the mail server could not be reached at all (DNS failure,
+// connection refused, timeout) — usually a transient network or proxy fault.
+// Genuine no-signal results, where the server answered but gave no verdict,
+// are never retried. Off by default; enable with UNKNOWN_GRACE_RETRY=true and
+// tune the pause with UNKNOWN_GRACE_DELAY.
+var (
+	UnknownGraceRetry = config.Bool("UNKNOWN_GRACE_RETRY", false)
+	UnknownGraceDelay = config.Duration("UNKNOWN_GRACE_DELAY", 5*time.Second)
+)
+
+// VerifyEmail verifies the canonical form of email (see lookup.NormalizeEmail),
+// so subaddressed variants of one mailbox share probes and cache entries. The
+// result reports the address as given in Email and the one probed in
+// NormalizedEmail.
+//
+// An internationalized domain is converted to punycode before any DNS or
+// SMTP work, and both forms are reported in DomainUnicode and DomainASCII.
 func VerifyEmail(ctx context.Context, email, domain string) (models.ValidationResult, error) {
-	analysis := models.RiskAnalysis{}
-	result := models.ValidationResult{Email: email}
+	probed := email
+	asciiDomain, err := lookup.ToASCIIDomain(domain)
+	if err != nil {
+		asciiDomain = domain
+	}
+	if asciiDomain != domain {
+		if at := strings.LastIndexByte(email, '@'); at >= 0 {
+			probed = email[:at+1] + asciiDomain
+		}
+	}
+	hotDomains.observe(asciiDomain)
+
+	result, err := verifyWithGrace(ctx, lookup.NormalizeEmail(probed), asciiDomain)
+	result.NormalizedEmail = result.Email
+	result.Email = email
+	result.Recommendation = Recommend(result.Status, result.Score, result.Analysis.CatchAllLowConfidence)
+	metrics.VerificationsTotal.Inc(string(result.Status))
+	if strings.HasPrefix(asciiDomain, "xn--") || strings.Contains(asciiDomain, ".xn--") {
+		result.DomainUnicode = domain
+		result.DomainASCII = asciiDomain
+	}
+	return result, err
+}
+
+func verifyWithGrace(ctx context.Context, email, domain string) (models.ValidationResult, error) {
+	result, unreachable, err := verifyOnce(ctx, email, domain)
+	if !UnknownGraceRetry || !unreachable || err != nil {
+		return result, err
+	}
+
+	select {
+	case <-time.After(UnknownGraceDelay):
+	case <-ctx.Done():
+		return result, nil
+	}
+	traceFrom(ctx).record("grace_retry", TraceSourceProbe, "first attempt could not reach the mail server", 0)
+	result, _, err = verifyOnce(ctx, email, domain)
+	return result, err
+}
+
+// verifyOnce runs a single verification. unreachable reports an unknown
+// verdict caused by failing to reach the mail server, as opposed to one where
+// the server answered without a verdict.
+func verifyOnce(ctx context.Context, email, domain string) (result models.ValidationResult, unreachable bool, err error) {
+	mode := modeFor(domain)
+	tr := traceFrom(ctx)
+	if tr == nil {
+		if cached, ok := getCachedResult(email, mode); ok {
+			return cached, false, nil
+		}
+	}
+
+	release, err := acquireSlot(ctx)
+	if err != nil {
+		return models.ValidationResult{
+			Email:  email,
+			Status: models.StatusUnknown,
+			Error:  "Validation timed out waiting for an in-flight verification slot",
+		}, false, err
+	}
+	defer release()
+
+	analysis := models.RiskAnalysis{SmtpSkipped: mode == ModeOSINT}
+	result = models.ValidationResult{Email: email}
 	var mu sync.Mutex
+	smtpUnreachable := false
+	smtpUTF8Unsupported := false
+	catchAllUnconfirmed := false
+	noMX := false
 
 	var pinnedProxy *url.URL
 	if proxy.Enabled() {
 		pinnedProxy = proxy.Global.Next()
 	}
 
+	if reason := checkLength(email); reason != "" {
+		result.Status = models.StatusInvalid
+		result.Score = 0
+		result.Reachability = models.ReachabilityBad
+		result.Reason = reason
+		return result, false, nil
+	}
+
+	// Malformed junk (spaces, stray commas, control characters) is rejected
+	// here, before it costs any DNS or SMTP work.
+	if lookup.ValidateSyntax(email) != nil {
+		result.Status = models.StatusInvalid
+		result.Score = 0
+		result.Reachability = models.ReachabilityBad
+		result.Reason = ReasonInvalidSyntax
+		return result, false, nil
+	}
+
 	if lookup.IsDisposableDomain(domain) {
 		result.Status = models.StatusInvalid
 		result.Score = 0
 		result.Reachability = models.ReachabilityBad
-		return result, nil
+		result.Reason = ReasonDisposable
+		return result, false, nil
 	}
 
 	if lookup.IsRoleAccount(email) {
 		analysis.IsRoleAccount = true
 	}
+	analysis.IsFreeProvider = lookup.IsFreeProvider(domain)
+	analysis.IsMixedScriptDomain = lookup.IsMixedScriptDomain(domain)
 
 	parts := strings.Split(email, "@")
 	if len(parts) == 2 {
 		analysis.EntropyScore = lookup.CalculateEntropy(parts[0])
 	}
+	analysis.TLD = domainTLD(domain)
 
 	var wg sync.WaitGroup
 
@@ -64,40 +236,48 @@ func VerifyEmail(ctx context.Context, email, domain string) (models.ValidationRe
 	go func() {
 		defer wg.Done()
 
-		cacheKey := "infra:" + domain
+		// The MX set is part of the cache key: if the domain migrates to a
+		// new provider, the stale infra entry (with the old provider) is
+		// simply never hit again instead of contradicting the SMTP
+		// collector, which always resolves the live MX.
+		mxRecords, _ := resolveMX(ctx, domain)
+		mu.Lock()
+		analysis.MxCount = len(mxRecords)
+		analysis.MxProviders = lookup.ProvidersForMXRecords(mxRecords)
+		mu.Unlock()
+		cacheKey := infraCacheKey(domain, mxRecords)
 		if cached, ok := cache.DomainCache.Get(cacheKey); ok {
 			d := cached.(DomainResult)
 			mu.Lock()
 			analysis.MxProvider = d.Provider
 			analysis.HasSPF = d.HasSPF
+			analysis.SPFOverLimit = d.SPFOverLimit
 			analysis.HasDMARC = d.HasDMARC
 			analysis.HasSaaSTokens = d.HasSaaSTokens
 			analysis.DomainAgeDays = d.DomainAge
+			analysis.Registrar = d.Registrar
+			analysis.HasWebsite = d.HasWebsite
+			analysis.HasSlackWorkspace = d.HasSlackWorkspace
 			mu.Unlock()
+			tr.record("infra", TraceSourceCache, "provider="+d.Provider, 0)
 			return
 		}
 
-		provider, _ := lookup.IdentifyProvider(ctx, domain)
-		if provider == "unknown" {
-			provider = "generic"
-		}
-
-		res := DomainResult{
-			Provider:      provider,
-			HasSPF:        lookup.CheckSPF(ctx, domain),
-			HasDMARC:      lookup.CheckDMARC(ctx, domain),
-			HasSaaSTokens: lookup.CheckSaaSTokens(ctx, domain),
-			DomainAge:     lookup.CheckDomainAge(ctx, domain, pinnedProxy),
-		}
-
-		cache.DomainCache.Set(cacheKey, res, 15*time.Minute)
+		infraStart := time.Now()
+		res := fetchInfra(ctx, domain, mxRecords, pinnedProxy)
+		cache.DomainCache.Set(cacheKey, res, InfraCacheTTL)
+		tr.record("infra", TraceSourceProbe, "provider="+res.Provider, time.Since(infraStart))
 
 		mu.Lock()
 		analysis.MxProvider = res.Provider
 		analysis.HasSPF = res.HasSPF
+		analysis.SPFOverLimit = res.SPFOverLimit
 		analysis.HasDMARC = res.HasDMARC
 		analysis.HasSaaSTokens = res.HasSaaSTokens
 		analysis.DomainAgeDays = res.DomainAge
+		analysis.Registrar = res.Registrar
+		analysis.HasWebsite = res.HasWebsite
+		analysis.HasSlackWorkspace = res.HasSlackWorkspace
 		mu.Unlock()
 	}()
 
@@ -105,23 +285,63 @@ func VerifyEmail(ctx context.Context, email, domain string) (models.ValidationRe
 	go func() {
 		defer wg.Done()
 
-		mxRecords, err := lookup.CheckDNS(ctx, domain)
+		if mode == ModeOSINT {
+			tr.record("smtp", TraceSourceSkipped, "consumer free-mail domain", 0)
+			return
+		}
+
+		mxStart := time.Now()
+		mxRecords, err := resolveMX(ctx, domain)
 		if err != nil || len(mxRecords) == 0 {
+			tr.record("mx", TraceSourceProbe, fmt.Sprintf("no usable MX: %v", err), time.Since(mxStart))
+			var dnsErr *net.DNSError
 			mu.Lock()
 			analysis.SmtpStatus = 0
+			// A domain that does not exist is a genuine answer; a resolver
+			// that could not be reached is not.
+			smtpUnreachable = err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound)
+			noMX = !smtpUnreachable
 			mu.Unlock()
 			return
 		}
 		sort.Slice(mxRecords, func(i, j int) bool { return mxRecords[i].Pref < mxRecords[j].Pref })
 		primaryMX := mxRecords[0].Host
+		tr.setMX(mxRecords)
+		tr.record("mx", TraceSourceProbe, primaryMX, time.Since(mxStart))
+
+		if lookup.IsParkedDomain(primaryMX) {
+			mu.Lock()
+			analysis.IsParked = true
+			mu.Unlock()
+		}
+
+		// Known global-accept infrastructure: the per-email probes could
+		// only ever return catch-all, so skip them.
+		if isGlobalCatchAll(primaryMX, time.Now()) {
+			tr.record("smtp", TraceSourceSkipped, "known global catch-all host", 0)
+			mu.Lock()
+			analysis.IsCatchAll = true
+			analysis.SmtpStatus = 0
+			mu.Unlock()
+			return
+		}
 
-		if lookup.CheckVRFY(ctx, primaryMX, email, pinnedProxy) {
+		vrfyStart := time.Now()
+		vrfyOK := vrfyProbe(ctx, primaryMX, email, pinnedProxy)
+		tr.record("vrfy", TraceSourceProbe, strconv.FormatBool(vrfyOK), time.Since(vrfyStart))
+		if vrfyOK && !VRFYRequireCorroboration {
 			mu.Lock()
 			analysis.HasVRFY = true
 			analysis.SmtpStatus = 250
 			mu.Unlock()
 			return
 		}
+		if vrfyOK {
+			// The RCPT probe below has to corroborate the hit.
+			mu.Lock()
+			analysis.HasVRFY = true
+			mu.Unlock()
+		}
 
 		hostCacheKey := "smtp_host:" + primaryMX + ":" + domain
 		var cachedHost SmtpHostResult
@@ -131,30 +351,97 @@ func VerifyEmail(ctx context.Context, email, domain string) (models.ValidationRe
 		if val, ok := cache.DomainCache.Get(hostCacheKey); ok {
 			cachedHost = val.(SmtpHostResult)
 			hostCached = true
+			tr.record("smtp_host", TraceSourceCache,
+				fmt.Sprintf("catch_all=%v postmaster_broken=%v", cachedHost.IsCatchAll, cachedHost.IsPostmasterBroken), 0)
 		} else {
+			pmStart := time.Now()
 			isBroken = !lookup.CheckPostmaster(ctx, primaryMX, domain, pinnedProxy)
 			cachedHost.IsPostmasterBroken = isBroken
+			tr.record("postmaster", TraceSourceProbe, fmt.Sprintf("broken=%v", isBroken), time.Since(pmStart))
 		}
 
 		if !hostCached {
 			time.Sleep(500 * time.Millisecond)
 		}
 
-		status, delta, isCatchAll := runSmtpProbes(ctx, email, domain, primaryMX, pinnedProxy)
+		// With adaptive timeouts on, the SMTP phase gets a deadline learned
+		// from how long this provider usually takes; the whole verification
+		// keeps its own, longer deadline either way.
+		smtpCtx := ctx
+		provider := latencyProvider(primaryMX)
+		if AdaptiveTimeouts {
+			if timeout, n := providerLatency.timeout(provider); timeout > 0 {
+				var cancel context.CancelFunc
+				smtpCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+				tr.record("smtp_timeout", TraceSourceCache, fmt.Sprintf("%s (p95 of %d samples for %s)", timeout, n, provider), 0)
+			} else {
+				tr.record("smtp_timeout", TraceSourceSkipped, fmt.Sprintf("%d of %d samples for %s", n, minLatencySamples, provider), 0)
+			}
+		}
+
+		smtpStart := time.Now()
+		report := probeMXHosts(smtpCtx, email, domain, mxRecords, pinnedProxy)
+		providerLatency.observe(provider, time.Since(smtpStart))
+		if report.Host != primaryMX {
+			tr.record("smtp_mx_fallback", TraceSourceProbe, "answered by "+report.Host, 0)
+		}
+		status, delta, isCatchAll := report.Status, report.Delta, report.IsCatchAll
+		tr.recordProbe("smtp_target", report.Target)
+		tr.recordProbe("smtp_ghost", report.Ghost)
 
 		if isCatchAll && delta > 100 && delta < 400 {
 			select {
 			case <-time.After(250 * time.Millisecond):
-				status2, delta2, _ := runSmtpProbes(ctx, email, domain, primaryMX, pinnedProxy)
-				delta = (delta + delta2) / 2
-				status = status2
+				report2 := runSmtpProbes(smtpCtx, email, domain, report.Host, pinnedProxy)
+				tr.recordProbe("smtp_target_retry", report2.Target)
+				tr.recordProbe("smtp_ghost_retry", report2.Ghost)
+				delta = (delta + report2.Delta) / 2
+				status = report2.Status
+				report.Target, report.Session = report2.Target, report2.Session
 			case <-ctx.Done():
 			}
 		}
 
+		// A ghost answered exactly like the target may be a random-name
+		// collision with a real mailbox rather than a catch-all. That is
+		// only in doubt the first time: a host already cached as catch-all
+		// for this domain has accepted other ghosts before, so most
+		// catch-all results need no second ghost.
+		knownCatchAll := hostCached && cachedHost.IsCatchAll
+		lowConfidence := false
+		if isCatchAll && !knownCatchAll && ghostMirrorsTarget(report) {
+			lowConfidence = true
+			if GhostCollisionReprobe {
+				alt := reprobeGhost(smtpCtx, domain, report.Host, pinnedProxy)
+				tr.recordProbe("smtp_ghost_alt", alt)
+				switch {
+				case alt.Accepted:
+					lowConfidence = false
+				case lookup.IsNoSuchUserError(alt.Err):
+					isCatchAll, status, lowConfidence = false, 250, false
+				}
+			}
+		}
+
+		unconfirmed := false
+		if isCatchAll && ConservativeCatchAll {
+			confirm := confirmCatchAll(smtpCtx, domain, report.Host)
+			tr.recordProbe("smtp_ghost_confirm", confirm)
+			if !confirm.Accepted {
+				isCatchAll, status, unconfirmed = false, 0, true
+			}
+		}
+
+		if isCatchAll {
+			recordGhostAccept(report.Host, domain, time.Now())
+		} else if report.Ghost.Address != "" && lookup.IsNoSuchUserError(report.Ghost.Err) {
+			recordGhostReject(report.Host, domain, time.Now())
+		}
+
 		if !hostCached {
 			cachedHost.IsCatchAll = isCatchAll
-			cache.DomainCache.Set(hostCacheKey, cachedHost, 30*time.Minute)
+			cache.DomainCache.Set(hostCacheKey, cachedHost, CatchAllCacheTTL)
 		}
 
 		mu.Lock()
@@ -164,8 +451,25 @@ func VerifyEmail(ctx context.Context, email, domain string) (models.ValidationRe
 			analysis.IsPostmasterBroken = isBroken
 		}
 		analysis.IsCatchAll = isCatchAll
+		analysis.CatchAllLowConfidence = isCatchAll && lowConfidence
+		analysis.SmtpHost = report.Host
+		analysis.IsGreylisted = report.Greylisted
+		analysis.HasTLS13 = report.Session.TLSVersion() == tls.VersionTLS13
+		caps := report.Session.Capabilities()
+		analysis.SmtpExtensions = caps.Extensions
+		analysis.SmtpMaxSize = caps.Size
+		analysis.SmtpAuthMechanisms = caps.Auth
+		analysis.SmtpAuthRequired = lookup.IsAuthRequired(report.Target.Err)
 		analysis.SmtpStatus = status
 		analysis.TimingDeltaMs = delta
+		smtpUnreachable = !report.Target.Accepted && report.Target.Err != nil &&
+			lookup.ResponseText(report.Target.Err) == "" &&
+			!errors.Is(report.Target.Err, lookup.ErrSMTPUTF8Unsupported)
+		smtpUTF8Unsupported = errors.Is(report.Target.Err, lookup.ErrSMTPUTF8Unsupported)
+		catchAllUnconfirmed = unconfirmed
+		if IncludeSmtpMessage {
+			analysis.SmtpMessage = report.Target.message()
+		}
 		mu.Unlock()
 	}()
 
@@ -173,80 +477,68 @@ func VerifyEmail(ctx context.Context, email, domain string) (models.ValidationRe
 	go func() {
 		defer wg.Done()
 
-		var hasGCal, hasTeams, hasSharePoint, hasAdobe, hasGravatar, hasGitHub bool
+		probes := tr.traceProbes(osintProbes())
+		signals := make([]string, len(probes))
+		timedOut := make([]bool, len(probes))
 		var breachCount int
+		hibpTimedOut := false
 		var probeWg sync.WaitGroup
 
-		probeWg.Add(1)
-		go func() {
-			defer probeWg.Done()
-			if lookup.CheckGoogleCalendar(ctx, email, pinnedProxy) {
-				mu.Lock()
-				hasGCal = true
-				mu.Unlock()
-			}
-		}()
-
-		probeWg.Add(1)
-		go func() {
-			defer probeWg.Done()
-			if lookup.CheckTeamsPresence(ctx, email, domain, pinnedProxy) {
-				mu.Lock()
-				hasTeams = true
-				mu.Unlock()
-			}
-		}()
-
-		probeWg.Add(1)
-		go func() {
-			defer probeWg.Done()
-			if lookup.CheckSharePoint(ctx, email, pinnedProxy) {
-				mu.Lock()
-				hasSharePoint = true
-				mu.Unlock()
-			}
-		}()
-
-		probeWg.Add(1)
-		go func() {
-			defer probeWg.Done()
-			if lookup.CheckAdobe(ctx, email, pinnedProxy) {
-				mu.Lock()
-				hasAdobe = true
-				mu.Unlock()
-			}
-		}()
-
-		probeWg.Add(1)
-		go func() {
-			defer probeWg.Done()
-			if lookup.CheckGravatar(ctx, email, pinnedProxy) {
-				mu.Lock()
-				hasGravatar = true
-				mu.Unlock()
-			}
-		}()
+		// When the OSINT providers are refusing the proxy pool wholesale,
+		// probe direct; SMTP keeps the pinned proxy regardless.
+		osintProxy := pinnedProxy
+		if osintProxy != nil && lookup.OSINTDirect() {
+			osintProxy = nil
+		}
+		osintCtx := lookup.WithProbeProxy(lookup.WithOSINTTracking(ctx), osintProxy)
 
-		probeWg.Add(1)
-		go func() {
-			defer probeWg.Done()
-			if lookup.CheckGitHub(ctx, email, pinnedProxy) {
+		for i, p := range probes {
+			probeWg.Add(1)
+			go func() {
+				defer probeWg.Done()
+				if !acquireOSINT(osintCtx) {
+					return
+				}
+				defer releaseOSINT()
+				var signal string
+				var ok bool
+				expired := withProbeTimeout(osintCtx, func(ctx context.Context) {
+					signal, ok = p.Run(ctx, email, domain)
+				})
 				mu.Lock()
-				hasGitHub = true
+				if ok {
+					signals[i] = signal
+				}
+				timedOut[i] = expired
 				mu.Unlock()
-			}
-		}()
+			}()
+		}
 
 		apiKey := os.Getenv("HIBP_API_KEY")
 		if apiKey != "" {
 			probeWg.Add(1)
 			go func() {
 				defer probeWg.Done()
-				bc := lookup.CheckHIBP(ctx, email, apiKey, pinnedProxy)
+				if !acquireOSINT(osintCtx) {
+					return
+				}
+				defer releaseOSINT()
+				start := time.Now()
+				var bc int
+				expired := withProbeTimeout(osintCtx, func(ctx context.Context) {
+					bc = lookup.CheckHIBP(ctx, email, apiKey, osintProxy)
+				})
+				detail := strconv.Itoa(bc) + " breaches"
+				if expired {
+					detail = "timed out"
+				}
+				tr.record("osint:hibp", TraceSourceProbe, detail, time.Since(start))
 				mu.Lock()
-				breachCount = bc
+				breachCount, hibpTimedOut = bc, expired
 				mu.Unlock()
 			}()
+		} else {
+			tr.record("osint:hibp", TraceSourceSkipped, "HIBP_API_KEY not set", 0)
 		}
 
 		c := make(chan struct{})
@@ -258,13 +550,18 @@ func VerifyEmail(ctx context.Context, email, domain string) (models.ValidationRe
 		select {
 		case <-c:
 			mu.Lock()
-			analysis.HasGoogleCalendar = hasGCal
-			analysis.HasTeamsPresence = hasTeams
-			analysis.HasSharePoint = hasSharePoint
-			analysis.HasAdobe = hasAdobe
-			analysis.HasGravatar = hasGravatar
-			analysis.HasGitHub = hasGitHub
+			for i, p := range probes {
+				if signals[i] != "" {
+					applyProbeSignal(&analysis, p.Name(), signals[i])
+				}
+				if timedOut[i] {
+					analysis.OsintTimeouts = append(analysis.OsintTimeouts, p.Name())
+				}
+			}
 			analysis.BreachCount = breachCount
+			if hibpTimedOut {
+				analysis.OsintTimeouts = append(analysis.OsintTimeouts, "hibp")
+			}
 			mu.Unlock()
 		case <-ctx.Done():
 			return
@@ -279,28 +576,170 @@ func VerifyEmail(ctx context.Context, email, domain string) (models.ValidationRe
 
 	select {
 	case <-c:
-		finalScore, breakdown, reachability, status := CalculateRobustScore(analysis)
-		result.Score = finalScore
-		result.ScoreBreakdown = breakdown
-		result.Reachability = reachability
-		result.Status = status
+		scored := ScoreAnalysis(analysis)
+		result.Score = scored.Score
+		result.ScoreBreakdown = scored.ScoreBreakdown
+		result.Explanation = scored.Explanation
+		result.Reachability = scored.Reachability
+		result.Status, result.NuancedStatus = scored.Status, scored.NuancedStatus
+		result.ConfirmedBy = scored.ConfirmedBy
+		result.Recommendation = scored.Recommendation
 		result.Analysis = analysis
+		if IsLikelyDisposable(analysis) {
+			result.Reason = ReasonLikelyDisposable
+		} else if smtpUTF8Unsupported && result.Status == models.StatusUnknown {
+			result.Reason = ReasonSMTPUTF8Unsupported
+		} else if catchAllUnconfirmed && result.Status == models.StatusUnknown {
+			result.Reason = ReasonCatchAllUnconfirmed
+		}
 		if result.Score == 0 && result.Status == models.StatusUnknown {
 			result.Error = "Connection failed or no signals found"
 		}
-		return result, nil
+		result.Undeliverable = undeliverableCause(noMX, analysis, result.Status)
+		if noMX && len(parts) == 2 {
+			if suggested, ok := lookup.SuggestDomain(domain); ok {
+				result.SuggestedEmail = parts[0] + "@" + suggested
+			}
+		}
+		setCachedResult(email, mode, result)
+		return result, result.Status == models.StatusUnknown && smtpUnreachable, nil
 
 	case <-ctx.Done():
 		result.Status = models.StatusUnknown
 		result.Error = "Validation timed out due to slow proxy or unresponsive server"
-		return result, ctx.Err()
+		return result, false, ctx.Err()
+	}
+}
+
+// undeliverableCause attributes a failed address to its domain or mailbox
+// from the DNS and SMTP collector outcomes. A parked domain only counts when
+// it drove the verdict to invalid; proof of a live mailbox overrides it.
+func undeliverableCause(noMX bool, analysis models.RiskAnalysis, status models.VerificationStatus) models.UndeliverableCause {
+	switch {
+	case noMX:
+		return models.CauseDomainNoMX
+	case analysis.IsParked && status == models.StatusInvalid:
+		return models.CauseDomainParked
+	case analysis.SmtpStatus == 550:
+		return models.CauseMailboxNotFound
+	}
+	return ""
+}
+
+// IncludeSmtpMessage controls whether the target's raw SMTP rejection text is
+// surfaced as RiskAnalysis.SmtpMessage. On by default because it is the only
+// way to audit an invalid verdict; disable with SMTP_MESSAGE_IN_RESULT=false.
+var IncludeSmtpMessage = config.Bool("SMTP_MESSAGE_IN_RESULT", true)
+
+// InfraCacheTTL is how long a domain's infrastructure signals (provider, SPF,
+// DMARC, SaaS tokens, RDAP) are cached. Set via INFRA_CACHE_TTL.
+var InfraCacheTTL = config.Duration("INFRA_CACHE_TTL", 15*time.Minute)
+
+func init() {
+	// A domain that had no MX a moment ago may have been fixed; its
+	// negative entry must not outlive the positive entries around it.
+	if limit := min(InfraCacheTTL, CatchAllCacheTTL); lookup.NegativeMXCacheTTL >= limit {
+		log.Printf("⚠️  NEGATIVE_CACHE_TTL %s is not below the positive cache TTLs, using %s", lookup.NegativeMXCacheTTL, limit/2)
+		lookup.NegativeMXCacheTTL = limit / 2
+	}
+}
+
+// fetchInfra collects a domain's infrastructure signals. It is a variable so
+// tests can observe cache refreshes without DNS or RDAP.
+var fetchInfra = func(ctx context.Context, domain string, mxRecords []lookup.MXRecord, pURL *url.URL) DomainResult {
+	rdap := lookup.CheckRDAP(ctx, domain, pURL)
+	return DomainResult{
+		Provider:      lookup.ProviderForMXRecords(mxRecords),
+		HasSPF:        lookup.CheckSPF(ctx, domain),
+		SPFOverLimit:  lookup.CheckSPFOverLimit(ctx, domain),
+		HasDMARC:      lookup.CheckDMARC(ctx, domain),
+		HasSaaSTokens: lookup.CheckSaaSTokens(ctx, domain),
+		DomainAge:     rdap.AgeDays,
+		Registrar:     rdap.Registrar,
+		HasWebsite:    lookup.CheckWebPresence(ctx, domain, pURL),
+
+		HasSlackWorkspace: lookup.CheckSlackWorkspace(ctx, domain, pURL),
+	}
+}
+
+// vrfyProbe attempts SMTP VRFY. It is a variable so tests can avoid a real
+// SMTP server.
+var vrfyProbe = lookup.CheckVRFY
+
+// smtpProbe performs a single RCPT TO probe. It is a variable so tests can
+// substitute a fake SMTP server.
+var smtpProbe = lookup.CheckSMTPRotating
+
+// probeOutcome is the raw result of one RCPT TO probe.
+type probeOutcome struct {
+	Address  string
+	Accepted bool
+	Duration time.Duration
+	Err      error
+}
+
+// message returns the server's reply to RCPT TO for this probe: the raw
+// rejection text, or "" when the address was accepted or no reply was read.
+func (o probeOutcome) message() string {
+	if o.Accepted {
+		return ""
+	}
+	return lookup.ResponseText(o.Err)
+}
+
+// transient reports whether the probe failed without a mailbox verdict.
+func (o probeOutcome) transient() bool {
+	return !o.Accepted && o.Err != nil && !lookup.IsNoSuchUserError(o.Err)
+}
+
+// smtpProbeReport is the full result of a target + ghost probe pair.
+type smtpProbeReport struct {
+	Host       string // the MX host both probes ran against
+	Status     int
+	Delta      int64
+	IsCatchAll bool
+	// Greylisted is set when the target was deferred with a greylisting
+	// reply and then accepted on the retry.
+	Greylisted bool
+	Target     probeOutcome
+	Ghost      probeOutcome // zero if the ghost probe never ran
+	// Session is what the target's RCPT session learned about the server:
+	// its TLS version and EHLO capabilities. Ghost sessions do not report.
+	Session *lookup.SessionInfo
+}
+
+// maxMXFallback caps how many MX hosts, in preference order, are tried before
+// the SMTP probe gives up.
+const maxMXFallback = 3
+
+// probeMXHosts runs the target and ghost probes against the most-preferred MX
+// host, falling through to the next one when the target probe fails without a
+// mailbox verdict (connection failure, tarpit, deferral). Both probes of a
+// pair always run against the same host, so their timing delta stays
+// comparable. mxRecords must be sorted by preference.
+func probeMXHosts(ctx context.Context, email, domain string, mxRecords []lookup.MXRecord, pURL *url.URL) smtpProbeReport {
+	var report smtpProbeReport
+	for i, mx := range mxRecords {
+		if i == maxMXFallback || ctx.Err() != nil {
+			break
+		}
+		if i > 0 {
+			log.Printf("[DEBUG] MX %s gave no verdict for %s, falling back to %s", report.Host, email, mx.Host)
+		}
+		report = runSmtpProbes(ctx, email, domain, mx.Host, pURL)
+		if !report.Target.transient() || errors.Is(report.Target.Err, lookup.ErrSMTPUTF8Unsupported) {
+			break
+		}
 	}
+	return report
 }
 
-func runSmtpProbes(ctx context.Context, email, domain, primaryMX string, pURL *url.URL) (int, int64, bool) {
+func runSmtpProbes(ctx context.Context, email, domain, mxHost string, pURL *url.URL) smtpProbeReport {
 	var targetValid bool
 	var targetTime time.Duration
 	var targetErr error
+	var deferred bool
+	var session *lookup.SessionInfo
 
 	for attempt := 1; attempt <= 2; attempt++ {
 		currentProxy := pURL
@@ -308,10 +747,18 @@ func runSmtpProbes(ctx context.Context, email, domain, primaryMX string, pURL *u
 			currentProxy = nil
 		}
 
-		targetValid, targetTime, targetErr = lookup.CheckSMTP(ctx, primaryMX, email, currentProxy)
+		session = &lookup.SessionInfo{}
+		targetValid, targetTime, targetErr = smtpProbe(lookup.WithSessionInfo(ctx, session), mxHost, email, currentProxy)
 		targetTransient := !targetValid && targetErr != nil && !lookup.IsNoSuchUserError(targetErr)
+		if attempt == 1 && lookup.IsGreylistError(targetErr) {
+			deferred = true
+		}
 
-		if !targetTransient {
+		if !targetTransient || errors.Is(targetErr, lookup.ErrSMTPUTF8Unsupported) {
+			break
+		}
+		if lookup.IsPolicyError(targetErr) && !lookup.PolicyRejectRetry {
+			log.Printf("[DEBUG] Policy rejection for TARGET %s, not retrying: %v", email, targetErr)
 			break
 		}
 
@@ -320,19 +767,34 @@ func runSmtpProbes(ctx context.Context, email, domain, primaryMX string, pURL *u
 			select {
 			case <-time.After(2 * time.Second):
 			case <-ctx.Done():
-				return 0, 0, false
+				return smtpProbeReport{Host: mxHost}
 			}
 		}
 	}
 
+	report := smtpProbeReport{
+		Host:       mxHost,
+		Greylisted: deferred && targetValid,
+		Target:     probeOutcome{Address: email, Accepted: targetValid, Duration: targetTime, Err: targetErr},
+		Session:    session,
+	}
+
 	targetTransient := !targetValid && targetErr != nil && !lookup.IsNoSuchUserError(targetErr)
 	if targetTransient {
-		return 0, 0, false
+		return report
 	}
 
 	if !targetValid && lookup.IsNoSuchUserError(targetErr) {
 		log.Printf("[ERROR] Final target transient failure for %s: %v", email, targetErr)
-		return 550, 0, false
+		report.Status = 550
+		return report
+	}
+
+	if NeverCatchAllProviders[lookup.ProviderForMX(mxHost)] {
+		if targetValid {
+			report.Status = 250
+		}
+		return report
 	}
 
 	time.Sleep(500 * time.Millisecond)
@@ -352,7 +814,7 @@ func runSmtpProbes(ctx context.Context, email, domain, primaryMX string, pURL *u
 			currentProxy = nil
 		}
 
-		ghostValid, ghostTime, ghostErr = lookup.CheckSMTP(ctx, primaryMX, ghostEmail, currentProxy)
+		ghostValid, ghostTime, ghostErr = smtpProbe(ctx, mxHost, ghostEmail, currentProxy)
 		ghostTransient := !ghostValid && ghostErr != nil && !lookup.IsNoSuchUserError(ghostErr)
 
 		if !ghostTransient {
@@ -364,11 +826,13 @@ func runSmtpProbes(ctx context.Context, email, domain, primaryMX string, pURL *u
 			select {
 			case <-time.After(2 * time.Second):
 			case <-ctx.Done():
-				return 0, 0, false
+				return smtpProbeReport{Host: mxHost}
 			}
 		}
 	}
 
+	report.Ghost = probeOutcome{Address: ghostEmail, Accepted: ghostValid, Duration: ghostTime, Err: ghostErr}
+
 	delta := int64(0)
 	if ghostTime > 0 && targetTime > 0 {
 		d := float64(ghostTime.Milliseconds()) - float64(targetTime.Milliseconds())
@@ -392,7 +856,59 @@ func runSmtpProbes(ctx context.Context, email, domain, primaryMX string, pURL *u
 		}
 	}
 
-	return status, delta, isCatchAll
+	report.Status = status
+	report.Delta = delta
+	report.IsCatchAll = isCatchAll
+	return report
+}
+
+// confirmCatchAll repeats the ghost probe for ConservativeCatchAll with a new
+// address on a new connection. It always goes direct, so a catch-all seen
+// through a proxy is confirmed over a different path.
+func confirmCatchAll(ctx context.Context, domain, mxHost string) probeOutcome {
+	select {
+	case <-time.After(500 * time.Millisecond):
+	case <-ctx.Done():
+		return probeOutcome{Err: ctx.Err()}
+	}
+	ghostEmail := generateGhostAddress() + "@" + domain
+	accepted, d, err := smtpProbe(ctx, mxHost, ghostEmail, nil)
+	return probeOutcome{Address: ghostEmail, Accepted: accepted, Duration: d, Err: err}
+}
+
+// ghostMirrorsTarget reports whether the ghost was accepted just like the
+// target, with latencies within GhostCollisionMs of each other.
+func ghostMirrorsTarget(r smtpProbeReport) bool {
+	return GhostCollisionMs > 0 && r.Target.Accepted && r.Ghost.Accepted && r.Ghost.Address != "" &&
+		r.Delta <= GhostCollisionMs
+}
+
+// reprobeGhost probes one more ghost, shaped unlike generateGhostAddress so
+// it cannot collide with the same real mailbox pattern.
+func reprobeGhost(ctx context.Context, domain, mxHost string, pURL *url.URL) probeOutcome {
+	select {
+	case <-time.After(500 * time.Millisecond):
+	case <-ctx.Done():
+		return probeOutcome{Err: ctx.Err()}
+	}
+	ghostEmail := generateAltGhostAddress() + "@" + domain
+	accepted, d, err := smtpProbe(ctx, mxHost, ghostEmail, pURL)
+	return probeOutcome{Address: ghostEmail, Accepted: accepted, Duration: d, Err: err}
+}
+
+// generateAltGhostAddress returns an initial-plus-surname-plus-digits local
+// part ("jwilson4821"), a different shape from generateGhostAddress.
+func generateAltGhostAddress() string {
+	initials := "abcdeghjklmnprstw"
+	lastNames := []string{"anderson", "thomas", "jackson", "white", "harris", "clark", "lewis", "walker", "hall", "young"}
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "jwalker4821"
+	}
+
+	n := (int(b[2])<<8 | int(b[3])) % 9000
+	return string(initials[int(b[0])%len(initials)]) + lastNames[int(b[1])%len(lastNames)] + strconv.Itoa(1000+n)
 }
 
 func generateGhostAddress() string {
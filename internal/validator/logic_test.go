@@ -0,0 +1,682 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/lookup"
+	"mailvetter/internal/models"
+)
+
+// stubCollectors isolates VerifyEmail from the network: domain-level signals
+// come from a primed cache, VRFY fails, and no OSINT probe runs. Each RCPT TO
+// probe is answered by probe and counted.
+func stubCollectors(t *testing.T, domain string, probe func(email string) (bool, time.Duration, error)) *int32 {
+	t.Helper()
+	mx := []lookup.MXRecord{{Host: "mx." + domain, Pref: 10}}
+
+	savedMX, savedProbe, savedVRFY, savedOSINT := resolveMX, smtpProbe, vrfyProbe, osintProbes
+	t.Cleanup(func() { resolveMX, smtpProbe, vrfyProbe, osintProbes = savedMX, savedProbe, savedVRFY, savedOSINT })
+	t.Setenv("HIBP_API_KEY", "")
+
+	var probes int32
+	resolveMX = func(ctx context.Context, d string) ([]lookup.MXRecord, error) { return mx, nil }
+	vrfyProbe = func(ctx context.Context, mxHost, email string, pURL *url.URL) bool { return false }
+	smtpProbe = func(ctx context.Context, mxHost, email string, pURL *url.URL) (bool, time.Duration, error) {
+		atomic.AddInt32(&probes, 1)
+		return probe(email)
+	}
+	osintProbes = func() []lookup.Probe { return nil }
+
+	cache.DomainCache.Set(infraCacheKey(domain, mx), DomainResult{Provider: "generic"}, time.Minute)
+	cache.DomainCache.Set("smtp_host:mx."+domain+":"+domain, SmtpHostResult{}, time.Minute)
+	return &probes
+}
+
+// fakeProbe is a lookup.Probe answering with run and reporting signal.
+type fakeProbe struct {
+	name   string
+	signal string
+	run    func(ctx context.Context, email, domain string) bool
+}
+
+func (p fakeProbe) Name() string { return p.name }
+
+func (p fakeProbe) Run(ctx context.Context, email, domain string) (string, bool) {
+	return p.signal, p.run(ctx, email, domain)
+}
+
+// useProbes makes probes the OSINT collector's probe set.
+func useProbes(probes ...lookup.Probe) {
+	osintProbes = func() []lookup.Probe { return probes }
+}
+
+func failWith(err error) func(string) (bool, time.Duration, error) {
+	return func(string) (bool, time.Duration, error) { return false, 10 * time.Millisecond, err }
+}
+
+func TestUnknownGraceRetry(t *testing.T) {
+	savedRetry, savedDelay := UnknownGraceRetry, UnknownGraceDelay
+	defer func() { UnknownGraceRetry, UnknownGraceDelay = savedRetry, savedDelay }()
+	UnknownGraceRetry = true
+	UnknownGraceDelay = 10 * time.Millisecond
+
+	// runSmtpProbes retries a transient target once itself, so one
+	// verification makes two RCPT TO probes.
+	const probesPerAttempt = 2
+
+	t.Run("connection failure retries once", func(t *testing.T) {
+		probes := stubCollectors(t, "unreachable.example", failWith(errors.New("connection failed: dial tcp: i/o timeout")))
+		res, err := VerifyEmail(context.Background(), "jane@unreachable.example", "unreachable.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != models.StatusUnknown {
+			t.Errorf("status %q, expected unknown", res.Status)
+		}
+		if got := atomic.LoadInt32(probes); got != 2*probesPerAttempt {
+			t.Errorf("%d probes, expected %d (one grace retry)", got, 2*probesPerAttempt)
+		}
+	})
+
+	t.Run("server reply without verdict does not retry", func(t *testing.T) {
+		probes := stubCollectors(t, "greylist.example", failWith(&textproto.Error{Code: 451, Msg: "4.7.1 try again later"}))
+		if _, err := VerifyEmail(context.Background(), "jane@greylist.example", "greylist.example"); err != nil {
+			t.Fatal(err)
+		}
+		if got := atomic.LoadInt32(probes); got != probesPerAttempt {
+			t.Errorf("%d probes, expected %d (no grace retry)", got, probesPerAttempt)
+		}
+	})
+}
+
+func TestGreylistedThenAccepted(t *testing.T) {
+	var targetCalls int32
+	stubCollectors(t, "greylisted.example", func(email string) (bool, time.Duration, error) {
+		if email != "jane@greylisted.example" {
+			return false, 10 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+		}
+		if atomic.AddInt32(&targetCalls, 1) == 1 {
+			return false, 10 * time.Millisecond, &lookup.GreylistError{Err: &textproto.Error{Code: 451, Msg: "4.7.1 greylisted, try again later"}}
+		}
+		return true, 10 * time.Millisecond, nil
+	})
+
+	res, err := VerifyEmail(context.Background(), "jane@greylisted.example", "greylisted.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Analysis.IsGreylisted {
+		t.Errorf("expected IsGreylisted after a deferred-then-accepted RCPT")
+	}
+	if res.Analysis.SmtpStatus != 250 || !hasKey(res.ScoreBreakdown, "p2_greylisted") {
+		t.Errorf("smtp_status=%d breakdown=%v", res.Analysis.SmtpStatus, res.ScoreBreakdown)
+	}
+}
+
+func TestVRFYCorroborationProbesRCPT(t *testing.T) {
+	saved := VRFYRequireCorroboration
+	defer func() { VRFYRequireCorroboration = saved }()
+
+	lyingVRFY := func(t *testing.T, domain string) *int32 {
+		probes := stubCollectors(t, domain, failWith(&textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}))
+		vrfyProbe = func(ctx context.Context, mxHost, email string, pURL *url.URL) bool { return true }
+		return probes
+	}
+
+	t.Run("default trusts VRFY", func(t *testing.T) {
+		VRFYRequireCorroboration = false
+		probes := lyingVRFY(t, "vrfy-fast.example")
+		res, err := VerifyEmail(context.Background(), "jane@vrfy-fast.example", "vrfy-fast.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != models.StatusValid || atomic.LoadInt32(probes) != 0 {
+			t.Errorf("status %q after %d RCPT probes, expected valid with none", res.Status, atomic.LoadInt32(probes))
+		}
+	})
+
+	t.Run("corroboration runs RCPT", func(t *testing.T) {
+		VRFYRequireCorroboration = true
+		probes := lyingVRFY(t, "vrfy-strict.example")
+		res, err := VerifyEmail(context.Background(), "jane@vrfy-strict.example", "vrfy-strict.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != models.StatusInvalid || atomic.LoadInt32(probes) == 0 {
+			t.Errorf("status %q after %d RCPT probes, expected invalid after probing", res.Status, atomic.LoadInt32(probes))
+		}
+		if !res.Analysis.HasVRFY {
+			t.Errorf("the VRFY hit should still be reported in the analysis")
+		}
+	})
+}
+
+func TestMXFallback(t *testing.T) {
+	const domain = "backup-mx.example"
+	stubCollectors(t, domain, nil)
+
+	resolveMX = func(ctx context.Context, d string) ([]lookup.MXRecord, error) {
+		return []lookup.MXRecord{
+			{Host: "mx1." + domain, Pref: 10},
+			{Host: "mx2." + domain, Pref: 20},
+			{Host: "mx3." + domain, Pref: 30},
+		}, nil
+	}
+	cache.DomainCache.Set("smtp_host:mx1."+domain+":"+domain, SmtpHostResult{}, time.Minute)
+
+	var mu sync.Mutex
+	hosts := map[string][]string{} // email -> hosts probed
+	smtpProbe = func(ctx context.Context, mxHost, email string, pURL *url.URL) (bool, time.Duration, error) {
+		mu.Lock()
+		hosts[email] = append(hosts[email], mxHost)
+		mu.Unlock()
+		switch {
+		case mxHost == "mx1."+domain:
+			return false, 0, errors.New("connection failed: dial tcp: i/o timeout")
+		case email == "jane@"+domain:
+			return true, 10 * time.Millisecond, nil
+		default:
+			return false, 10 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+		}
+	}
+
+	res, err := VerifyEmail(context.Background(), "jane@"+domain, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != models.StatusValid || res.Analysis.SmtpHost != "mx2."+domain {
+		t.Errorf("status %q via %q, expected valid via the secondary MX", res.Status, res.Analysis.SmtpHost)
+	}
+
+	for email, probed := range hosts {
+		if email == "jane@"+domain {
+			if want := []string{"mx1." + domain, "mx1." + domain, "mx2." + domain}; !reflect.DeepEqual(probed, want) {
+				t.Errorf("target probed %v, want %v", probed, want)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(probed, []string{"mx2." + domain}) {
+			t.Errorf("ghost %s probed %v, expected only the host that answered the target", email, probed)
+		}
+	}
+}
+
+func TestSessionInfoComesFromTargetProbe(t *testing.T) {
+	const domain = "session.example"
+	saved := smtpProbe
+	defer func() { smtpProbe = saved }()
+
+	var mu sync.Mutex
+	reporting := map[string]*lookup.SessionInfo{}
+	smtpProbe = func(ctx context.Context, mxHost, email string, pURL *url.URL) (bool, time.Duration, error) {
+		mu.Lock()
+		reporting[email] = lookup.SessionInfoFrom(ctx)
+		mu.Unlock()
+		return true, 10 * time.Millisecond, nil
+	}
+
+	report := runSmtpProbes(context.Background(), "jane@"+domain, domain, "mx."+domain, nil)
+	if report.Ghost.Address == "" {
+		t.Fatal("expected the ghost probe to run")
+	}
+	if report.Session == nil || reporting["jane@"+domain] != report.Session {
+		t.Errorf("report.Session should be the target session's record")
+	}
+	if reporting[report.Ghost.Address] != nil {
+		t.Errorf("the ghost probe must not report into any SessionInfo")
+	}
+}
+
+func TestSuggestedEmailForTypoDomain(t *testing.T) {
+	stubCollectors(t, "gmial.com", failWith(errors.New("unreachable")))
+	resolveMX = func(ctx context.Context, d string) ([]lookup.MXRecord, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: d, IsNotFound: true}
+	}
+
+	res, err := VerifyEmail(context.Background(), "jane@gmial.com", "gmial.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SuggestedEmail != "jane@gmail.com" {
+		t.Errorf("expected suggestion jane@gmail.com, got %q", res.SuggestedEmail)
+	}
+
+	// A typo-like domain that does have MX is taken at its word.
+	stubCollectors(t, "gmial.com", failWith(&textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}))
+	res, _ = VerifyEmail(context.Background(), "john@gmial.com", "gmial.com")
+	if res.SuggestedEmail != "" {
+		t.Errorf("no suggestion expected when the domain has MX, got %q", res.SuggestedEmail)
+	}
+}
+
+func TestVerifyEmailProbesNormalizedAddress(t *testing.T) {
+	var probed []string
+	var mu sync.Mutex
+	stubCollectors(t, "plus.example", func(email string) (bool, time.Duration, error) {
+		mu.Lock()
+		probed = append(probed, email)
+		mu.Unlock()
+		if email != "jane@plus.example" {
+			return false, 10 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+		}
+		return true, 10 * time.Millisecond, nil
+	})
+
+	res, err := VerifyEmail(context.Background(), "jane+news@plus.example", "plus.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Email != "jane+news@plus.example" || res.NormalizedEmail != "jane@plus.example" {
+		t.Errorf("email=%q normalized=%q", res.Email, res.NormalizedEmail)
+	}
+	for _, e := range probed {
+		if strings.Contains(e, "+") {
+			t.Errorf("the tagged address was probed: %s", e)
+		}
+	}
+	if res.Analysis.SmtpStatus != 250 {
+		t.Errorf("expected the canonical mailbox to be accepted, got %d", res.Analysis.SmtpStatus)
+	}
+}
+
+func TestInvalidSyntaxSkipsProbes(t *testing.T) {
+	probes := stubCollectors(t, "syntax.example", failWith(errors.New("must not be probed")))
+	resolved := false
+	resolveMX = func(ctx context.Context, d string) ([]lookup.MXRecord, error) {
+		resolved = true
+		return nil, nil
+	}
+
+	res, err := VerifyEmail(context.Background(), "jane doe@syntax.example", "syntax.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != models.StatusInvalid || res.Score != 0 || res.Reason != ReasonInvalidSyntax {
+		t.Errorf("got status=%s score=%d reason=%q", res.Status, res.Score, res.Reason)
+	}
+	if resolved || atomic.LoadInt32(probes) != 0 {
+		t.Errorf("a malformed address must not reach DNS or SMTP")
+	}
+}
+
+func TestVerifyEmailConvertsIDNDomain(t *testing.T) {
+	var probed []string
+	var mu sync.Mutex
+	stubCollectors(t, "xn--mnchen-3ya.de", func(email string) (bool, time.Duration, error) {
+		mu.Lock()
+		probed = append(probed, email)
+		mu.Unlock()
+		return email == "jane@xn--mnchen-3ya.de", 10 * time.Millisecond, nil
+	})
+
+	res, err := VerifyEmail(context.Background(), "jane@münchen.de", "münchen.de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Email != "jane@münchen.de" || res.DomainUnicode != "münchen.de" || res.DomainASCII != "xn--mnchen-3ya.de" {
+		t.Errorf("email=%q unicode=%q ascii=%q", res.Email, res.DomainUnicode, res.DomainASCII)
+	}
+	for _, e := range probed {
+		if !strings.HasSuffix(e, "@xn--mnchen-3ya.de") {
+			t.Errorf("probed %q, want the punycode domain", e)
+		}
+	}
+	if res.Analysis.IsMixedScriptDomain {
+		t.Errorf("a single-script IDN must not be flagged as a homograph")
+	}
+}
+
+func TestConservativeCatchAll(t *testing.T) {
+	saved := ConservativeCatchAll
+	defer func() { ConservativeCatchAll = saved }()
+	ConservativeCatchAll = true
+
+	// The target is always accepted and the first ghost too; confirmAccepts
+	// decides how the confirming ghost is answered. Ghosts answer 50ms slower
+	// than the target so the ghost-collision check stays out of the way.
+	run := func(t *testing.T, domain string, confirmAccepts bool) (models.ValidationResult, int32) {
+		target := "jane@" + domain
+		var ghosts int32
+		probes := stubCollectors(t, domain, func(email string) (bool, time.Duration, error) {
+			if email == target {
+				return true, 10 * time.Millisecond, nil
+			}
+			if atomic.AddInt32(&ghosts, 1) == 1 || confirmAccepts {
+				return true, 60 * time.Millisecond, nil
+			}
+			return false, 60 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+		})
+		res, err := VerifyEmail(context.Background(), target, domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res, atomic.LoadInt32(probes)
+	}
+
+	t.Run("double-confirmed catch-all", func(t *testing.T) {
+		res, probes := run(t, "confirmed-catchall.example", true)
+		if res.Status != models.StatusCatchAll || !res.Analysis.IsCatchAll {
+			t.Errorf("status %q catch_all=%v, expected a catch-all verdict", res.Status, res.Analysis.IsCatchAll)
+		}
+		if probes != 3 {
+			t.Errorf("%d probes, expected target + ghost + confirmation", probes)
+		}
+		if res.Reason != "" {
+			t.Errorf("reason %q, expected none", res.Reason)
+		}
+	})
+
+	t.Run("single observation downgrades to unknown", func(t *testing.T) {
+		res, _ := run(t, "flaky-catchall.example", false)
+		if res.Status != models.StatusUnknown || res.Analysis.IsCatchAll {
+			t.Errorf("status %q catch_all=%v, expected unknown", res.Status, res.Analysis.IsCatchAll)
+		}
+		if res.Reason != ReasonCatchAllUnconfirmed {
+			t.Errorf("reason %q, expected %q", res.Reason, ReasonCatchAllUnconfirmed)
+		}
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		ConservativeCatchAll = false
+		defer func() { ConservativeCatchAll = true }()
+		res, probes := run(t, "single-catchall.example", false)
+		if res.Status != models.StatusCatchAll || probes != 2 {
+			t.Errorf("status %q after %d probes, expected catch-all without confirmation", res.Status, probes)
+		}
+	})
+}
+
+func TestUndeliverableCause(t *testing.T) {
+	t.Run("domain without MX", func(t *testing.T) {
+		stubCollectors(t, "nomx-cause.example", failWith(errors.New("unreachable")))
+		resolveMX = func(ctx context.Context, d string) ([]lookup.MXRecord, error) {
+			return nil, &net.DNSError{Err: "no such host", Name: d, IsNotFound: true}
+		}
+		res, err := VerifyEmail(context.Background(), "jane@nomx-cause.example", "nomx-cause.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Undeliverable != models.CauseDomainNoMX {
+			t.Errorf("undeliverable %q, expected %q", res.Undeliverable, models.CauseDomainNoMX)
+		}
+	})
+
+	t.Run("working domain, missing mailbox", func(t *testing.T) {
+		stubCollectors(t, "badbox-cause.example", failWith(&textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}))
+		res, err := VerifyEmail(context.Background(), "nobody@badbox-cause.example", "badbox-cause.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != models.StatusInvalid || res.Undeliverable != models.CauseMailboxNotFound {
+			t.Errorf("status %q undeliverable %q, expected invalid %q", res.Status, res.Undeliverable, models.CauseMailboxNotFound)
+		}
+	})
+
+	t.Run("deliverable address", func(t *testing.T) {
+		domain := "fine-cause.example"
+		stubCollectors(t, domain, func(email string) (bool, time.Duration, error) {
+			if email == "jane@"+domain {
+				return true, 10 * time.Millisecond, nil
+			}
+			return false, 10 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+		})
+		res, _ := VerifyEmail(context.Background(), "jane@"+domain, domain)
+		if res.Undeliverable != "" {
+			t.Errorf("undeliverable %q for a valid address", res.Undeliverable)
+		}
+	})
+}
+
+func TestUndeliverableCauseParked(t *testing.T) {
+	parked := models.RiskAnalysis{IsParked: true}
+	if got := undeliverableCause(false, parked, models.StatusInvalid); got != models.CauseDomainParked {
+		t.Errorf("parked invalid: got %q", got)
+	}
+	if got := undeliverableCause(false, parked, models.StatusValid); got != "" {
+		t.Errorf("parked domain proven live should carry no cause, got %q", got)
+	}
+}
+
+func TestMXCountAndProviders(t *testing.T) {
+	domain := "redundant-mx.example"
+	stubCollectors(t, domain, func(email string) (bool, time.Duration, error) {
+		if email == "jane@"+domain {
+			return true, 10 * time.Millisecond, nil
+		}
+		return false, 10 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+	})
+
+	mx := []lookup.MXRecord{
+		{Host: "aspmx.l.google.com", Pref: 1},
+		{Host: "alt1.aspmx.l.google.com", Pref: 5},
+		{Host: "eu-smtp-inbound-1.mimecast.com", Pref: 20},
+	}
+	resolveMX = func(ctx context.Context, d string) ([]lookup.MXRecord, error) { return mx, nil }
+	cache.DomainCache.Set(infraCacheKey(domain, mx), DomainResult{Provider: "google"}, time.Minute)
+	cache.DomainCache.Set("smtp_host:aspmx.l.google.com:"+domain, SmtpHostResult{}, time.Minute)
+
+	res, _ := VerifyEmail(context.Background(), "jane@"+domain, domain)
+	if res.Analysis.MxCount != 3 {
+		t.Errorf("mx_count = %d, want 3", res.Analysis.MxCount)
+	}
+	if want := []string{"google", "mimecast"}; !reflect.DeepEqual(res.Analysis.MxProviders, want) {
+		t.Errorf("mx_providers = %v, want %v", res.Analysis.MxProviders, want)
+	}
+	if _, ok := res.ScoreBreakdown["p3_mx_redundancy"]; !ok {
+		t.Errorf("expected the MX redundancy signal in the breakdown, got %v", res.ScoreBreakdown)
+	}
+}
+
+func TestNeverCatchAllSkipsGhostProbe(t *testing.T) {
+	saved := NeverCatchAllProviders
+	defer func() { NeverCatchAllProviders = saved }()
+
+	// The host accepts every address, so only the ghost probe would reveal
+	// anything: with it skipped, the accepted target is taken as valid.
+	var probed []string
+	var mu sync.Mutex
+	savedProbe := smtpProbe
+	defer func() { smtpProbe = savedProbe }()
+	smtpProbe = func(ctx context.Context, mxHost, email string, pURL *url.URL) (bool, time.Duration, error) {
+		mu.Lock()
+		probed = append(probed, email)
+		mu.Unlock()
+		return true, 10 * time.Millisecond, nil
+	}
+
+	NeverCatchAllProviders = stringSet([]string{"Google"})
+	report := runSmtpProbes(context.Background(), "jane@acme.example", "acme.example", "aspmx.l.google.com", nil)
+	if len(probed) != 1 || probed[0] != "jane@acme.example" {
+		t.Fatalf("expected only the target to be probed, got %v", probed)
+	}
+	if report.Status != 250 || report.IsCatchAll || report.Ghost.Address != "" {
+		t.Errorf("unexpected report: status=%d catch_all=%v ghost=%q", report.Status, report.IsCatchAll, report.Ghost.Address)
+	}
+
+	// Other providers keep the ghost probe.
+	probed = nil
+	report = runSmtpProbes(context.Background(), "jane@acme.example", "acme.example", "mx.acme.example", nil)
+	if len(probed) != 2 || !report.IsCatchAll {
+		t.Errorf("expected target and ghost probes on a generic host, got %v (catch_all=%v)", probed, report.IsCatchAll)
+	}
+}
+
+func TestGhostCollision(t *testing.T) {
+	saved := GhostCollisionReprobe
+	defer func() { GhostCollisionReprobe = saved }()
+	GhostCollisionReprobe = true
+
+	// The target and the first ghost (first.last.hex) are answered
+	// identically; altAccepts decides the differently shaped re-probe.
+	run := func(t *testing.T, domain string, altAccepts bool) (models.ValidationResult, int32) {
+		target := "jane@" + domain
+		probes := stubCollectors(t, domain, func(email string) (bool, time.Duration, error) {
+			local := strings.SplitN(email, "@", 2)[0]
+			if email == target || strings.Count(local, ".") == 2 || altAccepts {
+				return true, 10 * time.Millisecond, nil
+			}
+			return false, 10 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+		})
+		res, err := VerifyEmail(context.Background(), target, domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res, atomic.LoadInt32(probes)
+	}
+
+	t.Run("collision with a real mailbox", func(t *testing.T) {
+		res, probes := run(t, "ghost-collision.example", false)
+		if probes != 3 {
+			t.Errorf("%d probes, expected target + ghost + re-probe", probes)
+		}
+		if res.Analysis.IsCatchAll || res.Status != models.StatusValid {
+			t.Errorf("status %q catch_all=%v, expected the rejected re-probe to clear the catch-all", res.Status, res.Analysis.IsCatchAll)
+		}
+		if res.Analysis.CatchAllLowConfidence {
+			t.Error("a resolved collision should not be marked low confidence")
+		}
+	})
+
+	t.Run("genuine catch-all", func(t *testing.T) {
+		res, _ := run(t, "ghost-genuine.example", true)
+		if !res.Analysis.IsCatchAll || res.Analysis.CatchAllLowConfidence {
+			t.Errorf("catch_all=%v low_confidence=%v, expected a confirmed catch-all", res.Analysis.IsCatchAll, res.Analysis.CatchAllLowConfidence)
+		}
+	})
+
+	t.Run("host already known catch-all", func(t *testing.T) {
+		domain := "ghost-known.example"
+		probes := stubCollectors(t, domain, func(email string) (bool, time.Duration, error) {
+			return true, 10 * time.Millisecond, nil
+		})
+		cache.DomainCache.Set("smtp_host:mx."+domain+":"+domain, SmtpHostResult{IsCatchAll: true}, time.Minute)
+
+		res, err := VerifyEmail(context.Background(), "jane@"+domain, domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := atomic.LoadInt32(probes); n != 2 || !res.Analysis.IsCatchAll || res.Analysis.CatchAllLowConfidence {
+			t.Errorf("after %d probes catch_all=%v low_confidence=%v, expected target + ghost only and a confident catch-all", n, res.Analysis.IsCatchAll, res.Analysis.CatchAllLowConfidence)
+		}
+	})
+
+	t.Run("re-probe disabled", func(t *testing.T) {
+		GhostCollisionReprobe = false
+		defer func() { GhostCollisionReprobe = true }()
+		res, probes := run(t, "ghost-noreprobe.example", false)
+		if probes != 2 || !res.Analysis.IsCatchAll || !res.Analysis.CatchAllLowConfidence {
+			t.Errorf("after %d probes catch_all=%v low_confidence=%v, expected a low-confidence catch-all", probes, res.Analysis.IsCatchAll, res.Analysis.CatchAllLowConfidence)
+		}
+	})
+}
+
+func TestOSINTSemaphoreBoundsProbes(t *testing.T) {
+	domain := "osintcap.example"
+	stubCollectors(t, domain, failWith(&textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}))
+
+	saved := OSINTSemaphore
+	defer func() { OSINTSemaphore = saved }()
+	OSINTSemaphore = make(chan struct{}, 2)
+
+	var inFlight, peak, ran int32
+	check := func(ctx context.Context, _, _ string) bool {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		atomic.AddInt32(&ran, 1)
+		return false
+	}
+	var probes []lookup.Probe
+	for i := 0; i < 6; i++ {
+		probes = append(probes, fakeProbe{"p" + strconv.Itoa(i), lookup.SignalGravatar, check})
+	}
+	useProbes(probes...)
+
+	if _, err := VerifyEmail(context.Background(), "jane@"+domain, domain); err != nil {
+		t.Fatal(err)
+	}
+	if ran != 6 {
+		t.Errorf("%d probes ran, want 6", ran)
+	}
+	if peak > 2 {
+		t.Errorf("%d probes in flight at once, want at most 2", peak)
+	}
+}
+
+func TestEnabledOSINTProbes(t *testing.T) {
+	names := func(probes []lookup.Probe) []string {
+		var out []string
+		for _, p := range probes {
+			out = append(out, p.Name())
+		}
+		return out
+	}
+
+	all := lookup.Probes()
+	def := names(enabledOSINTProbes(all, nil))
+	for _, n := range def {
+		if n == "twitter" || n == "spotify" {
+			t.Errorf("opt-in probe %q enabled by default", n)
+		}
+	}
+	if len(def) != len(all)-len(optInOSINTProbes) {
+		t.Errorf("default probes %v", def)
+	}
+
+	got := names(enabledOSINTProbes(all, []string{"Spotify", "github", "nosuch"}))
+	if want := []string{"github", "spotify"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("allowlisted probes %v, want %v", got, want)
+	}
+}
+
+func TestOSINTProbeTimeout(t *testing.T) {
+	domain := "osinttimeout.example"
+	stubCollectors(t, domain, failWith(&textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}))
+
+	saved := OSINTProbeTimeout
+	defer func() { OSINTProbeTimeout = saved }()
+	OSINTProbeTimeout = 50 * time.Millisecond
+
+	useProbes(
+		fakeProbe{"hung", lookup.SignalGravatar, func(ctx context.Context, _, _ string) bool {
+			<-ctx.Done()
+			return false
+		}},
+		fakeProbe{"teams", lookup.SignalTeams, func(ctx context.Context, _, _ string) bool {
+			return true
+		}},
+	)
+
+	start := time.Now()
+	res, err := VerifyEmail(context.Background(), "jane@"+domain, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("verification took %s with a hung probe", elapsed)
+	}
+	if !reflect.DeepEqual(res.Analysis.OsintTimeouts, []string{"hung"}) {
+		t.Errorf("OsintTimeouts = %v, want [hung]", res.Analysis.OsintTimeouts)
+	}
+	if !res.Analysis.HasTeamsPresence {
+		t.Error("the probe that answered in time was lost")
+	}
+}
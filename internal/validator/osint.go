@@ -0,0 +1,126 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/lookup"
+	"mailvetter/internal/models"
+)
+
+// OSINTSemaphore bounds the OSINT HTTP probes in flight across every
+// verification in the process, so their number no longer grows with worker
+// count times probe count. It is separate from proxy.Semaphore, which only
+// covers proxied requests. Sized via OSINT_CONCURRENCY.
+var OSINTSemaphore = make(chan struct{}, osintConcurrency(config.Int("OSINT_CONCURRENCY", 64)))
+
+func osintConcurrency(n int) int {
+	if n <= 0 {
+		return 64
+	}
+	return n
+}
+
+// OSINTProbeTimeout caps each OSINT probe on its own, counted from when it
+// gets an OSINTSemaphore slot, so one hung endpoint cannot hold a
+// verification until the job deadline. Set via OSINT_PROBE_TIMEOUT; 0 leaves
+// probes bounded by the verification's context alone.
+var OSINTProbeTimeout = config.NonNegativeDuration("OSINT_PROBE_TIMEOUT", 8*time.Second)
+
+// withProbeTimeout runs fn under OSINTProbeTimeout and reports whether that
+// timeout, rather than ctx ending, cut it short.
+func withProbeTimeout(ctx context.Context, fn func(ctx context.Context)) (timedOut bool) {
+	if OSINTProbeTimeout <= 0 {
+		fn(ctx)
+		return false
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, OSINTProbeTimeout)
+	defer cancel()
+	fn(probeCtx)
+	return errors.Is(probeCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+}
+
+// acquireOSINT waits for an OSINTSemaphore slot, giving up when ctx is done.
+func acquireOSINT(ctx context.Context) bool {
+	select {
+	case OSINTSemaphore <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func releaseOSINT() { <-OSINTSemaphore }
+
+// osintProbes returns the probes the OSINT collector runs: those registered
+// with lookup.RegisterProbe, narrowed by OSINT_PROBES. It is a variable so
+// tests can avoid real HTTP.
+var osintProbes = func() []lookup.Probe {
+	return enabledOSINTProbes(lookup.Probes(), osintProbeAllow)
+}
+
+var osintProbeAllow = config.List("OSINT_PROBES")
+
+// optInOSINTProbes are left out unless OSINT_PROBES names them: consumer
+// services that add little for business addresses.
+var optInOSINTProbes = map[string]bool{"twitter": true, "spotify": true}
+
+// enabledOSINTProbes returns the probes named in allow, in probes order, or
+// every probe but the opt-in ones when allow is empty. Unknown names are
+// logged and ignored.
+func enabledOSINTProbes(probes []lookup.Probe, allow []string) []lookup.Probe {
+	if len(allow) == 0 {
+		var out []lookup.Probe
+		for _, p := range probes {
+			if !optInOSINTProbes[p.Name()] {
+				out = append(out, p)
+			}
+		}
+		return out
+	}
+
+	want := make(map[string]bool, len(allow))
+	for _, name := range allow {
+		want[strings.ToLower(name)] = true
+	}
+	var out []lookup.Probe
+	for _, p := range probes {
+		if want[p.Name()] {
+			out = append(out, p)
+			delete(want, p.Name())
+		}
+	}
+	for name := range want {
+		log.Printf("⚠️  OSINT_PROBES: unknown probe %q ignored", name)
+	}
+	return out
+}
+
+// probeSignals records a probe hit on the analysis, by the signal key the
+// probe returned. A probe reporting a key missing here is logged and ignored.
+var probeSignals = map[string]func(a *models.RiskAnalysis){
+	lookup.SignalGoogleCalendar: func(a *models.RiskAnalysis) { a.HasGoogleCalendar = true },
+	lookup.SignalTeams:          func(a *models.RiskAnalysis) { a.HasTeamsPresence = true },
+	lookup.SignalSharePoint:     func(a *models.RiskAnalysis) { a.HasSharePoint = true },
+	lookup.SignalAdobe:          func(a *models.RiskAnalysis) { a.HasAdobe = true },
+	lookup.SignalGravatar:       func(a *models.RiskAnalysis) { a.HasGravatar = true },
+	lookup.SignalGitHub:         func(a *models.RiskAnalysis) { a.HasGitHub = true },
+	lookup.SignalLinkedIn:       func(a *models.RiskAnalysis) { a.HasLinkedIn = true },
+	lookup.SignalSlack:          func(a *models.RiskAnalysis) { a.HasSlack = true },
+	lookup.SignalTwitter:        func(a *models.RiskAnalysis) { a.HasTwitter = true },
+	lookup.SignalSpotify:        func(a *models.RiskAnalysis) { a.HasSpotify = true },
+}
+
+// applyProbeSignal records the hit signal on a.
+func applyProbeSignal(a *models.RiskAnalysis, probe, signal string) {
+	apply, ok := probeSignals[signal]
+	if !ok {
+		log.Printf("⚠️  OSINT probe %s: unknown signal %q ignored", probe, signal)
+		return
+	}
+	apply(a)
+}
@@ -0,0 +1,98 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"mailvetter/internal/models"
+)
+
+// RawSignals is a verification's collected evidence without a verdict, for
+// callers that feed the signals into their own scoring model. It carries no
+// score, status, or reachability.
+type RawSignals struct {
+	Email           string `json:"email"`
+	NormalizedEmail string `json:"normalized_email,omitempty"`
+	// Reason is set when the address was rejected before any signal was
+	// collected (over-length, disposable domain) or could not be probed.
+	Reason   string              `json:"reason,omitempty"`
+	Analysis models.RiskAnalysis `json:"analysis"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// VerifyRaw collects the same signals as VerifyEmail and returns them
+// without the verdict CalculateRobustScore derives from them.
+func VerifyRaw(ctx context.Context, email, domain string) (RawSignals, error) {
+	result, err := VerifyEmail(ctx, email, domain)
+	return rawSignals(result), err
+}
+
+func rawSignals(r models.ValidationResult) RawSignals {
+	raw := RawSignals{Email: r.Email, NormalizedEmail: r.NormalizedEmail, Reason: r.Reason, Analysis: r.Analysis, Error: r.Error}
+	// Likely-disposable is a scoring conclusion, not an observation.
+	if raw.Reason == ReasonLikelyDisposable {
+		raw.Reason = ""
+	}
+	return raw
+}
+
+// Scored is the verdict CalculateRobustScore gives a RiskAnalysis, as returned
+// by POST /score.
+type Scored struct {
+	Score          int                       `json:"score"`
+	ScoreBreakdown map[string]float64        `json:"score_details"`
+	Explanation    []string                  `json:"explanation,omitempty"`
+	Status         models.VerificationStatus `json:"status"`
+	NuancedStatus  models.VerificationStatus `json:"nuanced_status,omitempty"`
+	Reachability   models.Reachability       `json:"reachability"`
+	Recommendation models.Recommendation     `json:"recommendation,omitempty"`
+	ConfirmedBy    string                    `json:"confirmed_by,omitempty"`
+}
+
+// ScoreAnalysis scores signals collected elsewhere — by the caller, or a
+// VerifyRaw run stored earlier — without probing anything.
+func ScoreAnalysis(a models.RiskAnalysis) Scored {
+	score, breakdown, reachability, status, confirmedBy := CalculateRobustScore(a)
+	s := Scored{
+		Score:          score,
+		ScoreBreakdown: breakdown,
+		Explanation:    ExplainScore(breakdown, a),
+		Reachability:   reachability,
+		ConfirmedBy:    confirmedBy,
+	}
+	s.Status, s.NuancedStatus = ApplyInvalidBelow(score, status)
+	s.Recommendation = Recommend(s.Status, s.Score, a.CatchAllLowConfidence)
+	return s
+}
+
+// DecodeAnalysis reads one RiskAnalysis JSON object from r, rejecting unknown
+// fields (usually a misspelt signal that would otherwise score as absent) and
+// values no probe could have produced.
+func DecodeAnalysis(r io.Reader) (models.RiskAnalysis, error) {
+	var a models.RiskAnalysis
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&a); err != nil {
+		return a, fmt.Errorf("malformed analysis: %w", err)
+	}
+	if dec.More() {
+		return a, errors.New("malformed analysis: trailing data after the object")
+	}
+
+	switch {
+	case a.SmtpStatus != 0 && (a.SmtpStatus < 200 || a.SmtpStatus > 599):
+		return a, fmt.Errorf("smtp_status %d is not an SMTP reply code", a.SmtpStatus)
+	case a.EntropyScore < 0 || a.EntropyScore > 1:
+		return a, fmt.Errorf("entropy_score %v is outside [0, 1]", a.EntropyScore)
+	case a.DomainAgeDays < 0:
+		return a, errors.New("domain_age_days must not be negative")
+	case a.BreachCount < 0:
+		return a, errors.New("breach_count must not be negative")
+	case a.MxCount < 0:
+		return a, errors.New("mx_count must not be negative")
+	}
+	return a, nil
+}
@@ -0,0 +1,83 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"mailvetter/internal/models"
+)
+
+func TestVerifyRawOmitsVerdict(t *testing.T) {
+	stubCollectors(t, "raw.example", func(email string) (bool, time.Duration, error) {
+		if email != "jane@raw.example" {
+			return false, 10 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+		}
+		return true, 10 * time.Millisecond, nil
+	})
+
+	raw, err := VerifyRaw(context.Background(), "jane@raw.example", "raw.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Email != "jane@raw.example" || raw.Analysis.SmtpStatus != 250 || raw.Analysis.MxProvider != "generic" {
+		t.Errorf("expected the collected signals, got %+v", raw)
+	}
+
+	body, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	json.Unmarshal(body, &fields)
+	for _, verdict := range []string{"score", "score_details", "status", "reachability", "confirmed_by"} {
+		if _, ok := fields[verdict]; ok {
+			t.Errorf("raw response must not carry %q: %s", verdict, body)
+		}
+	}
+	if _, ok := fields["analysis"]; !ok {
+		t.Errorf("raw response is missing the analysis: %s", body)
+	}
+}
+
+func TestRawSignalsDropsScoringReason(t *testing.T) {
+	if raw := rawSignals(models.ValidationResult{Reason: ReasonLikelyDisposable}); raw.Reason != "" {
+		t.Errorf("a scoring-derived reason leaked into raw mode: %q", raw.Reason)
+	}
+	if raw := rawSignals(models.ValidationResult{Reason: ReasonDisposable}); raw.Reason != ReasonDisposable {
+		t.Errorf("the pre-probe gate reason must be kept, got %q", raw.Reason)
+	}
+}
+
+func TestScoreAnalysis(t *testing.T) {
+	// The "Standard Valid Business Email" case from TestCalculateRobustScore,
+	// as a client would post it to /score.
+	a, err := DecodeAnalysis(strings.NewReader(`{"smtp_status": 250, "has_spf": true, "has_dmarc": true}`))
+	if err != nil {
+		t.Fatalf("DecodeAnalysis: %v", err)
+	}
+	got := ScoreAnalysis(a)
+	if got.Score < 90 || got.Score > 99 || got.Status != models.StatusValid || got.Reachability != models.ReachabilitySafe {
+		t.Errorf("scored %d/%s/%s, want 90-99/valid/safe", got.Score, got.Status, got.Reachability)
+	}
+	if got.ScoreBreakdown["base_smtp_valid"] == 0 || got.Recommendation == "" {
+		t.Errorf("missing breakdown or recommendation: %+v", got)
+	}
+
+	for _, body := range []string{
+		`{"smtp_status": 250, "has_spff": true}`,
+		`{"smtp_status": "250"}`,
+		`{"smtp_status": 25}`,
+		`{"entropy_score": 1.5}`,
+		`{"breach_count": -1}`,
+		`{} {}`,
+		`[]`,
+	} {
+		if _, err := DecodeAnalysis(strings.NewReader(body)); err == nil {
+			t.Errorf("DecodeAnalysis(%s) accepted invalid input", body)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package validator
+
+import (
+	"strings"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/config"
+	"mailvetter/internal/models"
+)
+
+// Mode identifies how much verification work produced a result. A result is
+// only ever reused for a request of the same mode: a cheap syntax-only result
+// lacks the SMTP and OSINT signals a full-mode caller asked for.
+type Mode string
+
+const (
+	// ModeFull runs every collector: infrastructure, SMTP and OSINT.
+	ModeFull Mode = "full"
+	// ModeOSINT skips SMTP probing and scores on OSINT and syntax signals.
+	ModeOSINT Mode = "osint"
+)
+
+// ResultCacheTTL is how long a conclusive verification result is reused for
+// repeat lookups of the same address. Off (0) by default, since a cached
+// verdict hides a mailbox created or deleted in the meantime. Set via
+// RESULT_CACHE_TTL (e.g. "10m").
+var ResultCacheTTL = config.NonNegativeDuration("RESULT_CACHE_TTL", 0)
+
+// resultCacheKey builds the cache key for a result. The mode is part of the
+// key so results from different modes can never satisfy one another.
+//
+// Results are keyed by the full address, never by domain: the SMTP and OSINT
+// signals behind a verdict (a Teams presence, a SharePoint site) belong to
+// one mailbox, and serving them for another address on the same domain would
+// confirm a mailbox nobody checked.
+func resultCacheKey(email string, mode Mode) string {
+	return "result:" + string(mode) + ":" + normalizeCacheEmail(email)
+}
+
+func normalizeCacheEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// getCachedResult returns a previously stored result for (email, mode),
+// marked Cached so callers do not record it as a fresh verdict. A stored
+// result for any other address is treated as a miss.
+func getCachedResult(email string, mode Mode) (models.ValidationResult, bool) {
+	if ResultCacheTTL <= 0 {
+		return models.ValidationResult{}, false
+	}
+	if val, ok := cache.DomainCache.Get(resultCacheKey(email, mode)); ok {
+		res := val.(models.ValidationResult)
+		if normalizeCacheEmail(res.Email) == normalizeCacheEmail(email) {
+			res.Cached = true
+			return res, true
+		}
+	}
+	return models.ValidationResult{}, false
+}
+
+// setCachedResult stores a result for (email, mode). Inconclusive results
+// (unknown status or an error) are not cached so the next request retries,
+// and neither is a result whose Email is not the address being keyed.
+func setCachedResult(email string, mode Mode, res models.ValidationResult) {
+	if ResultCacheTTL <= 0 {
+		return
+	}
+	if res.Status == models.StatusUnknown || res.Error != "" {
+		return
+	}
+	if !strings.Contains(email, "@") || normalizeCacheEmail(res.Email) != normalizeCacheEmail(email) {
+		return
+	}
+	cache.DomainCache.Set(resultCacheKey(email, mode), res, ResultCacheTTL)
+}
@@ -0,0 +1,113 @@
+package validator
+
+import (
+	"context"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"mailvetter/internal/lookup"
+	"mailvetter/internal/models"
+)
+
+// withResultCache turns the result cache on for the test.
+func withResultCache(t *testing.T) {
+	t.Helper()
+	saved := ResultCacheTTL
+	t.Cleanup(func() { ResultCacheTTL = saved })
+	ResultCacheTTL = time.Minute
+}
+
+func TestResultCacheIsKeyedByMode(t *testing.T) {
+	withResultCache(t)
+	email := "Cache.Mode@Example.com"
+	osintResult := models.ValidationResult{
+		Email:  email,
+		Score:  20,
+		Status: models.StatusRisky,
+	}
+
+	setCachedResult(email, ModeOSINT, osintResult)
+
+	if _, ok := getCachedResult(email, ModeFull); ok {
+		t.Errorf("OSINT-mode result must not satisfy a full-mode request")
+	}
+
+	got, ok := getCachedResult("cache.mode@example.com", ModeOSINT)
+	if !ok {
+		t.Fatalf("expected a hit for a second OSINT-mode request")
+	}
+	if got.Score != osintResult.Score || got.Status != osintResult.Status {
+		t.Errorf("cached result %+v != stored %+v", got, osintResult)
+	}
+	if !got.Cached {
+		t.Errorf("a cache hit must be marked Cached")
+	}
+}
+
+func TestResultCacheOffByDefault(t *testing.T) {
+	email := "off@example.com"
+	setCachedResult(email, ModeFull, models.ValidationResult{Email: email, Score: 90, Status: models.StatusValid})
+	if _, ok := getCachedResult(email, ModeFull); ok {
+		t.Errorf("results must not be cached without RESULT_CACHE_TTL")
+	}
+}
+
+func TestResultCacheSkipsInconclusive(t *testing.T) {
+	withResultCache(t)
+	email := "inconclusive@example.com"
+	setCachedResult(email, ModeFull, models.ValidationResult{Email: email, Status: models.StatusUnknown})
+
+	if _, ok := getCachedResult(email, ModeFull); ok {
+		t.Errorf("unknown results must not be cached")
+	}
+}
+
+func TestResultCacheIsKeyedPerAddress(t *testing.T) {
+	withResultCache(t)
+	alice := "alice@percache.example"
+	setCachedResult(alice, ModeFull, models.ValidationResult{
+		Email:  alice,
+		Score:  90,
+		Status: models.StatusValid,
+	})
+
+	if _, ok := getCachedResult("bob@percache.example", ModeFull); ok {
+		t.Errorf("a verdict for %s must not be served for another address on the domain", alice)
+	}
+	if _, ok := getCachedResult("percache.example", ModeFull); ok {
+		t.Errorf("a bare domain must never hit a per-address entry")
+	}
+
+	// A result stored under the wrong address is refused outright.
+	mismatched := "carol@percache.example"
+	setCachedResult(mismatched, ModeFull, models.ValidationResult{Email: alice, Score: 90, Status: models.StatusValid})
+	if _, ok := getCachedResult(mismatched, ModeFull); ok {
+		t.Errorf("a result for %s must not be cached under %s", alice, mismatched)
+	}
+}
+
+func TestOSINTVerdictNotSharedAcrossAddresses(t *testing.T) {
+	domain := "osintcache.example"
+	withResultCache(t)
+	stubCollectors(t, domain, failWith(&textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}))
+	useProbes(fakeProbe{"teams", lookup.SignalTeams, func(ctx context.Context, email, _ string) bool {
+		return email == "alice@"+domain
+	}})
+
+	alice, err := VerifyEmail(context.Background(), "alice@"+domain, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !alice.Analysis.HasTeamsPresence {
+		t.Fatalf("expected alice's Teams presence to be detected")
+	}
+
+	bob, err := VerifyEmail(context.Background(), "bob@"+domain, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.Email != "bob@"+domain || bob.Analysis.HasTeamsPresence || hasKey(bob.ScoreBreakdown, "p0_teams_identity") {
+		t.Errorf("bob inherited alice's OSINT verdict: %+v", bob)
+	}
+}
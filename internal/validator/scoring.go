@@ -1,60 +1,251 @@
 package validator
 
 import (
+	"mailvetter/internal/config"
 	"mailvetter/internal/models"
 	"math"
+	"strconv"
+	"strings"
 )
 
-const (
-	WeightTeams      = 15.0
-	WeightSharePoint = 60.0
-	WeightCalendar   = 42.5
+// UnknownInfraUpgrade, when enabled, lets an overwhelming infrastructure
+// footprint upgrade an unprobeable address (SmtpStatus == 0, e.g. port 25
+// blocked) from StatusUnknown to StatusRisky even without SMTP or OSINT proof.
+// Off by default; enable with SCORE_UNKNOWN_INFRA_UPGRADE=true.
+var UnknownInfraUpgrade = config.Bool("SCORE_UNKNOWN_INFRA_UPGRADE", false)
+
+// VRFYRequireCorroboration stops a VRFY 250 from validating an address on its
+// own. Some servers answer VRFY positively for every address; with this set,
+// the RCPT TO probe still runs after a VRFY hit and the golden ticket only
+// applies if RCPT accepts the address too. Off by default; enable with
+// VRFY_REQUIRE_CORROBORATION=true.
+var VRFYRequireCorroboration = config.Bool("VRFY_REQUIRE_CORROBORATION", false)
 
-	WeightProofpoint = 15.0
-	WeightSalesforce = 10.0
+// GatewayRequiresCorroboration tightens the empty-catch-all exemption for
+// enterprise-gateway domains. By default a gateway MX alone exempts a
+// catch-all from the resolution_catchall_empty penalty; when enabled, the
+// gateway only counts if the domain also publishes SPF and DMARC (an
+// established domain age exempts on its own either way). This stops parked
+// domains fronted by a gateway from inheriting its credibility.
+// Enable with SCORE_GATEWAY_REQUIRE_CORROBORATION=true.
+var GatewayRequiresCorroboration = config.Bool("SCORE_GATEWAY_REQUIRE_CORROBORATION", false)
 
-	WeightGitHub   = 12.0
-	WeightGravatar = 10.0
-	WeightAdobe    = 18.5
-	WeightBreach   = 45.0
+// isEnterpriseGateway reports whether provider is a secure email gateway.
+func isEnterpriseGateway(provider string) bool {
+	return provider == "proofpoint" ||
+		provider == "mimecast" ||
+		provider == "barracuda" ||
+		provider == "ironport"
+}
 
-	WeightVRFY = 99.0
+// LikelyDisposableMaxAgeDays is the domain age below which a footprint-free
+// catch-all is treated as a fresh burner.
+const LikelyDisposableMaxAgeDays = 7
 
-	WeightSPF   = 3.5
-	WeightDMARC = 4.5
+// IsLikelyDisposable reports the behavioural disposable pattern: a domain
+// registered within the last week, accepting every address, with no OSINT
+// trace of the mailbox and no mail infrastructure beyond the MX. Burner
+// services that rotate domains faster than static lists can track look
+// exactly like this; real new businesses almost always publish SPF first.
+func IsLikelyDisposable(analysis models.RiskAnalysis) bool {
+	if !analysis.IsCatchAll ||
+		analysis.DomainAgeDays <= 0 || analysis.DomainAgeDays >= LikelyDisposableMaxAgeDays {
+		return false
+	}
+	hasOSINT := analysis.HasTeamsPresence || analysis.HasSharePoint || analysis.HasGoogleCalendar ||
+		analysis.HasAdobe || analysis.HasGitHub || analysis.HasGravatar || analysis.HasLinkedIn ||
+		analysis.HasSlack || analysis.HasTwitter || analysis.HasSpotify || analysis.BreachCount > 0
+	hasInfra := analysis.HasSPF || analysis.HasDMARC || analysis.HasSaaSTokens || analysis.HasSlackWorkspace ||
+		isEnterpriseGateway(analysis.MxProvider)
+	return !hasOSINT && !hasInfra
+}
+
+// hasStrongInfra reports whether the domain shows every sign of handling real
+// mail: SPF and DMARC published, at least five years old, and either fronted by
+// an enterprise gateway or carrying SaaS verification tokens.
+func hasStrongInfra(analysis models.RiskAnalysis, hasEnterpriseGateway bool) bool {
+	return analysis.HasSPF &&
+		analysis.HasDMARC &&
+		analysis.DomainAgeDays >= Scoring.DomainAgeVettedDays &&
+		(hasEnterpriseGateway || analysis.HasSaaSTokens)
+}
 
-	DomainAgeThresholdEstablished = 365
-	DomainAgeThresholdVetted      = 1825
-	WeightDomainAgeEstablished    = 10.0
-	WeightDomainAgeVetted         = 15.0
+// RegistrarReputation maps a case-insensitive registrar name fragment to a
+// score adjustment. It is empty by default because registrar reputation is
+// opinionated; operators opt in via REGISTRAR_REPUTATION, e.g.
+//
+//	REGISTRAR_REPUTATION="markmonitor=10,csc corporate=8,bulkreg=-10"
+var RegistrarReputation = parseRegistrarReputation(config.List("REGISTRAR_REPUTATION"))
+
+func parseRegistrarReputation(entries []string) map[string]float64 {
+	rep := make(map[string]float64)
+	for _, e := range entries {
+		name, val, ok := strings.Cut(e, "=")
+		if !ok {
+			continue
+		}
+		adj, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			continue
+		}
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			rep[name] = adj
+		}
+	}
+	return rep
+}
+
+// registrarAdjustment returns the configured adjustment for registrar, or 0 if
+// no configured fragment matches. When several match, the longest wins (ties
+// by name), so "godaddy europe" is not shadowed by "godaddy" at random.
+func registrarAdjustment(registrar string) float64 {
+	if registrar == "" || len(RegistrarReputation) == 0 {
+		return 0
+	}
+	lower := strings.ToLower(registrar)
+	best := ""
+	for name := range RegistrarReputation {
+		if !strings.Contains(lower, name) {
+			continue
+		}
+		if len(name) > len(best) || (len(name) == len(best) && name < best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return 0
+	}
+	return RegistrarReputation[best]
+}
+
+// TLDReputation maps a top-level domain (without the dot), or a longer
+// ending such as "co.uk", to a score adjustment. Like RegistrarReputation it
+// is empty by default; operators opt in via TLD_REPUTATION, e.g.
+//
+//	TLD_REPUTATION="tk=-15,top=-10,xyz=-10,click=-10,gov=10,edu=8,mil=10"
+var TLDReputation = parseTLDReputation(config.List("TLD_REPUTATION"))
+
+func parseTLDReputation(entries []string) map[string]float64 {
+	rep := make(map[string]float64)
+	for name, adj := range parseRegistrarReputation(entries) {
+		if name = strings.TrimPrefix(name, "."); name != "" {
+			rep[name] = adj
+		}
+	}
+	return rep
+}
+
+// domainTLD returns the domain ending domain is judged by: the longest
+// TLDReputation entry it ends in, so "co.uk" can be listed apart from "uk",
+// or else its last label.
+func domainTLD(domain string) string {
+	domain = strings.ToLower(domain)
+	best := ""
+	for suffix := range TLDReputation {
+		if len(suffix) > len(best) && strings.HasSuffix(domain, "."+suffix) {
+			best = suffix
+		}
+	}
+	if best != "" {
+		return best
+	}
+	if dot := strings.LastIndex(domain, "."); dot >= 0 {
+		return domain[dot+1:]
+	}
+	return ""
+}
+
+// tldAdjustment returns the configured adjustment for tld, or 0 if it is not
+// listed.
+func tldAdjustment(tld string) float64 {
+	if tld == "" || len(TLDReputation) == 0 {
+		return 0
+	}
+	return TLDReputation[strings.ToLower(tld)]
+}
+
+// Canonical names for the proof that upgraded a catch-all or unknown result to
+// valid. Surfaced as ValidationResult.ConfirmedBy.
+const (
+	ProofSharePoint = "microsoft_sharepoint"
+	ProofBreach     = "breach_history"
+	ProofCalendar   = "google_calendar"
+	ProofTiming     = "smtp_timing"
+	ProofTeams      = "microsoft_teams"
 )
 
-func CalculateRobustScore(analysis models.RiskAnalysis) (int, map[string]float64, models.Reachability, models.VerificationStatus) {
+// proofSignals pairs each absolute proof with the breakdown entry it adds.
+// Their order only breaks ties.
+var proofSignals = []struct{ proof, key string }{
+	{ProofSharePoint, "p0_sharepoint_license"},
+	{ProofBreach, "p1_historical_breach"},
+	{ProofCalendar, "p0_calendar"},
+	{ProofTiming, "p2_timing_strong"},
+	{ProofTeams, "p0_teams_identity"},
+}
+
+// strongestProof returns the absolute proof that contributed most to
+// breakdown, or "" if none is in it. Weights can be reconfigured, so the
+// answer is read from the actual contributions rather than a fixed ranking.
+func strongestProof(breakdown map[string]float64) string {
+	best := ""
+	bestWeight := 0.0
+	for _, s := range proofSignals {
+		w, ok := breakdown[s.key]
+		if ok && (best == "" || w > bestWeight) {
+			best, bestWeight = s.proof, w
+		}
+	}
+	return best
+}
+
+// CalculateRobustScore returns the final score, its breakdown, reachability,
+// status, and — when OSINT proof upgraded a catch-all or unknown result to
+// valid — the name of the strongest proof responsible (otherwise "").
+//
+// Status is set by the SMTP verdict, VRFY, the O365 zombie correction,
+// parking and absolute or soft proof. Every other signal, reputation
+// adjustments included, only moves the score — but the score still reaches
+// status twice: step 9 promotes a catch-all scoring at least
+// catchAllRiskyScore to risky, and ApplyInvalidBelow, when enabled, turns any
+// result under its threshold invalid.
+func CalculateRobustScore(analysis models.RiskAnalysis) (int, map[string]float64, models.Reachability, models.VerificationStatus, string) {
 	score := 0.0
 	breakdown := make(map[string]float64)
 	var reachability models.Reachability
 	var status models.VerificationStatus
+	confirmedBy := ""
 
 	// ── 1. Base score ────────────────────────────────────────────────────────
 	if analysis.SmtpStatus == 250 {
-		score = 90.0
-		breakdown["base_smtp_valid"] = 90.0
+		score = Scoring.BaseSMTPValid
+		breakdown["base_smtp_valid"] = Scoring.BaseSMTPValid
 		status = models.StatusValid
 	} else if analysis.SmtpStatus == 550 {
-		return 0, map[string]float64{"base_hard_bounce": 0}, models.ReachabilityBad, models.StatusInvalid
+		return 0, map[string]float64{"base_hard_bounce": 0}, models.ReachabilityBad, models.StatusInvalid, ""
+	} else if analysis.SmtpSkipped {
+		score = Scoring.BaseOSINTOnly
+		breakdown["base_osint_only"] = Scoring.BaseOSINTOnly
+		status = models.StatusUnknown
 	} else if analysis.IsCatchAll {
-		score = 30.0
-		breakdown["base_catch_all"] = 30.0
+		score = Scoring.BaseCatchAll
+		breakdown["base_catch_all"] = Scoring.BaseCatchAll
 		status = models.StatusCatchAll
 	} else {
-		score = 20.0
-		breakdown["base_unknown"] = 20.0
+		score = Scoring.BaseUnknown
+		breakdown["base_unknown"] = Scoring.BaseUnknown
 		status = models.StatusUnknown
 	}
 
 	// ── 2. VRFY golden ticket — short-circuit immediately ───────────────────
-	if analysis.HasVRFY {
-		return 99, map[string]float64{"p0_vrfy_verified": 99.0}, models.ReachabilitySafe, models.StatusValid
+	// A hard bounce has already returned above, so under corroboration RCPT
+	// acceptance is all that is left to check. A catch-all accepts every
+	// address, and a VRFY-for-everything server is typically one, so its
+	// 250 corroborates nothing.
+	rcptAccepted := analysis.SmtpStatus == 250 && !analysis.IsCatchAll
+	if analysis.HasVRFY && (!VRFYRequireCorroboration || rcptAccepted) {
+		finalScore := clampScore(Scoring.WeightVRFY)
+		return finalScore, map[string]float64{"p0_vrfy_verified": Scoring.WeightVRFY}, reachabilityFor(finalScore), models.StatusValid, ""
 	}
 
 	// ── 3. O365 zombie correction (SmtpStatus == 250 only) ───────────────────
@@ -63,154 +254,275 @@ func CalculateRobustScore(analysis models.RiskAnalysis) (int, map[string]float64
 	if analysis.MxProvider == "office365" && analysis.SmtpStatus == 250 {
 		if analysis.HasTeamsPresence && !analysis.HasSharePoint {
 			o365ZombieCorrected = true
-			score += -80.0 // Combine the false positive and unlicensed penalties
-			breakdown["correction_o365_zombie"] = -80.0
+			score += Scoring.PenaltyO365Zombie // Combine the false positive and unlicensed penalties
+			breakdown["correction_o365_zombie"] = Scoring.PenaltyO365Zombie
 			status = models.StatusInvalid // A Zombie is definitively Invalid
 		}
 	}
 
 	// ── 4. Proof signals ─────────────────────────────────────────────────────
-	hasAbsoluteProof := analysis.HasVRFY ||
-		analysis.BreachCount > 0 ||
+	// An uncorroborated VRFY hit is ignored from here on.
+	hasAbsoluteProof := analysis.BreachCount > 0 ||
 		analysis.HasGoogleCalendar ||
-		analysis.TimingDeltaMs > 3000 ||
+		analysis.TimingDeltaMs > Scoring.TimingStrongMs ||
 		analysis.HasTeamsPresence ||
 		analysis.HasSharePoint
 
-	hasSoftProof := analysis.HasGitHub || analysis.HasAdobe || analysis.HasGravatar
+	hasSoftProof := analysis.HasGitHub || analysis.HasAdobe || analysis.HasGravatar || analysis.HasLinkedIn ||
+		analysis.HasSlack || analysis.HasTwitter || analysis.HasSpotify
 
 	if analysis.HasTeamsPresence {
-		score += WeightTeams
-		breakdown["p0_teams_identity"] = WeightTeams
+		score += Scoring.WeightTeams
+		breakdown["p0_teams_identity"] = Scoring.WeightTeams
 	}
 	if analysis.HasSharePoint {
-		score += WeightSharePoint
-		breakdown["p0_sharepoint_license"] = WeightSharePoint
+		score += Scoring.WeightSharePoint
+		breakdown["p0_sharepoint_license"] = Scoring.WeightSharePoint
 	}
 	if analysis.HasGoogleCalendar {
-		score += WeightCalendar
-		breakdown["p0_calendar"] = WeightCalendar
+		score += Scoring.WeightCalendar
+		breakdown["p0_calendar"] = Scoring.WeightCalendar
 	}
 	if analysis.HasAdobe {
-		score += WeightAdobe
-		breakdown["p2_adobe"] = WeightAdobe
+		score += Scoring.WeightAdobe
+		breakdown["p2_adobe"] = Scoring.WeightAdobe
 	}
 	if analysis.HasGitHub {
-		score += WeightGitHub
-		breakdown["p2_github"] = WeightGitHub
+		score += Scoring.WeightGitHub
+		breakdown["p2_github"] = Scoring.WeightGitHub
 	}
 	if analysis.HasGravatar {
-		score += WeightGravatar
-		breakdown["p2_gravatar"] = WeightGravatar
+		score += Scoring.WeightGravatar
+		breakdown["p2_gravatar"] = Scoring.WeightGravatar
+	}
+	if analysis.HasLinkedIn {
+		score += Scoring.WeightLinkedIn
+		breakdown["p2_linkedin"] = Scoring.WeightLinkedIn
+	}
+	if analysis.HasSlack {
+		score += Scoring.WeightSlack
+		breakdown["p2_slack"] = Scoring.WeightSlack
+	}
+	if analysis.HasTwitter {
+		score += Scoring.WeightTwitter
+		breakdown["p2_twitter"] = Scoring.WeightTwitter
+	}
+	if analysis.HasSpotify {
+		score += Scoring.WeightSpotify
+		breakdown["p2_spotify"] = Scoring.WeightSpotify
 	}
 
 	if analysis.BreachCount > 0 {
-		boost := WeightBreach
+		boost := Scoring.WeightBreach
 		if analysis.BreachCount > 5 {
-			boost += 10.0
+			boost += Scoring.WeightManyBreaches
 		}
 		score += boost
 		breakdown["p1_historical_breach"] = boost
 
+		// Catch-all resolution below names the confirming proof, once
+		// every contribution is in the breakdown.
 		if status == models.StatusCatchAll && !o365ZombieCorrected {
 			status = models.StatusValid
 		}
 	}
 
-	hasEnterpriseGateway := analysis.MxProvider == "proofpoint" ||
-		analysis.MxProvider == "mimecast" ||
-		analysis.MxProvider == "barracuda" ||
-		analysis.MxProvider == "ironport"
+	hasEnterpriseGateway := isEnterpriseGateway(analysis.MxProvider)
 
-	if hasEnterpriseGateway {
-		score += WeightProofpoint
-		breakdown["p1_enterprise_sec"] = WeightProofpoint
-	}
+	// Infrastructure signals describe the mailbox provider, not the mailbox.
+	// On a consumer free-mail domain every address shares them, so OSINT-only
+	// results leave them out.
+	if !analysis.SmtpSkipped {
+		if hasEnterpriseGateway {
+			score += Scoring.WeightGateway
+			breakdown["p1_enterprise_sec"] = Scoring.WeightGateway
+		}
 
-	if analysis.HasSaaSTokens {
-		score += WeightSalesforce
-		breakdown["p1_saas_usage"] = WeightSalesforce
-	}
-	if analysis.HasSPF {
-		score += WeightSPF
-		breakdown["p2_spf"] = WeightSPF
-	}
-	if analysis.HasDMARC {
-		score += WeightDMARC
-		breakdown["p2_dmarc"] = WeightDMARC
-	}
+		if analysis.HasSaaSTokens {
+			score += Scoring.WeightSaaS
+			breakdown["p1_saas_usage"] = Scoring.WeightSaaS
+		}
+		if analysis.HasSPF {
+			score += Scoring.WeightSPF
+			breakdown["p2_spf"] = Scoring.WeightSPF
+		}
+		// An SPF record past the RFC 7208 lookup limit fails for the
+		// domain's own mail — a sign nobody is maintaining it.
+		if analysis.SPFOverLimit {
+			score += Scoring.PenaltySPFOverLimit
+			breakdown["penalty_spf_over_limit"] = Scoring.PenaltySPFOverLimit
+		}
+		if analysis.HasDMARC {
+			score += Scoring.WeightDMARC
+			breakdown["p2_dmarc"] = Scoring.WeightDMARC
+		}
+		// A server that greylists is running real anti-spam on a live
+		// mail flow, not a parked or throwaway host.
+		if analysis.IsGreylisted {
+			score += Scoring.WeightGreylisted
+			breakdown["p2_greylisted"] = Scoring.WeightGreylisted
+		}
+		if analysis.HasTLS13 {
+			score += Scoring.WeightTLS13
+			breakdown["p3_tls13"] = Scoring.WeightTLS13
+		}
+		// A live website is a modest sign the domain is in active use
+		// rather than abandoned or parked.
+		if analysis.HasWebsite {
+			score += Scoring.WeightWebsite
+			breakdown["p3_website"] = Scoring.WeightWebsite
+		}
+		if len(analysis.MxProviders) > 1 {
+			score += Scoring.WeightMXRedundancy
+			breakdown["p3_mx_redundancy"] = Scoring.WeightMXRedundancy
+		}
+		if analysis.HasSlackWorkspace {
+			score += Scoring.WeightSlackWorkspace
+			breakdown["p3_slack_workspace"] = Scoring.WeightSlackWorkspace
+		}
 
-	if analysis.TimingDeltaMs > 3000 {
-		score += 50.0
-		breakdown["p2_timing_strong"] = 50.0
-	} else if analysis.TimingDeltaMs > 1500 {
-		score += 25.0
-		breakdown["p2_timing_weak"] = 25.0
-	}
+		if analysis.TimingDeltaMs > Scoring.TimingStrongMs {
+			score += Scoring.WeightTimingStrong
+			breakdown["p2_timing_strong"] = Scoring.WeightTimingStrong
+		} else if analysis.TimingDeltaMs > Scoring.TimingWeakMs {
+			score += Scoring.WeightTimingWeak
+			breakdown["p2_timing_weak"] = Scoring.WeightTimingWeak
+		}
+
+		if analysis.DomainAgeDays >= Scoring.DomainAgeVettedDays {
+			score += Scoring.WeightDomainAgeVetted
+			breakdown["p2_domain_age_vetted"] = Scoring.WeightDomainAgeVetted
+		} else if analysis.DomainAgeDays >= Scoring.DomainAgeEstablishedDays {
+			score += Scoring.WeightDomainAgeEstablished
+			breakdown["p2_domain_age_established"] = Scoring.WeightDomainAgeEstablished
+		}
 
-	if analysis.DomainAgeDays >= DomainAgeThresholdVetted {
-		score += WeightDomainAgeVetted
-		breakdown["p2_domain_age_vetted"] = WeightDomainAgeVetted
-	} else if analysis.DomainAgeDays >= DomainAgeThresholdEstablished {
-		score += WeightDomainAgeEstablished
-		breakdown["p2_domain_age_established"] = WeightDomainAgeEstablished
+		if adj := registrarAdjustment(analysis.Registrar); adj != 0 {
+			score += adj
+			breakdown["p3_registrar_reputation"] = adj
+		}
+		if adj := tldAdjustment(analysis.TLD); adj != 0 {
+			score += adj
+			breakdown["p3_tld_reputation"] = adj
+		}
 	}
 
-	isEstablishedDomain := analysis.DomainAgeDays >= DomainAgeThresholdEstablished
+	isEstablishedDomain := analysis.DomainAgeDays >= Scoring.DomainAgeEstablishedDays
 
 	// ── 5. Penalties (only when no proof exists to shield them) ──────────────
 	if !hasAbsoluteProof && !hasSoftProof {
 		if analysis.EntropyScore > 0.5 {
-			score -= 20.0
-			breakdown["penalty_high_entropy"] = -20.0
+			score += Scoring.PenaltyHighEntropy
+			breakdown["penalty_high_entropy"] = Scoring.PenaltyHighEntropy
 		}
 		if analysis.IsRoleAccount {
-			score -= 10.0
-			breakdown["penalty_role_account"] = -10.0
+			score += Scoring.PenaltyRoleAccount
+			breakdown["penalty_role_account"] = Scoring.PenaltyRoleAccount
 		}
 		if analysis.DomainAgeDays > 0 && analysis.DomainAgeDays < 30 {
-			score -= 50.0
-			breakdown["penalty_new_domain"] = -50.0
+			score += Scoring.PenaltyNewDomain
+			breakdown["penalty_new_domain"] = Scoring.PenaltyNewDomain
 		}
 	}
 
+	// A parked domain has no real mailboxes, so only absolute proof or a
+	// clean RCPT verdict (a rejected ghost, so not catch-all) outweighs it.
+	// Parking alone never proves the mailbox absent: when SMTP gave no
+	// definite answer the verdict drops to unknown, and only an actual
+	// rejection makes it invalid.
+	if analysis.IsParked && !hasAbsoluteProof && !(analysis.SmtpStatus == 250 && !analysis.IsCatchAll) {
+		score += Scoring.PenaltyParked
+		breakdown["penalty_parked_domain"] = Scoring.PenaltyParked
+		if status != models.StatusInvalid {
+			status = models.StatusUnknown
+		}
+	}
+
+	// A homograph lookalike domain is a phishing hallmark. Unlike the
+	// penalties above it is not shielded by proof: a live mailbox on
+	// "pаypal.com" is exactly what a phishing list would contain.
+	if analysis.IsMixedScriptDomain {
+		score += Scoring.PenaltyMixedScript
+		breakdown["penalty_mixed_script"] = Scoring.PenaltyMixedScript
+	}
+
 	// ── 6. Catch-all resolution ───────────────────────────────────────────────
 	if analysis.IsCatchAll {
 		if hasAbsoluteProof {
-			score += 50.0
-			breakdown["resolution_catchall_strong"] = 50.0
+			score += Scoring.ResolutionStrong
+			breakdown["resolution_catchall_strong"] = Scoring.ResolutionStrong
 			status = models.StatusValid
+			confirmedBy = strongestProof(breakdown)
 		} else if hasSoftProof {
-			score += 25.0
-			breakdown["resolution_catchall_medium"] = 25.0
+			score += Scoring.ResolutionMedium
+			breakdown["resolution_catchall_medium"] = Scoring.ResolutionMedium
 		} else {
-			applyEmptyPenalty := !hasEnterpriseGateway && !isEstablishedDomain
+			gatewayExempt := hasEnterpriseGateway
+			if GatewayRequiresCorroboration {
+				gatewayExempt = hasEnterpriseGateway && analysis.HasSPF && analysis.HasDMARC
+			}
+			applyEmptyPenalty := !gatewayExempt && !isEstablishedDomain
 
 			if applyEmptyPenalty {
 				if analysis.MxProvider == "office365" {
-					score += -30.0
-					breakdown["penalty_o365_ghost"] = -30.0
+					score += Scoring.PenaltyO365Ghost
+					breakdown["penalty_o365_ghost"] = Scoring.PenaltyO365Ghost
 				} else {
-					score += -20.0
-					breakdown["resolution_catchall_empty"] = -20.0
+					score += Scoring.PenaltyCatchAllEmpty
+					breakdown["resolution_catchall_empty"] = Scoring.PenaltyCatchAllEmpty
 				}
 			}
 		}
 	}
 
+	// ── 6b. Behavioural disposable ────────────────────────────────────────────
+	// A heuristic, so it only costs score: the status still says what the
+	// server did, and INVALID_BELOW decides whether the result is invalid.
+	if IsLikelyDisposable(analysis) {
+		score += Scoring.PenaltyLikelyDisposable
+		breakdown["penalty_likely_disposable"] = Scoring.PenaltyLikelyDisposable
+	}
+
 	// ── 7. Unknown domain resolution ─────────────────────────────────────────
 	if status == models.StatusUnknown {
 		if hasAbsoluteProof {
-			score += 50.0
-			breakdown["resolution_unknown_strong"] = 50.0
+			score += Scoring.ResolutionStrong
+			breakdown["resolution_unknown_strong"] = Scoring.ResolutionStrong
 			status = models.StatusValid
+			confirmedBy = strongestProof(breakdown)
 		} else if hasSoftProof {
-			score += 25.0
-			breakdown["resolution_unknown_medium"] = 25.0
+			score += Scoring.ResolutionMedium
+			breakdown["resolution_unknown_medium"] = Scoring.ResolutionMedium
+			// Without SMTP, an OSINT footprint is the best evidence available.
+			if analysis.SmtpSkipped {
+				status = models.StatusRisky
+			}
 		}
 	}
 
+	// ── 7b. Strong-infrastructure upgrade (opt-in) ────────────────────────────
+	if UnknownInfraUpgrade && status == models.StatusUnknown && analysis.SmtpStatus == 0 && !analysis.SmtpSkipped &&
+		hasStrongInfra(analysis, hasEnterpriseGateway) {
+		breakdown["resolution_unknown_infra"] = 0
+		status = models.StatusRisky
+	}
+
 	// ── 8. Clamp and band ─────────────────────────────────────────────────────
+	finalScore := clampScore(score)
+	reachability = reachabilityFor(finalScore)
+
+	// ── 9. Catch-all status upgrade ───────────────────────────────────────────
+	// The bar defaults to RiskyScore but can be set per provider, so trusted
+	// infrastructure can be upgraded more readily than a generic host.
+	if status == models.StatusCatchAll && !o365ZombieCorrected && finalScore >= Scoring.catchAllRiskyScore(analysis.MxProvider) {
+		status = models.StatusRisky
+	}
+
+	return finalScore, breakdown, reachability, status, confirmedBy
+}
+
+// clampScore rounds score into the reported 0–99 range.
+func clampScore(score float64) int {
 	finalScore := int(math.Round(score))
 	if finalScore > 99 {
 		finalScore = 99
@@ -218,19 +530,63 @@ func CalculateRobustScore(analysis models.RiskAnalysis) (int, map[string]float64
 	if finalScore < 0 {
 		finalScore = 0
 	}
+	return finalScore
+}
 
-	if finalScore >= 90 {
-		reachability = models.ReachabilitySafe
-	} else if finalScore >= 60 {
-		reachability = models.ReachabilityRisky
-	} else {
-		reachability = models.ReachabilityBad
+// reachabilityFor bands a final score by Scoring.SafeScore and RiskyScore.
+func reachabilityFor(score int) models.Reachability {
+	switch {
+	case score >= Scoring.SafeScore:
+		return models.ReachabilitySafe
+	case score >= Scoring.RiskyScore:
+		return models.ReachabilityRisky
+	default:
+		return models.ReachabilityBad
 	}
+}
 
-	// ── 9. Catch-all status upgrade ───────────────────────────────────────────
-	if status == models.StatusCatchAll && !o365ZombieCorrected && finalScore >= 60 {
-		status = models.StatusRisky
+// Recommend maps a final verdict to the sending decision most callers key
+// their campaign logic on, via Scoring.Recommendations. It is the only place
+// a recommendation is derived, so a result reads the same from the API, the
+// results table and a rescore: from the final status, the score (a valid
+// address must also reach SafeScore to be sent without caution) and whether
+// the catch-all evidence was low-confidence, which defers any positive
+// verdict.
+func Recommend(status models.VerificationStatus, score int, lowConfidence bool) models.Recommendation {
+	m := Scoring.Recommendations
+	switch status {
+	case models.StatusValid, models.StatusRisky, models.StatusCatchAll:
+		if lowConfidence {
+			return m.LowConfidence
+		}
+	}
+	switch status {
+	case models.StatusValid:
+		if score >= Scoring.SafeScore {
+			return m.ValidSafe
+		}
+		return m.Valid
+	case models.StatusRisky:
+		return m.Risky
+	case models.StatusCatchAll:
+		return m.CatchAll
+	case models.StatusInvalid:
+		return m.Invalid
+	default:
+		return m.Unknown
 	}
+}
 
-	return finalScore, breakdown, reachability, status
+// ApplyInvalidBelow is the opt-in binary banding step: with
+// Scoring.InvalidBelow set, it returns StatusInvalid for any score under the
+// threshold, along with the nuanced status it replaced. With the threshold
+// off it returns status unchanged and an empty nuanced status.
+func ApplyInvalidBelow(score int, status models.VerificationStatus) (final, nuanced models.VerificationStatus) {
+	if Scoring.InvalidBelow <= 0 {
+		return status, ""
+	}
+	if score < Scoring.InvalidBelow {
+		return models.StatusInvalid, status
+	}
+	return status, status
 }
@@ -0,0 +1,264 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/models"
+)
+
+// ScoringConfig holds every signal weight and threshold CalculateRobustScore
+// uses. Different customers value OSINT signals differently, so operators can
+// override any subset via a JSON file named by SCORING_CONFIG; fields absent
+// from the file keep their defaults.
+type ScoringConfig struct {
+	// Starting scores by SMTP outcome: an accepted RCPT, a catch-all, and an
+	// address with no SMTP verdict, probed or (BaseOSINTOnly) not.
+	BaseSMTPValid float64 `json:"base_smtp_valid"`
+	BaseCatchAll  float64 `json:"base_catch_all"`
+	BaseUnknown   float64 `json:"base_unknown"`
+	BaseOSINTOnly float64 `json:"base_osint_only"`
+
+	// PenaltyO365Zombie offsets an Office 365 RCPT acceptance for a mailbox
+	// with a Teams identity but no SharePoint licence.
+	PenaltyO365Zombie float64 `json:"penalty_o365_zombie"`
+
+	WeightTeams      float64 `json:"weight_teams"`
+	WeightSharePoint float64 `json:"weight_sharepoint"`
+	WeightCalendar   float64 `json:"weight_calendar"`
+
+	WeightGateway float64 `json:"weight_gateway"`
+	WeightSaaS    float64 `json:"weight_saas"`
+
+	WeightGitHub   float64 `json:"weight_github"`
+	WeightGravatar float64 `json:"weight_gravatar"`
+	WeightLinkedIn float64 `json:"weight_linkedin"`
+	WeightSlack    float64 `json:"weight_slack"`
+	WeightTwitter  float64 `json:"weight_twitter"`
+	WeightSpotify  float64 `json:"weight_spotify"`
+	WeightAdobe    float64 `json:"weight_adobe"`
+	WeightBreach   float64 `json:"weight_breach"`
+	// WeightManyBreaches is added on top of WeightBreach for an address in
+	// more than five breaches.
+	WeightManyBreaches float64 `json:"weight_many_breaches"`
+
+	// WeightVRFY is the whole score of an address VRFY confirms.
+	WeightVRFY float64 `json:"weight_vrfy"`
+
+	WeightSPF   float64 `json:"weight_spf"`
+	WeightDMARC float64 `json:"weight_dmarc"`
+
+	PenaltySPFOverLimit float64 `json:"penalty_spf_over_limit"`
+	PenaltyMixedScript  float64 `json:"penalty_mixed_script"`
+	PenaltyParked       float64 `json:"penalty_parked"`
+
+	// Applied only when no OSINT proof shields the address.
+	PenaltyHighEntropy float64 `json:"penalty_high_entropy"`
+	PenaltyRoleAccount float64 `json:"penalty_role_account"`
+	PenaltyNewDomain   float64 `json:"penalty_new_domain"`
+	// PenaltyLikelyDisposable applies where IsLikelyDisposable matches.
+	PenaltyLikelyDisposable float64 `json:"penalty_likely_disposable"`
+
+	WeightGreylisted float64 `json:"weight_greylisted"`
+	WeightTLS13      float64 `json:"weight_tls13"`
+	WeightWebsite    float64 `json:"weight_website"`
+	// WeightMXRedundancy rewards MX records spread across more than one
+	// provider — a backup MX someone deliberately set up.
+	WeightMXRedundancy float64 `json:"weight_mx_redundancy"`
+	// WeightSlackWorkspace rewards a Slack workspace named after the domain.
+	WeightSlackWorkspace float64 `json:"weight_slack_workspace"`
+
+	DomainAgeEstablishedDays   int     `json:"domain_age_established_days"`
+	DomainAgeVettedDays        int     `json:"domain_age_vetted_days"`
+	WeightDomainAgeEstablished float64 `json:"weight_domain_age_established"`
+	WeightDomainAgeVetted      float64 `json:"weight_domain_age_vetted"`
+
+	// Ghost-minus-target RCPT latency, in milliseconds, above which timing
+	// counts as weak or strong (absolute) proof of a real mailbox, and what
+	// each adds to the score.
+	TimingWeakMs       int64   `json:"timing_weak_ms"`
+	TimingStrongMs     int64   `json:"timing_strong_ms"`
+	WeightTimingWeak   float64 `json:"weight_timing_weak"`
+	WeightTimingStrong float64 `json:"weight_timing_strong"`
+
+	// Resolving a catch-all or unknown result: absolute proof adds
+	// ResolutionStrong, soft proof ResolutionMedium. A catch-all with neither
+	// takes PenaltyCatchAllEmpty, or PenaltyO365Ghost on Office 365, unless
+	// its domain is established or fronted by a gateway.
+	ResolutionStrong     float64 `json:"resolution_strong"`
+	ResolutionMedium     float64 `json:"resolution_medium"`
+	PenaltyCatchAllEmpty float64 `json:"penalty_catch_all_empty"`
+	PenaltyO365Ghost     float64 `json:"penalty_o365_ghost"`
+
+	// Score bands: at or above SafeScore is ReachabilitySafe, at or above
+	// RiskyScore is ReachabilityRisky.
+	SafeScore  int `json:"safe_score"`
+	RiskyScore int `json:"risky_score"`
+
+	// CatchAllRiskyScore overrides, per MX provider (see
+	// lookup.ProviderForMX), the score at or above which a catch-all result is
+	// upgraded to StatusRisky. Providers absent from the map use RiskyScore.
+	CatchAllRiskyScore map[string]int `json:"catch_all_risky_score"`
+
+	Recommendations RecommendationMap `json:"recommendations"`
+
+	// InvalidBelow, when positive, marks every result scoring under it as
+	// StatusInvalid for callers that want a binary verdict. 0 disables it.
+	InvalidBelow int `json:"invalid_below"`
+}
+
+// catchAllRiskyScore is the catch-all upgrade threshold for provider.
+func (c ScoringConfig) catchAllRiskyScore(provider string) int {
+	if score, ok := c.CatchAllRiskyScore[provider]; ok {
+		return score
+	}
+	return c.RiskyScore
+}
+
+// RecommendationMap is the recommendation Recommend gives for each verdict.
+// Valid applies to a valid address that did not also score into the safe
+// band, e.g. one confirmed by OSINT alone. LowConfidence replaces the valid,
+// risky and catch-all entries when the catch-all evidence was inconclusive.
+type RecommendationMap struct {
+	ValidSafe     models.Recommendation `json:"valid_safe"`
+	Valid         models.Recommendation `json:"valid"`
+	Risky         models.Recommendation `json:"risky"`
+	CatchAll      models.Recommendation `json:"catch_all"`
+	Invalid       models.Recommendation `json:"invalid"`
+	Unknown       models.Recommendation `json:"unknown"`
+	LowConfidence models.Recommendation `json:"low_confidence"`
+}
+
+// validate reports the first entry that is not a known recommendation.
+func (m RecommendationMap) validate() error {
+	for verdict, r := range map[string]models.Recommendation{
+		"valid_safe": m.ValidSafe, "valid": m.Valid, "risky": m.Risky,
+		"catch_all": m.CatchAll, "invalid": m.Invalid, "unknown": m.Unknown,
+		"low_confidence": m.LowConfidence,
+	} {
+		switch r {
+		case models.RecommendSend, models.RecommendSendWithCaution, models.RecommendDoNotSend, models.RecommendVerifyLater:
+		default:
+			return fmt.Errorf("recommendations.%s: unknown recommendation %q", verdict, r)
+		}
+	}
+	return nil
+}
+
+// DefaultScoringConfig returns the built-in weights and thresholds.
+func DefaultScoringConfig() ScoringConfig {
+	return ScoringConfig{
+		BaseSMTPValid: 90.0,
+		BaseCatchAll:  30.0,
+		BaseUnknown:   20.0,
+		BaseOSINTOnly: 20.0,
+
+		PenaltyO365Zombie: -80.0,
+
+		WeightTeams:      15.0,
+		WeightSharePoint: 60.0,
+		WeightCalendar:   42.5,
+
+		WeightGateway: 15.0,
+		WeightSaaS:    10.0,
+
+		WeightGitHub:   12.0,
+		WeightGravatar: 10.0,
+		WeightLinkedIn: 12.0,
+		WeightSlack:    10.0,
+		WeightTwitter:  8.0,
+		WeightSpotify:  8.0,
+		WeightAdobe:    18.5,
+		WeightBreach:   45.0,
+
+		WeightManyBreaches: 10.0,
+
+		WeightVRFY: 99.0,
+
+		WeightSPF:   3.5,
+		WeightDMARC: 4.5,
+
+		PenaltySPFOverLimit: -5.0,
+		PenaltyMixedScript:  -30.0,
+		PenaltyParked:       -50.0,
+
+		PenaltyHighEntropy:      -20.0,
+		PenaltyRoleAccount:      -10.0,
+		PenaltyNewDomain:        -50.0,
+		PenaltyLikelyDisposable: -50.0,
+
+		WeightGreylisted: 5.0,
+		WeightTLS13:      2.0,
+		WeightWebsite:    3.0,
+
+		WeightMXRedundancy:   2.0,
+		WeightSlackWorkspace: 5.0,
+
+		DomainAgeEstablishedDays:   365,
+		DomainAgeVettedDays:        1825,
+		WeightDomainAgeEstablished: 10.0,
+		WeightDomainAgeVetted:      15.0,
+
+		TimingWeakMs:   1500,
+		TimingStrongMs: 3000,
+
+		WeightTimingWeak:   25.0,
+		WeightTimingStrong: 50.0,
+
+		ResolutionStrong:     50.0,
+		ResolutionMedium:     25.0,
+		PenaltyCatchAllEmpty: -20.0,
+		PenaltyO365Ghost:     -30.0,
+
+		SafeScore:  90,
+		RiskyScore: 60,
+
+		Recommendations: RecommendationMap{
+			ValidSafe: models.RecommendSend,
+			Valid:     models.RecommendSendWithCaution,
+			Risky:     models.RecommendSendWithCaution,
+			CatchAll:  models.RecommendSendWithCaution,
+			Invalid:   models.RecommendDoNotSend,
+			Unknown:   models.RecommendVerifyLater,
+
+			LowConfidence: models.RecommendVerifyLater,
+		},
+	}
+}
+
+// LoadScoringConfig returns the defaults overlaid with the JSON file named by
+// SCORING_CONFIG, if set. On error the defaults are returned alongside it.
+func LoadScoringConfig() (ScoringConfig, error) {
+	cfg := DefaultScoringConfig()
+	path := config.String("SCORING_CONFIG", "")
+	if path == "" {
+		return cfg, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return DefaultScoringConfig(), fmt.Errorf("read scoring config: %w", err)
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return DefaultScoringConfig(), fmt.Errorf("parse scoring config %s: %w", path, err)
+	}
+	if err := cfg.Recommendations.validate(); err != nil {
+		return DefaultScoringConfig(), fmt.Errorf("scoring config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Scoring is the configuration CalculateRobustScore reads. It is loaded once
+// at startup; a bad SCORING_CONFIG is logged and the defaults are used.
+var Scoring = loadScoringConfig()
+
+func loadScoringConfig() ScoringConfig {
+	cfg, err := LoadScoringConfig()
+	if err != nil {
+		log.Printf("⚠️  %v — using default scoring weights", err)
+	}
+	return cfg
+}
@@ -0,0 +1,80 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mailvetter/internal/models"
+)
+
+func TestLoadScoringConfig(t *testing.T) {
+	t.Setenv("SCORING_CONFIG", "")
+	cfg, err := LoadScoringConfig()
+	if err != nil || !reflect.DeepEqual(cfg, DefaultScoringConfig()) {
+		t.Fatalf("expected defaults without SCORING_CONFIG, got %+v err=%v", cfg, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "scoring.json")
+	if err := os.WriteFile(path, []byte(`{"weight_github": 40, "safe_score": 95}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCORING_CONFIG", path)
+	cfg, err = LoadScoringConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.WeightGitHub != 40 || cfg.SafeScore != 95 {
+		t.Errorf("overrides not applied: github=%v safe=%v", cfg.WeightGitHub, cfg.SafeScore)
+	}
+	if cfg.WeightSharePoint != DefaultScoringConfig().WeightSharePoint {
+		t.Errorf("fields absent from the file must keep their defaults")
+	}
+
+	if err := os.WriteFile(path, []byte(`{not json`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadScoringConfig()
+	if err == nil || !reflect.DeepEqual(cfg, DefaultScoringConfig()) {
+		t.Errorf("expected an error and defaults for malformed JSON, got %+v err=%v", cfg, err)
+	}
+}
+
+func TestLoadScoringConfigRejectsUnknownRecommendation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scoring.json")
+	if err := os.WriteFile(path, []byte(`{"recommendations": {"catch_all": "maybe"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCORING_CONFIG", path)
+	if cfg, err := LoadScoringConfig(); err == nil || !reflect.DeepEqual(cfg, DefaultScoringConfig()) {
+		t.Errorf("expected an error and defaults for an unknown recommendation, got err=%v", err)
+	}
+}
+
+func TestScoringConfigOverridesWeights(t *testing.T) {
+	saved := Scoring
+	defer func() { Scoring = saved }()
+
+	analysis := models.RiskAnalysis{SmtpStatus: 0, HasGitHub: true}
+	_, before, _, _, _ := CalculateRobustScore(analysis)
+
+	Scoring.WeightGitHub = 30
+	_, after, _, _, _ := CalculateRobustScore(analysis)
+
+	if before["p2_github"] != DefaultScoringConfig().WeightGitHub || after["p2_github"] != 30 {
+		t.Errorf("p2_github: before=%v after=%v", before["p2_github"], after["p2_github"])
+	}
+
+	// Bases, resolutions and VRFY are configurable too, not just the signals.
+	Scoring.BaseCatchAll = 40
+	Scoring.ResolutionStrong = 35
+	_, b, _, _, _ := CalculateRobustScore(models.RiskAnalysis{IsCatchAll: true, HasGoogleCalendar: true})
+	if b["base_catch_all"] != 40 || b["resolution_catchall_strong"] != 35 {
+		t.Errorf("catch-all base/resolution not configurable: %v", b)
+	}
+	Scoring.WeightVRFY = 80
+	if score, _, reach, _, _ := CalculateRobustScore(models.RiskAnalysis{HasVRFY: true}); score != 80 || reach != models.ReachabilityRisky {
+		t.Errorf("VRFY: score %d reachability %q, want 80 risky", score, reach)
+	}
+}
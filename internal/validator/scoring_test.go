@@ -49,6 +49,31 @@ func TestCalculateRobustScore(t *testing.T) {
 			expectedReach:    models.ReachabilityRisky,
 			expectedStatus:   models.StatusValid,
 		},
+		{
+			name: "Greylisted then accepted (High Entropy)",
+			input: models.RiskAnalysis{
+				SmtpStatus:   250,
+				IsGreylisted: true,
+				EntropyScore: 0.85,
+			},
+			expectedScoreMin: 74,
+			expectedScoreMax: 76,
+			expectedReach:    models.ReachabilityRisky,
+			expectedStatus:   models.StatusValid,
+		},
+		{
+			name: "Greylisted then accepted business email",
+			input: models.RiskAnalysis{
+				SmtpStatus:   250,
+				IsGreylisted: true,
+				HasSPF:       true,
+				HasDMARC:     true,
+			},
+			expectedScoreMin: 90,
+			expectedScoreMax: 99,
+			expectedReach:    models.ReachabilitySafe,
+			expectedStatus:   models.StatusValid,
+		},
 
 		// ── Catch-all status upgrade cases ───────────────────────────────────
 		{
@@ -419,7 +444,7 @@ func TestCalculateRobustScore(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			score, _, reach, status := CalculateRobustScore(tt.input)
+			score, _, reach, status, _ := CalculateRobustScore(tt.input)
 
 			if score < tt.expectedScoreMin || score > tt.expectedScoreMax {
 				t.Errorf("Score %d not in range [%d, %d]", score, tt.expectedScoreMin, tt.expectedScoreMax)
@@ -433,3 +458,614 @@ func TestCalculateRobustScore(t *testing.T) {
 		})
 	}
 }
+
+func TestConfirmedBy(t *testing.T) {
+	tests := []struct {
+		name  string
+		input models.RiskAnalysis
+		want  string
+	}{
+		{
+			name:  "Catch-all upgraded by SharePoint",
+			input: models.RiskAnalysis{IsCatchAll: true, MxProvider: "office365", HasSharePoint: true, HasTeamsPresence: true},
+			want:  ProofSharePoint,
+		},
+		{
+			name:  "Catch-all upgraded by breach history",
+			input: models.RiskAnalysis{IsCatchAll: true, BreachCount: 2},
+			want:  ProofBreach,
+		},
+		{
+			name:  "Catch-all upgraded by Google Calendar",
+			input: models.RiskAnalysis{IsCatchAll: true, MxProvider: "google", HasGoogleCalendar: true},
+			want:  ProofCalendar,
+		},
+		{
+			name:  "Unknown upgraded by timing delta",
+			input: models.RiskAnalysis{TimingDeltaMs: 3500},
+			want:  ProofTiming,
+		},
+		{
+			name:  "Unknown upgraded by Teams presence",
+			input: models.RiskAnalysis{MxProvider: "office365", HasTeamsPresence: true},
+			want:  ProofTeams,
+		},
+		{
+			name:  "Strongest proof wins when several are present",
+			input: models.RiskAnalysis{IsCatchAll: true, HasTeamsPresence: true, HasGoogleCalendar: true, BreachCount: 1},
+			want:  ProofBreach,
+		},
+		{
+			name:  "Strong timing outweighs a single breach",
+			input: models.RiskAnalysis{IsCatchAll: true, BreachCount: 1, TimingDeltaMs: 3500},
+			want:  ProofTiming,
+		},
+		{
+			name:  "Soft proof only — no upgrade",
+			input: models.RiskAnalysis{IsCatchAll: true, HasGitHub: true},
+			want:  "",
+		},
+		{
+			name:  "Plain SMTP valid — nothing to confirm",
+			input: models.RiskAnalysis{SmtpStatus: 250, HasSPF: true},
+			want:  "",
+		},
+		{
+			name:  "O365 zombie — no upgrade",
+			input: models.RiskAnalysis{SmtpStatus: 250, MxProvider: "office365", HasTeamsPresence: true},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, status, confirmedBy := CalculateRobustScore(tt.input)
+			if confirmedBy != tt.want {
+				t.Errorf("ConfirmedBy %q != expected %q", confirmedBy, tt.want)
+			}
+			if tt.want != "" && status != models.StatusValid {
+				t.Errorf("Status %q != expected %q when ConfirmedBy is set", status, models.StatusValid)
+			}
+		})
+	}
+}
+
+func TestConfirmedByFollowsConfiguredWeights(t *testing.T) {
+	saved := Scoring
+	defer func() { Scoring = saved }()
+
+	input := models.RiskAnalysis{IsCatchAll: true, HasGoogleCalendar: true, BreachCount: 1}
+	if _, _, _, _, got := CalculateRobustScore(input); got != ProofBreach {
+		t.Fatalf("default weights: ConfirmedBy %q, want %q", got, ProofBreach)
+	}
+	Scoring.WeightCalendar = 80
+	if _, _, _, _, got := CalculateRobustScore(input); got != ProofCalendar {
+		t.Errorf("calendar reweighted above breach: ConfirmedBy %q, want %q", got, ProofCalendar)
+	}
+}
+
+func TestRegistrarReputation(t *testing.T) {
+	saved := RegistrarReputation
+	defer func() { RegistrarReputation = saved }()
+
+	RegistrarReputation = parseRegistrarReputation([]string{"markmonitor=10", "bulkreg=-10", "malformed"})
+	if len(RegistrarReputation) != 2 {
+		t.Fatalf("expected 2 parsed entries, got %d", len(RegistrarReputation))
+	}
+
+	base := models.RiskAnalysis{IsCatchAll: true, HasSPF: true, HasDMARC: true}
+	baseScore, _, _, _, _ := CalculateRobustScore(base)
+
+	corporate := base
+	corporate.Registrar = "MarkMonitor Inc."
+	score, breakdown, _, _, _ := CalculateRobustScore(corporate)
+	if breakdown["p3_registrar_reputation"] != 10 {
+		t.Errorf("expected +10 registrar adjustment, got %v", breakdown["p3_registrar_reputation"])
+	}
+	if score != baseScore+10 {
+		t.Errorf("Score %d != expected %d", score, baseScore+10)
+	}
+
+	bulk := base
+	bulk.Registrar = "BulkReg Ltd"
+	_, breakdown, _, _, _ = CalculateRobustScore(bulk)
+	if breakdown["p3_registrar_reputation"] != -10 {
+		t.Errorf("expected -10 registrar adjustment, got %v", breakdown["p3_registrar_reputation"])
+	}
+
+	// Overlapping fragments resolve to the longest, whatever the map order.
+	RegistrarReputation = parseRegistrarReputation([]string{"markmonitor=10", "markmonitor bulk=-5"})
+	for i := 0; i < 20; i++ {
+		if adj := registrarAdjustment("MarkMonitor Bulk Services"); adj != -5 {
+			t.Fatalf("overlapping fragments: got %v, want -5", adj)
+		}
+	}
+
+	unlisted := base
+	unlisted.Registrar = "Some Registrar LLC"
+	_, breakdown, _, _, _ = CalculateRobustScore(unlisted)
+	if _, ok := breakdown["p3_registrar_reputation"]; ok {
+		t.Errorf("unlisted registrar must not be adjusted")
+	}
+}
+
+func TestTLDReputation(t *testing.T) {
+	saved := TLDReputation
+	defer func() { TLDReputation = saved }()
+
+	base := models.RiskAnalysis{IsCatchAll: true, HasSPF: true, HasDMARC: true}
+
+	TLDReputation = map[string]float64{}
+	baseScore, _, _, _, _ := CalculateRobustScore(base)
+
+	TLDReputation = parseTLDReputation([]string{".tk=-15", "gov=10", "bogus"})
+	if len(TLDReputation) != 2 {
+		t.Fatalf("expected 2 parsed entries, got %d", len(TLDReputation))
+	}
+
+	abused := base
+	abused.TLD = "tk"
+	score, breakdown, _, status, _ := CalculateRobustScore(abused)
+	if breakdown["p3_tld_reputation"] != -15 {
+		t.Errorf("expected -15 TLD adjustment, got %v", breakdown["p3_tld_reputation"])
+	}
+	if score != baseScore-15 {
+		t.Errorf("Score %d != expected %d", score, baseScore-15)
+	}
+	if status != models.StatusCatchAll {
+		t.Errorf("TLD adjustment must not change status, got %q", status)
+	}
+
+	trusted := base
+	trusted.TLD = "gov"
+	score, breakdown, _, _, _ = CalculateRobustScore(trusted)
+	if breakdown["p3_tld_reputation"] != 10 || score != baseScore+10 {
+		t.Errorf("gov: adjustment %v score %d, expected +10 and %d", breakdown["p3_tld_reputation"], score, baseScore+10)
+	}
+
+	TLDReputation = parseTLDReputation([]string{"uk=5", "co.uk=-5"})
+	for domain, want := range map[string]string{
+		"shop.co.uk":  "co.uk",
+		"gov.uk":      "uk",
+		"example.com": "com",
+	} {
+		if got := domainTLD(domain); got != want {
+			t.Errorf("domainTLD(%q) = %q, want %q", domain, got, want)
+		}
+	}
+
+	unlisted := base
+	unlisted.TLD = "com"
+	if _, b, _, _, _ := CalculateRobustScore(unlisted); hasKey(b, "p3_tld_reputation") {
+		t.Errorf("unlisted TLD must not be adjusted")
+	}
+}
+
+func TestUnknownInfraUpgrade(t *testing.T) {
+	saved := UnknownInfraUpgrade
+	defer func() { UnknownInfraUpgrade = saved }()
+
+	strongInfra := models.RiskAnalysis{
+		SmtpStatus:    0,
+		MxProvider:    "proofpoint",
+		HasSPF:        true,
+		HasDMARC:      true,
+		DomainAgeDays: 3650,
+	}
+	weakInfra := models.RiskAnalysis{
+		SmtpStatus:    0,
+		HasSPF:        true,
+		DomainAgeDays: 400,
+	}
+
+	UnknownInfraUpgrade = false
+	if _, _, _, status, _ := CalculateRobustScore(strongInfra); status != models.StatusUnknown {
+		t.Errorf("with upgrade disabled, status %q != expected %q", status, models.StatusUnknown)
+	}
+
+	UnknownInfraUpgrade = true
+	_, breakdown, _, status, _ := CalculateRobustScore(strongInfra)
+	if status != models.StatusRisky {
+		t.Errorf("strong infra unknown: status %q != expected %q", status, models.StatusRisky)
+	}
+	if _, ok := breakdown["resolution_unknown_infra"]; !ok {
+		t.Errorf("expected resolution_unknown_infra in breakdown")
+	}
+
+	saas := strongInfra
+	saas.MxProvider = "google"
+	saas.HasSaaSTokens = true
+	if _, _, _, status, _ := CalculateRobustScore(saas); status != models.StatusRisky {
+		t.Errorf("SaaS-backed strong infra: status %q != expected %q", status, models.StatusRisky)
+	}
+
+	if _, _, _, status, _ := CalculateRobustScore(weakInfra); status != models.StatusUnknown {
+		t.Errorf("weak infra unknown: status %q != expected %q", status, models.StatusUnknown)
+	}
+
+	catchAll := strongInfra
+	catchAll.IsCatchAll = true
+	if _, b, _, _, _ := CalculateRobustScore(catchAll); hasKey(b, "resolution_unknown_infra") {
+		t.Errorf("infra upgrade must not apply to catch-all results")
+	}
+}
+
+func hasKey(m map[string]float64, k string) bool {
+	_, ok := m[k]
+	return ok
+}
+
+func TestGatewayExemptionCorroboration(t *testing.T) {
+	saved := GatewayRequiresCorroboration
+	defer func() { GatewayRequiresCorroboration = saved }()
+
+	bare := models.RiskAnalysis{IsCatchAll: true, MxProvider: "proofpoint"}
+	corroborated := models.RiskAnalysis{IsCatchAll: true, MxProvider: "proofpoint", HasSPF: true, HasDMARC: true}
+	aged := models.RiskAnalysis{IsCatchAll: true, MxProvider: "proofpoint", DomainAgeDays: 800}
+
+	// Default: the gateway alone exempts the empty-catch-all penalty.
+	GatewayRequiresCorroboration = false
+	if _, b, _, _, _ := CalculateRobustScore(bare); hasKey(b, "resolution_catchall_empty") {
+		t.Errorf("default: bare gateway domain should be exempt from the empty penalty")
+	}
+
+	// Strict: an uncorroborated gateway no longer exempts.
+	GatewayRequiresCorroboration = true
+	if _, b, _, _, _ := CalculateRobustScore(bare); !hasKey(b, "resolution_catchall_empty") {
+		t.Errorf("strict: bare gateway domain should receive the empty penalty")
+	}
+	if _, b, _, _, _ := CalculateRobustScore(corroborated); hasKey(b, "resolution_catchall_empty") {
+		t.Errorf("strict: gateway with SPF+DMARC should stay exempt")
+	}
+	if _, b, _, _, _ := CalculateRobustScore(aged); hasKey(b, "resolution_catchall_empty") {
+		t.Errorf("strict: established domain should stay exempt regardless of gateway")
+	}
+}
+
+func TestOSINTOnlyScoring(t *testing.T) {
+	// A gmail.com address: SMTP never ran, and the provider's infrastructure
+	// is shared by every address on the domain.
+	gmail := models.RiskAnalysis{
+		SmtpSkipped:   true,
+		MxProvider:    "google",
+		HasSPF:        true,
+		HasDMARC:      true,
+		DomainAgeDays: 10000,
+	}
+
+	_, b, _, status, _ := CalculateRobustScore(gmail)
+	if status != models.StatusUnknown {
+		t.Errorf("no OSINT: status %q != expected %q", status, models.StatusUnknown)
+	}
+	if !hasKey(b, "base_osint_only") {
+		t.Errorf("expected base_osint_only in breakdown")
+	}
+	for _, k := range []string{"p2_spf", "p2_dmarc", "p2_domain_age_vetted"} {
+		if hasKey(b, k) {
+			t.Errorf("provider infrastructure %s must not score in OSINT-only mode", k)
+		}
+	}
+
+	soft := gmail
+	soft.HasGravatar = true
+	soft.HasGitHub = true
+	if _, _, _, status, _ := CalculateRobustScore(soft); status != models.StatusRisky {
+		t.Errorf("Gravatar+GitHub: status %q != expected %q", status, models.StatusRisky)
+	}
+
+	calendar := gmail
+	calendar.HasGoogleCalendar = true
+	score, _, _, status, confirmedBy := CalculateRobustScore(calendar)
+	if status != models.StatusValid || confirmedBy != ProofCalendar {
+		t.Errorf("Calendar: status %q confirmed_by %q, expected %q via %q",
+			status, confirmedBy, models.StatusValid, ProofCalendar)
+	}
+	if score < 90 {
+		t.Errorf("Calendar: score %d below safe band", score)
+	}
+
+	bot := gmail
+	bot.EntropyScore = 0.8
+	if score, _, _, _, _ := CalculateRobustScore(bot); score != 0 {
+		t.Errorf("high-entropy gmail with no OSINT: score %d != expected 0", score)
+	}
+
+	saved := UnknownInfraUpgrade
+	defer func() { UnknownInfraUpgrade = saved }()
+	UnknownInfraUpgrade = true
+	gmail.HasSaaSTokens = true
+	if _, b, _, _, _ := CalculateRobustScore(gmail); hasKey(b, "resolution_unknown_infra") {
+		t.Errorf("infra upgrade must not apply to OSINT-only results")
+	}
+}
+
+func TestLikelyDisposable(t *testing.T) {
+	burner := models.RiskAnalysis{
+		IsCatchAll:    true,
+		MxProvider:    "generic",
+		DomainAgeDays: 3,
+	}
+
+	if !IsLikelyDisposable(burner) {
+		t.Fatalf("3-day-old footprint-free catch-all should be likely disposable")
+	}
+	// The heuristic costs score but does not override what the server said.
+	score, b, reach, status, _ := CalculateRobustScore(burner)
+	if status != models.StatusCatchAll || reach != models.ReachabilityBad || score != 0 {
+		t.Errorf("burner: score %d reach %q status %q, expected 0/bad/catch_all", score, reach, status)
+	}
+	if !hasKey(b, "penalty_likely_disposable") {
+		t.Errorf("expected penalty_likely_disposable in breakdown")
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(*models.RiskAnalysis)
+	}{
+		{"older than a week", func(a *models.RiskAnalysis) { a.DomainAgeDays = 10 }},
+		{"unknown age", func(a *models.RiskAnalysis) { a.DomainAgeDays = 0 }},
+		{"not catch-all", func(a *models.RiskAnalysis) { a.IsCatchAll = false }},
+		{"publishes SPF", func(a *models.RiskAnalysis) { a.HasSPF = true }},
+		{"enterprise gateway", func(a *models.RiskAnalysis) { a.MxProvider = "mimecast" }},
+		{"OSINT footprint", func(a *models.RiskAnalysis) { a.HasGitHub = true }},
+	}
+	for _, c := range cases {
+		a := burner
+		c.mutate(&a)
+		if IsLikelyDisposable(a) {
+			t.Errorf("%s: must not be likely disposable", c.name)
+		}
+		if _, b, _, _, _ := CalculateRobustScore(a); hasKey(b, "penalty_likely_disposable") {
+			t.Errorf("%s: unexpected penalty_likely_disposable", c.name)
+		}
+	}
+}
+
+func TestSPFOverLimitPenalty(t *testing.T) {
+	base := models.RiskAnalysis{SmtpStatus: 250, HasSPF: true, HasDMARC: true}
+	clean, _, _, _, _ := CalculateRobustScore(base)
+
+	base.SPFOverLimit = true
+	score, breakdown, _, status, _ := CalculateRobustScore(base)
+
+	if !hasKey(breakdown, "penalty_spf_over_limit") {
+		t.Fatalf("expected penalty_spf_over_limit, got %v", breakdown)
+	}
+	if score >= clean {
+		t.Errorf("score %d should be below the clean-SPF score %d", score, clean)
+	}
+	if status != models.StatusValid {
+		t.Errorf("a management-quality signal must not change the verdict, got %q", status)
+	}
+}
+
+func TestTLS13Signal(t *testing.T) {
+	base := models.RiskAnalysis{SmtpStatus: 250, EntropyScore: 0.85}
+	plain, _, _, _, _ := CalculateRobustScore(base)
+
+	base.HasTLS13 = true
+	score, breakdown, _, _, _ := CalculateRobustScore(base)
+	if !hasKey(breakdown, "p3_tls13") || score != plain+int(Scoring.WeightTLS13) {
+		t.Errorf("score %d (plain %d), breakdown %v", score, plain, breakdown)
+	}
+
+	// Infrastructure signals are not scored for OSINT-only results.
+	_, breakdown, _, _, _ = CalculateRobustScore(models.RiskAnalysis{SmtpSkipped: true, HasTLS13: true})
+	if hasKey(breakdown, "p3_tls13") {
+		t.Errorf("p3_tls13 must not apply when SMTP was skipped")
+	}
+}
+
+func TestWebsiteSignal(t *testing.T) {
+	base := models.RiskAnalysis{IsCatchAll: true, MxProvider: "generic"}
+	without, _, _, _, _ := CalculateRobustScore(base)
+
+	base.HasWebsite = true
+	score, breakdown, _, _, _ := CalculateRobustScore(base)
+	if !hasKey(breakdown, "p3_website") || score != without+int(Scoring.WeightWebsite) {
+		t.Errorf("score %d (without site %d), breakdown %v", score, without, breakdown)
+	}
+
+	_, breakdown, _, _, _ = CalculateRobustScore(models.RiskAnalysis{SmtpSkipped: true, HasWebsite: true})
+	if hasKey(breakdown, "p3_website") {
+		t.Errorf("p3_website must not apply when SMTP was skipped")
+	}
+}
+
+func TestMixedScriptDomainPenalty(t *testing.T) {
+	// Proof of a mailbox does not shield a homograph domain.
+	base := models.RiskAnalysis{SmtpStatus: 250, HasGitHub: true}
+	clean, _, _, _, _ := CalculateRobustScore(base)
+
+	base.IsMixedScriptDomain = true
+	score, breakdown, _, _, _ := CalculateRobustScore(base)
+	if !hasKey(breakdown, "penalty_mixed_script") || score >= clean {
+		t.Errorf("score %d (clean %d), breakdown %v", score, clean, breakdown)
+	}
+}
+
+func TestLinkedInSoftProof(t *testing.T) {
+	score, breakdown, _, status, confirmedBy := CalculateRobustScore(models.RiskAnalysis{
+		IsCatchAll:  true,
+		HasLinkedIn: true,
+	})
+	if !hasKey(breakdown, "p2_linkedin") || !hasKey(breakdown, "resolution_catchall_medium") {
+		t.Errorf("expected LinkedIn to count as soft proof, got %v", breakdown)
+	}
+	if status != models.StatusRisky || confirmedBy != "" {
+		t.Errorf("soft proof must not confirm a catch-all, got %q / %q", status, confirmedBy)
+	}
+	if score != 30+int(Scoring.WeightLinkedIn)+25 {
+		t.Errorf("score %d", score)
+	}
+}
+
+func TestVRFYCorroboration(t *testing.T) {
+	saved := VRFYRequireCorroboration
+	defer func() { VRFYRequireCorroboration = saved }()
+
+	lone := models.RiskAnalysis{HasVRFY: true}
+	corroborated := models.RiskAnalysis{HasVRFY: true, SmtpStatus: 250}
+	contradicted := models.RiskAnalysis{HasVRFY: true, SmtpStatus: 550}
+	catchAll := models.RiskAnalysis{HasVRFY: true, IsCatchAll: true}
+
+	VRFYRequireCorroboration = false
+	if score, breakdown, _, status, _ := CalculateRobustScore(lone); score != 99 || status != models.StatusValid || !hasKey(breakdown, "p0_vrfy_verified") {
+		t.Errorf("default mode: VRFY alone should validate, got %d %q %v", score, status, breakdown)
+	}
+
+	VRFYRequireCorroboration = true
+	if _, breakdown, _, status, _ := CalculateRobustScore(lone); status == models.StatusValid || hasKey(breakdown, "p0_vrfy_verified") {
+		t.Errorf("corroboration mode: VRFY alone must not validate, got %q %v", status, breakdown)
+	}
+	if score, _, _, status, _ := CalculateRobustScore(corroborated); score != 99 || status != models.StatusValid {
+		t.Errorf("corroboration mode: VRFY + RCPT accept should validate, got %d %q", score, status)
+	}
+	if _, _, _, status, _ := CalculateRobustScore(contradicted); status != models.StatusInvalid {
+		t.Errorf("corroboration mode: RCPT rejection should win, got %q", status)
+	}
+	if _, breakdown, _, status, _ := CalculateRobustScore(catchAll); status == models.StatusValid || hasKey(breakdown, "p0_vrfy_verified") {
+		t.Errorf("corroboration mode: a catch-all accept must not corroborate VRFY, got %q %v", status, breakdown)
+	}
+}
+
+func TestInvalidBelowThreshold(t *testing.T) {
+	saved := Scoring
+	defer func() { Scoring = saved }()
+
+	// A generic catch-all with no footprint scores well under 40.
+	analysis := models.RiskAnalysis{IsCatchAll: true, MxProvider: "generic"}
+	score, _, _, status, _ := CalculateRobustScore(analysis)
+	if status != models.StatusCatchAll {
+		t.Fatalf("precondition: expected catch_all, got %s (score %d)", status, score)
+	}
+
+	if final, nuanced := ApplyInvalidBelow(score, status); final != status || nuanced != "" {
+		t.Errorf("threshold off: got final=%s nuanced=%q", final, nuanced)
+	}
+
+	Scoring.InvalidBelow = 40
+	if final, nuanced := ApplyInvalidBelow(score, status); final != models.StatusInvalid || nuanced != models.StatusCatchAll {
+		t.Errorf("score %d under 40: got final=%s nuanced=%s", score, final, nuanced)
+	}
+	if final, nuanced := ApplyInvalidBelow(95, models.StatusValid); final != models.StatusValid || nuanced != models.StatusValid {
+		t.Errorf("score above threshold: got final=%s nuanced=%s", final, nuanced)
+	}
+}
+
+func TestRecommendationPerStatusPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		input models.RiskAnalysis
+		want  models.Recommendation
+	}{
+		{"SMTP-confirmed mailbox", models.RiskAnalysis{SmtpStatus: 250, HasSPF: true, HasDMARC: true}, models.RecommendSend},
+		{"catch-all without footprint", models.RiskAnalysis{IsCatchAll: true, MxProvider: "generic"}, models.RecommendSendWithCaution},
+		{"hard bounce", models.RiskAnalysis{SmtpStatus: 550}, models.RecommendDoNotSend},
+		{"O365 zombie", models.RiskAnalysis{SmtpStatus: 250, MxProvider: "office365", HasTeamsPresence: true}, models.RecommendDoNotSend},
+		{"no signals", models.RiskAnalysis{}, models.RecommendVerifyLater},
+		{"OSINT-only footprint", models.RiskAnalysis{SmtpSkipped: true, HasGitHub: true}, models.RecommendSendWithCaution},
+		{"low-confidence catch-all", models.RiskAnalysis{IsCatchAll: true, CatchAllLowConfidence: true, MxProvider: "generic"}, models.RecommendVerifyLater},
+	}
+	for _, tt := range tests {
+		scored := ScoreAnalysis(tt.input)
+		if scored.Recommendation != tt.want {
+			t.Errorf("%s (status=%s score=%d): recommendation = %s, want %s", tt.name, scored.Status, scored.Score, scored.Recommendation, tt.want)
+		}
+	}
+
+	if got := Recommend(models.StatusValid, Scoring.SafeScore-1, false); got != models.RecommendSendWithCaution {
+		t.Errorf("valid outside the safe band: got %s", got)
+	}
+}
+
+func TestRecommendationMapIsConfigurable(t *testing.T) {
+	saved := Scoring
+	defer func() { Scoring = saved }()
+
+	Scoring.Recommendations.CatchAll = models.RecommendDoNotSend
+	if got := Recommend(models.StatusCatchAll, 0, false); got != models.RecommendDoNotSend {
+		t.Errorf("configured catch_all mapping not applied: %s", got)
+	}
+}
+
+func TestParkedDomain(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       models.RiskAnalysis
+		wantPenalty bool
+		wantStatus  models.VerificationStatus
+	}{
+		{"parked catch-all, no proof", models.RiskAnalysis{IsParked: true, IsCatchAll: true}, true, models.StatusUnknown},
+		{"parked, unreachable, no proof", models.RiskAnalysis{IsParked: true}, true, models.StatusUnknown},
+		{"parked with soft OSINT only", models.RiskAnalysis{IsParked: true, IsCatchAll: true, HasGitHub: true}, true, models.StatusUnknown},
+		{"parked and rejected over RCPT", models.RiskAnalysis{IsParked: true, SmtpStatus: 550}, false, models.StatusInvalid},
+		{"parked with absolute OSINT proof", models.RiskAnalysis{IsParked: true, IsCatchAll: true, HasGoogleCalendar: true}, false, models.StatusValid},
+		{"registrar-hosted mailbox confirmed over RCPT", models.RiskAnalysis{IsParked: true, SmtpStatus: 250}, false, models.StatusValid},
+	}
+	for _, tt := range tests {
+		_, breakdown, _, status, _ := CalculateRobustScore(tt.input)
+		if hasKey(breakdown, "penalty_parked_domain") != tt.wantPenalty || status != tt.wantStatus {
+			t.Errorf("%s: status=%s breakdown=%v", tt.name, status, breakdown)
+		}
+	}
+}
+
+func TestCatchAllRiskyScorePerProvider(t *testing.T) {
+	saved := Scoring
+	defer func() { Scoring = saved }()
+
+	// A Google catch-all with weak timing scores in the 30s: under the flat
+	// 60 it stays catch_all.
+	weak := models.RiskAnalysis{IsCatchAll: true, MxProvider: "google", TimingDeltaMs: 2000}
+	score, _, _, status, _ := CalculateRobustScore(weak)
+	if status != models.StatusCatchAll {
+		t.Fatalf("default threshold: expected catch_all, got %s (score %d)", status, score)
+	}
+
+	Scoring.CatchAllRiskyScore = map[string]int{"google": score}
+	if _, _, _, status, _ := CalculateRobustScore(weak); status != models.StatusRisky {
+		t.Errorf("lenient google threshold %d: expected risky, got %s", score, status)
+	}
+
+	// The override is per provider: the same signals on a generic host keep
+	// the flat bar.
+	generic := weak
+	generic.MxProvider = "generic"
+	if _, _, _, status, _ := CalculateRobustScore(generic); status != models.StatusCatchAll {
+		t.Errorf("generic host: expected catch_all under the default threshold, got %s", status)
+	}
+
+	// A stricter bar keeps a catch-all that would otherwise upgrade.
+	github := models.RiskAnalysis{IsCatchAll: true, MxProvider: "google", HasGitHub: true}
+	Scoring.CatchAllRiskyScore = nil
+	score, _, _, status, _ = CalculateRobustScore(github)
+	if status != models.StatusRisky {
+		t.Fatalf("default threshold: expected risky, got %s (score %d)", status, score)
+	}
+	Scoring.CatchAllRiskyScore = map[string]int{"google": score + 1}
+	if _, _, _, status, _ := CalculateRobustScore(github); status != models.StatusCatchAll {
+		t.Errorf("strict google threshold %d: expected catch_all, got %s", score+1, status)
+	}
+}
+
+func TestSlackSignals(t *testing.T) {
+	// A workspace describes the domain: it scores, but does not resolve a
+	// catch-all the way a mailbox-level signal does.
+	base := models.RiskAnalysis{IsCatchAll: true, MxProvider: "generic"}
+	without, _, _, _, _ := CalculateRobustScore(base)
+
+	base.HasSlackWorkspace = true
+	score, breakdown, _, _, _ := CalculateRobustScore(base)
+	if !hasKey(breakdown, "p3_slack_workspace") || hasKey(breakdown, "resolution_catchall_medium") ||
+		score != without+int(Scoring.WeightSlackWorkspace) {
+		t.Errorf("workspace: score %d (without %d), breakdown %v", score, without, breakdown)
+	}
+
+	// Membership is soft proof of the mailbox.
+	base.HasSlack = true
+	_, breakdown, _, _, _ = CalculateRobustScore(base)
+	if !hasKey(breakdown, "p2_slack") || !hasKey(breakdown, "resolution_catchall_medium") {
+		t.Errorf("member: breakdown %v", breakdown)
+	}
+}
@@ -0,0 +1,15 @@
+package validator
+
+import (
+	"mailvetter/internal/cache"
+	"mailvetter/internal/models"
+)
+
+// Register every type this package stores in cache.DomainCache so it survives
+// a snapshot round-trip.
+func init() {
+	cache.Register(DomainResult{})
+	cache.Register(SmtpHostResult{})
+	cache.Register(globalAcceptRecord{})
+	cache.Register(models.ValidationResult{})
+}
@@ -0,0 +1,47 @@
+package validator
+
+import (
+	"strings"
+
+	"mailvetter/internal/lookup"
+)
+
+// Reason codes reported in ValidationResult.Reason when the early syntax gate
+// rejects an address before any network probe is made.
+const (
+	ReasonLocalPartTooLong = "local_part_too_long"
+	ReasonDomainTooLong    = "domain_too_long"
+	ReasonAddressTooLong   = "address_too_long"
+	ReasonDisposable       = "disposable_domain"
+	// ReasonInvalidSyntax marks an address lookup.ValidateSyntax rejects.
+	ReasonInvalidSyntax = "invalid_syntax"
+	// ReasonLikelyDisposable is set after scoring, not by the syntax gate,
+	// when the behavioural pattern in IsLikelyDisposable matches.
+	ReasonLikelyDisposable = "likely_disposable"
+	// ReasonSMTPUTF8Unsupported marks an internationalized address whose mail
+	// server cannot receive it over SMTP, so no SMTP verdict is possible.
+	ReasonSMTPUTF8Unsupported = "smtputf8_unsupported"
+	// ReasonCatchAllUnconfirmed marks a catch-all observation that
+	// ConservativeCatchAll could not repeat, so no verdict was committed.
+	ReasonCatchAllUnconfirmed = "catch_all_unconfirmed"
+)
+
+// checkLength enforces the RFC 5321 size limits. Returns a reason code for
+// the first limit exceeded, or "" if the address is within bounds. No server
+// will accept an over-length address, so probing one is pure waste.
+func checkLength(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	if len(email[:at]) > lookup.MaxLocalPartLength {
+		return ReasonLocalPartTooLong
+	}
+	if len(email[at+1:]) > lookup.MaxDomainLength {
+		return ReasonDomainTooLong
+	}
+	if len(email) > lookup.MaxAddressLength {
+		return ReasonAddressTooLong
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckLength(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		want  string
+	}{
+		{"Normal address", "jane.doe@example.com", ""},
+		{"64-char local part is allowed", strings.Repeat("a", 64) + "@example.com", ""},
+		{"65-char local part", strings.Repeat("a", 65) + "@example.com", ReasonLocalPartTooLong},
+		{"256-char domain", "a@" + strings.Repeat("b", 252) + ".com", ReasonDomainTooLong},
+		{"Total over 254", strings.Repeat("a", 64) + "@" + strings.Repeat("b", 186) + ".com", ReasonAddressTooLong},
+		{"Total exactly 254", strings.Repeat("a", 64) + "@" + strings.Repeat("b", 185) + ".com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkLength(tt.email); got != tt.want {
+				t.Errorf("checkLength(%d chars) = %q, want %q", len(tt.email), got, tt.want)
+			}
+		})
+	}
+}
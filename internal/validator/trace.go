@@ -0,0 +1,162 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mailvetter/internal/lookup"
+	"mailvetter/internal/models"
+)
+
+// Sources reported in TraceEvent.Source.
+const (
+	TraceSourceCache   = "cache"
+	TraceSourceProbe   = "probe"
+	TraceSourceSkipped = "skipped"
+)
+
+// TraceEvent is one step VerifyEmail took (or deliberately skipped) while
+// producing a verdict.
+type TraceEvent struct {
+	Stage      string `json:"stage"`
+	Source     string `json:"source"`
+	Outcome    string `json:"outcome,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Trace is the full diagnostic record of one traced verification: which
+// signals came from cache or a live probe, which probes were skipped and why,
+// how long each took, and the raw SMTP exchange.
+type Trace struct {
+	Email      string                  `json:"email"`
+	Mode       Mode                    `json:"mode"`
+	MX         []string                `json:"mx,omitempty"`
+	Events     []TraceEvent            `json:"events"`
+	Transcript []string                `json:"smtp_transcript"`
+	Result     models.ValidationResult `json:"result"`
+	DurationMs int64                   `json:"duration_ms"`
+
+	mu     sync.Mutex
+	sealed bool // set once TraceEmail returns; late collectors are dropped
+}
+
+type traceKey struct{}
+
+// traceFrom returns the trace carried by ctx, or nil. Every Trace method is a
+// no-op on nil, so the collectors can record unconditionally.
+func traceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+func (t *Trace) record(stage, source, outcome string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sealed {
+		return
+	}
+	t.Events = append(t.Events, TraceEvent{Stage: stage, Source: source, Outcome: outcome, DurationMs: d.Milliseconds()})
+}
+
+// recordProbe records one RCPT TO probe; a zero outcome (probe never ran) is
+// ignored.
+func (t *Trace) recordProbe(stage string, o probeOutcome) {
+	if t == nil || o.Address == "" {
+		return
+	}
+	outcome := o.Address + ": "
+	switch {
+	case o.Accepted:
+		outcome += "accepted"
+	case o.Err != nil:
+		outcome += o.Err.Error()
+	default:
+		outcome += "no reply"
+	}
+	t.record(stage, TraceSourceProbe, outcome, o.Duration)
+}
+
+// traceProbes wraps probes so each run is recorded as an "osint:<name>"
+// event. On a nil trace probes is returned as is.
+func (t *Trace) traceProbes(probes []lookup.Probe) []lookup.Probe {
+	if t == nil {
+		return probes
+	}
+	out := make([]lookup.Probe, len(probes))
+	for i, p := range probes {
+		out[i] = tracedProbe{Probe: p, t: t}
+	}
+	return out
+}
+
+type tracedProbe struct {
+	lookup.Probe
+	t *Trace
+}
+
+func (p tracedProbe) Run(ctx context.Context, email, domain string) (string, bool) {
+	start := time.Now()
+	signal, ok := p.Probe.Run(ctx, email, domain)
+	detail := strconv.FormatBool(ok)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		detail = "timed out"
+	}
+	p.t.record("osint:"+p.Name(), TraceSourceProbe, detail, time.Since(start))
+	return signal, ok
+}
+
+func (t *Trace) setMX(records []lookup.MXRecord) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sealed {
+		return
+	}
+	t.MX = t.MX[:0]
+	for _, mx := range records {
+		t.MX = append(t.MX, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+	}
+}
+
+// TraceEmail replays a verification of email with full diagnostics. The
+// result cache is bypassed so every collector actually runs; the domain-level
+// caches are still consulted, and the trace reports which signals they served.
+func TraceEmail(ctx context.Context, email string) (*Trace, error) {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return nil, fmt.Errorf("malformed email %q", email)
+	}
+	domain := email[at+1:]
+
+	t := &Trace{Email: email, Mode: modeFor(domain), Events: []TraceEvent{}}
+	t.record("result_cache", TraceSourceSkipped, "bypassed for trace", 0)
+
+	transcript := &lookup.Transcript{}
+	ctx = lookup.WithTranscript(context.WithValue(ctx, traceKey{}, t), transcript)
+
+	start := time.Now()
+	res, err := VerifyEmail(ctx, email, domain)
+	res.Duration = time.Since(start).String()
+
+	t.mu.Lock()
+	t.sealed = true
+	t.Result = res
+	t.DurationMs = time.Since(start).Milliseconds()
+	t.Transcript = transcript.Lines()
+	if t.Transcript == nil {
+		t.Transcript = []string{}
+	}
+	t.mu.Unlock()
+	return t, err
+}
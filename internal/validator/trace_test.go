@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"context"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/lookup"
+	"mailvetter/internal/models"
+)
+
+func TestTraceEmail(t *testing.T) {
+	const domain = "trace.example"
+	mx := []lookup.MXRecord{{Host: "mx.trace.example", Pref: 10}}
+
+	savedMX, savedProbe, savedVRFY, savedOSINT := resolveMX, smtpProbe, vrfyProbe, osintProbes
+	defer func() { resolveMX, smtpProbe, vrfyProbe, osintProbes = savedMX, savedProbe, savedVRFY, savedOSINT }()
+	t.Setenv("HIBP_API_KEY", "")
+
+	resolveMX = func(ctx context.Context, d string) ([]lookup.MXRecord, error) { return mx, nil }
+	vrfyProbe = func(ctx context.Context, mxHost, email string, pURL *url.URL) bool { return false }
+	smtpProbe = func(ctx context.Context, mxHost, email string, pURL *url.URL) (bool, time.Duration, error) {
+		tr := lookup.TranscriptFrom(ctx)
+		tr.Add("C: RCPT TO:<" + email + ">")
+		if strings.HasPrefix(email, "jane@") {
+			tr.Add("S: 250 2.1.5 OK")
+			return true, 40 * time.Millisecond, nil
+		}
+		tr.Add("S: 550 5.1.1 user unknown")
+		return false, 45 * time.Millisecond, &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"}
+	}
+	useProbes(fakeProbe{"gravatar", lookup.SignalGravatar, func(ctx context.Context, email, domain string) bool {
+		time.Sleep(5 * time.Millisecond)
+		return true
+	}})
+
+	// Domain-level signals served from cache must be reported as such.
+	cache.DomainCache.Set(infraCacheKey(domain, mx), DomainResult{Provider: "generic", HasSPF: true}, time.Minute)
+	cache.DomainCache.Set("smtp_host:mx.trace.example:"+domain, SmtpHostResult{}, time.Minute)
+
+	tr, err := TraceEmail(context.Background(), "jane@"+domain)
+	if err != nil {
+		t.Fatalf("TraceEmail: %v", err)
+	}
+
+	if tr.Result.Status != models.StatusValid {
+		t.Errorf("result status %q != %q", tr.Result.Status, models.StatusValid)
+	}
+	if len(tr.MX) != 1 || tr.MX[0] != "10 mx.trace.example" {
+		t.Errorf("MX = %v", tr.MX)
+	}
+
+	transcript := strings.Join(tr.Transcript, "\n")
+	if !strings.Contains(transcript, "S: 250 2.1.5 OK") || !strings.Contains(transcript, "S: 550 5.1.1 user unknown") {
+		t.Errorf("transcript missing target or ghost exchange:\n%s", transcript)
+	}
+
+	events := make(map[string]TraceEvent)
+	for _, e := range tr.Events {
+		events[e.Stage] = e
+	}
+	want := map[string]string{
+		"result_cache":   TraceSourceSkipped,
+		"infra":          TraceSourceCache,
+		"smtp_host":      TraceSourceCache,
+		"vrfy":           TraceSourceProbe,
+		"smtp_target":    TraceSourceProbe,
+		"smtp_ghost":     TraceSourceProbe,
+		"osint:gravatar": TraceSourceProbe,
+		"osint:hibp":     TraceSourceSkipped,
+	}
+	for stage, source := range want {
+		e, ok := events[stage]
+		if !ok {
+			t.Errorf("trace missing stage %q", stage)
+			continue
+		}
+		if e.Source != source {
+			t.Errorf("stage %q source %q != %q", stage, e.Source, source)
+		}
+	}
+	if d := events["smtp_target"].DurationMs; d != 40 {
+		t.Errorf("smtp_target duration %dms != 40ms", d)
+	}
+	if d := events["osint:gravatar"].DurationMs; d < 5 {
+		t.Errorf("osint:gravatar duration %dms, expected at least 5ms", d)
+	}
+}
@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"mailvetter/internal/models"
+)
+
+// ErrVerdictOnly is returned by Rescore for a result stored as a Verdict:
+// without its analysis there is nothing to score it from again.
+var ErrVerdictOnly = errors.New("result was stored verdict-only and cannot be re-scored")
+
+// Verdict is a verification's outcome without the evidence behind it, for
+// deployments that never re-score and would rather not store the full
+// analysis per result. ReasonCodes are the score_details keys that fired.
+type Verdict struct {
+	Email          string                    `json:"email"`
+	Score          int                       `json:"score"`
+	Status         models.VerificationStatus `json:"status"`
+	Reachability   models.Reachability       `json:"reachability"`
+	Recommendation models.Recommendation     `json:"recommendation,omitempty"`
+	ReasonCodes    []string                  `json:"reason_codes,omitempty"`
+	Reason         string                    `json:"reason,omitempty"`
+	Error          string                    `json:"error,omitempty"`
+	// VerdictOnly tells readers of stored results which shape they hold.
+	VerdictOnly bool `json:"verdict_only"`
+}
+
+// VerdictOf condenses r to its Verdict.
+func VerdictOf(r models.ValidationResult) Verdict {
+	codes := make([]string, 0, len(r.ScoreBreakdown))
+	for code := range r.ScoreBreakdown {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return Verdict{
+		Email:          r.Email,
+		Score:          r.Score,
+		Status:         r.Status,
+		Reachability:   r.Reachability,
+		Recommendation: r.Recommendation,
+		ReasonCodes:    codes,
+		Reason:         r.Reason,
+		Error:          r.Error,
+		VerdictOnly:    true,
+	}
+}
+
+// Rescore scores a stored result's analysis again under the current scoring
+// config and returns the result with its verdict replaced. Results stored as
+// a Verdict fail with ErrVerdictOnly.
+func Rescore(data []byte) (models.ValidationResult, error) {
+	var shape struct {
+		VerdictOnly bool `json:"verdict_only"`
+	}
+	if err := json.Unmarshal(data, &shape); err != nil {
+		return models.ValidationResult{}, err
+	}
+	if shape.VerdictOnly {
+		return models.ValidationResult{}, ErrVerdictOnly
+	}
+
+	var result models.ValidationResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return models.ValidationResult{}, err
+	}
+	scored := ScoreAnalysis(result.Analysis)
+	result.Score = scored.Score
+	result.ScoreBreakdown = scored.ScoreBreakdown
+	result.Explanation = scored.Explanation
+	result.Reachability = scored.Reachability
+	result.Status, result.NuancedStatus = scored.Status, scored.NuancedStatus
+	result.ConfirmedBy = scored.ConfirmedBy
+	result.Recommendation = scored.Recommendation
+	return result, nil
+}
@@ -0,0 +1,179 @@
+package validator
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/config"
+	"mailvetter/internal/proxy"
+)
+
+// Cache warming keeps the infra entries of the most-requested domains fresh,
+// refreshing them shortly before they expire so a hot domain never takes a
+// cold miss — and never sends a burst of concurrent requests into the same
+// RDAP and DNS lookups when it does.
+var (
+	// CacheWarmTopK is how many of the most-requested domains are kept warm.
+	// Set via CACHE_WARM_TOP_K.
+	CacheWarmTopK = config.Int("CACHE_WARM_TOP_K", 50)
+
+	// CacheWarmInterval is how often the warmer checks for entries nearing
+	// expiry. Set via CACHE_WARM_INTERVAL.
+	CacheWarmInterval = config.Duration("CACHE_WARM_INTERVAL", time.Minute)
+
+	// CacheWarmLead is how close to expiry an entry must be before it is
+	// refreshed. It should exceed CacheWarmInterval so no entry lapses
+	// between checks. Set via CACHE_WARM_LEAD.
+	CacheWarmLead = config.Duration("CACHE_WARM_LEAD", 3*time.Minute)
+
+	// CacheWarmIdle is how long a domain may go unrequested before the
+	// warmer forgets it and lets its entry expire. Set via CACHE_WARM_IDLE.
+	CacheWarmIdle = config.Duration("CACHE_WARM_IDLE", 30*time.Minute)
+)
+
+// maxTrackedDomains bounds the frequency counter. When it is full, every
+// count is halved and domains that drop to zero are forgotten, so memory
+// stays bounded and old popularity decays in favour of recent traffic.
+const maxTrackedDomains = 10000
+
+// domainCounter counts verification requests per domain and remembers when
+// each domain was last requested.
+type domainCounter struct {
+	mu       sync.Mutex
+	counts   map[string]uint32
+	lastSeen map[string]time.Time
+	limit    int
+}
+
+func newDomainCounter(limit int) *domainCounter {
+	return &domainCounter{
+		counts:   make(map[string]uint32),
+		lastSeen: make(map[string]time.Time),
+		limit:    limit,
+	}
+}
+
+// hotDomains records every domain passed to VerifyEmail.
+var hotDomains = newDomainCounter(maxTrackedDomains)
+
+func (c *domainCounter) observe(domain string) {
+	domain = strings.ToLower(domain)
+	if domain == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.counts[domain]; !ok && len(c.counts) >= c.limit {
+		c.decay()
+	}
+	c.counts[domain]++
+	c.lastSeen[domain] = time.Now()
+}
+
+// evictIdle forgets every domain last requested before cutoff, so a domain
+// that has gone quiet stops being refreshed.
+func (c *domainCounter) evictIdle(cutoff time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for d, seen := range c.lastSeen {
+		if seen.Before(cutoff) {
+			delete(c.counts, d)
+			delete(c.lastSeen, d)
+		}
+	}
+}
+
+// decay halves every count and drops domains that reach zero. Callers hold mu.
+func (c *domainCounter) decay() {
+	for d, n := range c.counts {
+		if n /= 2; n == 0 {
+			delete(c.counts, d)
+			delete(c.lastSeen, d)
+		} else {
+			c.counts[d] = n
+		}
+	}
+}
+
+// top returns up to k domains, most requested first. Ties are broken by name
+// so the result is deterministic.
+func (c *domainCounter) top(k int) []string {
+	c.mu.Lock()
+	domains := make([]string, 0, len(c.counts))
+	for d := range c.counts {
+		domains = append(domains, d)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		ni, nj := c.counts[domains[i]], c.counts[domains[j]]
+		if ni != nj {
+			return ni > nj
+		}
+		return domains[i] < domains[j]
+	})
+	c.mu.Unlock()
+
+	if len(domains) > k {
+		domains = domains[:k]
+	}
+	return domains
+}
+
+// warmOnce refreshes the infra entry of every top-K domain that is missing
+// or expires within CacheWarmLead, and returns the domains it refreshed.
+// Domains not requested within CacheWarmIdle are forgotten first.
+// Domains whose MX lookup fails are skipped; a request will surface the
+// failure on its own.
+func warmOnce(ctx context.Context) []string {
+	hotDomains.evictIdle(time.Now().Add(-CacheWarmIdle))
+
+	var refreshed []string
+	for _, domain := range hotDomains.top(CacheWarmTopK) {
+		if ctx.Err() != nil {
+			break
+		}
+		mxRecords, err := resolveMX(ctx, domain)
+		if err != nil || len(mxRecords) == 0 {
+			continue
+		}
+		key := infraCacheKey(domain, mxRecords)
+		if remaining, ok := cache.DomainCache.TTL(key); ok && remaining > CacheWarmLead {
+			continue
+		}
+
+		var pURL *url.URL
+		if proxy.Enabled() {
+			pURL = proxy.Global.Next()
+		}
+		cache.DomainCache.Set(key, fetchInfra(ctx, domain, mxRecords, pURL), InfraCacheTTL)
+		refreshed = append(refreshed, domain)
+	}
+	return refreshed
+}
+
+// StartCacheWarmer launches a goroutine that keeps the most-requested
+// domains' infra entries warm until ctx is cancelled.
+func StartCacheWarmer(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(CacheWarmInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if n := len(warmOnce(ctx)); n > 0 {
+					log.Printf("[cache] warmed infra entries for %d hot domains", n)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,104 @@
+package validator
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mailvetter/internal/cache"
+	"mailvetter/internal/lookup"
+)
+
+func TestDomainCounterTopK(t *testing.T) {
+	c := newDomainCounter(100)
+	for domain, n := range map[string]int{"hot.example": 5, "warm.example": 3, "Cold.example": 1, "tied.example": 3} {
+		for i := 0; i < n; i++ {
+			c.observe(domain)
+		}
+	}
+
+	if got, want := c.top(3), []string{"hot.example", "tied.example", "warm.example"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("top(3) = %v, want %v", got, want)
+	}
+	if got := c.top(10); len(got) != 4 || got[3] != "cold.example" {
+		t.Errorf("top(10) = %v, expected all 4 domains with names lower-cased", got)
+	}
+}
+
+func TestDomainCounterDecaysWhenFull(t *testing.T) {
+	c := newDomainCounter(3)
+	for i := 0; i < 4; i++ {
+		c.observe("hot.example")
+	}
+	c.observe("a.example")
+	c.observe("b.example")
+
+	// A fourth distinct domain halves every count: singletons drop out,
+	// the hot domain survives with its lead intact.
+	c.observe("new.example")
+
+	if got, want := c.top(10), []string{"hot.example", "new.example"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after decay top = %v, want %v", got, want)
+	}
+	if n := c.counts["hot.example"]; n != 2 {
+		t.Errorf("hot.example count = %d, want 2", n)
+	}
+}
+
+func TestDomainCounterEvictsIdle(t *testing.T) {
+	c := newDomainCounter(100)
+	for i := 0; i < 5; i++ {
+		c.observe("quiet.example")
+	}
+	c.observe("recent.example")
+	c.lastSeen["quiet.example"] = time.Now().Add(-time.Hour)
+
+	c.evictIdle(time.Now().Add(-time.Minute))
+
+	if got, want := c.top(10), []string{"recent.example"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after eviction top = %v, want %v", got, want)
+	}
+	if _, ok := c.lastSeen["quiet.example"]; ok {
+		t.Errorf("expected quiet.example to be forgotten entirely")
+	}
+}
+
+func TestWarmOnceRefreshesExpiringHotDomains(t *testing.T) {
+	savedCounter, savedMX, savedFetch, savedK := hotDomains, resolveMX, fetchInfra, CacheWarmTopK
+	defer func() { hotDomains, resolveMX, fetchInfra, CacheWarmTopK = savedCounter, savedMX, savedFetch, savedK }()
+
+	hotDomains = newDomainCounter(100)
+	CacheWarmTopK = 2
+	for domain, n := range map[string]int{"expiring.example": 5, "fresh.example": 4, "cold.example": 1} {
+		for i := 0; i < n; i++ {
+			hotDomains.observe(domain)
+		}
+	}
+
+	mxFor := func(d string) []lookup.MXRecord { return []lookup.MXRecord{{Host: "mx." + d, Pref: 10}} }
+	resolveMX = func(ctx context.Context, d string) ([]lookup.MXRecord, error) { return mxFor(d), nil }
+	var fetches int32
+	fetchInfra = func(ctx context.Context, d string, mx []lookup.MXRecord, pURL *url.URL) DomainResult {
+		atomic.AddInt32(&fetches, 1)
+		return DomainResult{Provider: "warmed"}
+	}
+
+	cache.DomainCache.Set(infraCacheKey("expiring.example", mxFor("expiring.example")), DomainResult{}, time.Second)
+	cache.DomainCache.Set(infraCacheKey("fresh.example", mxFor("fresh.example")), DomainResult{}, InfraCacheTTL)
+
+	refreshed := warmOnce(context.Background())
+	if !reflect.DeepEqual(refreshed, []string{"expiring.example"}) || fetches != 1 {
+		t.Fatalf("refreshed %v with %d fetches, expected only expiring.example", refreshed, fetches)
+	}
+
+	key := infraCacheKey("expiring.example", mxFor("expiring.example"))
+	if v, ok := cache.DomainCache.Get(key); !ok || v.(DomainResult).Provider != "warmed" {
+		t.Errorf("expected the refreshed entry in the cache, got %v", v)
+	}
+	if remaining, _ := cache.DomainCache.TTL(key); remaining <= CacheWarmLead {
+		t.Errorf("refreshed entry should have a full TTL, has %s", remaining)
+	}
+}
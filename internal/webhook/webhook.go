@@ -0,0 +1,158 @@
+// Package webhook notifies uploaders when their job finishes, so large jobs
+// do not have to be polled via /status.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/outbound"
+)
+
+// Secret keys the HMAC-SHA256 signature sent in SignatureHeader. Receivers
+// recompute it over the raw request body to verify a callback came from us.
+// Callbacks are sent unsigned when it is empty. Set via WEBHOOK_SECRET.
+var Secret = config.String("WEBHOOK_SECRET", "")
+
+// SignatureHeader carries "sha256=<hex HMAC of the body>".
+const SignatureHeader = "X-Mailvetter-Signature"
+
+// maxAttempts bounds delivery of one callback.
+const maxAttempts = 3
+
+// retryBackoff is the pause before the second attempt, doubled before each
+// later one. It is a variable so tests can shorten it.
+var retryBackoff = 2 * time.Second
+
+// AllowedHosts, when non-empty, restricts callback URLs to these hosts and
+// their subdomains. Set via WEBHOOK_URL_HOSTS (comma-separated).
+var AllowedHosts = config.List("WEBHOOK_URL_HOSTS")
+
+// client is used for callbacks. Receivers are expected to acknowledge quickly
+// and do any real work asynchronously.
+var client = outbound.Client(10 * time.Second)
+
+// Payload is the JSON body POSTed to a job's callback URL.
+type Payload struct {
+	JobID      string `json:"job_id"`
+	Status     string `json:"status"`
+	TotalCount int    `json:"total_count"`
+
+	// For jobs with an export: "uploaded" or "failed", and where the
+	// export was written (the export URL without its presigned query).
+	ExportStatus   string `json:"export_status,omitempty"`
+	ExportLocation string `json:"export_location,omitempty"`
+}
+
+// Sign returns the SignatureHeader value for body under secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver POSTs p to callbackURL, retrying failed attempts with exponential
+// backoff. Any 2xx response counts as delivered.
+func Deliver(ctx context.Context, callbackURL string, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err = post(ctx, callbackURL, body)
+		if err == nil || attempt == maxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func post(ctx context.Context, callbackURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid callback URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("callback failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback rejected: %s", resp.Status)
+	}
+	return nil
+}
+
+// delivery is one queued callback.
+type delivery struct {
+	url     string
+	payload Payload
+}
+
+// enqueueTimeout bounds how long Enqueue waits for room in the queue before
+// giving up on a callback. It is a variable so tests can shorten it.
+var enqueueTimeout = 30 * time.Second
+
+// pending feeds the dispatcher; stopped is closed once it has drained.
+var (
+	pending = make(chan delivery, 256)
+	stopped = make(chan struct{})
+)
+
+// Enqueue schedules a callback for the dispatcher started by Start. It waits
+// up to enqueueTimeout for room in the queue, and only then drops the
+// callback.
+func Enqueue(callbackURL string, p Payload) {
+	select {
+	case pending <- delivery{url: callbackURL, payload: p}:
+	case <-time.After(enqueueTimeout):
+		log.Printf("⚠️  Webhook queue full for %s, dropping callback for job %s", enqueueTimeout, p.JobID)
+	}
+}
+
+// Start launches the dispatcher goroutine, which delivers queued callbacks
+// one at a time until Close. Deliveries are not tied to the worker's
+// context, so callbacks queued as shutdown begins are still sent.
+func Start() {
+	go func() {
+		defer close(stopped)
+		for d := range pending {
+			if err := Deliver(context.Background(), d.url, d.payload); err != nil {
+				log.Printf("❌ Webhook for job %s failed after %d attempts: %v", d.payload.JobID, maxAttempts, err)
+			} else {
+				log.Printf("📣 Webhook delivered for job %s", d.payload.JobID)
+			}
+		}
+	}()
+}
+
+// Close stops accepting callbacks and waits for the dispatcher to deliver
+// the ones already queued. Nothing may call Enqueue after it.
+func Close() {
+	close(pending)
+	<-stopped
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mailvetter/internal/outbound"
+)
+
+// allowLoopback lets the callback client reach the local test server.
+func allowLoopback(t *testing.T) {
+	saved := outbound.AllowPrivate
+	t.Cleanup(func() { outbound.AllowPrivate = saved })
+	outbound.AllowPrivate = true
+}
+
+func TestDeliverSignsAndRetries(t *testing.T) {
+	savedSecret, savedBackoff := Secret, retryBackoff
+	defer func() { Secret, retryBackoff = savedSecret, savedBackoff }()
+	Secret = "shh"
+	retryBackoff = time.Millisecond
+	allowLoopback(t)
+
+	var attempts int32
+	var got Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if sig := r.Header.Get(SignatureHeader); sig != Sign("shh", body) {
+			t.Errorf("signature %q does not match the body", sig)
+		}
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		json.Unmarshal(body, &got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	want := Payload{JobID: "job-1", Status: "completed", TotalCount: 42}
+	if err := Deliver(context.Background(), srv.URL, want); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("%d attempts, expected success on the third", attempts)
+	}
+	if got != want {
+		t.Errorf("received %+v, want %+v", got, want)
+	}
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	savedBackoff := retryBackoff
+	defer func() { retryBackoff = savedBackoff }()
+	retryBackoff = time.Millisecond
+	allowLoopback(t)
+
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if err := Deliver(context.Background(), srv.URL, Payload{JobID: "job-2"}); err == nil {
+		t.Fatal("expected an error after every attempt failed")
+	}
+	if attempts != maxAttempts {
+		t.Errorf("%d attempts, expected %d", attempts, maxAttempts)
+	}
+}
+
+func TestCloseDrainsQueue(t *testing.T) {
+	allowLoopback(t)
+	savedPending, savedStopped := pending, stopped
+	defer func() { pending, stopped = savedPending, savedStopped }()
+	pending, stopped = make(chan delivery, 1), make(chan struct{})
+
+	var delivered int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&delivered, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// With a one-slot queue the later callbacks wait for room rather than
+	// being dropped.
+	for i := 0; i < 3; i++ {
+		Enqueue(srv.URL, Payload{JobID: "job"})
+		if i == 0 {
+			Start()
+		}
+	}
+	Close()
+	if delivered != 3 {
+		t.Errorf("%d callbacks delivered before Close returned, want 3", delivered)
+	}
+}
+
+func TestBlockedPrivateCallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("callback reached a loopback address")
+	}))
+	defer srv.Close()
+
+	if err := post(context.Background(), srv.URL, []byte("{}")); err == nil {
+		t.Error("expected the loopback callback to be refused")
+	}
+}
+
+func TestSign(t *testing.T) {
+	// The widely published HMAC-SHA256 example for this key and message.
+	got := Sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
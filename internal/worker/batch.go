@@ -0,0 +1,250 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"mailvetter/internal/config"
+	"mailvetter/internal/store"
+)
+
+// ResultBatchSize switches result writes from one transaction per task to
+// batches: workers hand finished results to a single flusher, which inserts
+// up to this many rows per statement and bumps each job's processed_count
+// once per batch. That trades four database round trips per result for a
+// handful per batch. Set via RESULT_BATCH_SIZE; 0 or 1 keeps per-task
+// writes.
+var ResultBatchSize = config.Int("RESULT_BATCH_SIZE", 0)
+
+// ResultBatchInterval bounds how long a result waits for its batch to fill
+// before it is written anyway. Set via RESULT_BATCH_INTERVAL.
+var ResultBatchInterval = config.Duration("RESULT_BATCH_INTERVAL", 250*time.Millisecond)
+
+// maxResultBatch caps ResultBatchSize: at six parameters a row, a batch must
+// stay under Postgres's 65535 bind parameters.
+const maxResultBatch = 1000
+
+// activeBatcher receives results while batching is on; nil in per-task mode.
+var activeBatcher *resultBatcher
+
+// writeBatch stores a batch and returns each affected job's progress. It is
+// a variable so tests can exercise the flusher without a database.
+var writeBatch = writeResultBatch
+
+// writeOne stores a single result, for the row-by-row fallback when a batch
+// cannot be written. It is a variable so tests can run without a database.
+var writeOne = writeResult
+
+const (
+	// batchWriteAttempts is how many times a batch insert is tried before
+	// its rows are written one by one.
+	batchWriteAttempts = 2
+	batchRetryDelay    = 500 * time.Millisecond
+)
+
+// resultBatcher collects finished results for a single flusher goroutine.
+// The side effects of a written batch (history, sink, calibration, job
+// completion) run on a goroutine of their own, so a slow sink or export
+// never holds up the next write.
+type resultBatcher struct {
+	in       chan pendingResult
+	written  chan writtenBatch
+	done     chan struct{}
+	size     int
+	interval time.Duration
+}
+
+// writtenBatch is stored results whose side effects have yet to run, and the
+// job progress their writes returned.
+type writtenBatch struct {
+	results []pendingResult
+	jobs    []jobUpdate
+}
+
+type jobUpdate struct {
+	workerID int
+	jobID    string
+	progress jobProgress
+}
+
+func newResultBatcher(size int, interval time.Duration) *resultBatcher {
+	if size > maxResultBatch {
+		size = maxResultBatch
+	}
+	return &resultBatcher{
+		in:       make(chan pendingResult, size),
+		written:  make(chan writtenBatch, 4),
+		done:     make(chan struct{}),
+		size:     size,
+		interval: interval,
+	}
+}
+
+func (b *resultBatcher) add(r pendingResult) { b.in <- r }
+
+// close stops accepting results and waits for the last batch to be written
+// and its side effects run. Every worker must have stopped calling add.
+func (b *resultBatcher) close() {
+	close(b.in)
+	<-b.done
+}
+
+// run flushes whenever a batch fills or the oldest result has waited
+// interval, and once more when the batcher is closed. ctx is only used for
+// the side effects after a write; the writes themselves outlive shutdown so
+// results verified before it are not lost.
+func (b *resultBatcher) run(ctx context.Context) {
+	effectsDone := make(chan struct{})
+	go func() {
+		defer close(effectsDone)
+		for w := range b.written {
+			runSideEffects(ctx, w)
+		}
+	}()
+	defer func() {
+		close(b.written)
+		<-effectsDone
+		close(b.done)
+	}()
+
+	batch := make([]pendingResult, 0, b.size)
+	timer := time.NewTimer(b.interval)
+	timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		if len(batch) > 0 {
+			b.written <- b.flush(ctx, batch)
+			// The side-effect goroutine now owns batch.
+			batch = make([]pendingResult, 0, b.size)
+		}
+	}
+
+	for {
+		select {
+		case r, ok := <-b.in:
+			if !ok {
+				flush()
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(b.interval)
+			}
+			batch = append(batch, r)
+			if len(batch) >= b.size {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+// flush stores batch and returns what was written. A batch that fails is
+// retried, then written row by row so one bad row or a transient error does
+// not cost the rest; rows that fail on their own too are logged and dropped,
+// as in per-task mode.
+func (b *resultBatcher) flush(ctx context.Context, batch []pendingResult) writtenBatch {
+	var err error
+	for attempt := 1; attempt <= batchWriteAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(batchRetryDelay)
+		}
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		var progress map[string]jobProgress
+		progress, err = writeBatch(writeCtx, batch)
+		cancel()
+		if err == nil {
+			lastWorker := make(map[string]int, len(progress))
+			for _, r := range batch {
+				lastWorker[r.task.JobID] = r.workerID
+			}
+			w := writtenBatch{results: batch}
+			for jobID, p := range progress {
+				w.jobs = append(w.jobs, jobUpdate{lastWorker[jobID], jobID, p})
+			}
+			return w
+		}
+	}
+	log.Printf("⚠️  Failed to write a batch of %d results, writing them one by one: %v", len(batch), err)
+
+	w := writtenBatch{results: make([]pendingResult, 0, len(batch))}
+	for _, r := range batch {
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		p, err := writeOne(writeCtx, r)
+		cancel()
+		if err != nil {
+			continue
+		}
+		w.results = append(w.results, r)
+		w.jobs = append(w.jobs, jobUpdate{r.workerID, r.task.JobID, p})
+	}
+	return w
+}
+
+func runSideEffects(ctx context.Context, w writtenBatch) {
+	for _, r := range w.results {
+		afterWrite(ctx, r)
+	}
+	for _, j := range w.jobs {
+		completeJob(ctx, j.workerID, j.jobID, j.progress)
+	}
+}
+
+// writeResultBatch inserts batch in one statement and counts it towards each
+// job, all in one transaction.
+func writeResultBatch(ctx context.Context, batch []pendingResult) (map[string]jobProgress, error) {
+	tx, err := store.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query, args := batchInsertSQL(batch)
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, r := range batch {
+		counts[r.task.JobID]++
+	}
+	progress := make(map[string]jobProgress, len(counts))
+	for jobID, n := range counts {
+		p, err := scanJobProgress(tx.QueryRow(ctx, jobProgressSQL, jobID, n))
+		if err != nil {
+			return nil, err
+		}
+		progress[jobID] = p
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// batchInsertSQL builds a multi-row INSERT for batch, in batch order.
+func batchInsertSQL(batch []pendingResult) (string, []any) {
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO results (job_id, email, score, status, reachability, data) VALUES ")
+	args := make([]any, 0, len(batch)*6)
+	for i, r := range batch {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		for j := 1; j <= 6; j++ {
+			if j > 1 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("$" + strconv.Itoa(i*6+j))
+		}
+		sb.WriteString(")")
+		args = append(args, r.task.JobID, r.task.Email, r.parts.Score, string(r.parts.Status), string(r.parts.Reachability), r.data)
+	}
+	return sb.String(), args
+}
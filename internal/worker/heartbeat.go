@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"mailvetter/internal/queue"
+)
+
+// HeartbeatKey is the Redis hash every worker goroutine writes its current
+// task into. Field = worker identity, value = JSON-encoded Heartbeat.
+const HeartbeatKey = "workers:heartbeat"
+
+// StuckMargin is how far past jobTimeout a task may run before the worker is
+// reported as stuck. The per-job context should already have interrupted the
+// task at jobTimeout; anything still running after the margin is almost
+// certainly blocked in a call that does not honour the deadline.
+const StuckMargin = 1 * time.Minute
+
+// staleHeartbeatAge is the age after which a heartbeat is assumed to belong to
+// a process that crashed mid-task (and so never cleared its entry). Such
+// entries are pruned rather than reported as stuck forever.
+const staleHeartbeatAge = 1 * time.Hour
+
+// Heartbeat records the task a worker goroutine is currently processing.
+type Heartbeat struct {
+	Worker    string    `json:"worker"`
+	JobID     string    `json:"job_id"`
+	Email     string    `json:"email"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// instanceID identifies this worker process across the fleet so that the
+// heartbeat fields written by different containers never collide.
+var instanceID = func() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}()
+
+func heartbeatField(workerID int) string {
+	return fmt.Sprintf("%s/%d", instanceID, workerID)
+}
+
+// beginHeartbeat records that workerID has started task. Failures are logged
+// but never block the task — the heartbeat is observability, not control flow.
+func beginHeartbeat(ctx context.Context, workerID int, task queue.Task) {
+	hb := Heartbeat{
+		Worker:    heartbeatField(workerID),
+		JobID:     task.JobID,
+		Email:     task.Email,
+		StartedAt: time.Now(),
+	}
+	data, err := json.Marshal(hb)
+	if err != nil {
+		return
+	}
+	if err := queue.Client.HSet(ctx, HeartbeatKey, hb.Worker, data).Err(); err != nil {
+		log.Printf("[Worker %d] ⚠️  Failed to write heartbeat: %v", workerID, err)
+	}
+}
+
+// endHeartbeat clears workerID's entry once it is idle again. A background
+// context is used so the entry is removed even when ctx was cancelled by
+// shutdown mid-task.
+func endHeartbeat(workerID int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	queue.Client.HDel(ctx, HeartbeatKey, heartbeatField(workerID))
+}
+
+// ListHeartbeats returns every heartbeat currently recorded in Redis, across
+// all worker processes, sorted by start time (oldest first).
+func ListHeartbeats(ctx context.Context) ([]Heartbeat, error) {
+	raw, err := queue.Client.HGetAll(ctx, HeartbeatKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read heartbeats: %w", err)
+	}
+
+	beats := make([]Heartbeat, 0, len(raw))
+	for field, val := range raw {
+		var hb Heartbeat
+		if err := json.Unmarshal([]byte(val), &hb); err != nil {
+			continue
+		}
+		if hb.Worker == "" {
+			hb.Worker = field
+		}
+		beats = append(beats, hb)
+	}
+	sort.Slice(beats, func(i, j int) bool { return beats[i].StartedAt.Before(beats[j].StartedAt) })
+	return beats, nil
+}
+
+// FindStuck returns the heartbeats whose task started more than
+// jobTimeout+StuckMargin before now. Heartbeats older than staleHeartbeatAge
+// are excluded: they belong to processes that died without cleaning up.
+func FindStuck(beats []Heartbeat, now time.Time) []Heartbeat {
+	threshold := jobTimeout + StuckMargin
+
+	var stuck []Heartbeat
+	for _, hb := range beats {
+		age := now.Sub(hb.StartedAt)
+		if age > threshold && age <= staleHeartbeatAge {
+			stuck = append(stuck, hb)
+		}
+	}
+	return stuck
+}
+
+// pruneStale deletes heartbeats older than staleHeartbeatAge.
+func pruneStale(ctx context.Context, beats []Heartbeat, now time.Time) {
+	var fields []string
+	for _, hb := range beats {
+		if now.Sub(hb.StartedAt) > staleHeartbeatAge {
+			fields = append(fields, hb.Worker)
+		}
+	}
+	if len(fields) > 0 {
+		queue.Client.HDel(ctx, HeartbeatKey, fields...)
+	}
+}
+
+// StartMonitor launches a background goroutine that checks all heartbeats on
+// the given interval and logs any worker whose current task has exceeded the
+// job timeout by more than StuckMargin. It exits when ctx is cancelled.
+func StartMonitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				beats, err := ListHeartbeats(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Printf("[monitor] ⚠️  %v", err)
+					}
+					continue
+				}
+				now := time.Now()
+				pruneStale(ctx, beats, now)
+				for _, hb := range FindStuck(beats, now) {
+					log.Printf("[monitor] 🚨 Worker %s stuck on %s (job %s) for %s",
+						hb.Worker, hb.Email, hb.JobID, now.Sub(hb.StartedAt).Round(time.Second))
+				}
+			case <-ctx.Done():
+				log.Println("[monitor] heartbeat monitor exiting")
+				return
+			}
+		}
+	}()
+}
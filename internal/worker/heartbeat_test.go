@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindStuck(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	beats := []Heartbeat{
+		{Worker: "fresh", StartedAt: now.Add(-10 * time.Second)},
+		{Worker: "at-timeout", StartedAt: now.Add(-jobTimeout)},
+		{Worker: "within-margin", StartedAt: now.Add(-jobTimeout - StuckMargin + time.Second)},
+		{Worker: "stuck", StartedAt: now.Add(-jobTimeout - StuckMargin - time.Second)},
+		{Worker: "very-stuck", StartedAt: now.Add(-30 * time.Minute)},
+		{Worker: "dead-process", StartedAt: now.Add(-staleHeartbeatAge - time.Minute)},
+	}
+
+	stuck := FindStuck(beats, now)
+
+	got := make(map[string]bool)
+	for _, hb := range stuck {
+		got[hb.Worker] = true
+	}
+
+	for _, want := range []string{"stuck", "very-stuck"} {
+		if !got[want] {
+			t.Errorf("expected %q to be reported as stuck", want)
+		}
+	}
+	for _, notWant := range []string{"fresh", "at-timeout", "within-margin", "dead-process"} {
+		if got[notWant] {
+			t.Errorf("did not expect %q to be reported as stuck", notWant)
+		}
+	}
+	if len(stuck) != 2 {
+		t.Errorf("expected 2 stuck workers, got %d", len(stuck))
+	}
+}
+
+func TestFindStuckEmpty(t *testing.T) {
+	if stuck := FindStuck(nil, time.Now()); len(stuck) != 0 {
+		t.Errorf("expected no stuck workers, got %d", len(stuck))
+	}
+}
@@ -9,18 +9,65 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+
+	"mailvetter/internal/calibration"
+	"mailvetter/internal/config"
+	"mailvetter/internal/export"
+	"mailvetter/internal/models"
+	"mailvetter/internal/outbound"
 	"mailvetter/internal/queue"
+	"mailvetter/internal/sink"
 	"mailvetter/internal/store"
 	"mailvetter/internal/validator"
+	"mailvetter/internal/webhook"
 )
 
+// jobTimeout is the per-task verification deadline. The heartbeat monitor
+// uses it to decide when a worker should be considered stuck.
+const jobTimeout = 5 * time.Minute
+
+// MaxTaskRetries is how many times a task whose verification fails (for
+// instance by running into jobTimeout) is re-queued before it is moved to
+// queue.DeadQueueName and recorded as unknown, so its job can still complete.
+// 0 turns retries off. Set via TASK_MAX_RETRIES.
+var MaxTaskRetries = config.NonNegativeInt("TASK_MAX_RETRIES", 3)
+
+// TaskRetryBackoff is the delay before a task's first retry, doubled for each
+// later one. Set via TASK_RETRY_BACKOFF.
+var TaskRetryBackoff = config.Duration("TASK_RETRY_BACKOFF", 30*time.Second)
+
+// StoreFullAnalysis keeps each result's full analysis in results.data, which
+// validator.Rescore (GET /result?rescore=true) needs. Turned off, only the
+// validator.Verdict is stored, a fraction of the size for high-volume jobs
+// that are never re-scored. Set via STORE_FULL_ANALYSIS.
+var StoreFullAnalysis = config.Bool("STORE_FULL_ANALYSIS", true)
+
+// Fleet names the worker fleet this process belongs to; it consumes only the
+// tasks routed to that fleet (see queue.Route). Empty, the default, consumes
+// the shared queue. Set via WORKER_FLEET.
+var Fleet = config.String("WORKER_FLEET", "")
+
+// retryPromoteInterval is how often each process moves due retries back onto
+// the work queue.
+const retryPromoteInterval = 1 * time.Second
+
 // Start launches a pool of worker goroutines and blocks until every goroutine
 // has exited. The caller signals shutdown by cancelling ctx.
 func Start(ctx context.Context, concurrency int) {
-	log.Printf("👷 Starting Worker Pool with %d concurrent routines...", concurrency)
+	log.Printf("👷 Starting Worker Pool with %d concurrent routines on %s...", concurrency, queue.QueueFor(Fleet))
+	list := queue.QueueFor(Fleet)
 
 	var wg sync.WaitGroup
 
+	go promoteRetries(ctx)
+
+	if ResultBatchSize > 1 {
+		activeBatcher = newResultBatcher(ResultBatchSize, ResultBatchInterval)
+		go activeBatcher.run(ctx)
+		log.Printf("👷 Batching result writes (up to %d rows, every %s)", activeBatcher.size, ResultBatchInterval)
+	}
+
 	for i := 1; i <= concurrency; i++ {
 		wg.Add(1)
 
@@ -38,7 +85,7 @@ func Start(ctx context.Context, concurrency int) {
 				// shutdown feels instant to an operator, long enough that we
 				// are not hammering Redis with constant re-connects on an empty
 				// queue. Adjust to taste — anything under ~10 s is fine.
-				result, err := queue.Client.BLPop(ctx, 2*time.Second, queue.QueueName).Result()
+				rawJSON, err := popTask(ctx, list, 2*time.Second)
 				if err != nil {
 					// Context cancelled or deadline exceeded — this is the clean
 					// shutdown path. Exit the goroutine immediately.
@@ -67,11 +114,12 @@ func Start(ctx context.Context, concurrency int) {
 					continue
 				}
 
-				// BLPop returns a two-element slice: [queueName, payload].
-				rawJSON := result[1]
 				var task queue.Task
 				if err := json.Unmarshal([]byte(rawJSON), &task); err != nil {
-					log.Printf("[Worker %d] ❌ Malformed task (skipping): %s — %v", workerID, rawJSON, err)
+					log.Printf("[Worker %d] ❌ Malformed task (dead-lettered): %s — %v", workerID, rawJSON, err)
+					if err := deadLetter(ctx, []byte(rawJSON)); err != nil {
+						log.Printf("[Worker %d] ❌ Failed to dead-letter malformed task: %v", workerID, err)
+					}
 					continue
 				}
 
@@ -85,13 +133,73 @@ func Start(ctx context.Context, concurrency int) {
 	// zero, and this call returns — allowing main() to proceed with its exit
 	// log line and then terminate the process.
 	wg.Wait()
+	if activeBatcher != nil {
+		// Write whatever the workers finished before they stopped.
+		activeBatcher.close()
+		activeBatcher = nil
+	}
+	exports.Wait()
+	sink.Wait()
 	log.Println("👷 All workers exited. Pool shut down.")
 }
 
+// promoteRetries moves retried tasks whose backoff has elapsed back onto the
+// work queue until ctx is cancelled. Every worker process runs one; the move
+// is atomic in Redis, so they never duplicate a task.
+func promoteRetries(ctx context.Context) {
+	ticker := time.NewTicker(retryPromoteInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := queue.PromoteDue(ctx, Fleet, 500); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️  Failed to promote retried tasks: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// popTask blocks up to timeout for the next payload on list. jobCancelled
+// reports whether a task's job has been cancelled, and verifyEmail runs the
+// verification. retryTask and deadLetter hand failed tasks back to Redis.
+// They are variables so tests can exercise the pool and processTask's skip
+// and retry paths without Redis, a database or network.
+var (
+	popTask = func(ctx context.Context, list string, timeout time.Duration) (string, error) {
+		result, err := queue.Client.BLPop(ctx, timeout, list).Result()
+		if err != nil {
+			return "", err
+		}
+		// BLPop returns a two-element slice: [queueName, payload].
+		return result[1], nil
+	}
+	jobCancelled = store.JobCancelled
+	verifyEmail  = validator.VerifyEmail
+	retryTask    = queue.Retry
+	deadLetter   = queue.DeadLetter
+)
+
+// retryDelay is the backoff before the given attempt (1-based).
+func retryDelay(attempt int) time.Duration {
+	return TaskRetryBackoff << (attempt - 1)
+}
+
 // processTask runs a single verification job inside a closure so that defer
 // statements (cancel, tx.Rollback) have a well-defined scope that ends when
 // the task is complete, not at the end of the outer goroutine loop.
 func processTask(ctx context.Context, workerID int, task queue.Task) {
+	// A cancelled job's remaining tasks are dropped rather than verified or
+	// re-queued. If the check itself fails, verify anyway: wasted budget is
+	// cheaper than silently losing results for a live job.
+	if cancelled, err := jobCancelled(ctx, task.JobID); err != nil {
+		log.Printf("[Worker %d] ⚠️  Could not check job %s status, processing anyway: %v", workerID, task.JobID, err)
+	} else if cancelled {
+		log.Printf("[Worker %d] ⏭️  Skipping %s: job %s was cancelled", workerID, task.Email, task.JobID)
+		return
+	}
+
 	// Each job gets its own 5-minute deadline. If a particular email causes
 	// a probe to hang (e.g. a firewall silently dropping TCP to port 25),
 	// this ceiling ensures the worker slot is recycled within a bounded time.
@@ -99,55 +207,244 @@ func processTask(ctx context.Context, workerID int, task queue.Task) {
 	// Because valCtx is derived from ctx, cancelling ctx (shutdown) also
 	// cancels valCtx — so in-flight jobs are interrupted promptly on shutdown
 	// rather than being allowed to run out their full 5-minute window.
-	valCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	valCtx, cancel := context.WithTimeout(ctx, jobTimeout)
 	defer cancel()
 
-	parts, _ := validator.VerifyEmail(valCtx, task.Email, extractDomain(task.Email))
+	beginHeartbeat(ctx, workerID, task)
+	defer endHeartbeat(workerID)
+
+	parts, verr := verifyEmail(valCtx, task.Email, extractDomain(task.Email))
+
+	// A failed verification is retried with backoff while the process is
+	// healthy. Once retries are exhausted (or cannot be scheduled) the task is
+	// dead-lettered and still recorded, as unknown, so processed_count keeps
+	// moving and the job can complete.
+	if verr != nil && ctx.Err() == nil {
+		if task.Attempts < MaxTaskRetries {
+			retry := task
+			retry.Attempts++
+			delay := retryDelay(retry.Attempts)
+			err := retryTask(ctx, retry, delay)
+			if err == nil {
+				log.Printf("[Worker %d] 🔁 Verification of %s failed (%v), retry %d/%d in %s", workerID, task.Email, verr, retry.Attempts, MaxTaskRetries, delay)
+				return
+			}
+			log.Printf("[Worker %d] ❌ Failed to schedule retry for %s, recording as unknown: %v", workerID, task.Email, err)
+		} else {
+			task.LastError = verr.Error()
+			if data, err := json.Marshal(task); err == nil {
+				if err := deadLetter(ctx, data); err != nil {
+					log.Printf("[Worker %d] ❌ Failed to dead-letter %s: %v", workerID, task.Email, err)
+				}
+			}
+			log.Printf("[Worker %d] ☠️  %s failed %d times, dead-lettered and recorded as unknown: %v", workerID, task.Email, task.Attempts+1, verr)
+		}
+		parts.Status = models.StatusUnknown
+		parts.Recommendation = validator.Recommend(parts.Status, parts.Score, parts.Analysis.CatchAllLowConfidence)
+		if parts.Error == "" {
+			parts.Error = verr.Error()
+		}
+	}
 
-	resultJSON, err := json.Marshal(parts)
+	resultJSON, err := storedResult(parts)
 	if err != nil {
 		log.Printf("[Worker %d] ❌ Failed to marshal result for %s: %v", workerID, task.Email, err)
 		return
 	}
 
+	r := pendingResult{workerID: workerID, task: task, parts: parts, data: resultJSON}
+	if b := activeBatcher; b != nil {
+		b.add(r)
+		return
+	}
+
 	// Use the parent ctx (not valCtx) for the DB transaction. The verification
 	// timeout should not also cut off our ability to persist the result. If ctx
 	// itself is cancelled (shutdown) we accept that this write may not complete.
+	progress, err := writeResult(ctx, r)
+	if err != nil {
+		return
+	}
+	afterWrite(ctx, r)
+	completeJob(ctx, workerID, task.JobID, progress)
+}
+
+// pendingResult is a finished verification waiting to be written.
+type pendingResult struct {
+	workerID int
+	task     queue.Task
+	parts    models.ValidationResult
+	data     []byte // results.data, as storedResult encodes it
+}
+
+// jobProgress is a job's row as left by a processed_count increment.
+type jobProgress struct {
+	processed, total int
+	status           string
+	exportURL        *string
+	exportFormat     *string
+	callbackURL      *string
+}
+
+// jobProgressSQL bumps processed_count by $2 and completes the job once it
+// reaches total_count.
+const jobProgressSQL = `
+		UPDATE jobs
+		SET processed_count = processed_count + $2,
+		    status = CASE WHEN processed_count + $2 >= total_count AND status <> 'cancelled' THEN 'completed' ELSE status END,
+		    completed_at = CASE WHEN processed_count + $2 >= total_count THEN NOW() ELSE completed_at END
+		WHERE id = $1
+		RETURNING processed_count, total_count, status, export_url, export_format, callback_url
+	`
+
+func scanJobProgress(row pgx.Row) (jobProgress, error) {
+	var p jobProgress
+	err := row.Scan(&p.processed, &p.total, &p.status, &p.exportURL, &p.exportFormat, &p.callbackURL)
+	return p, err
+}
+
+// writeResult stores one result and counts it towards its job in a
+// transaction of its own.
+func writeResult(ctx context.Context, r pendingResult) (jobProgress, error) {
+	workerID, task, parts := r.workerID, r.task, r.parts
+
 	tx, err := store.DB.Begin(ctx)
 	if err != nil {
 		log.Printf("[Worker %d] ❌ DB transaction error for %s: %v", workerID, task.Email, err)
-		return
+		return jobProgress{}, err
 	}
 	// Rollback is a no-op if Commit succeeds, so it is always safe to defer.
 	defer tx.Rollback(ctx)
 
 	_, err = tx.Exec(ctx, `
-		INSERT INTO results (job_id, email, score, data)
-		VALUES ($1, $2, $3, $4)
-	`, task.JobID, task.Email, parts.Score, resultJSON)
+		INSERT INTO results (job_id, email, score, status, reachability, data)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, task.JobID, task.Email, parts.Score, string(parts.Status), string(parts.Reachability), r.data)
 	if err != nil {
 		log.Printf("[Worker %d] ❌ Failed to insert result for %s: %v", workerID, task.Email, err)
-		return
+		return jobProgress{}, err
 	}
 
-	_, err = tx.Exec(ctx, `
-		UPDATE jobs
-		SET processed_count = processed_count + 1,
-		    status = CASE WHEN processed_count + 1 >= total_count THEN 'completed' ELSE status END,
-		    completed_at = CASE WHEN processed_count + 1 >= total_count THEN NOW() ELSE completed_at END
-		WHERE id = $1
-	`, task.JobID)
+	// RETURNING lets exactly one worker — the one whose increment reaches
+	// total_count — observe the job's completion and run the export and
+	// completion webhook.
+	progress, err := scanJobProgress(tx.QueryRow(ctx, jobProgressSQL, task.JobID, 1))
 	if err != nil {
 		log.Printf("[Worker %d] ❌ Failed to update job progress for %s: %v", workerID, task.Email, err)
-		return
+		return jobProgress{}, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		log.Printf("[Worker %d] ❌ Failed to commit for %s: %v", workerID, task.Email, err)
-		return
+		return jobProgress{}, err
 	}
+	return progress, nil
+}
+
+// afterWrite runs the per-result side effects once r is safely stored.
+func afterWrite(ctx context.Context, r pendingResult) {
+	workerID, task, parts := r.workerID, r.task, r.parts
 
 	fmt.Printf("[Worker %d] ✅ Processed: %s (Score: %d)\n", workerID, task.Email, parts.Score)
+
+	sink.Publish(ctx, sink.Result{JobID: task.JobID, Email: task.Email, Score: parts.Score, Data: r.data})
+
+	// A cached verdict was already recorded when it was first verified.
+	if parts.Cached {
+		return
+	}
+
+	if err := store.RecordHistory(ctx, task.Email, string(parts.Status), parts.Score); err != nil {
+		log.Printf("[Worker %d] ⚠️  Failed to record history for %s: %v", workerID, task.Email, err)
+	}
+
+	calibration.MaybeCompare(ctx, task.Email, parts.Status)
+}
+
+// exports tracks export uploads still running, so Start can wait for them
+// on shutdown.
+var exports sync.WaitGroup
+
+// completeJob exports a job and fires its completion webhook when p is the
+// increment that finished it. An export can take minutes for a large job, so
+// it runs on a goroutine of its own and the webhook, which reports where the
+// export went, is sent once it is done.
+func completeJob(ctx context.Context, workerID int, jobID string, p jobProgress) {
+	if p.processed != p.total {
+		return
+	}
+
+	var callbackURL string
+	if p.status == "completed" && p.callbackURL != nil {
+		callbackURL = *p.callbackURL
+	}
+	payload := webhook.Payload{JobID: jobID, Status: p.status, TotalCount: p.total}
+
+	if p.exportURL == nil || *p.exportURL == "" {
+		if callbackURL != "" {
+			webhook.Enqueue(callbackURL, payload)
+		}
+		return
+	}
+
+	exportURL := *p.exportURL
+	format := export.FormatNDJSON
+	if p.exportFormat != nil {
+		format = export.ParseFormat(*p.exportFormat)
+	}
+	exports.Add(1)
+	go func() {
+		defer exports.Done()
+		// An export already under way finishes even if shutdown begins.
+		payload.ExportStatus = exportJob(context.WithoutCancel(ctx), workerID, jobID, exportURL, format)
+		if payload.ExportStatus == "uploaded" {
+			payload.ExportLocation = outbound.Location(exportURL)
+		}
+		if callbackURL != "" {
+			webhook.Enqueue(callbackURL, payload)
+		}
+	}()
+}
+
+// exportJob uploads every result of a completed job to its configured export
+// destination, records the outcome in jobs.export_status and returns it.
+func exportJob(ctx context.Context, workerID int, jobID, exportURL string, format export.Format) string {
+	src := func(yield func(export.Row) error) error {
+		rows, err := store.DB.Query(ctx, `
+			SELECT email, score, data
+			FROM   results
+			WHERE  job_id = $1
+			ORDER  BY id ASC
+		`, jobID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row export.Row
+			if err := rows.Scan(&row.Email, &row.Score, &row.Data); err != nil {
+				return err
+			}
+			if err := yield(row); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}
+
+	status := "uploaded"
+	if err := export.Upload(ctx, exportURL, format, src); err != nil {
+		log.Printf("[Worker %d] ❌ Export failed for job %s: %v", workerID, jobID, err)
+		status = "failed"
+	} else {
+		log.Printf("[Worker %d] 📦 Exported job %s (%s)", workerID, jobID, format)
+	}
+
+	if _, err := store.DB.Exec(ctx, `UPDATE jobs SET export_status = $2 WHERE id = $1`, jobID, status); err != nil {
+		log.Printf("[Worker %d] ❌ Failed to record export status for job %s: %v", workerID, jobID, err)
+	}
+	return status
 }
 
 // extractDomain returns the domain part of an email address.
@@ -159,3 +456,12 @@ func extractDomain(email string) string {
 	}
 	return ""
 }
+
+// storedResult encodes r in the shape StoreFullAnalysis selects for
+// results.data.
+func storedResult(r models.ValidationResult) ([]byte, error) {
+	if !StoreFullAnalysis {
+		return json.Marshal(validator.VerdictOf(r))
+	}
+	return json.Marshal(r)
+}
@@ -0,0 +1,204 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"mailvetter/internal/models"
+	"mailvetter/internal/queue"
+	"mailvetter/internal/store"
+	"mailvetter/internal/validator"
+)
+
+func TestProcessTaskSkipsCancelledJob(t *testing.T) {
+	savedCancelled, savedVerify := jobCancelled, verifyEmail
+	defer func() { jobCancelled, verifyEmail = savedCancelled, savedVerify }()
+
+	var checked string
+	jobCancelled = func(ctx context.Context, jobID string) (bool, error) {
+		checked = jobID
+		return true, nil
+	}
+	verifyEmail = func(ctx context.Context, email, domain string) (models.ValidationResult, error) {
+		t.Errorf("verification ran for %s despite the job being cancelled", email)
+		return models.ValidationResult{}, nil
+	}
+
+	// With the job cancelled, processTask must return before touching Redis
+	// (heartbeat) or Postgres (results), neither of which is set up here.
+	processTask(context.Background(), 1, queue.Task{JobID: "job-1", Email: "jane@example.com"})
+
+	if checked != "job-1" {
+		t.Errorf("expected the job status to be checked, got %q", checked)
+	}
+}
+
+func TestProcessTaskRetriesFailedVerification(t *testing.T) {
+	savedCancelled, savedVerify, savedRetry, savedDead, savedClient := jobCancelled, verifyEmail, retryTask, deadLetter, queue.Client
+	defer func() {
+		jobCancelled, verifyEmail, retryTask, deadLetter, queue.Client = savedCancelled, savedVerify, savedRetry, savedDead, savedClient
+	}()
+
+	// Heartbeats go to a Redis that is not there; processTask only logs that.
+	queue.Client = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer queue.Client.Close()
+
+	jobCancelled = func(ctx context.Context, jobID string) (bool, error) { return false, nil }
+	verifyEmail = func(ctx context.Context, email, domain string) (models.ValidationResult, error) {
+		return models.ValidationResult{Email: email, Status: models.StatusUnknown}, context.DeadlineExceeded
+	}
+	deadLetter = func(ctx context.Context, payload []byte) error {
+		t.Errorf("task dead-lettered with retries left: %s", payload)
+		return nil
+	}
+
+	var retried []queue.Task
+	var delays []time.Duration
+	retryTask = func(ctx context.Context, task queue.Task, delay time.Duration) error {
+		retried = append(retried, task)
+		delays = append(delays, delay)
+		return nil
+	}
+
+	// A scheduled retry returns before the result is written to Postgres,
+	// which is not set up here.
+	task := queue.Task{JobID: "job-1", Email: "jane@example.com"}
+	for i := 0; i < MaxTaskRetries; i++ {
+		processTask(context.Background(), 1, task)
+		if len(retried) != i+1 {
+			t.Fatalf("attempt %d was not retried", i+1)
+		}
+		task = retried[i]
+	}
+
+	for i, task := range retried {
+		if task.Attempts != i+1 {
+			t.Errorf("retry %d carries attempts=%d", i+1, task.Attempts)
+		}
+		if want := TaskRetryBackoff << i; delays[i] != want {
+			t.Errorf("retry %d delayed %s, want %s", i+1, delays[i], want)
+		}
+	}
+}
+
+func TestFleetConsumesOnlyItsRoutedTasks(t *testing.T) {
+	savedPop, savedCancelled, savedFleet, savedRoutes, savedClient := popTask, jobCancelled, Fleet, queue.Routes, queue.Client
+	defer func() {
+		popTask, jobCancelled, Fleet, queue.Routes, queue.Client = savedPop, savedCancelled, savedFleet, savedRoutes, savedClient
+	}()
+	queue.Client = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer queue.Client.Close()
+	queue.Routes = queue.ParseRoutes([]string{"bulk=a"})
+
+	// An in-memory stand-in for the Redis lists, holding one task of class
+	// "bulk".
+	fleet, ok := queue.Route("bulk")
+	if !ok || fleet != "a" {
+		t.Fatalf("Route(bulk) = %q, %v", fleet, ok)
+	}
+	data, _ := json.Marshal(queue.Task{JobID: "job-bulk", Email: "jane@example.com", Fleet: fleet})
+	var mu sync.Mutex
+	lists := map[string][]string{queue.QueueFor(fleet): {string(data)}}
+	popTask = func(ctx context.Context, list string, timeout time.Duration) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if items := lists[list]; len(items) > 0 {
+			lists[list] = items[1:]
+			return items[0], nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		time.Sleep(time.Millisecond)
+		return "", queue.ErrNil
+	}
+
+	// The job reads as cancelled so a consumed task stops before Postgres.
+	var consumedBy []string
+	jobCancelled = func(ctx context.Context, jobID string) (bool, error) {
+		consumedBy = append(consumedBy, Fleet)
+		return true, nil
+	}
+
+	run := func(name string) {
+		Fleet = name
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Start(ctx, 2)
+	}
+
+	run("b")
+	if len(consumedBy) != 0 {
+		t.Fatalf("fleet b consumed a task routed to fleet a")
+	}
+	run("")
+	if len(consumedBy) != 0 {
+		t.Fatalf("the default fleet consumed a task routed to fleet a")
+	}
+	run("a")
+	if len(consumedBy) != 1 || consumedBy[0] != "a" {
+		t.Errorf("expected fleet a to consume its task once, got %v", consumedBy)
+	}
+}
+
+func TestVerdictOnlyStorage(t *testing.T) {
+	saved := StoreFullAnalysis
+	defer func() { StoreFullAnalysis = saved }()
+
+	result := models.ValidationResult{
+		Email:          "jane@example.com",
+		Score:          92,
+		ScoreBreakdown: map[string]float64{"smtp_valid": 60, "p3_mx_redundancy": 2},
+		Status:         models.StatusValid,
+		Reachability:   models.ReachabilitySafe,
+		Analysis:       models.RiskAnalysis{SmtpStatus: 250, HasSPF: true},
+	}
+
+	StoreFullAnalysis = false
+	data, err := storedResult(result)
+	if err != nil {
+		t.Fatalf("storedResult: %v", err)
+	}
+
+	// The row goes out through /results exactly as stored.
+	page, _ := json.Marshal([]store.ResultRow{{ID: 1, Email: result.Email, Score: result.Score, Data: data}})
+	var rows []struct {
+		Email string          `json:"email"`
+		Score int             `json:"score"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(page, &rows); err != nil {
+		t.Fatalf("decode page: %v", err)
+	}
+	var got map[string]any
+	json.Unmarshal(rows[0].Data, &got)
+	if got["status"] != "valid" || got["reachability"] != "safe" || got["score"] != float64(92) {
+		t.Errorf("verdict-only row lost its verdict: %s", rows[0].Data)
+	}
+	if codes, _ := got["reason_codes"].([]any); len(codes) != 2 || codes[0] != "p3_mx_redundancy" {
+		t.Errorf("reason_codes = %v, want the sorted score_details keys", got["reason_codes"])
+	}
+	if _, ok := got["analysis"]; ok {
+		t.Errorf("verdict-only row still carries the analysis: %s", rows[0].Data)
+	}
+
+	if _, err := validator.Rescore(rows[0].Data); !errors.Is(err, validator.ErrVerdictOnly) {
+		t.Errorf("Rescore of a verdict-only row: err = %v, want ErrVerdictOnly", err)
+	}
+
+	StoreFullAnalysis = true
+	full, _ := storedResult(result)
+	rescored, err := validator.Rescore(full)
+	if err != nil {
+		t.Fatalf("Rescore of a full row: %v", err)
+	}
+	if rescored.Email != result.Email || rescored.Analysis.SmtpStatus != 250 {
+		t.Errorf("Rescore dropped stored fields: %+v", rescored)
+	}
+}